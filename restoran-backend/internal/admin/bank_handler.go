@@ -4,9 +4,9 @@ import (
 	"fmt"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -41,86 +41,6 @@ type BankAccountResponse struct {
 	UpdatedAt     string             `json:"updated_at"`
 }
 
-// branch_id çöz (branch_admin -> JWT, super_admin -> body/query)
-func resolveBranchIDFromBodyOrRole(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
-// Yardımcı: Kullanıcı bilgilerini al
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserRoleKey)
-	_, ok := userIDVal.(models.UserRole)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	userIDVal2 := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal2.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
 // POST /api/admin/bank-accounts
 func CreateBankAccountHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -137,7 +57,7 @@ func CreateBankAccountHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "name zorunlu")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -157,7 +77,7 @@ func CreateBankAccountHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &branchID,
@@ -190,7 +110,7 @@ func CreateBankAccountHandler() fiber.Handler {
 // GET /api/admin/bank-accounts
 func ListBankAccountsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -229,7 +149,7 @@ func UpdateBankAccountHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var account models.BankAccount
-		if err := database.DB.First(&account, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&account, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Hesap bulunamadı")
 		}
 
@@ -261,7 +181,7 @@ func UpdateBankAccountHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &account.BranchID,
@@ -297,7 +217,7 @@ func DeleteBankAccountHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var account models.BankAccount
-		if err := database.DB.First(&account, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&account, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Hesap bulunamadı")
 		}
 
@@ -313,7 +233,7 @@ func DeleteBankAccountHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &account.BranchID,
@@ -0,0 +1,435 @@
+package admin
+
+import (
+	"fmt"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreateBankTransactionRequest struct {
+	Type        models.TransactionType `json:"type"` // deposit / withdraw / payment
+	Amount      float64                `json:"amount"`
+	Date        *string                `json:"date"` // "2025-12-09" formatında, boşsa bugün
+	Description string                 `json:"description"`
+}
+
+type UpdateBankTransactionRequest struct {
+	Type        *models.TransactionType `json:"type"`
+	Amount      *float64                `json:"amount"`
+	Date        *string                 `json:"date"`
+	Description *string                 `json:"description"`
+}
+
+type BankTransactionResponse struct {
+	ID            uint                   `json:"id"`
+	BankAccountID uint                   `json:"bank_account_id"`
+	Type          models.TransactionType `json:"type"`
+	Amount        float64                `json:"amount"`
+	Date          string                 `json:"date"`
+	Description   string                 `json:"description"`
+	CreatedAt     string                 `json:"created_at"`
+}
+
+type BankStatementLine struct {
+	BankTransactionResponse
+	Balance float64 `json:"balance"` // işlem sonrası bakiye
+}
+
+type BankStatementResponse struct {
+	BankAccountID  uint                `json:"bank_account_id"`
+	AccountName    string              `json:"account_name"`
+	From           string              `json:"from"`
+	To             string              `json:"to"`
+	OpeningBalance float64             `json:"opening_balance"`
+	TotalIn        float64             `json:"total_in"`
+	TotalOut       float64             `json:"total_out"`
+	ClosingBalance float64             `json:"closing_balance"`
+	Transactions   []BankStatementLine `json:"transactions"`
+}
+
+func isValidTransactionType(t models.TransactionType) bool {
+	switch t {
+	case models.TransactionTypeDeposit, models.TransactionTypeWithdraw, models.TransactionTypePayment:
+		return true
+	}
+	return false
+}
+
+func toBankTransactionResponse(t models.BankTransaction) BankTransactionResponse {
+	return BankTransactionResponse{
+		ID:            t.ID,
+		BankAccountID: t.BankAccountID,
+		Type:          t.Type,
+		Amount:        t.Amount,
+		Date:          t.Date.Format("2006-01-02"),
+		Description:   t.Description,
+		CreatedAt:     t.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// Audit log için: BankAccount ilişkisini JSON'a koymamak için map kullan
+func bankTransactionLogData(t models.BankTransaction) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              t.ID,
+		"bank_account_id": t.BankAccountID,
+		"type":            t.Type,
+		"amount":          t.Amount,
+		"date":            t.Date,
+		"description":     t.Description,
+	}
+}
+
+// Yardımcı: URL'deki hesabı kullanıcının yetkili şubelerinde bul
+func loadBankAccountForRequest(c *fiber.Ctx) (models.BankAccount, error) {
+	var account models.BankAccount
+	if err := tenancy.DB(c).First(&account, "id = ?", c.Params("id")).Error; err != nil {
+		return account, fiber.NewError(fiber.StatusNotFound, "Hesap bulunamadı")
+	}
+	return account, nil
+}
+
+// Bakiyeyi aynı transaction içinde artır/azalt
+func adjustBankBalance(tx *gorm.DB, accountID uint, delta float64) error {
+	return tx.Model(&models.BankAccount{}).
+		Where("id = ?", accountID).
+		UpdateColumn("balance", gorm.Expr("balance + ?", delta)).Error
+}
+
+// POST /api/bank-accounts/:id/transactions
+func CreateBankTransactionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account, err := loadBankAccountForRequest(c)
+		if err != nil {
+			return err
+		}
+
+		var body CreateBankTransactionRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		if !isValidTransactionType(body.Type) {
+			return fiber.NewError(fiber.StatusBadRequest, "type 'deposit', 'withdraw' veya 'payment' olmalı")
+		}
+		if body.Amount <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Tutar 0'dan büyük olmalı")
+		}
+
+		var date time.Time
+		if body.Date == nil || *body.Date == "" {
+			now := time.Now()
+			date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		} else {
+			d, err := time.Parse("2006-01-02", *body.Date)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı geçersiz, 'YYYY-MM-DD' olmalı")
+			}
+			date = d
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(account.BranchID, date); err != nil {
+			return err
+		}
+
+		txn := models.BankTransaction{
+			BankAccountID: account.ID,
+			Type:          body.Type,
+			Amount:        body.Amount,
+			Date:          date,
+			Description:   body.Description,
+		}
+
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&txn).Error; err != nil {
+				return err
+			}
+			return adjustBankBalance(tx, account.ID, txn.BalanceEffect())
+		}); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşlem kaydedilemedi")
+		}
+
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &account.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "bank_transaction",
+				EntityID:    txn.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Hesap işlemi eklendi: %s - %s %.2f TL", account.Name, txn.Type, txn.Amount),
+				Before:      nil,
+				After:       bankTransactionLogData(txn),
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(toBankTransactionResponse(txn))
+	}
+}
+
+// GET /api/bank-accounts/:id/transactions?from=2025-12-01&to=2025-12-31
+func ListBankTransactionsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account, err := loadBankAccountForRequest(c)
+		if err != nil {
+			return err
+		}
+
+		dbq := database.DB.Model(&models.BankTransaction{}).
+			Where("bank_account_id = ?", account.ID)
+
+		if fromStr := c.Query("from"); fromStr != "" {
+			from, err := time.Parse("2006-01-02", fromStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "from geçersiz")
+			}
+			dbq = dbq.Where("date >= ?", from)
+		}
+		if toStr := c.Query("to"); toStr != "" {
+			to, err := time.Parse("2006-01-02", toStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "to geçersiz")
+			}
+			dbq = dbq.Where("date <= ?", to)
+		}
+
+		var txns []models.BankTransaction
+		if err := dbq.Order("date DESC, id DESC").Find(&txns).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşlemler listelenemedi")
+		}
+
+		resp := make([]BankTransactionResponse, 0, len(txns))
+		for _, t := range txns {
+			resp = append(resp, toBankTransactionResponse(t))
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// PUT /api/bank-accounts/:id/transactions/:txId
+func UpdateBankTransactionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account, err := loadBankAccountForRequest(c)
+		if err != nil {
+			return err
+		}
+
+		var txn models.BankTransaction
+		if err := database.DB.
+			Where("id = ? AND bank_account_id = ?", c.Params("txId"), account.ID).
+			First(&txn).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
+		}
+
+		var body UpdateBankTransactionRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		oldTxn := txn
+
+		if body.Type != nil {
+			if !isValidTransactionType(*body.Type) {
+				return fiber.NewError(fiber.StatusBadRequest, "type 'deposit', 'withdraw' veya 'payment' olmalı")
+			}
+			txn.Type = *body.Type
+		}
+		if body.Amount != nil {
+			if *body.Amount <= 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "Tutar 0'dan büyük olmalı")
+			}
+			txn.Amount = *body.Amount
+		}
+		if body.Date != nil {
+			d, err := time.Parse("2006-01-02", *body.Date)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı geçersiz, 'YYYY-MM-DD' olmalı")
+			}
+			txn.Date = d
+		}
+		if body.Description != nil {
+			txn.Description = *body.Description
+		}
+
+		// Kapalı döneme ait kayıt değiştirilemez (eski ve yeni tarih)
+		if err := period.EnsureOpen(account.BranchID, oldTxn.Date, txn.Date); err != nil {
+			return err
+		}
+
+		// Eski etkiyi geri al, yenisini uygula
+		delta := txn.BalanceEffect() - oldTxn.BalanceEffect()
+
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.BankTransaction{}).Where("id = ?", txn.ID).Updates(map[string]interface{}{
+				"type":        txn.Type,
+				"amount":      txn.Amount,
+				"date":        txn.Date,
+				"description": txn.Description,
+			}).Error; err != nil {
+				return err
+			}
+			if delta == 0 {
+				return nil
+			}
+			return adjustBankBalance(tx, account.ID, delta)
+		}); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşlem güncellenemedi")
+		}
+
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &account.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "bank_transaction",
+				EntityID:    txn.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Hesap işlemi güncellendi: %s - %s %.2f TL", account.Name, txn.Type, txn.Amount),
+				Before:      bankTransactionLogData(oldTxn),
+				After:       bankTransactionLogData(txn),
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.JSON(toBankTransactionResponse(txn))
+	}
+}
+
+// DELETE /api/bank-accounts/:id/transactions/:txId
+func DeleteBankTransactionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account, err := loadBankAccountForRequest(c)
+		if err != nil {
+			return err
+		}
+
+		var txn models.BankTransaction
+		if err := database.DB.
+			Where("id = ? AND bank_account_id = ?", c.Params("txId"), account.ID).
+			First(&txn).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
+		}
+
+		// Kapalı dönemdeki kayıt silinemez
+		if err := period.EnsureOpen(account.BranchID, txn.Date); err != nil {
+			return err
+		}
+
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&models.BankTransaction{}, "id = ?", txn.ID).Error; err != nil {
+				return err
+			}
+			return adjustBankBalance(tx, account.ID, -txn.BalanceEffect())
+		}); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşlem silinemedi")
+		}
+
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &account.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "bank_transaction",
+				EntityID:    txn.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Hesap işlemi silindi: %s - %s %.2f TL", account.Name, txn.Type, txn.Amount),
+				Before:      bankTransactionLogData(txn),
+				After:       nil,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// GET /api/bank-accounts/:id/statement?from=2025-12-01&to=2025-12-31
+func BankAccountStatementHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account, err := loadBankAccountForRequest(c)
+		if err != nil {
+			return err
+		}
+
+		fromStr := c.Query("from")
+		toStr := c.Query("to")
+		if fromStr == "" || toStr == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "from ve to zorunlu")
+		}
+		from, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "from geçersiz")
+		}
+		to, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "to geçersiz")
+		}
+		if to.Before(from) {
+			return fiber.NewError(fiber.StatusBadRequest, "to, from'dan önce olamaz")
+		}
+
+		// Bakiye sadece güncel değer olarak tutuluyor; açılış bakiyesi,
+		// from tarihinden sonraki tüm işlemlerin etkisi geri alınarak bulunur.
+		var later []models.BankTransaction
+		if err := database.DB.
+			Where("bank_account_id = ? AND date >= ?", account.ID, from).
+			Order("date ASC, id ASC").
+			Find(&later).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşlemler okunamadı")
+		}
+
+		opening := account.Balance
+		for _, t := range later {
+			opening -= t.BalanceEffect()
+		}
+
+		resp := BankStatementResponse{
+			BankAccountID:  account.ID,
+			AccountName:    account.Name,
+			From:           fromStr,
+			To:             toStr,
+			OpeningBalance: opening,
+			Transactions:   make([]BankStatementLine, 0),
+		}
+
+		balance := opening
+		for _, t := range later {
+			if t.Date.After(to) {
+				break
+			}
+			effect := t.BalanceEffect()
+			balance += effect
+			if effect >= 0 {
+				resp.TotalIn += effect
+			} else {
+				resp.TotalOut += -effect
+			}
+			resp.Transactions = append(resp.Transactions, BankStatementLine{
+				BankTransactionResponse: toBankTransactionResponse(t),
+				Balance:                 balance,
+			})
+		}
+		resp.ClosingBalance = balance
+
+		return c.JSON(resp)
+	}
+}
@@ -230,6 +230,9 @@ func CreateBranchAdminHandler() fiber.Handler {
 		if err := database.DB.Create(&user).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Şube admini oluşturulamadı")
 		}
+		if err := database.DB.Create(&models.UserBranch{UserID: user.ID, BranchID: branch.ID}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Şube yetkisi oluşturulamadı")
+		}
 
 		// NOT: Şifre sadece oluşturma sırasında bir kez döndürülür (güvenlik)
 		// Sonraki isteklerde şifre hash'lenmiş olarak saklanır ve geri dönüştürülemez
@@ -3,20 +3,28 @@ package admin
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"restoran-backend/internal/audit"
 	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type CreateMonthlyReportRequest struct {
 	Year  int `json:"year"`
 	Month int `json:"month"`
 	BranchID *uint `json:"branch_id"` // super_admin için
+	// Stok değerleme yöntemi: "fifo" veya "weighted_average". Verilirse satılan malın maliyeti
+	// sevkiyat toplamı yerine alımlar (sevkiyat + merkez sevkiyatı + gelen - giden transfer)
+	// + açılış stoğu - kapanış stoğu olarak hesaplanır.
+	InventoryMethod string `json:"inventory_method"`
 }
 
 type MonthlyReportResponse struct {
@@ -29,91 +37,15 @@ type MonthlyReportResponse struct {
 	TotalExpenses float64 `json:"total_expenses"`
 	TotalShipments float64 `json:"total_shipments"`
 	NetProfit   float64 `json:"net_profit"`
+	InventoryMethod       string  `json:"inventory_method,omitempty"`
+	OpeningInventoryValue float64 `json:"opening_inventory_value"`
+	ClosingInventoryValue float64 `json:"closing_inventory_value"`
+	IsClosed    bool    `json:"is_closed"` // dönem kilitli mi?
 	CreatedAt   string  `json:"created_at"`
 }
 
-// resolveBranchIDFromBodyOrRole: branch_id'yi body'den veya role'den çöz
-func resolveBranchIDFromBodyOrRoleForReport(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-func resolveBranchIDFromQueryOrRoleForReport(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
-// getUserInfo: Kullanıcı bilgilerini al
-func getUserInfoForReport(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserRoleKey)
-	_, ok := userIDVal.(models.UserRole)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	userIDVal2 := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal2.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
 // POST /api/admin/monthly-reports
-// Aylık rapor oluştur ve verileri sıfırla
+// Aylık rapor oluştur ve dönemi kapat (veriler silinmez, sadece kilitlenir)
 func CreateMonthlyReportHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var body CreateMonthlyReportRequest
@@ -124,20 +56,35 @@ func CreateMonthlyReportHandler() fiber.Handler {
 		if body.Year < 2000 || body.Month < 1 || body.Month > 12 {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz yıl veya ay")
 		}
+		switch body.InventoryMethod {
+		case "", ledger.ValuationFIFO, ledger.ValuationWeightedAverage:
+		default:
+			return fiber.NewError(fiber.StatusBadRequest, "inventory_method 'fifo' veya 'weighted_average' olmalı")
+		}
 
-		branchID, err := resolveBranchIDFromBodyOrRoleForReport(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
 
-		// Bu ay için rapor zaten var mı kontrol et
-		var existingReport models.MonthlyReport
-		err = database.DB.Where("branch_id = ? AND year = ? AND month = ?", branchID, body.Year, body.Month).
-			First(&existingReport).Error
-		if err == nil {
-			return fiber.NewError(fiber.StatusBadRequest, "Bu ay için rapor zaten oluşturulmuş")
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
 		}
 
+		// Dönem zaten kapalı mı kontrol et
+		var lock models.PeriodLock
+		lockErr := database.DB.Where("branch_id = ? AND year = ? AND month = ?", branchID, body.Year, body.Month).
+			First(&lock).Error
+		if lockErr == nil && lock.IsClosed {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu dönem zaten kapatılmış")
+		}
+
+		// Yeniden açılmış bir dönem tekrar kapatılıyorsa mevcut rapor güncellenir
+		var report models.MonthlyReport
+		reportExists := database.DB.Where("branch_id = ? AND year = ? AND month = ?", branchID, body.Year, body.Month).
+			First(&report).Error == nil
+
 		loc := time.Now().Location()
 		firstDay := time.Date(body.Year, time.Month(body.Month), 1, 0, 0, 0, 0, loc)
 		lastDay := firstDay.AddDate(0, 1, -1)
@@ -174,101 +121,203 @@ func CreateMonthlyReportHandler() fiber.Handler {
 			"expenses":       expenses,
 			"shipments":      shipments,
 		}
+
+		// Stok değeri: ay içinde tüketilmeyen alımlar maliyetten düşülür, önceki aydan devreden stok eklenir
+		var openingValue, closingValue float64
+		if body.InventoryMethod != "" {
+			opening, err := ledger.ValueStock(database.DB, branchID, firstDay)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Açılış stok değeri hesaplanamadı")
+			}
+			closing, err := ledger.ValueStock(database.DB, branchID, firstDay.AddDate(0, 1, 0))
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Kapanış stok değeri hesaplanamadı")
+			}
+			for _, v := range opening {
+				openingValue += v.Value(body.InventoryMethod)
+			}
+			for _, v := range closing {
+				closingValue += v.Value(body.InventoryMethod)
+			}
+			openingValue = math.Round(openingValue*100) / 100
+			closingValue = math.Round(closingValue*100) / 100
+
+			// Alımlar stok değeriyle aynı bazda: sevkiyat tutarları KDV'li (stok değeri de KDV'li birim
+			// fiyatla), merkez sevkiyatları ve transferler stok değerindeki maliyetleriyle
+			flows, err := ledger.PeriodPurchaseCosts(database.DB, branchID, firstDay, firstDay.AddDate(0, 1, 0))
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Merkez sevkiyatı ve transfer maliyetleri hesaplanamadı")
+			}
+			purchases := math.Round((totalShipments+flows.Net())*100) / 100
+
+			costOfGoods := purchases + openingValue - closingValue
+			netProfit = totalRevenue - totalExpenses - costOfGoods
+			reportData["inventory"] = map[string]interface{}{
+				"method":           body.InventoryMethod,
+				"opening_value":    openingValue,
+				"closing_value":    closingValue,
+				"center_shipments": flows.CenterShipments,
+				"transfers_in":     flows.TransfersIn,
+				"transfers_out":    flows.TransfersOut,
+				"purchases":        purchases,
+				"cost_of_goods":    costOfGoods,
+			}
+		}
 		reportDataJSON, _ := json.Marshal(reportData)
 
-		// Rapor oluştur
-		report := models.MonthlyReport{
-			BranchID:      branchID,
-			Year:          body.Year,
-			Month:         body.Month,
-			ReportDate:    time.Now(),
-			TotalRevenue:  totalRevenue,
-			TotalExpenses: totalExpenses,
-			TotalShipments: totalShipments,
-			NetProfit:     netProfit,
-			ReportData:    string(reportDataJSON),
+		// Yeniden kapatmada raporun önceki hali log'a "önce" olarak yazılır
+		var before interface{}
+		if reportExists {
+			before = monthlyReportAuditData(&report)
 		}
 
-		if err := database.DB.Create(&report).Error; err != nil {
+		now := time.Now()
+		report.BranchID = branchID
+		report.Year = body.Year
+		report.Month = body.Month
+		report.ReportDate = now
+		report.TotalRevenue = totalRevenue
+		report.TotalExpenses = totalExpenses
+		report.TotalShipments = totalShipments
+		report.NetProfit = netProfit
+		report.InventoryMethod = body.InventoryMethod
+		report.OpeningInventoryValue = openingValue
+		report.ClosingInventoryValue = closingValue
+		report.ReportData = string(reportDataJSON)
+
+		// Rapor ve dönem kilidi aynı transaction içinde yazılır
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&report).Error; err != nil {
+				return err
+			}
+
+			if lockErr == nil {
+				// Yeniden açılmış dönem: kilidi tekrar kapat
+				lock.IsClosed = true
+				lock.ClosedBy = userID
+				lock.ClosedAt = now
+				return tx.Save(&lock).Error
+			}
+
+			lock = models.PeriodLock{
+				BranchID: branchID,
+				Year:     body.Year,
+				Month:    body.Month,
+				IsClosed: true,
+				ClosedBy: userID,
+				ClosedAt: now,
+			}
+			return tx.Create(&lock).Error
+		}); err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Rapor oluşturulamadı")
 		}
 
-		// Verileri sıfırla (sil)
-		// Transaction içinde yap
-		tx := database.DB.Begin()
-		defer func() {
-			if r := recover(); r != nil {
-				tx.Rollback()
-			}
-		}()
+		// Audit log
+		action := models.AuditActionCreate
+		if reportExists {
+			action = models.AuditActionUpdate
+		}
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    &branchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "monthly_report",
+			EntityID:    report.ID,
+			Action:      action,
+			Description: fmt.Sprintf("Aylık rapor oluşturuldu ve dönem kapatıldı: %d/%d", body.Month, body.Year),
+			Before:      before,
+			After:       monthlyReportAuditData(&report),
+		})
+
+		return c.Status(fiber.StatusCreated).JSON(MonthlyReportResponse{
+			ID:             report.ID,
+			BranchID:       report.BranchID,
+			Year:           report.Year,
+			Month:          report.Month,
+			ReportDate:     report.ReportDate.Format("2006-01-02 15:04:05"),
+			TotalRevenue:   report.TotalRevenue,
+			TotalExpenses:  report.TotalExpenses,
+			TotalShipments: report.TotalShipments,
+			NetProfit:      report.NetProfit,
+			InventoryMethod:       report.InventoryMethod,
+			OpeningInventoryValue: report.OpeningInventoryValue,
+			ClosingInventoryValue: report.ClosingInventoryValue,
+			IsClosed:       true,
+			CreatedAt:      report.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+}
 
-		// Cash movements sil
-		if err := tx.Where("branch_id = ? AND date >= ? AND date <= ?", branchID, firstDay, lastDay).
-			Delete(&models.CashMovement{}).Error; err != nil {
-			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+// POST /api/admin/monthly-reports/:id/reopen
+// Kapatılmış dönemi yeniden aç (sadece super_admin; monthly_report:write izni özel rollere de
+// verilebildiği için rol ayrıca kontrol edilir)
+func ReopenMonthlyReportHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if role, _ := c.Locals(auth.CtxUserRoleKey).(models.UserRole); role != models.RoleSuperAdmin {
+			return fiber.NewError(fiber.StatusForbidden, "Dönemi sadece super admin yeniden açabilir")
 		}
 
-		// Expenses sil
-		if err := tx.Where("branch_id = ? AND date >= ? AND date <= ?", branchID, firstDay, lastDay).
-			Delete(&models.Expense{}).Error; err != nil {
-			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+		id := c.Params("id")
+
+		var report models.MonthlyReport
+		if err := tenancy.DB(c).First(&report, "id = ?", id).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Rapor bulunamadı")
 		}
 
-		// Shipments sil (items cascade ile silinir)
-		if err := tx.Where("branch_id = ? AND date >= ? AND date <= ?", branchID, firstDay, lastDay).
-			Delete(&models.Shipment{}).Error; err != nil {
-			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+		var lock models.PeriodLock
+		if err := database.DB.Where("branch_id = ? AND year = ? AND month = ?", report.BranchID, report.Year, report.Month).
+			First(&lock).Error; err != nil || !lock.IsClosed {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu dönem zaten açık")
 		}
 
-		// Stock entries sil
-		if err := tx.Where("branch_id = ? AND date >= ? AND date <= ?", branchID, firstDay, lastDay).
-			Delete(&models.StockEntry{}).Error; err != nil {
-			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
 		}
 
-		// Audit logs sil (opsiyonel - geriye dönük takip için tutulabilir)
-		// Şimdilik siliyoruz ama istenirse tutulabilir
-		if err := tx.Where("branch_id = ? AND created_at >= ? AND created_at <= ?", branchID, firstDay, lastDay.AddDate(0, 0, 1)).
-			Delete(&models.AuditLog{}).Error; err != nil {
-			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+		beforeData := map[string]interface{}{
+			"id":        lock.ID,
+			"branch_id": lock.BranchID,
+			"year":      lock.Year,
+			"month":     lock.Month,
+			"is_closed": lock.IsClosed,
+			"closed_by": lock.ClosedBy,
+			"closed_at": lock.ClosedAt,
 		}
 
-		if err := tx.Commit().Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Veriler sıfırlanamadı")
+		now := time.Now()
+		lock.IsClosed = false
+		lock.ReopenedBy = &userID
+		lock.ReopenedAt = &now
+
+		if err := database.DB.Save(&lock).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Dönem açılamadı")
 		}
 
-		// Audit log
-		userID, userName, _, err := getUserInfoForReport(c)
-		if err == nil {
-			_ = audit.WriteLog(audit.LogOptions{
-				BranchID:    &branchID,
-				UserID:      userID,
-				UserName:    userName,
-				EntityType:  "monthly_report",
-				EntityID:    report.ID,
-				Action:      models.AuditActionCreate,
-				Description: fmt.Sprintf("Aylık rapor oluşturuldu ve veriler sıfırlandı: %d/%d", body.Month, body.Year),
-				Before:      nil,
-				After:       report,
-			})
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    &lock.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "period_lock",
+			EntityID:    lock.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Dönem yeniden açıldı: %d/%d", lock.Month, lock.Year),
+			Before:      beforeData,
+			After: map[string]interface{}{
+				"id":          lock.ID,
+				"branch_id":   lock.BranchID,
+				"year":        lock.Year,
+				"month":       lock.Month,
+				"is_closed":   lock.IsClosed,
+				"reopened_by": userID,
+				"reopened_at": now,
+			},
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(MonthlyReportResponse{
-			ID:            report.ID,
-			BranchID:      report.BranchID,
-			Year:          report.Year,
-			Month:         report.Month,
-			ReportDate:    report.ReportDate.Format("2006-01-02 15:04:05"),
-			TotalRevenue:  report.TotalRevenue,
-			TotalExpenses: report.TotalExpenses,
-			TotalShipments: report.TotalShipments,
-			NetProfit:     report.NetProfit,
-			CreatedAt:     report.CreatedAt.Format("2006-01-02 15:04:05"),
+		return c.JSON(fiber.Map{
+			"message": "Dönem yeniden açıldı",
 		})
 	}
 }
@@ -277,7 +326,7 @@ func CreateMonthlyReportHandler() fiber.Handler {
 // Raporları listele
 func ListMonthlyReportsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRoleForReport(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -290,6 +339,14 @@ func ListMonthlyReportsHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Raporlar listelenemedi")
 		}
 
+		// Dönem kilit durumları
+		var locks []models.PeriodLock
+		database.DB.Where("branch_id = ?", branchID).Find(&locks)
+		closed := make(map[[2]int]bool, len(locks))
+		for _, l := range locks {
+			closed[[2]int{l.Year, l.Month}] = l.IsClosed
+		}
+
 		resp := make([]MonthlyReportResponse, 0, len(reports))
 		for _, r := range reports {
 			resp = append(resp, MonthlyReportResponse{
@@ -302,6 +359,10 @@ func ListMonthlyReportsHandler() fiber.Handler {
 				TotalExpenses: r.TotalExpenses,
 				TotalShipments: r.TotalShipments,
 				NetProfit:     r.NetProfit,
+				InventoryMethod:       r.InventoryMethod,
+				OpeningInventoryValue: r.OpeningInventoryValue,
+				ClosingInventoryValue: r.ClosingInventoryValue,
+				IsClosed:      closed[[2]int{r.Year, r.Month}],
 				CreatedAt:     r.CreatedAt.Format("2006-01-02 15:04:05"),
 			})
 		}
@@ -317,21 +378,10 @@ func GetMonthlyReportHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var report models.MonthlyReport
-		if err := database.DB.First(&report, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&report, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Rapor bulunamadı")
 		}
 
-		// Branch kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if ok && bPtr != nil && *bPtr != report.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu rapora erişim yetkiniz yok")
-			}
-		}
-
 		var reportData map[string]interface{}
 		if report.ReportData != "" {
 			if err := json.Unmarshal([]byte(report.ReportData), &reportData); err != nil {
@@ -343,6 +393,10 @@ func GetMonthlyReportHandler() fiber.Handler {
 			reportData = make(map[string]interface{})
 		}
 
+		var lock models.PeriodLock
+		isClosed := database.DB.Where("branch_id = ? AND year = ? AND month = ?", report.BranchID, report.Year, report.Month).
+			First(&lock).Error == nil && lock.IsClosed
+
 		return c.JSON(fiber.Map{
 			"id":             report.ID,
 			"branch_id":     report.BranchID,
@@ -353,9 +407,31 @@ func GetMonthlyReportHandler() fiber.Handler {
 			"total_expenses": report.TotalExpenses,
 			"total_shipments": report.TotalShipments,
 			"net_profit":     report.NetProfit,
+			"inventory_method":        report.InventoryMethod,
+			"opening_inventory_value": report.OpeningInventoryValue,
+			"closing_inventory_value": report.ClosingInventoryValue,
+			"is_closed":      isClosed,
 			"report_data":    reportData,
 			"created_at":     report.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 }
 
+// monthlyReportAuditData - Audit log'a yazılan rapor alanları
+func monthlyReportAuditData(report *models.MonthlyReport) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      report.ID,
+		"branch_id":               report.BranchID,
+		"year":                    report.Year,
+		"month":                   report.Month,
+		"report_date":             report.ReportDate,
+		"total_revenue":           report.TotalRevenue,
+		"total_expenses":          report.TotalExpenses,
+		"total_shipments":         report.TotalShipments,
+		"net_profit":              report.NetProfit,
+		"inventory_method":        report.InventoryMethod,
+		"opening_inventory_value": report.OpeningInventoryValue,
+		"closing_inventory_value": report.ClosingInventoryValue,
+		"report_data":             report.ReportData,
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Şifre işlemleri /api/auth altında yayınlanır; audit kaydı yazabilmek için burada
+// tanımlıdır (audit paketi auth'u import ettiğinden auth, audit'i kullanamaz).
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// POST /api/auth/change-password
+// Kullanıcı kendi şifresini değiştirir. Tüm oturumları (diğer cihazlar dahil) kapatılır,
+// bu istek için yeni bir oturum açılıp token çifti döner.
+func ChangePasswordHandler(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals(auth.CtxUserIDKey).(uint)
+		if !ok {
+			return fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
+		}
+
+		var body ChangePasswordRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		if body.CurrentPassword == "" || body.NewPassword == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Mevcut ve yeni şifre zorunlu")
+		}
+
+		var user models.User
+		if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Kullanıcı bulunamadı")
+		}
+		if !auth.CheckPassword(&user, body.CurrentPassword) {
+			return fiber.NewError(fiber.StatusBadRequest, "Mevcut şifre hatalı")
+		}
+		if body.CurrentPassword == body.NewPassword {
+			return fiber.NewError(fiber.StatusBadRequest, "Yeni şifre mevcut şifreden farklı olmalı")
+		}
+
+		hash, err := auth.HashPassword(body.NewPassword)
+		if err != nil {
+			return err
+		}
+
+		var revoked int64
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			n, err := auth.SetPassword(tx, user.ID, hash)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Şifre güncellenemedi")
+			}
+			revoked = n
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    user.BranchID,
+			UserID:      user.ID,
+			UserName:    user.Name,
+			EntityType:  "user",
+			EntityID:    user.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Şifre değiştirildi: %s (%d oturum kapatıldı)", user.Email, revoked),
+			Before:      nil,
+			After:       nil,
+		})
+
+		// Yeni token sürümüyle oturum aç
+		if err := database.DB.First(&user, "id = ?", user.ID).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı okunamadı")
+		}
+		pair, err := auth.StartSession(cfg, c, &user)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Token oluşturulamadı")
+		}
+		return c.JSON(pair)
+	}
+}
+
+// POST /api/auth/reset-password
+// Admin'in verdiği tek kullanımlık token ile yeni şifre belirlenir (giriş gerektirmez).
+// Kullanıcının tüm oturumları kapatılır, yeni şifreyle tekrar giriş yapması gerekir.
+func ResetPasswordHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body ResetPasswordRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		body.Token = strings.TrimSpace(body.Token)
+		if body.Token == "" || body.NewPassword == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "token ve new_password zorunlu")
+		}
+
+		var user models.User
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			u, err := auth.ConsumePasswordReset(tx, body.Token, body.NewPassword)
+			if err != nil {
+				return err
+			}
+			user = u
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    user.BranchID,
+			UserID:      user.ID,
+			UserName:    user.Name,
+			EntityType:  "user",
+			EntityID:    user.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Şifre sıfırlama token'ı ile şifre belirlendi: %s", user.Email),
+			Before:      nil,
+			After:       nil,
+		})
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,355 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Rol adı User.Role kolonuna yazılır (size:20)
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+type PermissionResponse struct {
+	Key         string `json:"key"` // "stock:write"
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	IsSystem    bool     `json:"is_system"`
+	Permissions []string `json:"permissions"`
+	UserCount   int64    `json:"user_count"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	Label       *string   `json:"label"`
+	Description *string   `json:"description"`
+	Permissions *[]string `json:"permissions"` // verilirse izinlerin tamamı bu liste ile değiştirilir
+}
+
+// GET /api/admin/permissions
+// Rollere atanabilecek tüm izinler
+func ListPermissionsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res := make([]PermissionResponse, 0, len(models.PermissionCatalog))
+		for _, p := range models.PermissionCatalog {
+			res = append(res, PermissionResponse{
+				Key:         p.Permission.String(),
+				Resource:    p.Permission.Resource,
+				Action:      p.Permission.Action,
+				Description: p.Description,
+			})
+		}
+		return c.JSON(res)
+	}
+}
+
+// GET /api/admin/roles
+func ListRolesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var roles []models.Role
+		if err := database.DB.Order("is_system DESC, name ASC").Find(&roles).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Roller listelenemedi")
+		}
+
+		res := make([]RoleResponse, 0, len(roles))
+		for _, r := range roles {
+			resp, err := toRoleResponse(r)
+			if err != nil {
+				return err
+			}
+			res = append(res, resp)
+		}
+		return c.JSON(res)
+	}
+}
+
+// POST /api/admin/roles
+func CreateRoleHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body CreateRoleRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri gönderildi")
+		}
+
+		body.Name = strings.ToLower(strings.TrimSpace(body.Name))
+		body.Label = strings.TrimSpace(body.Label)
+		if !roleNamePattern.MatchString(body.Name) {
+			return fiber.NewError(fiber.StatusBadRequest, "Rol adı küçük harfle başlamalı; sadece küçük harf, rakam ve _ içerebilir (en fazla 20 karakter)")
+		}
+		if body.Label == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Rol görünen adı zorunlu")
+		}
+		perms, err := parsePermissions(body.Permissions)
+		if err != nil {
+			return err
+		}
+
+		var exist models.Role
+		if err := database.DB.Where("name = ?", body.Name).First(&exist).Error; err == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu rol adı zaten kayıtlı")
+		}
+
+		role := models.Role{
+			Name:        models.UserRole(body.Name),
+			Label:       body.Label,
+			Description: strings.TrimSpace(body.Description),
+		}
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&role).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Rol oluşturulamadı")
+			}
+			return replaceRolePermissions(tx, role.Name, perms)
+		})
+		if err != nil {
+			return err
+		}
+		auth.InvalidatePermissions()
+
+		resp, err := toRoleResponse(role)
+		if err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "role",
+				EntityID:    role.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Rol eklendi: %s (%d izin)", role.Name, len(perms)),
+				Before:      nil,
+				After:       resp,
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(resp)
+	}
+}
+
+// PUT /api/admin/roles/:name
+// Super admin rolü düzenlenemez (tüm izinlere sahiptir)
+func UpdateRoleHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, err := findRole(c.Params("name"))
+		if err != nil {
+			return err
+		}
+		if role.Name == models.RoleSuperAdmin {
+			return fiber.NewError(fiber.StatusBadRequest, "Super admin rolü düzenlenemez")
+		}
+
+		var body UpdateRoleRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri gönderildi")
+		}
+
+		before, err := toRoleResponse(role)
+		if err != nil {
+			return err
+		}
+
+		if body.Label != nil {
+			label := strings.TrimSpace(*body.Label)
+			if label == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Rol görünen adı boş olamaz")
+			}
+			role.Label = label
+		}
+		if body.Description != nil {
+			role.Description = strings.TrimSpace(*body.Description)
+		}
+		var perms []models.Permission
+		if body.Permissions != nil {
+			if perms, err = parsePermissions(*body.Permissions); err != nil {
+				return err
+			}
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&role).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Rol güncellenemedi")
+			}
+			if body.Permissions == nil {
+				return nil
+			}
+			return replaceRolePermissions(tx, role.Name, perms)
+		})
+		if err != nil {
+			return err
+		}
+		auth.InvalidatePermissions()
+
+		resp, err := toRoleResponse(role)
+		if err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "role",
+				EntityID:    role.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Rol güncellendi: %s", role.Name),
+				Before:      before,
+				After:       resp,
+			})
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// DELETE /api/admin/roles/:name
+// Sistem rolleri ve kullanıcısı olan roller silinemez
+func DeleteRoleHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, err := findRole(c.Params("name"))
+		if err != nil {
+			return err
+		}
+		if role.IsSystem {
+			return fiber.NewError(fiber.StatusBadRequest, "Sistem rolleri silinemez")
+		}
+
+		before, err := toRoleResponse(role)
+		if err != nil {
+			return err
+		}
+		if before.UserCount > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Bu role atanmış %d kullanıcı var, önce kullanıcıların rolünü değiştirin", before.UserCount))
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("role = ?", role.Name).Delete(&models.RolePermission{}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Rol izinleri silinemedi")
+			}
+			if err := tx.Delete(&role).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Rol silinemedi")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		auth.InvalidatePermissions()
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "role",
+				EntityID:    role.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Rol silindi: %s", role.Name),
+				Before:      before,
+				After:       nil,
+			})
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func findRole(name string) (models.Role, error) {
+	var role models.Role
+	err := database.DB.Where("name = ?", strings.ToLower(strings.TrimSpace(name))).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return role, fiber.NewError(fiber.StatusNotFound, "Rol bulunamadı")
+	}
+	if err != nil {
+		return role, fiber.NewError(fiber.StatusInternalServerError, "Rol okunamadı")
+	}
+	return role, nil
+}
+
+// parsePermissions: "stock:read" biçimindeki izinleri katalogla doğrular (tekrarlar atılır)
+func parsePermissions(keys []string) ([]models.Permission, error) {
+	catalog := make(map[string]models.Permission, len(models.PermissionCatalog))
+	for _, p := range models.PermissionCatalog {
+		catalog[p.Permission.String()] = p.Permission
+	}
+
+	perms := make([]models.Permission, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		p, ok := catalog[k]
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Bilinmeyen izin: %s", k))
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		perms = append(perms, p)
+	}
+	return perms, nil
+}
+
+func replaceRolePermissions(tx *gorm.DB, role models.UserRole, perms []models.Permission) error {
+	if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Rol izinleri silinemedi")
+	}
+	if len(perms) == 0 {
+		return nil
+	}
+	rows := make([]models.RolePermission, 0, len(perms))
+	for _, p := range perms {
+		rows = append(rows, models.RolePermission{Role: role, Resource: p.Resource, Action: p.Action})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Rol izinleri kaydedilemedi")
+	}
+	return nil
+}
+
+func toRoleResponse(role models.Role) (RoleResponse, error) {
+	resp := RoleResponse{
+		Name:        string(role.Name),
+		Label:       role.Label,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+	}
+
+	perms, err := auth.RolePermissions(role.Name)
+	if err != nil {
+		return resp, fiber.NewError(fiber.StatusInternalServerError, "Rol izinleri okunamadı")
+	}
+	resp.Permissions = perms
+
+	if err := database.DB.Model(&models.User{}).Where("role = ?", role.Name).Count(&resp.UserCount).Error; err != nil {
+		return resp, fiber.NewError(fiber.StatusInternalServerError, "Rol kullanıcıları sayılamadı")
+	}
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"fmt"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+
+	"gorm.io/gorm"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	// Rol değişiklikleri tüm şubelerdeki kullanıcıları etkiler ve rol atama kontrollerinden
+	// geçmelidir; geri alınmaz, rol yönetiminden düzenlenir
+	audit.ExcludeEntity("role", "Rol değişiklikleri geri alınamaz, rolü rol yönetiminden düzenleyin")
+	// Kullanıcı log'ları yanıt verisini (şifre, oturum, şube yetkileri olmadan) tutar; geri yüklemek
+	// kapatılan oturumları, şube yetkilerini ve rol atama kontrollerini atlar. Kullanıcı yönetiminden düzenlenir.
+	audit.ExcludeEntity("user", "Kullanıcı değişiklikleri geri alınamaz, kullanıcıyı kullanıcı yönetiminden düzenleyin")
+	audit.RegisterEntity("bank_account", audit.EntityConfig{
+		Model:        &models.BankAccount{},
+		BranchColumn: "branch_id",
+		OnChange: func(tx *gorm.DB, before, after any) error {
+			// İşlemleri olan hesabın oluşturulması geri alınamaz
+			if before == nil || after != nil {
+				return nil
+			}
+			acc := before.(*models.BankAccount)
+			var count int64
+			tx.Model(&models.BankTransaction{}).Where("bank_account_id = ?", acc.ID).Count(&count)
+			if count > 0 {
+				return fmt.Errorf("bu hesaba ait işlemler var, önce işlemleri silin")
+			}
+			return nil
+		},
+	})
+
+	// Banka işlemi tablosunda şube yok; şube ve dönem kontrolü hesap üzerinden yapılır
+	audit.RegisterEntity("bank_transaction", audit.EntityConfig{
+		Model: &models.BankTransaction{},
+		OnChange: func(tx *gorm.DB, before, after any) error {
+			var delta float64
+			var accountID uint
+			var txns []models.BankTransaction
+			if b, ok := before.(*models.BankTransaction); ok && b != nil {
+				delta -= b.BalanceEffect()
+				accountID = b.BankAccountID
+				txns = append(txns, *b)
+			}
+			if a, ok := after.(*models.BankTransaction); ok && a != nil {
+				delta += a.BalanceEffect()
+				accountID = a.BankAccountID
+				txns = append(txns, *a)
+			}
+
+			var account models.BankAccount
+			if err := tx.First(&account, "id = ?", accountID).Error; err != nil {
+				return fmt.Errorf("hesap bulunamadı")
+			}
+			for _, t := range txns {
+				if err := period.EnsureOpen(account.BranchID, t.Date); err != nil {
+					return err
+				}
+			}
+
+			if delta == 0 {
+				return nil
+			}
+			return adjustBankBalance(tx, accountID, delta)
+		},
+	})
+
+	audit.RegisterEntity("monthly_report", audit.EntityConfig{
+		Model:          &models.MonthlyReport{},
+		BranchColumn:   "branch_id",
+		SuperAdminOnly: true, // dönem kapanışı/açılışı sadece super_admin tarafından geri alınır
+		OnChange: func(tx *gorm.DB, before, after any) error {
+			// Ay kapanışı geri alınırsa dönem kilidi de kalkar
+			if before == nil || after != nil {
+				return nil
+			}
+			r := before.(*models.MonthlyReport)
+			return tx.Where("branch_id = ? AND year = ? AND month = ?", r.BranchID, r.Year, r.Month).
+				Delete(&models.PeriodLock{}).Error
+		},
+	})
+
+	audit.RegisterEntity("period_lock", audit.EntityConfig{
+		Model:          &models.PeriodLock{},
+		BranchColumn:   "branch_id",
+		SuperAdminOnly: true,
+	})
+}
@@ -0,0 +1,678 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type UserResponse struct {
+	ID         uint   `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	BranchID   *uint  `json:"branch_id"` // varsayılan şube
+	BranchName string `json:"branch_name,omitempty"`
+	IsActive   bool   `json:"is_active"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type UpdateUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Role  *string `json:"role"` // değişirse kullanıcının oturumları kapatılır
+}
+
+// GET /api/admin/users?role=cashier&branch_id=1&active=true&q=ali
+// Şube kapsamlı yöneticiler sadece yetkili oldukları şubelerdeki kullanıcıları görür
+func ListUsersHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dbq := scopeUsers(c, database.DB.Preload("Branch"))
+
+		if role := strings.TrimSpace(c.Query("role")); role != "" {
+			dbq = dbq.Where("role = ?", role)
+		}
+		branchID, err := tenancy.QueryBranchID(c)
+		if err != nil {
+			return err
+		}
+		if branchID != nil {
+			dbq = dbq.Where("id IN (?)", database.DB.Model(&models.UserBranch{}).
+				Select("user_id").Where("branch_id = ?", *branchID))
+		}
+		if active := c.Query("active"); active != "" {
+			dbq = dbq.Where("is_active = ?", active == "true" || active == "1")
+		}
+		if q := strings.TrimSpace(c.Query("q")); q != "" {
+			like := "%" + strings.ToLower(q) + "%"
+			dbq = dbq.Where("(LOWER(name) LIKE ? OR LOWER(email) LIKE ?)", like, like)
+		}
+
+		var users []models.User
+		if err := dbq.Order("name ASC").Find(&users).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcılar listelenemedi")
+		}
+
+		res := make([]UserResponse, 0, len(users))
+		for _, u := range users {
+			res = append(res, toUserResponse(u))
+		}
+		return c.JSON(res)
+	}
+}
+
+// GET /api/admin/users/:id
+func GetUserHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(toUserResponse(user))
+	}
+}
+
+// PUT /api/admin/users/:id
+// Ad, email ve rol güncellenir. Rol değişikliği token'a yazılı olduğundan kullanıcının oturumları kapatılır.
+// Şube kapsamlı yöneticiler sadece kendi izinlerini aşmayan rolleri verebilir (bkz. ensureRoleAssignable).
+func UpdateUserHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+
+		var body UpdateUserRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri gönderildi")
+		}
+
+		before := toUserResponse(user)
+
+		if body.Name != nil {
+			name := strings.TrimSpace(*body.Name)
+			if name == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "İsim boş olamaz")
+			}
+			user.Name = name
+		}
+		if body.Email != nil {
+			email := strings.ToLower(strings.TrimSpace(*body.Email))
+			if email == "" || !strings.Contains(email, "@") {
+				return fiber.NewError(fiber.StatusBadRequest, "Geçerli bir email girin")
+			}
+			var exist models.User
+			if err := database.DB.Where("email = ? AND id <> ?", email, user.ID).First(&exist).Error; err == nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Bu email zaten kayıtlı")
+			}
+			user.Email = email
+		}
+
+		roleChanged := false
+		if body.Role != nil {
+			role := models.UserRole(strings.ToLower(strings.TrimSpace(*body.Role)))
+			if role != user.Role {
+				if role == models.RoleSuperAdmin || user.Role == models.RoleSuperAdmin {
+					return fiber.NewError(fiber.StatusBadRequest, "Super admin rolü verilemez veya değiştirilemez")
+				}
+				if _, err := findRole(string(role)); err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Rol bulunamadı: %s", role))
+				}
+				if err := ensureRoleAssignable(c, role, nil); err != nil {
+					return err
+				}
+				user.Role = role
+				roleChanged = true
+			}
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"name":  user.Name,
+				"email": user.Email,
+				"role":  user.Role,
+			}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı güncellenemedi")
+			}
+			if roleChanged {
+				if _, err := auth.RevokeUserSessions(tx, user.ID, auth.RevokeReasonAdmin); err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Oturumlar kapatılamadı")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		resp := toUserResponse(user)
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    user.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "user",
+				EntityID:    user.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Kullanıcı güncellendi: %s", user.Email),
+				Before:      before,
+				After:       resp,
+			})
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/admin/users/:id/deactivate
+// Kullanıcı pasife alınır: giriş yapamaz, açık oturumları kapatılır
+func DeactivateUserHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return setUserActive(c, false)
+	}
+}
+
+// POST /api/admin/users/:id/activate
+func ActivateUserHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return setUserActive(c, true)
+	}
+}
+
+func setUserActive(c *fiber.Ctx, active bool) error {
+	user, err := loadUser(c)
+	if err != nil {
+		return err
+	}
+	if user.IsActive == active {
+		return c.JSON(toUserResponse(user))
+	}
+	if !active {
+		if err := ensureCanRemoveUser(c, user); err != nil {
+			return err
+		}
+	}
+
+	before := toUserResponse(user)
+
+	var revoked int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("is_active", active).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı durumu güncellenemedi")
+		}
+		if active {
+			return nil
+		}
+		n, err := auth.RevokeUserSessions(tx, user.ID, auth.RevokeReasonUserDisabled)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Oturumlar kapatılamadı")
+		}
+		revoked = n
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	user.IsActive = active
+
+	resp := toUserResponse(user)
+
+	description := fmt.Sprintf("Kullanıcı aktifleştirildi: %s", user.Email)
+	if !active {
+		description = fmt.Sprintf("Kullanıcı pasife alındı: %s (%d oturum kapatıldı)", user.Email, revoked)
+	}
+	userID, userName, err := tenancy.CurrentUser(c)
+	if err == nil {
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    user.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "user",
+			EntityID:    user.ID,
+			Action:      models.AuditActionUpdate,
+			Description: description,
+			Before:      before,
+			After:       resp,
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// DELETE /api/admin/users/:id
+// Ayrılan personelin hesabı silinir; oturumları ve şube yetkileri de silinir.
+// Geçmiş işlem kayıtlarında kullanıcı adı korunur.
+func DeleteUserHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+		if err := ensureCanRemoveUser(c, user); err != nil {
+			return err
+		}
+
+		before := toUserResponse(user)
+
+		if err := database.DB.Delete(&user).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı silinemedi")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    user.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "user",
+				EntityID:    user.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Kullanıcı silindi: %s", user.Email),
+				Before:      before,
+				After:       nil,
+			})
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// POST /api/admin/users/:id/reset-password
+// Tek kullanımlık şifre sıfırlama token'ı üretir; kullanıcı bu token ile
+// POST /api/auth/reset-password üzerinden yeni şifresini belirler. Token sadece bu yanıtta döner.
+func CreatePasswordResetHandler(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+		if !user.IsActive {
+			return fiber.NewError(fiber.StatusBadRequest, "Pasif kullanıcı için şifre sıfırlanamaz, önce aktifleştirin")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		token, expiresAt, err := auth.CreatePasswordReset(database.DB, user.ID, userID, cfg.PasswordResetTTL)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Şifre sıfırlama token'ı oluşturulamadı")
+		}
+
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    user.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "user",
+			EntityID:    user.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Şifre sıfırlama token'ı oluşturuldu: %s", user.Email),
+			Before:      nil,
+			After:       nil,
+		})
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"user_id":     user.ID,
+			"reset_token": token, // sadece bir kez gösterilir
+			"expires_at":  expiresAt,
+		})
+	}
+}
+
+// POST /api/admin/users/:id/revoke-sessions
+// Kullanıcının tüm açık oturumlarını kapatır; mevcut access ve refresh tokenları geçersiz olur
+func RevokeUserSessionsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+
+		revoked, err := auth.RevokeUserSessions(database.DB, user.ID, auth.RevokeReasonAdmin)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Oturumlar kapatılamadı")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    user.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "user",
+				EntityID:    user.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Oturumlar kapatıldı: %s (%d oturum)", user.Email, revoked),
+				Before:      nil,
+				After:       nil,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"user_id":          user.ID,
+			"revoked_sessions": revoked,
+		})
+	}
+}
+
+type UserBranchRequest struct {
+	BranchID uint   `json:"branch_id"`
+	Role     string `json:"role"` // boş: kullanıcının genel rolü
+}
+
+type SaveUserBranchesRequest struct {
+	Branches        []UserBranchRequest `json:"branches"`
+	DefaultBranchID *uint               `json:"default_branch_id"` // boşsa listedeki ilk şube
+}
+
+type UserBranchResponse struct {
+	BranchID      uint   `json:"branch_id"`
+	BranchName    string `json:"branch_name"`
+	Role          string `json:"role"`           // kayıtlı rol (boş: genel rol)
+	EffectiveRole string `json:"effective_role"` // şubede geçerli rol
+	IsDefault     bool   `json:"is_default"`
+}
+
+type UserBranchesResponse struct {
+	UserID          uint                 `json:"user_id"`
+	Role            string               `json:"role"`
+	DefaultBranchID *uint                `json:"default_branch_id"`
+	Branches        []UserBranchResponse `json:"branches"`
+}
+
+// GET /api/admin/users/:id/branches
+func ListUserBranchesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+
+		resp, err := toUserBranchesResponse(user)
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
+	}
+}
+
+// PUT /api/admin/users/:id/branches
+// Kullanıcının şube yetkilerini verilen liste ile değiştirir. Şube kapsamlı yöneticiler sadece
+// yetkili oldukları şubeleri verebilir/kaldırabilir; diğer şubelerdeki yetkiler korunur.
+// Şube yetkileri token'a yazılı olduğundan kullanıcının oturumları kapatılır.
+func SaveUserBranchesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := loadUser(c)
+		if err != nil {
+			return err
+		}
+		if user.Role == models.RoleSuperAdmin {
+			return fiber.NewError(fiber.StatusBadRequest, "Super admin tüm şubelerde yetkilidir, şube atanamaz")
+		}
+
+		var body SaveUserBranchesRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri gönderildi")
+		}
+
+		rows := make([]models.UserBranch, 0, len(body.Branches))
+		seen := make(map[uint]bool, len(body.Branches))
+		for _, b := range body.Branches {
+			if b.BranchID == 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
+			}
+			if seen[b.BranchID] {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Şube birden fazla kez gönderildi (ID: %d)", b.BranchID))
+			}
+			seen[b.BranchID] = true
+			if !tenancy.BranchAllowed(c, b.BranchID) {
+				return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("Bu şubede işlem yetkiniz yok (ID: %d)", b.BranchID))
+			}
+
+			var branch models.Branch
+			if err := database.DB.First(&branch, "id = ?", b.BranchID).Error; err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Şube bulunamadı (ID: %d)", b.BranchID))
+			}
+
+			role := models.UserRole(strings.TrimSpace(b.Role))
+			if role != "" {
+				if role == models.RoleSuperAdmin {
+					return fiber.NewError(fiber.StatusBadRequest, "Şube bazında super admin rolü verilemez")
+				}
+				if _, err := findRole(string(role)); err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Rol bulunamadı: %s", role))
+				}
+				if err := ensureRoleAssignable(c, role, &b.BranchID); err != nil {
+					return err
+				}
+			}
+			rows = append(rows, models.UserBranch{UserID: user.ID, BranchID: b.BranchID, Role: role})
+		}
+
+		// İsteği yapanın yetkisi dışındaki şubelerdeki yetkiler olduğu gibi kalır
+		allowed := tenancy.AllowedBranchIDs(c)
+		var kept []models.UserBranch
+		if allowed != nil {
+			if err := database.DB.Where("user_id = ? AND branch_id NOT IN ?", user.ID, allowed).
+				Order("branch_id ASC").Find(&kept).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Şube yetkileri okunamadı")
+			}
+		}
+		keptIDs := make(map[uint]bool, len(kept))
+		for _, k := range kept {
+			keptIDs[k.BranchID] = true
+		}
+
+		var defaultBranchID *uint
+		switch {
+		case body.DefaultBranchID != nil:
+			if !seen[*body.DefaultBranchID] && !keptIDs[*body.DefaultBranchID] {
+				return fiber.NewError(fiber.StatusBadRequest, "Varsayılan şube, yetkili şubelerden biri olmalı")
+			}
+			defaultBranchID = body.DefaultBranchID
+		case user.BranchID != nil && keptIDs[*user.BranchID]:
+			defaultBranchID = user.BranchID
+		case len(rows) > 0:
+			defaultBranchID = &rows[0].BranchID
+		case len(kept) > 0:
+			defaultBranchID = &kept[0].BranchID
+		}
+
+		before, err := toUserBranchesResponse(user)
+		if err != nil {
+			return err
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			del := tx.Where("user_id = ?", user.ID)
+			if allowed != nil {
+				del = del.Where("branch_id IN ?", allowed)
+			}
+			if err := del.Delete(&models.UserBranch{}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Şube yetkileri silinemedi")
+			}
+			if len(rows) > 0 {
+				if err := tx.Create(&rows).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Şube yetkileri kaydedilemedi")
+				}
+			}
+			if err := tx.Model(&user).Update("branch_id", defaultBranchID).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Varsayılan şube güncellenemedi")
+			}
+			if _, err := auth.RevokeUserSessions(tx, user.ID, auth.RevokeReasonAdmin); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Oturumlar kapatılamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		user.BranchID = defaultBranchID
+
+		resp, err := toUserBranchesResponse(user)
+		if err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "user",
+				EntityID:    user.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Şube yetkileri güncellendi: %s (%d şube)", user.Email, len(rows)),
+				Before:      before,
+				After:       resp,
+			})
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+func toUserBranchesResponse(user models.User) (UserBranchesResponse, error) {
+	resp := UserBranchesResponse{
+		UserID:          user.ID,
+		Role:            string(user.Role),
+		DefaultBranchID: user.BranchID,
+		Branches:        []UserBranchResponse{},
+	}
+
+	var rows []models.UserBranch
+	if err := database.DB.Preload("Branch").Where("user_id = ?", user.ID).
+		Order("branch_id ASC").Find(&rows).Error; err != nil {
+		return resp, fiber.NewError(fiber.StatusInternalServerError, "Şube yetkileri okunamadı")
+	}
+	for _, r := range rows {
+		effective := r.Role
+		if effective == "" {
+			effective = user.Role
+		}
+		resp.Branches = append(resp.Branches, UserBranchResponse{
+			BranchID:      r.BranchID,
+			BranchName:    r.Branch.Name,
+			Role:          string(r.Role),
+			EffectiveRole: string(effective),
+			IsDefault:     user.BranchID != nil && *user.BranchID == r.BranchID,
+		})
+	}
+	return resp, nil
+}
+
+// loadUser: URL'deki kullanıcı (şube kapsamlı yöneticiler için yetkili şubelerindeki kullanıcılar)
+func loadUser(c *fiber.Ctx) (models.User, error) {
+	var user models.User
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return user, fiber.NewError(fiber.StatusBadRequest, "Geçersiz kullanıcı ID")
+	}
+	if err := scopeUsers(c, database.DB.Preload("Branch")).First(&user, "id = ?", id).Error; err != nil {
+		return user, fiber.NewError(fiber.StatusNotFound, "Kullanıcı bulunamadı")
+	}
+	return user, nil
+}
+
+// scopeUsers: Sorguyu isteği yapanın yetkili olduğu şubelerdeki kullanıcılarla sınırlar (super admin için koşulsuz)
+func scopeUsers(c *fiber.Ctx, db *gorm.DB) *gorm.DB {
+	ids := tenancy.AllowedBranchIDs(c)
+	if ids == nil {
+		return db
+	}
+	return db.Where("role <> ?", models.RoleSuperAdmin).
+		Where("id IN (?)", database.DB.Model(&models.UserBranch{}).Select("user_id").Where("branch_id IN ?", ids))
+}
+
+// ensureCanRemoveUser: Kullanıcı kendini ve son aktif super admini pasife alamaz / silemez
+func ensureCanRemoveUser(c *fiber.Ctx, user models.User) error {
+	if currentID, ok := c.Locals(auth.CtxUserIDKey).(uint); ok && currentID == user.ID {
+		return fiber.NewError(fiber.StatusBadRequest, "Kendi hesabınızı pasife alamaz veya silemezsiniz")
+	}
+	if user.Role == models.RoleSuperAdmin && user.IsActive {
+		var count int64
+		if err := database.DB.Model(&models.User{}).
+			Where("role = ? AND is_active = ? AND id <> ?", models.RoleSuperAdmin, true, user.ID).
+			Count(&count).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Kullanıcılar sayılamadı")
+		}
+		if count == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Son aktif super admin pasife alınamaz veya silinemez")
+		}
+	}
+	return nil
+}
+
+// ensureRoleAssignable: Şube kapsamlı yöneticiler sadece izinleri kendi izinlerinin alt kümesi olan
+// rolleri verebilir. branchID verilirse o şubedeki rolü, verilmezse yetkili olduğu tüm şubelerdeki
+// rolleri karşılaştırılır.
+func ensureRoleAssignable(c *fiber.Ctx, role models.UserRole, branchID *uint) error {
+	callerRole, _ := c.Locals(auth.CtxUserRoleKey).(models.UserRole)
+	if callerRole == models.RoleSuperAdmin {
+		return nil
+	}
+
+	callerRoles := []models.UserRole{callerRole}
+	if branches, ok := c.Locals(auth.CtxBranchesKey).(map[uint]models.UserRole); ok && len(branches) > 0 {
+		callerRoles = callerRoles[:0]
+		if branchID != nil {
+			callerRoles = append(callerRoles, branches[*branchID])
+		} else {
+			for _, id := range tenancy.AllowedBranchIDs(c) {
+				callerRoles = append(callerRoles, branches[id])
+			}
+		}
+	}
+
+	wanted, err := auth.RolePermissions(role)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Yetkiler okunamadı")
+	}
+	for _, r := range callerRoles {
+		have, err := auth.RolePermissions(r)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Yetkiler okunamadı")
+		}
+		haveSet := make(map[string]bool, len(have))
+		for _, p := range have {
+			haveSet[p] = true
+		}
+		for _, p := range wanted {
+			if !haveSet[p] {
+				return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("Kendi yetkinizde olmayan izinler içeren rol veremezsiniz: %s (%s)", role, p))
+			}
+		}
+	}
+	return nil
+}
+
+func toUserResponse(u models.User) UserResponse {
+	resp := UserResponse{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		Role:      string(u.Role),
+		BranchID:  u.BranchID,
+		IsActive:  u.IsActive,
+		CreatedAt: u.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: u.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if u.Branch != nil {
+		resp.BranchName = u.Branch.Name
+	}
+	return resp
+}
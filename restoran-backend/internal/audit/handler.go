@@ -6,6 +6,7 @@ import (
 	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -34,23 +35,14 @@ func ListAuditLogsHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
 		}
 
-		// Branch ID çöz
-		var branchID *uint
-		if role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if ok && bPtr != nil {
-				branchID = bPtr
-			}
-		} else {
-			// super_admin için query'den al
-			bidStr := c.Query("branch_id")
-			if bidStr != "" {
-				var bid uint
-				if _, err := fmt.Sscan(bidStr, &bid); err == nil && bid > 0 {
-					branchID = &bid
-				}
-			}
+		// Branch ID çöz: super_admin için opsiyonel filtre, diğer roller için yetkili şubelerden biri
+		// (verilmezse tüm yetkili şubeler)
+		branchID, err := tenancy.QueryBranchID(c)
+		if err != nil {
+			return err
+		}
+		if branchID != nil && !tenancy.BranchAllowed(c, *branchID) {
+			return fiber.NewError(fiber.StatusForbidden, "Bu şubede işlem yetkiniz yok")
 		}
 
 		entityType := c.Query("entity_type")
@@ -62,6 +54,8 @@ func ListAuditLogsHandler() fiber.Handler {
 		// Branch filtresi
 		if branchID != nil {
 			dbq = dbq.Where("branch_id = ?", *branchID)
+		} else if role.IsBranchScoped() {
+			dbq = dbq.Where("branch_id IN ?", tenancy.AllowedBranchIDs(c))
 		}
 
 		// User ID filtresi
@@ -146,21 +140,17 @@ func UndoAuditLogHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusNotFound, "Log bulunamadı")
 		}
 
-		// Yetki kontrolü
-		if role == models.RoleSuperAdmin {
-			// Super admin her şeyi geri alabilir
-		} else if role == models.RoleBranchAdmin {
-			// Branch admin kendi şubesindeki tüm kayıtları geri alabilir
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil {
-				return fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-			}
-			if log.BranchID == nil || *log.BranchID != *bPtr {
-				return fiber.NewError(fiber.StatusForbidden, "Bu işlemi sadece kendi şubenizdeki kayıtları geri alabilirsiniz")
-			}
-		} else {
-			return fiber.NewError(fiber.StatusForbidden, "Bu işlemi geri almak için yetkiniz yok")
+		// Yetki kontrolü (izin route'ta kontrol edilir: audit:write)
+		// Super admin her şeyi, diğer roller yetkili oldukları şubelerdeki kayıtları geri alabilir
+		if role.IsBranchScoped() && (log.BranchID == nil || !tenancy.BranchAllowed(c, *log.BranchID)) {
+			return fiber.NewError(fiber.StatusForbidden, "Bu işlemi sadece yetkili olduğunuz şubelerdeki kayıtları geri alabilirsiniz")
+		}
+		if role != models.RoleSuperAdmin && superAdminOnly(log.EntityType) {
+			return fiber.NewError(fiber.StatusForbidden, "Bu kaydı sadece super admin geri alabilir")
+		}
+		// Bilerek geri alınmayan kayıt tipleri (örn. rol) sebebiyle reddedilir
+		if reason, ok := excluded[log.EntityType]; ok {
+			return fiber.NewError(fiber.StatusBadRequest, reason)
 		}
 
 		// Kullanıcı adını al
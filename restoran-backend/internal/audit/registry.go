@@ -0,0 +1,439 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ChildRelation - Log verisinde parent ile birlikte saklanan alt kayıtlar
+// (örn. Shipment.Items, TradeTransaction.payments)
+type ChildRelation struct {
+	Key        string // log verisindeki alan adı (örn. "Items", "payments")
+	Model      any    // alt kaydın modeli (örn. &models.ShipmentItem{})
+	ForeignKey string // alt tablodaki parent kolonu (örn. "shipment_id")
+	// Restrict: alt kayıt varken parent'ın oluşturulması geri alınamaz
+	// (alt kayıtlar kendi log'larıyla ayrıca yazılıyorsa sessizce silinmesinler)
+	Restrict bool
+}
+
+// EntityConfig - Undo motorunun bir entity tipini nasıl geri alacağı
+type EntityConfig struct {
+	Model    any // örn. &models.Expense{}
+	Children []ChildRelation
+
+	// Şube kuralları: BranchColumn doluysa kaydın şubesi log'un şubesiyle eşleşmeli,
+	// geri oluşturulan kayıtta eksikse log'un şubesi kullanılır.
+	BranchColumn string
+	// DateColumn doluysa kapalı döneme düşen kayıtlar geri alınamaz
+	DateColumn string
+	// SuperAdminOnly: Bu tipteki log'ları sadece super_admin geri alabilir (örn. dönem kapanışı)
+	SuperAdminOnly bool
+
+	// OnChange - Undo sonrası ek işlemler (örn. bakiye düzeltme).
+	// before/after ilgili modelin pointer'ı; kayıt yoksa nil.
+	OnChange func(tx *gorm.DB, before, after any) error
+
+	// Undo - Log tek bir kaydı değil bir listeyi tutuyorsa (örn. ürünün birimleri) geri alma
+	// tamamen bu fonksiyonla yapılır; Model, Children ve OnChange kullanılmaz.
+	Undo func(tx *gorm.DB, log *models.AuditLog) error
+}
+
+var registry = map[string]EntityConfig{}
+
+// superAdminOnly - Entity tipinin geri alınması super_admin'e mi ayrılmış?
+func superAdminOnly(entityType string) bool {
+	cfg, ok := registry[entityType]
+	return ok && cfg.SuperAdminOnly
+}
+
+// RegisterEntity - Paketler init() içinde audit log'a yazdıkları entity tiplerini kaydeder
+func RegisterEntity(entityType string, cfg EntityConfig) {
+	registry[entityType] = cfg
+}
+
+// excluded - Bilerek geri alınamayan entity tipleri ve kullanıcıya gösterilecek sebep
+var excluded = map[string]string{}
+
+// ExcludeEntity - Audit log'a yazılan ama geri alınmaması gereken entity tipini kaydeder
+func ExcludeEntity(entityType, reason string) {
+	excluded[entityType] = reason
+}
+
+// undoer - Tek bir log'un geri alınması (tüm işlemler aynı transaction içinde)
+type undoer struct {
+	tx  *gorm.DB
+	cfg EntityConfig
+	log *models.AuditLog
+}
+
+func parseSchema(tx *gorm.DB, model any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+func newModel(model any) any {
+	return reflect.New(reflect.TypeOf(model).Elem()).Interface()
+}
+
+// load - Kaydı model pointer'ı olarak getirir, yoksa nil döner
+func (u *undoer) load(id uint) (any, error) {
+	obj := newModel(u.cfg.Model)
+	err := u.tx.First(obj, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// fieldOf - Model pointer'ından kolon değerini okur
+func fieldOf(sch *schema.Schema, obj any, column string) any {
+	if obj == nil || column == "" {
+		return nil
+	}
+	f := sch.LookUpField(column)
+	if f == nil {
+		return nil
+	}
+	return f.ReflectValueOf(context.Background(), reflect.ValueOf(obj).Elem()).Interface()
+}
+
+func toUint(v any) (uint, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(rv.Uint()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint(rv.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return uint(rv.Float()), true
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return 0, false
+		}
+		return toUint(rv.Elem().Interface())
+	}
+	return 0, false
+}
+
+// checkScope - Şube ve dönem kilidi kurallarını uygular
+func (u *undoer) checkScope(branch any, dates ...any) error {
+	if u.cfg.BranchColumn == "" {
+		return nil
+	}
+	branchID, ok := toUint(branch)
+	if !ok {
+		return nil
+	}
+	if u.log.BranchID != nil && *u.log.BranchID != branchID {
+		return fmt.Errorf("kayıt başka bir şubeye ait, geri alınamaz")
+	}
+	if u.cfg.DateColumn == "" {
+		return nil
+	}
+	var ts []time.Time
+	for _, d := range dates {
+		if t, ok := d.(time.Time); ok && !t.IsZero() {
+			ts = append(ts, t)
+		}
+	}
+	if err := period.EnsureOpen(branchID, ts...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// decodeValues - Log JSON'unu modelin kolonlarına çevirir.
+// Log'lar hem struct (Go alan adları) hem map (snake_case) olarak yazıldığı için ikisi de kabul edilir.
+func decodeValues(sch *schema.Schema, data map[string]any) map[string]any {
+	values := map[string]any{}
+	for _, f := range sch.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		raw, ok := data[f.DBName]
+		if !ok {
+			raw, ok = data[f.Name]
+		}
+		if !ok {
+			continue
+		}
+		if v, ok := convertValue(f, raw); ok {
+			values[f.DBName] = v
+		}
+	}
+	return values
+}
+
+func convertValue(f *schema.Field, raw any) (any, bool) {
+	t := f.FieldType
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if raw == nil {
+		return nil, isPtr
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, false
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if ts, err := time.Parse(layout, s); err == nil {
+				return ts, true
+			}
+		}
+		return nil, false
+	}
+
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		n, ok := raw.(float64)
+		if !ok {
+			return nil, false
+		}
+		return reflect.ValueOf(n).Convert(t).Interface(), true
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, false
+		}
+		return s, true
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		return b, ok
+	}
+	return nil, false
+}
+
+func parseLogData(dataJSON string) (map[string]any, error) {
+	if dataJSON == "" || dataJSON == "null" {
+		return nil, fmt.Errorf("bu işlemin verisi kaydedilmemiş, geri alınamaz")
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(dataJSON), &data); err != nil {
+		return nil, fmt.Errorf("log verisi okunamadı: %w", err)
+	}
+	return data, nil
+}
+
+// deleteEntity - Create işlemini geri al (kaydı ve alt kayıtlarını sil)
+func (u *undoer) deleteEntity() error {
+	sch, err := parseSchema(u.tx, u.cfg.Model)
+	if err != nil {
+		return err
+	}
+	current, err := u.load(u.log.EntityID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("kayıt bulunamadı, zaten silinmiş olabilir")
+	}
+	if err := u.checkScope(fieldOf(sch, current, u.cfg.BranchColumn), fieldOf(sch, current, u.cfg.DateColumn)); err != nil {
+		return err
+	}
+
+	for _, child := range u.cfg.Children {
+		if child.Restrict {
+			var count int64
+			if err := u.tx.Model(newModel(child.Model)).Where(child.ForeignKey+" = ?", u.log.EntityID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("bu kayda bağlı %d alt kayıt var, önce onları silin", count)
+			}
+			continue
+		}
+		if err := u.tx.Where(child.ForeignKey+" = ?", u.log.EntityID).Delete(newModel(child.Model)).Error; err != nil {
+			return err
+		}
+	}
+	if err := u.tx.Delete(newModel(u.cfg.Model), "id = ?", u.log.EntityID).Error; err != nil {
+		return err
+	}
+
+	if u.cfg.OnChange != nil {
+		return u.cfg.OnChange(u.tx, current, nil)
+	}
+	return nil
+}
+
+// restoreEntity - Update işlemini geri al (önceki değerleri yaz)
+func (u *undoer) restoreEntity(dataJSON string) error {
+	data, err := parseLogData(dataJSON)
+	if err != nil {
+		return err
+	}
+	sch, err := parseSchema(u.tx, u.cfg.Model)
+	if err != nil {
+		return err
+	}
+	current, err := u.load(u.log.EntityID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("kayıt bulunamadı, geri yüklenemez")
+	}
+
+	values := decodeValues(sch, data)
+	delete(values, "id")
+	delete(values, "created_at")
+	delete(values, "updated_at")
+	if len(values) == 0 {
+		return fmt.Errorf("geri yüklenecek alan bulunamadı")
+	}
+
+	if err := u.checkScope(fieldOf(sch, current, u.cfg.BranchColumn),
+		fieldOf(sch, current, u.cfg.DateColumn), values[u.cfg.DateColumn]); err != nil {
+		return err
+	}
+	if b, ok := values[u.cfg.BranchColumn]; ok {
+		if err := u.checkScope(b); err != nil {
+			return err
+		}
+	}
+
+	if err := u.tx.Model(newModel(u.cfg.Model)).Where("id = ?", u.log.EntityID).Updates(values).Error; err != nil {
+		return err
+	}
+
+	// Log'da alt kayıtlar varsa onları da önceki haline döndür
+	for _, child := range u.cfg.Children {
+		if _, ok := data[child.Key]; !ok {
+			continue
+		}
+		if err := u.tx.Where(child.ForeignKey+" = ?", u.log.EntityID).Delete(newModel(child.Model)).Error; err != nil {
+			return err
+		}
+		if err := u.createChildren(child, data); err != nil {
+			return err
+		}
+	}
+
+	if u.cfg.OnChange != nil {
+		after, err := u.load(u.log.EntityID)
+		if err != nil {
+			return err
+		}
+		return u.cfg.OnChange(u.tx, current, after)
+	}
+	return nil
+}
+
+// recreateEntity - Delete işlemini geri al (kaydı aynı ID ile alt kayıtlarıyla birlikte geri oluştur)
+func (u *undoer) recreateEntity(dataJSON string) error {
+	data, err := parseLogData(dataJSON)
+	if err != nil {
+		return err
+	}
+	sch, err := parseSchema(u.tx, u.cfg.Model)
+	if err != nil {
+		return err
+	}
+	existing, err := u.load(u.log.EntityID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("kayıt zaten mevcut, geri oluşturulamaz")
+	}
+
+	values := decodeValues(sch, data)
+	values["id"] = u.log.EntityID
+	if u.cfg.BranchColumn != "" {
+		if _, ok := values[u.cfg.BranchColumn]; !ok && u.log.BranchID != nil {
+			values[u.cfg.BranchColumn] = *u.log.BranchID
+		}
+	}
+	now := time.Now()
+	if sch.LookUpField("created_at") != nil {
+		if _, ok := values["created_at"]; !ok {
+			values["created_at"] = now
+		}
+	}
+	if sch.LookUpField("updated_at") != nil {
+		values["updated_at"] = now
+	}
+
+	if err := u.checkScope(values[u.cfg.BranchColumn], values[u.cfg.DateColumn]); err != nil {
+		return err
+	}
+
+	if err := u.tx.Model(newModel(u.cfg.Model)).Create(values).Error; err != nil {
+		return err
+	}
+
+	for _, child := range u.cfg.Children {
+		if err := u.createChildren(child, data); err != nil {
+			return err
+		}
+	}
+
+	if u.cfg.OnChange != nil {
+		after, err := u.load(u.log.EntityID)
+		if err != nil {
+			return err
+		}
+		return u.cfg.OnChange(u.tx, nil, after)
+	}
+	return nil
+}
+
+// createChildren - Log verisindeki alt kayıtları parent'a bağlayarak oluşturur
+func (u *undoer) createChildren(child ChildRelation, data map[string]any) error {
+	items, ok := data[child.Key].([]any)
+	if !ok || len(items) == 0 {
+		return nil
+	}
+	sch, err := parseSchema(u.tx, child.Model)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, item := range items {
+		itemData, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		values := decodeValues(sch, itemData)
+		values[child.ForeignKey] = u.log.EntityID
+		if sch.LookUpField("branch_id") != nil {
+			if _, ok := values["branch_id"]; !ok && u.log.BranchID != nil {
+				values["branch_id"] = *u.log.BranchID
+			}
+		}
+		if sch.LookUpField("created_at") != nil {
+			if _, ok := values["created_at"]; !ok {
+				values["created_at"] = now
+			}
+		}
+		if sch.LookUpField("updated_at") != nil {
+			values["updated_at"] = now
+		}
+		if err := u.tx.Model(newModel(child.Model)).Create(values).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
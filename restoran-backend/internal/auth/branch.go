@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"sort"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+)
+
+// CtxPermittedBranchesKey: RequirePermission'ın route iznini veren roldeki şubeleri yazdığı anahtar
+// (şube kapsamı tenancy paketinde bu anahtardan çözülür)
+const CtxPermittedBranchesKey = "permitted_branches"
+
+// UserBranches: Kullanıcının yetkili olduğu şubeler ve şubedeki etkin rolü (super admin için nil: tüm şubeler)
+func UserBranches(user *models.User) (map[uint]models.UserRole, error) {
+	if user.Role == models.RoleSuperAdmin {
+		return nil, nil
+	}
+
+	var rows []models.UserBranch
+	if err := database.DB.Where("user_id = ?", user.ID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	branches := make(map[uint]models.UserRole, len(rows)+1)
+	for _, r := range rows {
+		role := r.Role
+		if role == "" {
+			role = user.Role
+		}
+		branches[r.BranchID] = role
+	}
+	// Yetki kaydı henüz oluşmamış eski kullanıcı: varsayılan şube
+	if len(branches) == 0 && user.BranchID != nil {
+		branches[*user.BranchID] = user.Role
+	}
+	return branches, nil
+}
+
+func sortedBranchIDs(branches map[uint]models.UserRole) []uint {
+	ids := make([]uint, 0, len(branches))
+	for id := range branches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
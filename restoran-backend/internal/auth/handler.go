@@ -82,17 +82,24 @@ func LoginHandler(cfg *config.Config) fiber.Handler {
 			return fiber.NewError(fiber.StatusUnauthorized, "Email veya şifre hatalı")
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(body.Password)); err != nil {
+		if !CheckPassword(&user, body.Password) {
 			return fiber.NewError(fiber.StatusUnauthorized, "Email veya şifre hatalı")
 		}
+		if !user.IsActive {
+			return fiber.NewError(fiber.StatusForbidden, "Hesabınız pasif durumda, yöneticinize başvurun")
+		}
 
-		token, err := GenerateToken(cfg.JWTSecret, &user)
+		pair, err := StartSession(cfg, c, &user)
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Token oluşturulamadı")
 		}
 
 		return c.JSON(fiber.Map{
-			"token": token,
+			"token":              pair.AccessToken, // geriye dönük uyumluluk (access_token ile aynı)
+			"access_token":       pair.AccessToken,
+			"refresh_token":      pair.RefreshToken,
+			"expires_in":         pair.ExpiresIn,
+			"refresh_expires_at": pair.RefreshExpiresAt,
 			"user": fiber.Map{
 				"id":        user.ID,
 				"name":      user.Name,
@@ -104,6 +111,46 @@ func LoginHandler(cfg *config.Config) fiber.Handler {
 	}
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// POST /api/auth/refresh
+// Refresh token ile yeni access/refresh token çifti (eski refresh token geçersiz olur)
+func RefreshHandler(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body RefreshRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		body.RefreshToken = strings.TrimSpace(body.RefreshToken)
+		if body.RefreshToken == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "refresh_token zorunlu")
+		}
+
+		pair, err := RefreshSession(cfg, body.RefreshToken)
+		if err != nil {
+			return err
+		}
+		return c.JSON(pair)
+	}
+}
+
+// POST /api/auth/logout
+// Mevcut oturumu sonlandırır (access ve refresh token birlikte geçersiz olur)
+func LogoutHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sessionID, _ := c.Locals(CtxSessionKey).(string)
+		if sessionID == "" {
+			return fiber.NewError(fiber.StatusUnauthorized, "Oturum bilgisi alınamadı")
+		}
+		if err := RevokeSession(database.DB, sessionID, RevokeReasonLogout); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Oturum kapatılamadı")
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
 func MeHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userIDVal := c.Locals(CtxUserIDKey)
@@ -122,6 +169,33 @@ func MeHandler() fiber.Handler {
 					"branch_id": user.BranchID,
 				}
 
+				// Arayüzün menü/buton gösterimi için rolün izinleri
+				if perms, err := RolePermissions(user.Role); err == nil {
+					response["permissions"] = perms
+				}
+
+				// Yetkili şubeler ve şubedeki rolün izinleri
+				if branches, err := UserBranches(&user); err == nil && len(branches) > 0 {
+					list := make([]fiber.Map, 0, len(branches))
+					for _, id := range sortedBranchIDs(branches) {
+						var branch models.Branch
+						if err := database.DB.First(&branch, id).Error; err != nil {
+							continue
+						}
+						perms, err := RolePermissions(branches[id])
+						if err != nil {
+							continue
+						}
+						list = append(list, fiber.Map{
+							"id":          branch.ID,
+							"name":        branch.Name,
+							"role":        branches[id],
+							"permissions": perms,
+						})
+					}
+					response["branches"] = list
+				}
+
 				// Branch admin ise branch bilgisini de ekle
 				if user.BranchID != nil {
 					var branch models.Branch
@@ -9,22 +9,30 @@ import (
 )
 
 type JWTCustomClaims struct {
-	UserID   uint            `json:"user_id"`
-	Email    string          `json:"email"`
-	Role     models.UserRole `json:"role"`
-	BranchID *uint           `json:"branch_id"`
+	UserID       uint                     `json:"user_id"`
+	Email        string                   `json:"email"`
+	Role         models.UserRole          `json:"role"`
+	BranchID     *uint                    `json:"branch_id"`          // varsayılan şube
+	Branches     map[uint]models.UserRole `json:"branches,omitempty"` // yetkili şubeler ve şubedeki rol
+	TokenVersion int                      `json:"ver"`                // User.TokenVersion; şifre değişince eski tokenlar reddedilir
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(secret string, user *models.User) (string, error) {
+// GenerateToken: Oturuma bağlı kısa ömürlü access token üretir (jti = UserSession.SessionID).
+// branches: UserBranches ile okunan yetkili şubeler (super admin için nil)
+func GenerateToken(secret string, user *models.User, branches map[uint]models.UserRole, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := &JWTCustomClaims{
-		UserID:   user.ID,
-		Email:    user.Email,
-		Role:     user.Role,
-		BranchID: user.BranchID,
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		BranchID:     user.BranchID,
+		Branches:     branches,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 1 gün
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
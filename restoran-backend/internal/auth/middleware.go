@@ -15,6 +15,8 @@ const (
 	CtxUserIDKey   = "user_id"
 	CtxUserRoleKey = "user_role"
 	CtxBranchIDKey = "branch_id"
+	CtxSessionKey  = "session_id"
+	CtxBranchesKey = "branches" // map[uint]models.UserRole: yetkili şubeler ve şubedeki rol
 )
 
 func JWTMiddleware(cfg *config.Config) fiber.Handler {
@@ -46,9 +48,16 @@ func JWTMiddleware(cfg *config.Config) fiber.Handler {
 			return fiber.NewError(fiber.StatusUnauthorized, "Token çözümlenemedi")
 		}
 
+		// İptal edilmiş oturumların, eski token sürümlerinin ve pasif kullanıcıların tokenları reddedilir
+		if err := validateSession(claims); err != nil {
+			return err
+		}
+
 		c.Locals(CtxUserIDKey, claims.UserID)
 		c.Locals(CtxUserRoleKey, claims.Role)
 		c.Locals(CtxBranchIDKey, claims.BranchID)
+		c.Locals(CtxSessionKey, claims.ID)
+		c.Locals(CtxBranchesKey, claims.Branches)
 
 		return c.Next()
 	}
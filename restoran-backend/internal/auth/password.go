@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength: Şifre değiştirme ve sıfırlamada istenen en kısa şifre
+const MinPasswordLength = 8
+
+// HashPassword: Şifre kuralını kontrol eder ve bcrypt özetini döner
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MinPasswordLength {
+		return "", fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Şifre en az %d karakter olmalı", MinPasswordLength))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Şifre hashlenemedi")
+	}
+	return string(hash), nil
+}
+
+// CheckPassword: Şifre kullanıcının şifresiyle eşleşiyor mu?
+func CheckPassword(user *models.User, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// SetPassword: Yeni şifre özetini yazar; token sürümü artırılır ve tüm oturumlar kapatılır.
+// Kapatılan oturum sayısını döner.
+func SetPassword(tx *gorm.DB, userID uint, hash string) (int64, error) {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", hash).Error; err != nil {
+		return 0, err
+	}
+	if err := BumpTokenVersion(tx, userID); err != nil {
+		return 0, err
+	}
+	return RevokeUserSessions(tx, userID, RevokeReasonPasswordChange)
+}
+
+// CreatePasswordReset: Kullanıcı için tek kullanımlık şifre sıfırlama token'ı üretir.
+// Kullanıcının kullanılmamış eski tokenları silinir; düz token sadece burada döner.
+func CreatePasswordReset(tx *gorm.DB, userID, createdByID uint, ttl time.Duration) (string, time.Time, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := tx.Where("user_id = ? AND used_at IS NULL", userID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+
+	reset := models.PasswordResetToken{
+		UserID:      userID,
+		TokenHash:   hashToken(token),
+		CreatedByID: createdByID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := tx.Create(&reset).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, reset.ExpiresAt, nil
+}
+
+// ConsumePasswordReset: Token'ı doğrular, kullanıldı olarak işaretler ve yeni şifreyi yazar.
+// Aynı token ile eşzamanlı iki istekten sadece biri başarılı olur.
+func ConsumePasswordReset(tx *gorm.DB, token, newPassword string) (models.User, error) {
+	var user models.User
+
+	var reset models.PasswordResetToken
+	err := tx.Preload("User").Where("token_hash = ?", hashToken(token)).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, fiber.NewError(fiber.StatusBadRequest, "Geçersiz şifre sıfırlama bağlantısı")
+	}
+	if err != nil {
+		return user, fiber.NewError(fiber.StatusInternalServerError, "Şifre sıfırlama kaydı okunamadı")
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return user, fiber.NewError(fiber.StatusBadRequest, "Şifre sıfırlama bağlantısının süresi dolmuş veya kullanılmış")
+	}
+	if !reset.User.IsActive {
+		return user, fiber.NewError(fiber.StatusForbidden, "Hesabınız pasif durumda, yöneticinize başvurun")
+	}
+
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return user, err
+	}
+
+	res := tx.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return user, fiber.NewError(fiber.StatusInternalServerError, "Şifre sıfırlama kaydı güncellenemedi")
+	}
+	if res.RowsAffected == 0 {
+		return user, fiber.NewError(fiber.StatusBadRequest, "Şifre sıfırlama bağlantısının süresi dolmuş veya kullanılmış")
+	}
+	if _, err := SetPassword(tx, reset.UserID, hash); err != nil {
+		return user, fiber.NewError(fiber.StatusInternalServerError, "Şifre güncellenemedi")
+	}
+	return reset.User, nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rol -> izin eşlemesi her istekte veritabanından okunmaz; rol yönetimi değişiklikten sonra
+// InvalidatePermissions çağırır.
+var (
+	permMu    sync.RWMutex
+	permCache map[models.UserRole]map[string]bool
+)
+
+// RequirePermission: Kullanıcının rolü verilen izne sahip değilse 403 döner.
+// Super admin tüm izinlere sahiptir. Şube yetkisi olan kullanıcılarda rol şube bazındadır;
+// izni veren roldeki şubeler isteğe yazılır ve şube kapsamı (tenancy paketi) bunlarla sınırlanır.
+func RequirePermission(perm models.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, ok := c.Locals(CtxUserRoleKey).(models.UserRole)
+		if !ok {
+			return fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
+		}
+		if role == models.RoleSuperAdmin {
+			return c.Next()
+		}
+
+		branches, _ := c.Locals(CtxBranchesKey).(map[uint]models.UserRole)
+		if len(branches) == 0 {
+			allowed, err := HasPermission(role, perm)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Yetkiler okunamadı")
+			}
+			if !allowed {
+				return fiber.NewError(fiber.StatusForbidden, "Bu işlem için yetkiniz yok")
+			}
+			return c.Next()
+		}
+
+		permitted := make(map[uint]bool, len(branches))
+		for branchID, branchRole := range branches {
+			allowed, err := HasPermission(branchRole, perm)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Yetkiler okunamadı")
+			}
+			if allowed {
+				permitted[branchID] = true
+			}
+		}
+		if len(permitted) == 0 {
+			return fiber.NewError(fiber.StatusForbidden, "Bu işlem için yetkiniz yok")
+		}
+		c.Locals(CtxPermittedBranchesKey, permitted)
+		return c.Next()
+	}
+}
+
+// HasPermission: Rol verilen izne sahip mi?
+func HasPermission(role models.UserRole, perm models.Permission) (bool, error) {
+	if role == models.RoleSuperAdmin {
+		return true, nil
+	}
+	perms, err := rolePermissions(role)
+	if err != nil {
+		return false, err
+	}
+	return perms[perm.String()], nil
+}
+
+// RolePermissions: Rolün izinleri ("stock:read" biçiminde, sıralı)
+func RolePermissions(role models.UserRole) ([]string, error) {
+	if role == models.RoleSuperAdmin {
+		all := make([]string, 0, len(models.PermissionCatalog))
+		for _, p := range models.PermissionCatalog {
+			all = append(all, p.Permission.String())
+		}
+		return all, nil
+	}
+
+	perms, err := rolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(perms))
+	for p := range perms {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// InvalidatePermissions: Rol izinleri değiştiğinde önbelleği temizler
+func InvalidatePermissions() {
+	permMu.Lock()
+	permCache = nil
+	permMu.Unlock()
+}
+
+func rolePermissions(role models.UserRole) (map[string]bool, error) {
+	permMu.RLock()
+	cache := permCache
+	permMu.RUnlock()
+	if cache != nil {
+		return cache[role], nil
+	}
+
+	var rows []models.RolePermission
+	if err := database.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	cache = make(map[models.UserRole]map[string]bool)
+	for _, r := range rows {
+		if cache[r.Role] == nil {
+			cache[r.Role] = make(map[string]bool)
+		}
+		cache[r.Role][models.Permission{Resource: r.Resource, Action: r.Action}.String()] = true
+	}
+
+	permMu.Lock()
+	permCache = cache
+	permMu.Unlock()
+	return cache[role], nil
+}
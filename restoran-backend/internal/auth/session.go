@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Oturum iptal nedenleri (UserSession.RevokeReason)
+const (
+	RevokeReasonLogout         = "logout"
+	RevokeReasonAdmin          = "admin"
+	RevokeReasonPasswordChange = "password_change"
+	RevokeReasonUserDisabled   = "user_disabled"
+)
+
+// TokenPair: Giriş ve yenileme yanıtı
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        int       `json:"expires_in"` // access token süresi (saniye)
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// StartSession: Kullanıcı için yeni oturum açar ve token çifti üretir
+func StartSession(cfg *config.Config, c *fiber.Ctx, user *models.User) (TokenPair, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	now := time.Now()
+	session := models.UserSession{
+		SessionID:        sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		TokenVersion:     user.TokenVersion,
+		UserAgent:        truncate(c.Get(fiber.HeaderUserAgent), 255),
+		IP:               truncate(c.IP(), 64),
+		ExpiresAt:        now.Add(cfg.RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return TokenPair{}, err
+	}
+
+	return issueTokenPair(cfg, user, session, refreshToken)
+}
+
+// RefreshSession: Refresh token'ı doğrular, yenisiyle değiştirir (rotation) ve yeni token çifti üretir.
+// Kullanılmış (değiştirilmiş) bir refresh token bir daha geçmez.
+func RefreshSession(cfg *config.Config, refreshToken string) (TokenPair, error) {
+	var session models.UserSession
+	err := database.DB.Preload("User").
+		Where("refresh_token_hash = ?", hashToken(refreshToken)).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return TokenPair{}, fiber.NewError(fiber.StatusUnauthorized, "Geçersiz refresh token")
+	}
+	if err != nil {
+		return TokenPair{}, fiber.NewError(fiber.StatusInternalServerError, "Oturum okunamadı")
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) || session.TokenVersion != session.User.TokenVersion {
+		return TokenPair{}, fiber.NewError(fiber.StatusUnauthorized, "Oturum sonlanmış, tekrar giriş yapın")
+	}
+	if !session.User.IsActive {
+		return TokenPair{}, fiber.NewError(fiber.StatusUnauthorized, "Hesabınız pasif durumda")
+	}
+
+	newRefresh, err := randomToken(32)
+	if err != nil {
+		return TokenPair{}, fiber.NewError(fiber.StatusInternalServerError, "Token oluşturulamadı")
+	}
+	// Aynı refresh token ile eşzamanlı iki yenilemeden sadece biri başarılı olur
+	res := database.DB.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": hashToken(newRefresh),
+			"last_used_at":       now,
+		})
+	if res.Error != nil {
+		return TokenPair{}, fiber.NewError(fiber.StatusInternalServerError, "Oturum güncellenemedi")
+	}
+	if res.RowsAffected == 0 {
+		return TokenPair{}, fiber.NewError(fiber.StatusUnauthorized, "Geçersiz refresh token")
+	}
+
+	pair, err := issueTokenPair(cfg, &session.User, session, newRefresh)
+	if err != nil {
+		return TokenPair{}, fiber.NewError(fiber.StatusInternalServerError, "Token oluşturulamadı")
+	}
+	return pair, nil
+}
+
+// RevokeSession: Tek bir oturumu iptal eder (logout)
+func RevokeSession(tx *gorm.DB, sessionID, reason string) error {
+	return tx.Model(&models.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+// RevokeUserSessions: Kullanıcının tüm açık oturumlarını iptal eder, iptal edilen oturum sayısını döner
+func RevokeUserSessions(tx *gorm.DB, userID uint, reason string) (int64, error) {
+	res := tx.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason})
+	return res.RowsAffected, res.Error
+}
+
+// BumpTokenVersion: Kullanıcının token sürümünü artırır; o ana kadar verilmiş tüm tokenlar
+// ve oturumlar geçersiz olur (şifre değişimi vb.)
+func BumpTokenVersion(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// validateSession: Access token'ın bağlı olduğu oturum hâlâ geçerli mi?
+func validateSession(claims *JWTCustomClaims) error {
+	if claims.ID == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "Oturum bilgisi yok, tekrar giriş yapın")
+	}
+
+	var row struct {
+		RevokedAt    *time.Time
+		ExpiresAt    time.Time
+		TokenVersion int
+		IsActive     bool
+	}
+	err := database.DB.Table("user_sessions s").
+		Select("s.revoked_at, s.expires_at, u.token_version, u.is_active").
+		Joins("JOIN users u ON u.id = s.user_id").
+		Where("s.session_id = ? AND s.user_id = ?", claims.ID, claims.UserID).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Oturum bulunamadı, tekrar giriş yapın")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Oturum doğrulanamadı")
+	}
+	if row.RevokedAt != nil || time.Now().After(row.ExpiresAt) || row.TokenVersion != claims.TokenVersion {
+		return fiber.NewError(fiber.StatusUnauthorized, "Oturum sonlanmış, tekrar giriş yapın")
+	}
+	if !row.IsActive {
+		return fiber.NewError(fiber.StatusUnauthorized, "Hesabınız pasif durumda")
+	}
+	return nil
+}
+
+func issueTokenPair(cfg *config.Config, user *models.User, session models.UserSession, refreshToken string) (TokenPair, error) {
+	// Şube yetkileri token yenilendikçe güncel halleriyle yazılır
+	branches, err := UserBranches(user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	accessToken, err := GenerateToken(cfg.JWTSecret, user, branches, session.SessionID, cfg.AccessTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(cfg.AccessTokenTTL.Seconds()),
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken: Refresh token veritabanında düz metin olarak tutulmaz
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
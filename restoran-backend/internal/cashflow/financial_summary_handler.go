@@ -4,42 +4,13 @@ import (
 	"fmt"
 	"time"
 
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// resolveBranchIDFromQueryOrRole: branch_id'yi query'den veya role'den çöz
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 type FinancialSummaryResponse struct {
 	Period         string         `json:"period"` // "daily", "weekly", "monthly"
 	StartDate      string         `json:"start_date"`
@@ -64,7 +35,7 @@ type DailyRevenue struct {
 // Günlük ciro (tarih aralığı ile)
 func GetDailyFinancialSummaryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -171,7 +142,7 @@ func GetDailyFinancialSummaryHandler() fiber.Handler {
 // Haftalık ciro (hafta numarası ile)
 func GetWeeklyFinancialSummaryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -280,7 +251,7 @@ func GetWeeklyFinancialSummaryHandler() fiber.Handler {
 // Aylık ciro ve kar
 func GetMonthlyFinancialSummaryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -5,9 +5,10 @@ import (
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -43,48 +44,6 @@ type MonthlySummaryResponse struct {
 	GrandTotal float64              `json:"grand_total"`
 }
 
-// Yardımcı: Kullanıcı bilgilerini al
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
-// Yardımcı: context'ten branch id ve rolü çek
-func getBranchIDForRequest(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	role := c.Locals(auth.CtxUserRoleKey).(models.UserRole)
-
-	if role == models.RoleBranchAdmin {
-		// branch_admin ise, JWT'den branch id al
-		branchIDPtr, ok := c.Locals(auth.CtxBranchIDKey).(*uint)
-		if !ok || branchIDPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *branchIDPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
 // -------------------------------------------------
 // POST /api/cash-movements
 // -------------------------------------------------
@@ -107,7 +66,7 @@ func CreateCashMovementHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz method (cash|pos|yemeksepeti)")
 		}
 
-		branchID, err := getBranchIDForRequest(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -126,6 +85,11 @@ func CreateCashMovementHandler() fiber.Handler {
 			date = d
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, date); err != nil {
+			return err
+		}
+
 		mov := models.CashMovement{
 			BranchID:    branchID,
 			Date:        date,
@@ -140,7 +104,7 @@ func CreateCashMovementHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			methodName := string(mov.Method)
 			// Branch ilişkisini exclude et (JSON hatası önlemek için)
@@ -153,7 +117,7 @@ func CreateCashMovementHandler() fiber.Handler {
 				"amount":      mov.Amount,
 				"description": mov.Description,
 			}
-			// mov.BranchID'yi kullan (super admin için getUserInfo null dönebilir)
+			// mov.BranchID'yi kullan (super admin'in varsayılan şubesi yok)
 			branchIDForLog := &mov.BranchID
 			if logErr := audit.WriteLog(audit.LogOptions{
 				BranchID:    branchIDForLog,
@@ -187,27 +151,9 @@ func CreateCashMovementHandler() fiber.Handler {
 // -------------------------------------------------
 func ListCashMovementsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role := c.Locals(auth.CtxUserRoleKey).(models.UserRole)
-
-		var branchID uint
-		if role == models.RoleBranchAdmin {
-			branchIDPtr, ok := c.Locals(auth.CtxBranchIDKey).(*uint)
-			if !ok || branchIDPtr == nil {
-				return fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-			}
-			branchID = *branchIDPtr
-		} else {
-			// super_admin: branch_id queryden gelir
-			bidStr := c.Query("branch_id")
-			if bidStr == "" {
-				return fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-			}
-			var parsed uint
-			_, err := fmt.Sscan(bidStr, &parsed)
-			if err != nil || parsed == 0 {
-				return fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-			}
-			branchID = parsed
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
 		}
 
 		fromStr := c.Query("from")
@@ -263,27 +209,9 @@ func ListCashMovementsHandler() fiber.Handler {
 // -------------------------------------------------
 func MonthlySummaryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role := c.Locals(auth.CtxUserRoleKey).(models.UserRole)
-
-		var branchID uint
-		if role == models.RoleBranchAdmin {
-			branchIDPtr, ok := c.Locals(auth.CtxBranchIDKey).(*uint)
-			if !ok || branchIDPtr == nil {
-				return fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-			}
-			branchID = *branchIDPtr
-		} else {
-			// super_admin
-			bidStr := c.Query("branch_id")
-			if bidStr == "" {
-				return fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-			}
-			var parsed uint
-			_, err := fmt.Sscan(bidStr, &parsed)
-			if err != nil || parsed == 0 {
-				return fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-			}
-			branchID = parsed
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
 		}
 
 		yearStr := c.Query("year")
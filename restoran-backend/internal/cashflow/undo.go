@@ -0,0 +1,15 @@
+package cashflow
+
+import (
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("cash_movement", audit.EntityConfig{
+		Model:        &models.CashMovement{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+}
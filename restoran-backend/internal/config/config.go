@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -11,6 +13,11 @@ type Config struct {
 	JWTSecret      string
 	CORSOrigins    string
 	ProductImagePath string // Ürün fotoğraflarının kaydedileceği klasör yolu
+	CentralSupplierTaxIDs string // Merkez mutfağın e-Fatura VKN/TCKN numaraları (virgülle ayrılmış)
+	PriceAlertThresholdPct float64 // Sevkiyat fiyatı önceki fiyattan bu yüzdeden fazla saparsa uyarı oluşturulur
+	AccessTokenTTL  time.Duration // Access token geçerlilik süresi
+	RefreshTokenTTL time.Duration // Refresh token (oturum) geçerlilik süresi
+	PasswordResetTTL time.Duration // Admin'in verdiği tek kullanımlık şifre sıfırlama token'ının geçerlilik süresi
 }
 
 func Load() *Config {
@@ -20,8 +27,37 @@ func Load() *Config {
 		JWTSecret:       getEnv("JWT_SECRET", ""),
 		CORSOrigins:     getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173"),
 		ProductImagePath: getEnv("PRODUCT_IMAGE_PATH", "./product-images"), // Default: local development için
+		CentralSupplierTaxIDs: getEnv("CENTRAL_SUPPLIER_TAX_IDS", ""),
 	}
 
+	threshold, err := strconv.ParseFloat(getEnv("PRICE_ALERT_THRESHOLD_PCT", "10"), 64)
+	if err != nil || threshold <= 0 {
+		log.Println("[WARN] PRICE_ALERT_THRESHOLD_PCT geçersiz, varsayılan %10 kullanılıyor.")
+		threshold = 10
+	}
+	cfg.PriceAlertThresholdPct = threshold
+
+	accessMinutes, err := strconv.Atoi(getEnv("ACCESS_TOKEN_TTL_MINUTES", "15"))
+	if err != nil || accessMinutes <= 0 {
+		log.Println("[WARN] ACCESS_TOKEN_TTL_MINUTES geçersiz, varsayılan 15 dakika kullanılıyor.")
+		accessMinutes = 15
+	}
+	cfg.AccessTokenTTL = time.Duration(accessMinutes) * time.Minute
+
+	refreshDays, err := strconv.Atoi(getEnv("REFRESH_TOKEN_TTL_DAYS", "30"))
+	if err != nil || refreshDays <= 0 {
+		log.Println("[WARN] REFRESH_TOKEN_TTL_DAYS geçersiz, varsayılan 30 gün kullanılıyor.")
+		refreshDays = 30
+	}
+	cfg.RefreshTokenTTL = time.Duration(refreshDays) * 24 * time.Hour
+
+	resetHours, err := strconv.Atoi(getEnv("PASSWORD_RESET_TTL_HOURS", "24"))
+	if err != nil || resetHours <= 0 {
+		log.Println("[WARN] PASSWORD_RESET_TTL_HOURS geçersiz, varsayılan 24 saat kullanılıyor.")
+		resetHours = 24
+	}
+	cfg.PasswordResetTTL = time.Duration(resetHours) * time.Hour
+
 	// Production güvenlik kontrolleri
 	if cfg.JWTSecret == "" {
 		log.Fatal("[FATAL] JWT_SECRET environment değişkeni tanımlanmamış! Production için zorunludur.")
@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"time"
 
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -35,40 +35,10 @@ type CashChartResponse struct {
 	GrandTotals CashChartGrandTotals `json:"grand_totals"`
 }
 
-// context'ten branch id çıkar (branch_admin için JWT, super_admin için query param)
-// super_admin için ?branch_id=1 zorunlu
-func getBranchIDFromContext(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		branchIDVal := c.Locals(auth.CtxBranchIDKey)
-		branchIDPtr, ok := branchIDVal.(*uint)
-		if !ok || branchIDPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *branchIDPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 // GET /api/dashboard/cash-chart?period=daily&count=7&branch_id=1
 func CashChartHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := getBranchIDFromContext(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
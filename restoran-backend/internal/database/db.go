@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"restoran-backend/internal/config"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
 
 	"gorm.io/driver/postgres"
@@ -123,6 +124,26 @@ func Init(cfg *config.Config) {
 		&models.TradePayment{},         // Ticari ödemeler
 		&models.BranchProductOrder{},   // Şube bazlı ürün sıralama
 		&models.Property{},             // Mal Mülk
+		&models.PeriodLock{},           // Ay kapanışı (dönem kilidi)
+		&models.StockMovement{},        // Stok defteri hareketleri
+		&models.StockTransfer{},        // Şubeler arası transfer
+		&models.StockTransferItem{},
+		&models.MenuItem{},             // Menü ürünleri ve reçeteleri
+		&models.RecipeLine{},
+		&models.MenuSale{},             // Günlük menü satışları
+		&models.Job{},                  // Arka plan işleri (toplu içe aktarma vb.)
+		&models.PriceAlert{},           // Sevkiyat fiyat değişim uyarıları
+		&models.ParLevel{},             // Şube bazlı minimum / hedef stok seviyeleri
+		&models.PurchaseOrder{},        // Satın alma siparişleri
+		&models.PurchaseOrderLine{},
+		&models.CountSession{},         // Stok sayım oturumları
+		&models.CountSessionLine{},
+		&models.ProductUnit{},          // Ürün birimleri ve dönüşüm katsayıları
+		&models.UserSession{},          // Giriş oturumları (refresh token, iptal)
+		&models.Role{},                 // Roller
+		&models.RolePermission{},       // Rol izinleri (kaynak x işlem)
+		&models.UserBranch{},           // Kullanıcı - şube yetkileri (şube bazında rol)
+		&models.PasswordResetToken{},   // Tek kullanımlık şifre sıfırlama tokenları
 	)
 	if err != nil {
 		log.Fatalf("AutoMigrate hatası: %v", err)
@@ -157,5 +178,26 @@ func Init(cfg *config.Config) {
 		}
 	}
 
+	// Sistem rolleri ve varsayılan izinleri
+	if n, err := seedRoles(DB); err != nil {
+		log.Printf("Roller oluşturulamadı: %v", err)
+	} else if n > 0 {
+		log.Printf("%d sistem rolü oluşturuldu", n)
+	}
+
+	// Kullanıcıların varsayılan şubeleri -> şube yetkileri
+	if n, err := backfillUserBranches(DB); err != nil {
+		log.Printf("Kullanıcı şube yetkileri aktarılamadı: %v", err)
+	} else if n > 0 {
+		log.Printf("%d kullanıcı şube yetkisi oluşturuldu", n)
+	}
+
+	// Stok defteri: eski sayım/sevkiyat/zayiat kayıtlarını harekete çevir (defter boşsa bir kez çalışır)
+	if n, err := ledger.Backfill(DB); err != nil {
+		log.Printf("Stok defteri aktarımı başarısız: %v", err)
+	} else if n > 0 {
+		log.Printf("Stok defterine %d hareket aktarıldı", n)
+	}
+
 	log.Println("Veritabanı bağlantısı başarılı. Migration tamamlandı.")
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// seedRoles: Eksik sistem rollerini varsayılan izinleriyle oluşturur.
+// Var olan rollerin izinlerine dokunulmaz (rol yönetiminden değiştirilmiş olabilir).
+func seedRoles(db *gorm.DB) (int, error) {
+	created := 0
+	for _, sr := range models.SystemRoles {
+		var count int64
+		if err := db.Model(&models.Role{}).Where("name = ?", sr.Name).Count(&count).Error; err != nil {
+			return created, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			role := models.Role{
+				Name:        sr.Name,
+				Label:       sr.Label,
+				Description: sr.Description,
+				IsSystem:    true,
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			if len(sr.Permissions) == 0 {
+				return nil
+			}
+			perms := make([]models.RolePermission, 0, len(sr.Permissions))
+			for _, p := range sr.Permissions {
+				perms = append(perms, models.RolePermission{Role: sr.Name, Resource: p.Resource, Action: p.Action})
+			}
+			return tx.Create(&perms).Error
+		})
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
@@ -0,0 +1,16 @@
+package database
+
+import "gorm.io/gorm"
+
+// backfillUserBranches: Tek şubeli kullanıcıların User.BranchID'sini şube yetkisine çevirir.
+// Varsayılan şube her zaman yetkili şubelerden biri olmalı; eksikse eklenir (rol boş: User.Role).
+func backfillUserBranches(db *gorm.DB) (int64, error) {
+	res := db.Exec(`
+		INSERT INTO user_branches (user_id, branch_id, role, created_at, updated_at)
+		SELECT u.id, u.branch_id, '', NOW(), NOW()
+		FROM users u
+		JOIN branches b ON b.id = u.branch_id
+		WHERE u.branch_id IS NOT NULL
+		ON CONFLICT (user_id, branch_id) DO NOTHING`)
+	return res.RowsAffected, res.Error
+}
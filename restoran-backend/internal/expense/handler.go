@@ -6,9 +6,10 @@ import (
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -59,87 +60,10 @@ type MonthlyExpenseSummaryResponse struct {
 	GrandTotal float64                     `json:"grand_total"`
 }
 
-// -------------------------
-// Yardımcı: Kullanıcı bilgilerini al
-// -------------------------
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
 // -------------------------
 // Yardımcı: branch ID çöz
 // -------------------------
 
-// body'den gelen branch_id + role
-func resolveBranchIDFromBodyOrRole(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-// query'den gelen branch_id + role
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 // -------------------------
 // Expense Category CRUD
 // -------------------------
@@ -147,7 +71,7 @@ func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
 // GET /api/expense-categories  (auth olan herkes)
 func ListExpenseCategoriesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -181,7 +105,7 @@ func CreateExpenseCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Name zorunlu")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -200,6 +124,24 @@ func CreateExpenseCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Kategori oluşturulamadı")
 		}
 
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &cat.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "expense_category",
+				EntityID:    cat.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Gider kategorisi eklendi: %s", cat.Name),
+				Before:      nil,
+				After:       map[string]interface{}{"id": cat.ID, "branch_id": cat.BranchID, "name": cat.Name},
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.Status(fiber.StatusCreated).JSON(ExpenseCategoryResponse{
 			ID:   cat.ID,
 			Name: cat.Name,
@@ -213,26 +155,17 @@ func UpdateExpenseCategoryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var cat models.ExpenseCategory
-		if err := database.DB.First(&cat, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&cat, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
 		}
 
-		// Şube kontrolü - branch_admin sadece kendi şubesine erişebilir
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != cat.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu kategoriye erişim yetkiniz yok")
-			}
-		}
-
 		var body UpdateExpenseCategoryRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
 		}
 
+		beforeData := map[string]interface{}{"id": cat.ID, "branch_id": cat.BranchID, "name": cat.Name}
+
 		if body.Name != nil {
 			name := strings.TrimSpace(*body.Name)
 			if name == "" {
@@ -250,6 +183,24 @@ func UpdateExpenseCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Kategori güncellenemedi")
 		}
 
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &cat.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "expense_category",
+				EntityID:    cat.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Gider kategorisi güncellendi: %s", cat.Name),
+				Before:      beforeData,
+				After:       map[string]interface{}{"id": cat.ID, "branch_id": cat.BranchID, "name": cat.Name},
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.JSON(ExpenseCategoryResponse{
 			ID:   cat.ID,
 			Name: cat.Name,
@@ -263,21 +214,10 @@ func DeleteExpenseCategoryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var cat models.ExpenseCategory
-		if err := database.DB.First(&cat, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&cat, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
 		}
 
-		// Şube kontrolü - branch_admin sadece kendi şubesine erişebilir
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != cat.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu kategoriye erişim yetkiniz yok")
-			}
-		}
-
 		// Kategoriye ait expense kaydı var mı kontrol et
 		var expenseCount int64
 		if err := database.DB.Model(&models.Expense{}).Where("category_id = ?", id).Count(&expenseCount).Error; err != nil {
@@ -300,6 +240,24 @@ func DeleteExpenseCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Kategori silinemedi")
 		}
 
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &cat.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "expense_category",
+				EntityID:    cat.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Gider kategorisi silindi: %s", cat.Name),
+				Before:      map[string]interface{}{"id": cat.ID, "branch_id": cat.BranchID, "name": cat.Name},
+				After:       nil,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.SendStatus(fiber.StatusNoContent)
 	}
 }
@@ -320,7 +278,7 @@ func CreateExpenseHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "category_id ve amount zorunlu, amount > 0 olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -330,9 +288,14 @@ func CreateExpenseHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
-		// Kategori var mı?
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		// Kategori var mı? (kaydın şubesine ait olmalı)
 		var cat models.ExpenseCategory
-		if err := database.DB.First(&cat, "id = ?", body.CategoryID).Error; err != nil {
+		if err := database.DB.First(&cat, "id = ? AND branch_id = ?", body.CategoryID, branchID).Error; err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Kategori bulunamadı")
 		}
 
@@ -349,7 +312,7 @@ func CreateExpenseHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			// Branch ilişkisini exclude et (JSON hatası önlemek için)
 			afterData := map[string]interface{}{
@@ -360,7 +323,7 @@ func CreateExpenseHandler() fiber.Handler {
 				"amount":      exp.Amount,
 				"description": exp.Description,
 			}
-			// exp.BranchID'yi kullan (super admin için getUserInfo null dönebilir)
+			// exp.BranchID'yi kullan (super admin'in varsayılan şubesi yok)
 			branchIDForLog := &exp.BranchID
 			if logErr := audit.WriteLog(audit.LogOptions{
 				BranchID:    branchIDForLog,
@@ -393,7 +356,7 @@ func CreateExpenseHandler() fiber.Handler {
 // GET /api/expenses?from=...&to=...&category_id=...&branch_id=...
 func ListExpensesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -458,7 +421,7 @@ func ListExpensesHandler() fiber.Handler {
 // -------------------------
 func MonthlyExpenseSummaryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -588,7 +551,7 @@ func CreateExpensePaymentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "category_id ve amount zorunlu, amount > 0 olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -598,9 +561,14 @@ func CreateExpensePaymentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
-		// Kategori var mı?
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		// Kategori var mı? (kaydın şubesine ait olmalı)
 		var cat models.ExpenseCategory
-		if err := database.DB.First(&cat, "id = ?", body.CategoryID).Error; err != nil {
+		if err := database.DB.First(&cat, "id = ? AND branch_id = ?", body.CategoryID, branchID).Error; err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Kategori bulunamadı")
 		}
 
@@ -617,7 +585,7 @@ func CreateExpensePaymentHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          payment.ID,
@@ -658,7 +626,7 @@ func CreateExpensePaymentHandler() fiber.Handler {
 // GET /api/expense-payments?branch_id=...&category_id=...&from=...&to=...
 func ListExpensePaymentsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -720,7 +688,7 @@ func ListExpensePaymentsHandler() fiber.Handler {
 // GET /api/expense-payments/balance-by-category?branch_id=...
 func GetCategoryExpenseBalanceHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
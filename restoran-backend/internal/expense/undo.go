@@ -0,0 +1,24 @@
+package expense
+
+import (
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("expense_category", audit.EntityConfig{
+		Model:        &models.ExpenseCategory{},
+		BranchColumn: "branch_id",
+	})
+	audit.RegisterEntity("expense", audit.EntityConfig{
+		Model:        &models.Expense{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+	audit.RegisterEntity("expense_payment", audit.EntityConfig{
+		Model:        &models.ExpensePayment{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+}
@@ -1,30 +1,34 @@
 package inventory
 
 import (
+	"errors"
 	"log"
+	"restoran-backend/internal/auth"
 	"restoran-backend/internal/config"
+	"restoran-backend/internal/jobs"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // BulkImportB2BProductsRequest: Toplu import isteği
 type BulkImportB2BProductsRequest struct {
-	Prefix  string `json:"prefix"`  // "TM" veya "CD"
-	Start   int    `json:"start"`   // Başlangıç numarası (örn: 0)
-	End     int    `json:"end"`     // Bitiş numarası (örn: 9999)
-	DelayMs int    `json:"delay_ms"` // Requestler arası delay (milisaniye)
+	Prefix        string  `json:"prefix"`          // "TM" veya "CD"
+	Start         int     `json:"start"`           // Başlangıç numarası (örn: 0)
+	End           int     `json:"end"`             // Bitiş numarası (örn: 9999)
+	DelayMs       int     `json:"delay_ms"`        // Requestler arası delay (milisaniye) - rate_per_second yoksa hız sınırı buradan hesaplanır
+	Workers       int     `json:"workers"`         // Paralel çalışan worker sayısı (varsayılan 4)
+	RatePerSecond float64 `json:"rate_per_second"` // Saniyedeki en fazla istek (varsayılan 5)
+	Mode          string  `json:"mode"`            // "import" (varsayılan, mevcutları atlar) veya "sync" (mevcutları günceller)
 }
 
-// BulkImportB2BProductsResponse: Toplu import yanıtı
-type BulkImportB2BProductsResponse struct {
-	Imported int      `json:"imported"`  // İçe aktarılan ürün sayısı
-	Skipped  int      `json:"skipped"`   // Atlanan ürün sayısı
-	Errors   []string `json:"errors"`    // Hata mesajları
-	Cancelled bool    `json:"cancelled"` // İptal edildi mi?
-}
+const (
+	B2BImportModeImport = "import"
+	B2BImportModeSync   = "sync"
+)
 
 // BulkImportB2BProductsHandler: B2B sisteminden toplu ürün içe aktarma endpoint'i
 // POST /api/admin/products/bulk-import-b2b
+// İşi arka planda başlatır ve iş bilgisini döner (202)
 func BulkImportB2BProductsHandler(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var body BulkImportB2BProductsRequest
@@ -56,28 +60,35 @@ func BulkImportB2BProductsHandler(cfg *config.Config) fiber.Handler {
 		if body.DelayMs > 10000 {
 			return fiber.NewError(fiber.StatusBadRequest, "Delay maksimum 10000ms (10 saniye) olabilir")
 		}
+		if body.Mode != "" && body.Mode != B2BImportModeImport && body.Mode != B2BImportModeSync {
+			return fiber.NewError(fiber.StatusBadRequest, "Mode 'import' veya 'sync' olmalı")
+		}
+		if body.Workers < 0 || body.Workers > 16 {
+			return fiber.NewError(fiber.StatusBadRequest, "Worker sayısı 0-16 arasında olmalı")
+		}
+		if body.RatePerSecond < 0 || body.RatePerSecond > 50 {
+			return fiber.NewError(fiber.StatusBadRequest, "rate_per_second 0-50 arasında olmalı")
+		}
 
-		log.Printf("Bulk import başladı: %s%d-%s%d, delay: %dms", body.Prefix, body.Start, body.Prefix, body.End, body.DelayMs)
-
-		// Fiber context'inden cancellation channel'ını al
-		ctx := c.Context()
-		cancelChan := ctx.Done()
-
-		// Toplu import işlemini başlat (context ile - iptal edilebilir)
-		imported, skipped, errors, cancelled := BulkImportB2BProducts(cfg, body.Prefix, body.Start, body.End, body.DelayMs, cancelChan)
+		userID, _ := c.Locals(auth.CtxUserIDKey).(uint)
 
-		if cancelled {
-			log.Printf("Bulk import kullanıcı tarafından iptal edildi: %d imported, %d skipped, %d errors", imported, skipped, len(errors))
-		} else {
-			log.Printf("Bulk import tamamlandı: %d imported, %d skipped, %d errors", imported, skipped, len(errors))
+		// İş arka planda çalışır; ilerleme /api/admin/jobs/:id ile izlenir
+		job, err := jobs.Start(B2BBulkImportJobType, body, userID)
+		if err != nil {
+			if errors.Is(err, jobs.ErrAlreadyRunning) {
+				return fiber.NewError(fiber.StatusConflict, "Devam eden bir toplu içe aktarma işi var")
+			}
+			log.Printf("Bulk import işi başlatılamadı: %v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Toplu içe aktarma işi başlatılamadı")
 		}
 
-		return c.JSON(BulkImportB2BProductsResponse{
-			Imported: imported,
-			Skipped:  skipped,
-			Errors:   errors,
-			Cancelled: cancelled,
-		})
+		log.Printf("Bulk import işi başlatıldı (#%d): %s%d-%s%d, delay: %dms", job.ID, body.Prefix, body.Start, body.Prefix, body.End, body.DelayMs)
+
+		return c.Status(fiber.StatusAccepted).JSON(jobs.ToJobResponse(job))
 	}
 }
 
+// RegisterJobs: Envanter modülünün arka plan işlerini kaydeder (main'de çağrılır)
+func RegisterJobs(cfg *config.Config) {
+	jobs.Register(B2BBulkImportJobType, runB2BBulkImport(cfg))
+}
@@ -1,19 +1,22 @@
 package inventory
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"html"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"restoran-backend/internal/config"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/jobs"
 	"restoran-backend/internal/models"
 )
 
@@ -23,36 +26,27 @@ type B2BProductInfo struct {
 	Name      string
 	Category  string
 	ImageURL  string
+	Unit      string // "Birim : Adet" satırından (sayfada yoksa boş)
 }
 
-// ScrapeB2BProductPage: B2B ürün detay sayfasından bilgileri çeker
-func ScrapeB2BProductPage(stockCode string) (*B2BProductInfo, error) {
-	url := fmt.Sprintf("https://b2b.cadininevi.com.tr/Store/Detail/%s", stockCode)
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP isteği oluşturulamadı: %v", err)
-	}
+// errB2BProductNotFound: Stok koduna ait ürün sayfası yok (katalogdan kalkmış veya hiç olmamış)
+var errB2BProductNotFound = errors.New("ürün sayfası bulunamadı")
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP isteği başarısız: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP hatası: %d", resp.StatusCode)
+// ScrapeB2BProductPage: B2B ürün detay sayfasından bilgileri çeker
+// fetcher nil ise DefaultB2BFetcher kullanılır (hız sınırı ve tekrar deneme için)
+func ScrapeB2BProductPage(ctx context.Context, fetcher *B2BFetcher, stockCode string) (*B2BProductInfo, error) {
+	if fetcher == nil {
+		fetcher = DefaultB2BFetcher
 	}
+	url := fmt.Sprintf("%s/Store/Detail/%s", b2bBaseURL, stockCode)
 
-	htmlBytes, err := io.ReadAll(resp.Body)
+	htmlBytes, err := fetcher.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("HTML okunamadı: %v", err)
+		var statusErr *b2bStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, errB2BProductNotFound
+		}
+		return nil, err
 	}
 
 	htmlContent := string(htmlBytes)
@@ -63,7 +57,7 @@ func ScrapeB2BProductPage(stockCode string) (*B2BProductInfo, error) {
 	if len(titleMatch) > 1 {
 		title := strings.TrimSpace(titleMatch[1])
 		if strings.Contains(title, "HATA!") && strings.Contains(title, "Kodex B2B") {
-			return nil, fmt.Errorf("ürün sayfası bulunamadı (HATA sayfası)")
+			return nil, errB2BProductNotFound
 		}
 	}
 
@@ -166,6 +160,22 @@ func ScrapeB2BProductPage(stockCode string) (*B2BProductInfo, error) {
 		}
 	}
 
+	// Birimi çek: "Birim : Adet" formatında (kategori ile aynı bölümde)
+	if detailIndex != nil {
+		searchStart := detailIndex[0]
+		searchEnd := searchStart + 3000
+		if searchEnd > len(htmlContent) {
+			searchEnd = len(htmlContent)
+		}
+		unitRe := regexp.MustCompile(`Birim\s*[:\-]?\s*([^<\n\r]+)`)
+		if unitMatch := unitRe.FindStringSubmatch(htmlContent[searchStart:searchEnd]); len(unitMatch) > 1 {
+			unit := strings.TrimSpace(cleanHTML(unitMatch[1]))
+			if unit != "" && len(unit) <= 20 {
+				info.Unit = turkishToLower(unit)
+			}
+		}
+	}
+
 	// Fotoğraf URL'ini çek: Sadece /ProductImages/STOKKODU/... formatındaki img'leri çek
 	var imageURL string
 
@@ -178,7 +188,7 @@ func ScrapeB2BProductPage(stockCode string) (*B2BProductInfo, error) {
 			src := match[1]
 			// /ProductImages/ ile başlayan ve stok kodunu içeren path'i kontrol et
 			if strings.HasPrefix(src, "/ProductImages/") && strings.Contains(src, stockCode) {
-				imageURL = fmt.Sprintf("https://b2b.cadininevi.com.tr%s", src)
+				imageURL = b2bBaseURL + src
 				break
 			}
 		}
@@ -195,7 +205,7 @@ func ScrapeB2BProductPage(stockCode string) (*B2BProductInfo, error) {
 			if len(match) > 1 {
 				src := match[1]
 				if strings.HasPrefix(src, "/ProductImages/") {
-					imageURL = fmt.Sprintf("https://b2b.cadininevi.com.tr%s", src)
+					imageURL = b2bBaseURL + src
 					break
 				}
 			}
@@ -296,162 +306,217 @@ func turkishToLower(s string) string {
 	return string(result)
 }
 
-// BulkImportB2BProducts: B2B sisteminden tüm ürünleri toplu olarak içe aktarır
-// cancelChan: İşlemi iptal etmek için channel (nil olabilir)
-func BulkImportB2BProducts(cfg *config.Config, prefix string, startNum int, endNum int, delayMs int, cancelChan <-chan struct{}) (int, int, []string, bool) {
-	imported := 0
-	skipped := 0
-	errors := make([]string, 0)
-	total := endNum - startNum + 1
-
-	log.Printf("Bulk import başladı: %s%04d-%s%04d (toplam %d ürün)", prefix, startNum, prefix, endNum, total)
-
-	for num := startNum; num <= endNum; num++ {
-		// Cancellation kontrolü
-		if cancelChan != nil {
-			select {
-			case <-cancelChan:
-				log.Printf("Bulk import iptal edildi: %d imported, %d skipped", imported, skipped)
-				return imported, skipped, errors, true
-			default:
-				// Devam et
-			}
-		}
-		// Stock code oluştur (örn: TM0001, CD0123)
-		stockCode := fmt.Sprintf("%s%04d", prefix, num)
-
-		// Her 100 üründe bir ilerleme log'u
-		if (num-startNum+1)%100 == 0 || num == startNum || num == endNum {
-			progress := float64(num-startNum+1) / float64(total) * 100
-			log.Printf("İlerleme: %d/%d (%.1f%%) - Imported: %d, Skipped: %d", num-startNum+1, total, progress, imported, skipped)
-		}
-
-		// Önce veritabanında kontrol et (isim veya stok kodu ile)
-		var existingByName models.Product
-		var existingByStockCode models.Product
+// B2BBulkImportJobType: Toplu B2B ürün içe aktarma işinin tipi
+const B2BBulkImportJobType = "b2b_bulk_import"
 
-		db := database.DB
+// Toplu içe aktarmada B2B sitesine aynı anda açılabilecek en fazla bağlantı
+const b2bMaxConnsPerHost = 4
 
-		// Cancellation kontrolü - HTTP request'ten önce
-		if cancelChan != nil {
-			select {
-			case <-cancelChan:
-				log.Printf("Bulk import iptal edildi (scrape öncesi): %d imported, %d skipped", imported, skipped)
-				return imported, skipped, errors, true
-			default:
-				// Devam et
-			}
+// runB2BBulkImport: B2B sisteminden ürünleri toplu olarak içe aktaran arka plan işi.
+// Stok kodları Workers kadar goroutine ile paralel, RatePerSecond hız sınırıyla çekilir;
+// sonuçlar sırayla kaydedildiği için iş yeniden başlarsa son kaydedilen koddan devam eder.
+func runB2BBulkImport(cfg *config.Config) jobs.RunFunc {
+	return func(ctx context.Context, p *jobs.Progress) error {
+		var params BulkImportB2BProductsRequest
+		if err := p.Params(&params); err != nil {
+			return fmt.Errorf("iş parametreleri okunamadı: %v", err)
 		}
 
-		// Sayfayı scrape et
-		productInfo, err := ScrapeB2BProductPage(stockCode)
-		if err != nil {
-			// Hata sayfası veya ürün yok - skip (log'lamıyoruz, çok fazla olur)
-			skipped++
-			continue
+		total := params.End - params.Start + 1
+		if err := p.SetTotal(total); err != nil {
+			return err
 		}
 
-		// Cancellation kontrolü - scrape sonrası
-		if cancelChan != nil {
-			select {
-			case <-cancelChan:
-				log.Printf("Bulk import iptal edildi (scrape sonrası): %d imported, %d skipped", imported, skipped)
-				return imported, skipped, errors, true
-			default:
-				// Devam et
+		startNum := params.Start
+		if cursor := p.Cursor(); cursor != "" {
+			// Son işlenen koddan sonrakine geç (örn: TM0123 -> 124)
+			last, err := strconv.Atoi(strings.TrimPrefix(cursor, params.Prefix))
+			if err != nil {
+				return fmt.Errorf("geçersiz cursor: %s", cursor)
 			}
+			startNum = last + 1
 		}
 
-		// Şimdi veritabanında kontrol et
-		// İsim kontrolü
-		if err := db.Where("name = ?", productInfo.Name).First(&existingByName).Error; err == nil {
-			// İsim zaten var, skip
-			skipped++
-			continue
+		workers, rate := params.concurrency()
+		fetcher := NewB2BFetcher(B2BFetcherOptions{
+			RatePerSecond: rate,
+			Burst:         workers,
+			PerHost:       b2bMaxConnsPerHost,
+			MaxRetries:    3,
+			Backoff:       time.Second,
+		})
+
+		// Senkronizasyon modunda değişiklik raporu iş devam ettirilse de korunur
+		syncMode := params.Mode == B2BImportModeSync
+		var report CatalogSyncReport
+		var actor catalogSyncActor
+		if syncMode {
+			if err := p.Result(&report); err != nil {
+				return fmt.Errorf("değişiklik raporu okunamadı: %v", err)
+			}
+			if err := p.SetResult(report); err != nil {
+				return err
+			}
+			actor = loadCatalogSyncActor(p.CreatedBy())
 		}
 
-		// Stok kodu kontrolü
-		if err := db.Where("stock_code = ?", productInfo.StockCode).First(&existingByStockCode).Error; err == nil {
-			// Stok kodu zaten var, skip
-			skipped++
-			continue
+		log.Printf("Bulk import başladı (iş #%d, mod: %s): %s%04d-%s%04d (toplam %d ürün, %d worker, %.1f istek/sn)", p.ID(), params.mode(), params.Prefix, startNum, params.Prefix, params.End, total, workers, rate)
+
+		type codeResult struct {
+			stockCode string
+			result    jobs.StepResult
+			errMsg    string
+			change    *CatalogChange
 		}
+		err := runOrdered(ctx, workers, params.End-startNum+1,
+			func(ctx context.Context, i int) codeResult {
+				// Stock code oluştur (örn: TM0001, CD0123)
+				stockCode := fmt.Sprintf("%s%04d", params.Prefix, startNum+i)
+				if syncMode {
+					result, errMsg, change := syncB2BProduct(ctx, cfg, fetcher, stockCode, actor)
+					return codeResult{stockCode: stockCode, result: result, errMsg: errMsg, change: change}
+				}
+				result, errMsg := importB2BProduct(ctx, cfg, fetcher, stockCode)
+				return codeResult{stockCode: stockCode, result: result, errMsg: errMsg}
+			},
+			func(i int, r codeResult) error {
+				// Rapor cursor'dan önce kaydedilir; yeniden başlarsa aynı kod ikinci kez raporlanmaz
+				// (ürün zaten güncellendiği için tekrar değişiklik çıkmaz)
+				if r.change != nil {
+					report.add(*r.change)
+					if err := p.SetResult(report); err != nil {
+						return fmt.Errorf("değişiklik raporu kaydedilemedi: %v", err)
+					}
+				}
+				if err := p.Step(r.stockCode, r.result, r.errMsg); err != nil {
+					return fmt.Errorf("ilerleme kaydedilemedi: %v", err)
+				}
 
-		// Yeni ürün oluştur
-		product := models.Product{
-			Name:            productInfo.Name,
-			Unit:            "adet", // Hepsi adet olarak kaydedilecek
-			StockCode:       productInfo.StockCode,
-			Category:        productInfo.Category,
-			IsCenterProduct: true,
+				// Her 100 üründe bir ilerleme log'u
+				processed, imported, skipped, _ := p.Counts()
+				if processed%100 == 0 {
+					log.Printf("İlerleme (iş #%d): %d/%d (%.1f%%) - Imported: %d, Skipped: %d", p.ID(), processed, total, float64(processed)/float64(total)*100, imported, skipped)
+				}
+				return nil
+			})
+		if err != nil {
+			return err
 		}
 
-		if err := db.Create(&product).Error; err != nil {
-			errMsg := fmt.Sprintf("%s: Veritabanı hatası - %v", stockCode, err)
-			errors = append(errors, errMsg)
-			log.Printf("HATA: %s", errMsg)
-			skipped++
-			continue
+		_, imported, skipped, errorCount := p.Counts()
+		log.Printf("Bulk import tamamlandı (iş #%d): %d imported, %d skipped, %d errors", p.ID(), imported, skipped, errorCount)
+		if syncMode {
+			log.Printf("Katalog senkronizasyonu (iş #%d): %d yeni, %d güncellendi, %d pasife alındı, %d fotoğraf", p.ID(), report.Created, report.Updated, report.Deactivated, report.ImagesUpdated)
 		}
+		return nil
+	}
+}
+
+// mode: İş modu (varsayılan import)
+func (r BulkImportB2BProductsRequest) mode() string {
+	if r.Mode == "" {
+		return B2BImportModeImport
+	}
+	return r.Mode
+}
 
-		log.Printf("Ürün eklendi: %s - %s", stockCode, productInfo.Name)
+// concurrency: Worker sayısı ve saniyedeki istek sınırı (varsayılanlarla)
+// rate_per_second verilmemişse eski delay_ms alanından hesaplanır.
+func (r BulkImportB2BProductsRequest) concurrency() (workers int, rate float64) {
+	workers = r.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	rate = r.RatePerSecond
+	if rate <= 0 && r.DelayMs > 0 {
+		rate = 1000 / float64(r.DelayMs)
+	}
+	if rate <= 0 {
+		rate = 5
+	}
+	return workers, rate
+}
 
-		// Cancellation kontrolü - fotoğraf indirmeden önce
-		if cancelChan != nil {
-			select {
-			case <-cancelChan:
-				log.Printf("Bulk import iptal edildi (fotoğraf indirme öncesi): %d imported, %d skipped", imported, skipped)
-				return imported, skipped, errors, true
-			default:
-				// Devam et
-			}
+// importB2BProduct: Tek bir stok kodunu B2B'den çekip ürün olarak ekler.
+// Ürün sayfası yoksa veya ürün zaten kayıtlıysa atlanır, sayfa okunamazsa başarısız sayılır;
+// errMsg kritik olmayan hataları da içerebilir.
+func importB2BProduct(ctx context.Context, cfg *config.Config, fetcher *B2BFetcher, stockCode string) (result jobs.StepResult, errMsg string) {
+	db := database.DB
+
+	// Sayfayı scrape et
+	productInfo, err := ScrapeB2BProductPage(ctx, fetcher, stockCode)
+	if errors.Is(err, errB2BProductNotFound) {
+		// Hata sayfası veya ürün yok - skip (log'lamıyoruz, çok fazla olur)
+		return jobs.StepSkipped, ""
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return jobs.StepSkipped, ""
 		}
+		// Ağ hatası, 5xx, okunamayan sayfa vb. - ürün var olabilir, hata olarak raporlanır
+		return jobs.StepFailed, fmt.Sprintf("%s: %v", stockCode, err)
+	}
 
-		// Fotoğrafı indir (eğer varsa)
-		if productInfo.ImageURL != "" {
-			_, err := downloadImageFromURL(productInfo.ImageURL, productInfo.StockCode, cfg.ProductImagePath)
-			if err != nil {
-				// Fotoğraf indirme hatası kritik değil, log'la ama devam et
-				errMsg := fmt.Sprintf("%s: Fotoğraf indirilemedi - %v", stockCode, err)
-				errors = append(errors, errMsg)
-				log.Printf("UYARI: %s", errMsg)
-			} else {
-				log.Printf("Fotoğraf indirildi: %s.jpg", stockCode)
-			}
-		} else {
-			// Fotoğraf yoksa DownloadProductImage fonksiyonunu dene (eski yöntem)
-			_, err := DownloadProductImage(productInfo.StockCode, cfg.ProductImagePath)
-			if err != nil {
-				// Fotoğraf yok, kritik değil (log'lamıyoruz)
-			} else {
-				log.Printf("Fotoğraf indirildi (eski yöntem): %s.jpg", stockCode)
-			}
-		}
+	// İsim kontrolü
+	var existingByName models.Product
+	if err := db.Where("name = ?", productInfo.Name).First(&existingByName).Error; err == nil {
+		return jobs.StepSkipped, ""
+	}
 
-		imported++
-
-		// Rate limiting - delay ekle (cancellation ile)
-		if delayMs > 0 {
-			if cancelChan != nil {
-				select {
-				case <-cancelChan:
-					log.Printf("Bulk import iptal edildi (delay sırasında): %d imported, %d skipped", imported, skipped)
-					return imported, skipped, errors, true
-				case <-time.After(time.Duration(delayMs) * time.Millisecond):
-					// Delay tamamlandı, devam et
-				}
-			} else {
-				time.Sleep(time.Duration(delayMs) * time.Millisecond)
-			}
+	// Stok kodu kontrolü
+	var existingByStockCode models.Product
+	if err := db.Where("stock_code = ?", productInfo.StockCode).First(&existingByStockCode).Error; err == nil {
+		return jobs.StepSkipped, ""
+	}
+
+	// Yeni ürün oluştur
+	product := newB2BProduct(productInfo)
+
+	if err := db.Create(&product).Error; err != nil {
+		errMsg := fmt.Sprintf("%s: Veritabanı hatası - %v", stockCode, err)
+		log.Printf("HATA: %s", errMsg)
+		return jobs.StepFailed, errMsg
+	}
+
+	log.Printf("Ürün eklendi: %s - %s", stockCode, productInfo.Name)
+
+	// Fotoğrafı indir (eğer varsa). Ürün eklendiği için iş iptal edilse de indirme tamamlanır;
+	// aksi halde devam ettirildiğinde ürün "zaten var" diye atlanır ve fotoğrafsız kalır.
+	// Sayfada fotoğraf bulunamadıysa DownloadProductImage aynı sayfayı tekrar tarayacağı için denenmez.
+	if productInfo.ImageURL != "" {
+		if _, err := downloadImageFromURL(context.WithoutCancel(ctx), fetcher, productInfo.ImageURL, productInfo.StockCode, cfg.ProductImagePath); err != nil {
+			// Fotoğraf indirme hatası kritik değil, log'la ama devam et
+			errMsg = fmt.Sprintf("%s: Fotoğraf indirilemedi - %v", stockCode, err)
+			log.Printf("UYARI: %s", errMsg)
+		} else {
+			log.Printf("Fotoğraf indirildi: %s.jpg", stockCode)
 		}
 	}
 
-	log.Printf("Bulk import tamamlandı: %d imported, %d skipped, %d errors", imported, skipped, len(errors))
-	return imported, skipped, errors, false
+	return jobs.StepSucceeded, errMsg
 }
 
-// downloadImageFromURL: Belirli bir URL'den resim indirir
-func downloadImageFromURL(imageURL string, stockCode string, savePath string) (string, error) {
+// newB2BProduct: B2B'den çekilen bilgilerden yeni merkez ürünü oluşturur
+// Sayfada birim yoksa adet olarak kaydedilir
+func newB2BProduct(info *B2BProductInfo) models.Product {
+	unit := info.Unit
+	if unit == "" {
+		unit = "adet"
+	}
+	return models.Product{
+		Name:            info.Name,
+		Unit:            unit,
+		StockCode:       info.StockCode,
+		Category:        info.Category,
+		IsCenterProduct: true,
+		IsActive:        true,
+		ImageURL:        info.ImageURL,
+	}
+}
+
+// downloadImageFromURL: Belirli bir URL'den resim indirir (dosya zaten varsa indirmez)
+// fetcher nil ise DefaultB2BFetcher kullanılır
+func downloadImageFromURL(ctx context.Context, fetcher *B2BFetcher, imageURL string, stockCode string, savePath string) (string, error) {
 	if imageURL == "" || stockCode == "" {
 		return "", fmt.Errorf("resim URL veya stok kodu boş")
 	}
@@ -465,46 +530,37 @@ func downloadImageFromURL(imageURL string, stockCode string, savePath string) (s
 		return filePath, nil
 	}
 
-	// HTTP client oluştur
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	if err := saveImageFromURL(ctx, fetcher, imageURL, filePath); err != nil {
+		return "", err
 	}
+	return filePath, nil
+}
 
-	req, err := http.NewRequest("GET", imageURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("HTTP isteği oluşturulamadı: %v", err)
+// saveImageFromURL: Resmi indirip filePath'e yazar (varsa üzerine yazar).
+// Önce geçici dosyaya yazılır, indirme yarıda kalırsa eski/yarım dosya kalmaz.
+func saveImageFromURL(ctx context.Context, fetcher *B2BFetcher, imageURL string, filePath string) error {
+	if fetcher == nil {
+		fetcher = DefaultB2BFetcher
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-
 	// Resmi indir
-	imageResp, err := client.Do(req)
+	image, err := fetcher.Get(ctx, imageURL)
 	if err != nil {
-		return "", fmt.Errorf("resim indirilemedi: %v", err)
-	}
-	defer imageResp.Body.Close()
-
-	if imageResp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("resim indirme hatası: %d", imageResp.StatusCode)
+		return fmt.Errorf("resim indirilemedi: %v", err)
 	}
 
 	// Klasörü oluştur (yoksa)
-	if err := os.MkdirAll(savePath, 0755); err != nil {
-		return "", fmt.Errorf("klasör oluşturulamadı: %v", err)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("klasör oluşturulamadı: %v", err)
 	}
 
-	// Dosyayı oluştur
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("dosya oluşturulamadı: %v", err)
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, image, 0644); err != nil {
+		return fmt.Errorf("resim yazılamadı: %v", err)
 	}
-	defer file.Close()
-
-	// Resmi dosyaya yaz
-	_, err = io.Copy(file, imageResp.Body)
-	if err != nil {
-		return "", fmt.Errorf("resim yazılamadı: %v", err)
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("resim yazılamadı: %v", err)
 	}
-
-	return filePath, nil
+	return nil
 }
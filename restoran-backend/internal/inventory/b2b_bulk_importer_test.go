@@ -0,0 +1,47 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/jobs"
+	"restoran-backend/internal/models"
+)
+
+// Sayfası olmayan (404) stok kodu atlanır; sunucu hatası (503) başarısız sayılır ve rapora yazılır
+func TestImportB2BProductPageErrors(t *testing.T) {
+	newB2BCatalogServer(t, map[string]string{
+		"TI0001": b2bProductPage("TI0001", "Import Kürdan", "Ambalaj", "Paket"),
+	}, map[string]int{
+		"TI0503": http.StatusServiceUnavailable,
+	})
+	cfg := &config.Config{ProductImagePath: t.TempDir()}
+	fetcher := NewB2BFetcher(B2BFetcherOptions{MaxRetries: 0})
+
+	tests := []struct {
+		stockCode  string
+		wantResult jobs.StepResult
+		wantMsg    string
+	}{
+		{"TI0404", jobs.StepSkipped, ""},
+		{"TI0503", jobs.StepFailed, "TI0503: HTTP hatası: 503"},
+		{"TI0001", jobs.StepSucceeded, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stockCode, func(t *testing.T) {
+			result, errMsg := importB2BProduct(context.Background(), cfg, fetcher, tt.stockCode)
+			if result != tt.wantResult || errMsg != tt.wantMsg {
+				t.Errorf("sonuç = %v, hata = %q; %v, %q bekleniyordu", result, errMsg, tt.wantResult, tt.wantMsg)
+			}
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.Product{}).Where("stock_code IN ?", []string{"TI0001", "TI0404", "TI0503"}).Count(&count)
+	if count != 1 {
+		t.Errorf("eklenen ürün = %d, sadece TI0001 bekleniyordu", count)
+	}
+}
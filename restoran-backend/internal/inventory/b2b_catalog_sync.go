@@ -0,0 +1,263 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/jobs"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/units"
+
+	"gorm.io/gorm"
+)
+
+// CatalogSyncReport: Katalog senkronizasyonunda yapılan değişiklikler (iş sonucu olarak saklanır)
+type CatalogSyncReport struct {
+	Created       int             `json:"created"`
+	Updated       int             `json:"updated"`
+	Deactivated   int             `json:"deactivated"`
+	ImagesUpdated int             `json:"images_updated"`
+	Changes       []CatalogChange `json:"changes"`
+}
+
+// CatalogChange: Tek bir üründeki değişiklik
+type CatalogChange struct {
+	StockCode    string               `json:"stock_code"`
+	ProductID    uint                 `json:"product_id"`
+	ProductName  string               `json:"product_name"`
+	Action       string               `json:"action"` // "created", "updated", "deactivated"
+	Fields       []CatalogFieldChange `json:"fields,omitempty"`
+	ImageUpdated bool                 `json:"image_updated,omitempty"`
+}
+
+type CatalogFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+const (
+	catalogChangeCreated     = "created"
+	catalogChangeUpdated     = "updated"
+	catalogChangeDeactivated = "deactivated"
+)
+
+func (r *CatalogSyncReport) add(c CatalogChange) {
+	switch c.Action {
+	case catalogChangeCreated:
+		r.Created++
+	case catalogChangeUpdated:
+		r.Updated++
+	case catalogChangeDeactivated:
+		r.Deactivated++
+	}
+	if c.ImageUpdated {
+		r.ImagesUpdated++
+	}
+	r.Changes = append(r.Changes, c)
+}
+
+// catalogSyncActor: Audit log'a yazılacak kullanıcı (işi başlatan)
+type catalogSyncActor struct {
+	UserID   uint
+	UserName string
+}
+
+func loadCatalogSyncActor(userID uint) catalogSyncActor {
+	actor := catalogSyncActor{UserID: userID, UserName: "Katalog senkronizasyonu"}
+	var user models.User
+	if err := database.DB.Select("id", "name").First(&user, "id = ?", userID).Error; err == nil {
+		actor.UserName = user.Name
+	}
+	return actor
+}
+
+// syncB2BProduct: Stok kodunu B2B'den çekip mevcut ürünle karşılaştırır.
+// Yeni ürünleri ekler; ad, kategori ve birim değişikliklerini günceller, fotoğraf
+// adresi değiştiyse fotoğrafı yeniden indirir. Sayfası kalkan ürünler pasife alınır.
+// Her değişiklik için audit log yazılır ve rapor satırı döner.
+func syncB2BProduct(ctx context.Context, cfg *config.Config, fetcher *B2BFetcher, stockCode string, actor catalogSyncActor) (jobs.StepResult, string, *CatalogChange) {
+	db := database.DB
+
+	info, scrapeErr := ScrapeB2BProductPage(ctx, fetcher, stockCode)
+	if scrapeErr != nil && !errors.Is(scrapeErr, errB2BProductNotFound) {
+		if ctx.Err() != nil {
+			return jobs.StepSkipped, "", nil
+		}
+		// Ağ hatası vb. - ürün pasife alınmaz, hata olarak raporlanır
+		return jobs.StepFailed, fmt.Sprintf("%s: Sayfa okunamadı - %v", stockCode, scrapeErr), nil
+	}
+
+	// Mevcut ürünü bul: önce stok kodu, yoksa stok kodu boş olan aynı isimli ürün
+	var existing *models.Product
+	var byCode models.Product
+	err := db.Where("stock_code = ? AND is_center_product = ?", stockCode, true).First(&byCode).Error
+	switch {
+	case err == nil:
+		existing = &byCode
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return jobs.StepFailed, fmt.Sprintf("%s: Veritabanı hatası - %v", stockCode, err), nil
+	case info != nil:
+		var byName models.Product
+		if err := db.Where("name = ? AND (stock_code = '' OR stock_code IS NULL)", info.Name).First(&byName).Error; err == nil {
+			existing = &byName
+		}
+	}
+
+	// Katalogdan kalkmış
+	if info == nil {
+		if existing == nil || !existing.IsActive {
+			return jobs.StepSkipped, "", nil
+		}
+		before := *existing
+		existing.IsActive = false
+		if err := db.Model(existing).Update("is_active", false).Error; err != nil {
+			return jobs.StepFailed, fmt.Sprintf("%s: Ürün pasife alınamadı - %v", stockCode, err), nil
+		}
+		change := &CatalogChange{
+			StockCode:   stockCode,
+			ProductID:   existing.ID,
+			ProductName: existing.Name,
+			Action:      catalogChangeDeactivated,
+			Fields:      []CatalogFieldChange{{Field: "is_active", Old: "true", New: "false"}},
+		}
+		writeCatalogAudit(actor, change, before, *existing)
+		return jobs.StepSucceeded, "", change
+	}
+
+	// Yeni ürün
+	if existing == nil {
+		product := newB2BProduct(info)
+		if err := db.Create(&product).Error; err != nil {
+			errMsg := fmt.Sprintf("%s: Veritabanı hatası - %v", stockCode, err)
+			log.Printf("HATA: %s", errMsg)
+			return jobs.StepFailed, errMsg, nil
+		}
+		change := &CatalogChange{
+			StockCode:   stockCode,
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Action:      catalogChangeCreated,
+		}
+		var errMsg string
+		if info.ImageURL != "" {
+			if _, err := downloadImageFromURL(context.WithoutCancel(ctx), fetcher, info.ImageURL, info.StockCode, cfg.ProductImagePath); err != nil {
+				errMsg = fmt.Sprintf("%s: Fotoğraf indirilemedi - %v", stockCode, err)
+			} else {
+				change.ImageUpdated = true
+			}
+		}
+		writeCatalogAudit(actor, change, nil, product)
+		return jobs.StepSucceeded, errMsg, change
+	}
+
+	// Mevcut ürün: farkları uygula
+	before := *existing
+	change := &CatalogChange{
+		StockCode:   stockCode,
+		ProductID:   existing.ID,
+		ProductName: info.Name,
+		Action:      catalogChangeUpdated,
+	}
+	setField := func(field string, current *string, value string) {
+		if value != "" && *current != value {
+			change.Fields = append(change.Fields, CatalogFieldChange{Field: field, Old: *current, New: value})
+			*current = value
+		}
+	}
+	setField("name", &existing.Name, info.Name)
+	setField("category", &existing.Category, info.Category)
+	setField("stock_code", &existing.StockCode, info.StockCode)
+	if !existing.IsActive {
+		change.Fields = append(change.Fields, CatalogFieldChange{Field: "is_active", Old: "false", New: "true"})
+		existing.IsActive = true
+	}
+
+	// Ek birimleri tanımlı ürünün temel birimi değiştirilmez (katsayılar ve defter eski birimde);
+	// çakışma rapora yazılır, diğer alanlar yine güncellenir
+	var errMsgs []string
+	if info.Unit != "" && existing.Unit != info.Unit {
+		if err := units.EnsureBaseUnitChange(db, models.UnitKindProduct, existing.ID, existing.Unit, info.Unit); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: Birim '%s' -> '%s' değiştirilmedi - %v", stockCode, existing.Unit, info.Unit, err))
+		} else {
+			setField("unit", &existing.Unit, info.Unit)
+		}
+	}
+
+	if info.ImageURL != "" && info.ImageURL != existing.ImageURL {
+		filePath := filepath.Join(cfg.ProductImagePath, fmt.Sprintf("%s.jpg", existing.StockCode))
+		_, statErr := os.Stat(filePath)
+		if existing.ImageURL == "" && statErr == nil {
+			// İlk senkronizasyon: fotoğraf zaten indirilmiş, sadece adresi kaydet
+			existing.ImageURL = info.ImageURL
+		} else if err := saveImageFromURL(context.WithoutCancel(ctx), fetcher, info.ImageURL, filePath); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: Fotoğraf indirilemedi - %v", stockCode, err))
+		} else {
+			change.Fields = append(change.Fields, CatalogFieldChange{Field: "image_url", Old: existing.ImageURL, New: info.ImageURL})
+			change.ImageUpdated = true
+			existing.ImageURL = info.ImageURL
+		}
+	}
+
+	errMsg := strings.Join(errMsgs, "; ")
+	if existing.ImageURL == before.ImageURL && len(change.Fields) == 0 {
+		return jobs.StepSkipped, errMsg, nil
+	}
+
+	if err := db.Save(existing).Error; err != nil {
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			return jobs.StepFailed, fmt.Sprintf("%s: '%s' adıyla başka bir ürün var, güncellenemedi", stockCode, info.Name), nil
+		}
+		return jobs.StepFailed, fmt.Sprintf("%s: Ürün güncellenemedi - %v", stockCode, err), nil
+	}
+
+	// Sadece fotoğraf adresi kaydedildiyse (ilk senkronizasyon) rapora yazılmaz
+	if len(change.Fields) == 0 {
+		return jobs.StepSkipped, errMsg, nil
+	}
+
+	writeCatalogAudit(actor, change, before, *existing)
+	return jobs.StepSucceeded, errMsg, change
+}
+
+func writeCatalogAudit(actor catalogSyncActor, change *CatalogChange, before any, after models.Product) {
+	action := models.AuditActionUpdate
+	if change.Action == catalogChangeCreated {
+		action = models.AuditActionCreate
+	}
+
+	fields := make([]string, 0, len(change.Fields))
+	for _, f := range change.Fields {
+		fields = append(fields, f.Field)
+	}
+	description := fmt.Sprintf("B2B katalog senkronizasyonu: %s (%s)", after.Name, change.StockCode)
+	switch change.Action {
+	case catalogChangeCreated:
+		description += " - yeni ürün"
+	case catalogChangeDeactivated:
+		description += " - katalogdan kalktı, pasife alındı"
+	default:
+		description += " - değişen: " + strings.Join(fields, ", ")
+	}
+
+	if logErr := audit.WriteLog(audit.LogOptions{
+		UserID:      actor.UserID,
+		UserName:    actor.UserName,
+		EntityType:  "product",
+		EntityID:    after.ID,
+		Action:      action,
+		Description: description,
+		Before:      before,
+		After:       after,
+	}); logErr != nil {
+		fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+	}
+}
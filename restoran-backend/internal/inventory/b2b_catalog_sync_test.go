@@ -0,0 +1,93 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/jobs"
+	"restoran-backend/internal/models"
+)
+
+// newB2BCatalogServer: /Store/Detail/KOD için ürün sayfası döner; pages'ta olmayan kod için
+// statuses'taki durum kodu (yoksa 404) yazılır. Test süresince b2bBaseURL bu sunucuya çevrilir.
+func newB2BCatalogServer(t *testing.T, pages map[string]string, statuses map[string]int) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := strings.TrimPrefix(r.URL.Path, "/Store/Detail/")
+		if page, ok := pages[code]; ok {
+			fmt.Fprint(w, page)
+			return
+		}
+		if status, ok := statuses[code]; ok {
+			w.WriteHeader(status)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	oldURL := b2bBaseURL
+	b2bBaseURL = srv.URL
+	t.Cleanup(func() { b2bBaseURL = oldURL })
+}
+
+// b2bProductPage: B2B ürün detay sayfasının scraper'ın okuduğu kısımları
+func b2bProductPage(stockCode, name, category, unit string) string {
+	return fmt.Sprintf(`<html><head><title>%s - Kodex B2B</title></head><body>
+<h3>Ürün Detayı</h3>
+<h4>%s</h4>
+<span>STK : %s</span>
+<p>Kategori : %s</p>
+<p>Birim : %s</p>
+</body></html>`, name, name, stockCode, category, unit)
+}
+
+// Ek birimleri tanımlı ürünün temel birimi senkronizasyonda değiştirilmez, çakışma rapora yazılır;
+// ek birimi olmayan üründe birim B2B'deki haline güncellenir
+func TestSyncB2BProductUnitChange(t *testing.T) {
+	db := database.DB
+	withUnits := models.Product{Name: "Sync Peçete", StockCode: "TS0001", Unit: "adet", Category: "Ambalaj", IsCenterProduct: true, IsActive: true}
+	plain := models.Product{Name: "Sync Bardak", StockCode: "TS0002", Unit: "adet", Category: "Ambalaj", IsCenterProduct: true, IsActive: true}
+	for _, p := range []*models.Product{&withUnits, &plain} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.ProductUnit{Kind: models.UnitKindProduct, ProductID: withUnits.ID, Unit: "koli", Label: "Koli", Factor: 12}).Error; err != nil {
+		t.Fatal(err)
+	}
+	newB2BCatalogServer(t, map[string]string{
+		"TS0001": b2bProductPage("TS0001", "Sync Peçete", "Ambalaj", "Paket"),
+		"TS0002": b2bProductPage("TS0002", "Sync Bardak", "Ambalaj", "Paket"),
+	}, nil)
+
+	cfg := &config.Config{ProductImagePath: t.TempDir()}
+	fetcher := NewB2BFetcher(B2BFetcherOptions{MaxRetries: 0})
+	actor := catalogSyncActor{UserID: 1, UserName: "test"}
+
+	result, errMsg, _ := syncB2BProduct(context.Background(), cfg, fetcher, "TS0001", actor)
+	if result != jobs.StepSkipped || !strings.Contains(errMsg, "Birim") {
+		t.Errorf("ek birimli ürün: sonuç = %v, hata = %q; atlanması ve birim çakışmasının raporlanması bekleniyordu", result, errMsg)
+	}
+	var got models.Product
+	db.First(&got, withUnits.ID)
+	if got.Unit != "adet" {
+		t.Errorf("ek birimli ürünün birimi = %q, adet kalması bekleniyordu", got.Unit)
+	}
+
+	result, errMsg, change := syncB2BProduct(context.Background(), cfg, fetcher, "TS0002", actor)
+	if result != jobs.StepSucceeded || errMsg != "" || change == nil {
+		t.Fatalf("ek birimsiz ürün: sonuç = %v, hata = %q; güncellenmesi bekleniyordu", result, errMsg)
+	}
+	var gotPlain models.Product
+	db.First(&gotPlain, plain.ID)
+	if gotPlain.Unit != "paket" {
+		t.Errorf("ek birimsiz ürünün birimi = %q, paket bekleniyordu", gotPlain.Unit)
+	}
+}
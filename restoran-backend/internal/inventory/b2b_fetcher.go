@@ -0,0 +1,307 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// b2bBaseURL: B2B sitesinin adresi (ürün sayfaları ve fotoğraflar)
+var b2bBaseURL = "https://" + b2bOrderHost
+
+// B2BFetcherOptions: B2B sitesine yapılan isteklerin sınırları
+type B2BFetcherOptions struct {
+	RatePerSecond float64       // saniyedeki en fazla istek (token bucket); 0 ise sınırsız
+	Burst         int           // art arda yapılabilecek en fazla istek
+	PerHost       int           // aynı host'a aynı anda açık en fazla istek; 0 ise sınırsız
+	MaxRetries    int           // 5xx, 429 ve zaman aşımında tekrar deneme sayısı
+	Backoff       time.Duration // ilk tekrar denemeden önceki bekleme (her denemede iki katına çıkar)
+	Timeout       time.Duration // tek bir isteğin zaman aşımı
+}
+
+// B2BFetcher: Hız sınırlı, host başına eşzamanlılık sınırlı ve hata durumunda
+// tekrar deneyen HTTP istemcisi. Aynı anda birden fazla goroutine kullanabilir.
+type B2BFetcher struct {
+	client     *http.Client
+	limiter    *tokenBucket
+	hosts      *hostLimiter
+	maxRetries int
+	backoff    time.Duration
+}
+
+// DefaultB2BFetcher: Tek seferlik istekler için varsayılan istemci
+var DefaultB2BFetcher = NewB2BFetcher(B2BFetcherOptions{
+	RatePerSecond: 5,
+	Burst:         5,
+	PerHost:       4,
+	MaxRetries:    3,
+	Backoff:       500 * time.Millisecond,
+})
+
+func NewB2BFetcher(opts B2BFetcherOptions) *B2BFetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	return &B2BFetcher{
+		client:     &http.Client{Timeout: opts.Timeout},
+		limiter:    newTokenBucket(opts.RatePerSecond, opts.Burst),
+		hosts:      newHostLimiter(opts.PerHost),
+		maxRetries: opts.MaxRetries,
+		backoff:    opts.Backoff,
+	}
+}
+
+// b2bStatusError: Sunucunun 200 dışında döndüğü durum kodu
+type b2bStatusError struct {
+	StatusCode int
+}
+
+func (e *b2bStatusError) Error() string {
+	return fmt.Sprintf("HTTP hatası: %d", e.StatusCode)
+}
+
+// Get: URL'i indirir; 5xx, 429 ve zaman aşımında artan beklemeyle tekrar dener
+func (f *B2BFetcher) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz URL: %v", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= f.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := f.backoff << (attempt - 1)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		body, err := f.do(ctx, u)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = err
+		if !isRetryable(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (f *B2BFetcher) do(ctx context.Context, u *url.URL) ([]byte, error) {
+	if err := f.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	release, err := f.hosts.acquire(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP isteği oluşturulamadı: %v", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP isteği başarısız: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &b2bStatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("yanıt okunamadı: %w", err)
+	}
+	return body, nil
+}
+
+// isRetryable: Geçici hatalar (5xx, 429, zaman aşımı) tekrar denenir
+func isRetryable(err error) bool {
+	var statusErr *b2bStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// tokenBucket: Saniyede rate kadar jeton üreten, en fazla burst jeton biriktiren hız sınırlayıcı
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait: Jeton alınana veya ctx iptal edilene kadar bekler
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return ctx.Err()
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// hostLimiter: Host başına eşzamanlı istek sınırı
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	sems  map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, sems: make(map[string]chan struct{})}
+}
+
+func (h *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	if h.limit <= 0 {
+		return func() {}, nil
+	}
+	h.mu.Lock()
+	sem, ok := h.sems[host]
+	if !ok {
+		sem = make(chan struct{}, h.limit)
+		h.sems[host] = sem
+	}
+	h.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runOrdered: 0..n-1 arasındaki işleri workers kadar goroutine ile paralel çalıştırır,
+// sonuçları sıra numarasına göre emit'e verir. Böylece emit'e gelen son sıra numarası,
+// kendisinden önceki tüm işlerin bittiğini garanti eder (kaldığı yerden devam için).
+// ctx iptal edilince veya emit hata dönünce yeni iş dağıtılmaz, bekleyen sonuçlar atılır.
+func runOrdered[T any](ctx context.Context, workers, n int, work func(ctx context.Context, i int) T, emit func(i int, result T) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexed struct {
+		i      int
+		result T
+	}
+	indexes := make(chan int)
+	results := make(chan indexed, workers)
+
+	go func() {
+		defer close(indexes)
+		for i := 0; i < n; i++ {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results <- indexed{i: i, result: work(ctx, i)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]T)
+	next := 0
+	var emitErr error
+	for r := range results {
+		// İptal veya hata sonrası gelen sonuçlar kaydedilmez (kısmi sonuç olabilir)
+		if emitErr != nil || ctx.Err() != nil {
+			continue
+		}
+		pending[r.i] = r.result
+		for {
+			result, ok := pending[next]
+			if !ok || ctx.Err() != nil {
+				break
+			}
+			delete(pending, next)
+			if err := emit(next, result); err != nil {
+				emitErr = err
+				cancel()
+				break
+			}
+			next++
+		}
+	}
+
+	if emitErr != nil {
+		return emitErr
+	}
+	if next < n {
+		return ctx.Err()
+	}
+	return nil
+}
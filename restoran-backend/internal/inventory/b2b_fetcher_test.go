@@ -0,0 +1,251 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newB2BTestServer: /item/N için "item-N" döner; yüksek numaralı sayfalar daha hızlı yanıt verir
+// ki sonuçlar istek sırasından farklı sırayla tamamlansın
+func newB2BTestServer(t *testing.T, n int) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/item/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		select {
+		case <-time.After(time.Duration(n-i) * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprintf(w, "item-%d", i)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestRunOrderedEmitsInIndexOrder(t *testing.T) {
+	const n = 30
+	srv, _ := newB2BTestServer(t, n)
+	fetcher := NewB2BFetcher(B2BFetcherOptions{PerHost: 8, MaxRetries: 0})
+
+	var got []string
+	err := runOrdered(context.Background(), 8, n,
+		func(ctx context.Context, i int) string {
+			body, err := fetcher.Get(ctx, fmt.Sprintf("%s/item/%d", srv.URL, i))
+			if err != nil {
+				return err.Error()
+			}
+			return string(body)
+		},
+		func(i int, result string) error {
+			if i != len(got) {
+				t.Errorf("sonuç %d, beklenen sıra %d", i, len(got))
+			}
+			got = append(got, result)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != n {
+		t.Fatalf("sonuç sayısı = %d, beklenen %d", len(got), n)
+	}
+	for i, body := range got {
+		if want := fmt.Sprintf("item-%d", i); body != want {
+			t.Errorf("sonuç %d = %q, beklenen %q", i, body, want)
+		}
+	}
+}
+
+func TestRunOrderedStopsOnCancel(t *testing.T) {
+	const n = 200
+	srv, hits := newB2BTestServer(t, 20)
+	fetcher := NewB2BFetcher(B2BFetcherOptions{PerHost: 4})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	emitted := 0
+	err := runOrdered(ctx, 4, n,
+		func(ctx context.Context, i int) error {
+			_, err := fetcher.Get(ctx, fmt.Sprintf("%s/item/%d", srv.URL, i%20))
+			return err
+		},
+		func(i int, result error) error {
+			if result != nil {
+				t.Errorf("iş %d hata döndü: %v", i, result)
+			}
+			if i != emitted {
+				t.Errorf("sonuç %d, beklenen sıra %d", i, emitted)
+			}
+			emitted++
+			if emitted == 5 {
+				cancel()
+			}
+			return nil
+		})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("hata = %v, beklenen context.Canceled", err)
+	}
+	if emitted != 5 {
+		t.Errorf("iptalden sonra sonuç verildi: %d sonuç, beklenen 5", emitted)
+	}
+	// İptalden sonra yeni iş dağıtılmaz (sıra bekleyen sonuçlar kadar fazladan istek olabilir)
+	if h := atomic.LoadInt32(hits); h > 40 {
+		t.Errorf("iptalden sonra istek atılmaya devam etti: %d istek", h)
+	}
+}
+
+func TestRunOrderedStopsOnEmitError(t *testing.T) {
+	stop := errors.New("dur")
+	emitted := 0
+	err := runOrdered(context.Background(), 3, 50,
+		func(ctx context.Context, i int) int { return i },
+		func(i int, result int) error {
+			emitted++
+			if i == 9 {
+				return stop
+			}
+			return nil
+		})
+	if !errors.Is(err, stop) {
+		t.Fatalf("hata = %v, beklenen %v", err, stop)
+	}
+	if emitted != 10 {
+		t.Errorf("sonuç sayısı = %d, beklenen 10", emitted)
+	}
+}
+
+func TestB2BFetcherRateLimit(t *testing.T) {
+	srv, hits := newB2BTestServer(t, 0)
+	// Saniyede 20 istek, 2 jetonluk birikim: 8 istek en az (8-2)/20 = 300ms sürer
+	fetcher := NewB2BFetcher(B2BFetcherOptions{RatePerSecond: 20, Burst: 2})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := fetcher.Get(context.Background(), fmt.Sprintf("%s/item/%d", srv.URL, i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 280*time.Millisecond {
+		t.Errorf("8 istek %v sürdü, hız sınırı uygulanmadı (en az 300ms beklenirdi)", elapsed)
+	}
+	if h := atomic.LoadInt32(hits); h != 8 {
+		t.Errorf("istek sayısı = %d, beklenen 8", h)
+	}
+}
+
+func TestB2BFetcherRateLimitHonorsCancel(t *testing.T) {
+	srv, _ := newB2BTestServer(t, 0)
+	fetcher := NewB2BFetcher(B2BFetcherOptions{RatePerSecond: 0.5, Burst: 1})
+
+	if _, err := fetcher.Get(context.Background(), srv.URL+"/item/0"); err != nil {
+		t.Fatal(err)
+	}
+	// Sonraki jeton 2 saniye sonra; iptal beklemeyi kesmeli
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := fetcher.Get(ctx, srv.URL+"/item/1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("hata = %v, beklenen context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("iptal edilen istek %v bekledi", elapsed)
+	}
+}
+
+func TestB2BFetcherPerHostLimit(t *testing.T) {
+	var current, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	fetcher := NewB2BFetcher(B2BFetcherOptions{PerHost: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fetcher.Get(context.Background(), srv.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("aynı anda %d istek açıldı, sınır 2", p)
+	}
+}
+
+func TestB2BFetcherRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int // sırayla dönülecek durum kodları, sonrası 200
+		wantErr   bool
+		wantCalls int32
+	}{
+		{name: "geçici 5xx tekrar denenir", statuses: []int{503, 502}, wantCalls: 3},
+		{name: "429 tekrar denenir", statuses: []int{429}, wantCalls: 2},
+		{name: "404 tekrar denenmez", statuses: []int{404}, wantErr: true, wantCalls: 1},
+		{name: "deneme hakkı biter", statuses: []int{500, 500, 500, 500, 500}, wantErr: true, wantCalls: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if int(n) <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[n-1])
+					return
+				}
+				fmt.Fprint(w, "ok")
+			}))
+			defer srv.Close()
+
+			fetcher := NewB2BFetcher(B2BFetcherOptions{MaxRetries: 3, Backoff: time.Millisecond})
+			body, err := fetcher.Get(context.Background(), srv.URL)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("hata = %v, beklenen hata: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(body) != "ok" {
+				t.Errorf("yanıt = %q, beklenen ok", body)
+			}
+			if c := atomic.LoadInt32(&calls); c != tt.wantCalls {
+				t.Errorf("istek sayısı = %d, beklenen %d", c, tt.wantCalls)
+			}
+		})
+	}
+}
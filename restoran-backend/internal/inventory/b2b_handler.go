@@ -8,9 +8,10 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// ParseB2BOrderURLHandler: B2B URL'den sipariş bilgilerini çeker ve parse eder
+// ParseB2BOrderURLHandler: Tedarikçi URL'inden sipariş bilgilerini çeker ve parse eder
 // POST /api/shipments/parse-order-url
 // Body: { "url": "https://b2b.cadininevi.com.tr/Store/OrderDetail/..." }
+// Parser, URL host'u veya dönen içerik tipine göre kayıtlı parser'lar arasından seçilir
 func ParseB2BOrderURLHandler(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var body struct {
@@ -26,15 +27,25 @@ func ParseB2BOrderURLHandler(cfg *config.Config) fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "URL boş olamaz")
 		}
 
-		// URL'i parse et
+		// Belgeyi indir, parser'ı URL host'u veya içerik tipine göre seç
 		log.Printf("B2B URL parsing başladı: %s", body.URL)
-		result, err := ParseB2BOrderURL(body.URL)
+		doc, err := FetchOrderDocument(body.URL)
+		if err != nil {
+			log.Printf("B2B URL fetch error: %v", err)
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Sipariş bilgileri alınamadı: %v", err))
+		}
+		result, parser, err := ParseOrderDocument(doc)
 		if err != nil {
 			log.Printf("B2B URL parse error: %v", err)
 			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Sipariş bilgileri alınamadı: %v", err))
 		}
 
-		log.Printf("B2B URL parse başarılı, %d ürün bulundu", len(result.Products))
+		log.Printf("B2B URL parse başarılı (%s), %d ürün bulundu", parser.Name(), len(result.Products))
+
+		// Fotoğraflar sadece merkez mutfağın B2B sisteminden indirilebilir
+		if _, ok := parser.(cadininEviOrderParser); !ok {
+			return c.JSON(result)
+		}
 
 		// Tüm ürünler için fotoğraf indir (sync - parse işlemi sırasında)
 		// Fotoğraf zaten varsa indirme yapılmaz (DownloadProductImage içinde kontrol ediliyor)
@@ -1,48 +1,39 @@
 package inventory
 
 import (
+	"bytes"
 	"fmt"
 	"html"
-	"io"
-	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
-// ParseB2BOrderURL: B2B sisteminden sipariş bilgilerini çeker
-func ParseB2BOrderURL(url string) (*ParsePDFResponse, error) {
-	// HTTP isteği yap
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP isteği oluşturulamadı: %v", err)
-	}
-	
-	// User-Agent ekle (bazı siteler bot isteklerini engeller)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-	
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP isteği başarısız: %v", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP hatası: %d", resp.StatusCode)
+// b2bOrderHost: Merkez mutfağın B2B sipariş sistemi
+const b2bOrderHost = "b2b.cadininevi.com.tr"
+
+// cadininEviOrderParser: b2b.cadininevi.com.tr sipariş detay sayfası (HTML)
+type cadininEviOrderParser struct{}
+
+func (cadininEviOrderParser) Name() string { return "cadininevi_html" }
+
+// Match: URL'den çekilen sayfada host (veya alt alan adı), dosya olarak yüklenen kayıtlı sayfada
+// (host yok) içerik - sipariş başlığı ve ürün tablosu - esas alınır
+func (cadininEviOrderParser) Match(doc OrderDocument) bool {
+	if doc.ContentType != ContentTypeHTML {
+		return false
 	}
-	
-	// HTML içeriğini oku
-	htmlBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("HTML okunamadı: %v", err)
+	if doc.Host != "" {
+		return doc.Host == b2bOrderHost || strings.HasSuffix(doc.Host, "."+b2bOrderHost)
 	}
-	
-	htmlContent := string(htmlBytes)
-	
+	return bytes.Contains(doc.Data, []byte("Sipariş Tarihi")) && bytes.Contains(doc.Data, []byte("Stok Kodu"))
+}
+
+func (cadininEviOrderParser) Parse(doc OrderDocument) (*ParsePDFResponse, error) {
+	return parseCadininEviOrderHTML(string(doc.Data))
+}
+
+// parseCadininEviOrderHTML: Sipariş detay sayfasındaki ürün tablosunu parse eder
+func parseCadininEviOrderHTML(htmlContent string) (*ParsePDFResponse, error) {
 	// Sipariş numarasını çıkar: "No:CB22901AC48C501"
 	orderNumberRe := regexp.MustCompile(`No:\s*([A-Z0-9]+)`)
 	orderNumberMatch := orderNumberRe.FindStringSubmatch(htmlContent)
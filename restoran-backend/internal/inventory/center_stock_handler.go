@@ -5,11 +5,14 @@ import (
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type CreateCenterShipmentRequest struct {
@@ -60,86 +63,6 @@ type MonthlyStockReportResponse struct {
 	Rows     []MonthlyStockRow `json:"rows"`
 }
 
-// Yardımcı: Kullanıcı bilgilerini al
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserRoleKey)
-	_, ok := userIDVal.(models.UserRole)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	userIDVal2 := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal2.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
-// branch_id çöz (branch_admin -> JWT, super_admin -> body/query)
-func resolveBranchIDFromBodyOrRole(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 // ---------------------------------------------
 // MERKEZDEN GELEN ÜRÜN KAYDI
 // POST /api/center-shipments
@@ -155,7 +78,7 @@ func CreateCenterShipmentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "product_id, quantity ve unit_price zorunlu ve 0'dan büyük olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -165,6 +88,11 @@ func CreateCenterShipmentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Ürün var mı?
 		var product models.Product
 		if err := database.DB.First(&product, "id = ?", body.ProductID).Error; err != nil {
@@ -183,20 +111,25 @@ func CreateCenterShipmentHandler() fiber.Handler {
 			Note:       body.Note,
 		}
 
-		if err := database.DB.Create(&sh).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Kayıt oluşturulamadı")
+		// Kayıt ve stok defteri girişi birlikte yazılır
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&sh).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Kayıt oluşturulamadı")
+			}
+			if err := ledger.Record(tx, ledger.CenterShipmentMovement(sh)); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		// Audit log yaz
-		userID, userName, branchIDPtr, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
-			// branch_id log'da boş kalmasın; super_admin için body'den gelen branchID'yi kullan
-			logBranchID := branchIDPtr
-			if logBranchID == nil {
-				logBranchID = &branchID
-			}
 			_ = audit.WriteLog(audit.LogOptions{
-				BranchID:    logBranchID,
+				BranchID:    &branchID,
 				UserID:      userID,
 				UserName:    userName,
 				EntityType:  "center_shipment",
@@ -226,7 +159,7 @@ func CreateCenterShipmentHandler() fiber.Handler {
 // GET /api/center-shipments?from=...&to=...&product_id=...
 func ListCenterShipmentsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -306,7 +239,7 @@ func CreateStockSnapshotHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "type start_of_month veya end_of_month olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -316,6 +249,11 @@ func CreateStockSnapshotHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// ürün kontrol
 		var product models.Product
 		if err := database.DB.First(&product, "id = ?", body.ProductID).Error; err != nil {
@@ -335,19 +273,14 @@ func CreateStockSnapshotHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, branchIDPtr, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
-			// branch_id log'da boş kalmasın; super_admin için body'den gelen branchID'yi kullan
-			logBranchID := branchIDPtr
-			if logBranchID == nil {
-				logBranchID = &branchID
-			}
 			typeName := "Ay Başı"
 			if ss.Type == models.SnapshotEndOfMonth {
 				typeName = "Ay Sonu"
 			}
 			_ = audit.WriteLog(audit.LogOptions{
-				BranchID:    logBranchID,
+				BranchID:    &branchID,
 				UserID:      userID,
 				UserName:    userName,
 				EntityType:  "stock_snapshot",
@@ -384,7 +317,7 @@ type StockSnapshotResponse struct {
 // GET /api/stock-snapshots
 func ListStockSnapshotsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -422,7 +355,7 @@ func ListStockSnapshotsHandler() fiber.Handler {
 // ---------------------------------------------
 func MonthlyStockReportHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
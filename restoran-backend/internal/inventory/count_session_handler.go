@@ -0,0 +1,653 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreateCountSessionRequest struct {
+	Date       string `json:"date"`  // "2025-12-09"
+	Blind      bool   `json:"blind"` // kör sayım
+	Note       string `json:"note"`
+	ProductIDs []uint `json:"product_ids"` // boşsa tüm aktif merkez ürünleri
+	BranchID   *uint  `json:"branch_id"`   // super_admin için
+}
+
+type RecordCountLinesRequest struct {
+	Lines []CountLineRequest `json:"lines"`
+}
+
+type CountLineRequest struct {
+	ProductID       uint     `json:"product_id"`
+	CountedQuantity *float64 `json:"counted_quantity"` // null: sayımı temizle
+	Unit            string   `json:"unit"`             // opsiyonel, boşsa ürünün temel birimi
+	Note            string   `json:"note"`
+}
+
+type CountSessionResponse struct {
+	ID            uint                       `json:"id"`
+	BranchID      uint                       `json:"branch_id"`
+	Date          string                     `json:"date"`
+	Status        models.CountSessionStatus  `json:"status"`
+	Blind         bool                       `json:"blind"`
+	Note          string                     `json:"note"`
+	TotalLines    int                        `json:"total_lines"`
+	CountedLines  int                        `json:"counted_lines"`
+	VarianceLines int                        `json:"variance_lines,omitempty"` // farkı olan sayılmış ürünler
+	VarianceValue *float64                   `json:"variance_value,omitempty"` // farkların son alış fiyatıyla toplam tutarı
+	OpenedBy      uint                       `json:"opened_by"`
+	SubmittedAt   string                     `json:"submitted_at,omitempty"`
+	ApprovedBy    *uint                      `json:"approved_by,omitempty"`
+	ApprovedAt    string                     `json:"approved_at,omitempty"`
+	CreatedAt     string                     `json:"created_at"`
+	Lines         []CountSessionLineResponse `json:"lines,omitempty"`
+}
+
+type CountSessionLineResponse struct {
+	ProductID        uint     `json:"product_id"`
+	ProductName      string   `json:"product_name"`
+	StockCode        string   `json:"stock_code"`
+	Unit             string   `json:"unit"` // temel birim
+	SortOrder        int      `json:"sort_order"`
+	CountedQuantity  *float64 `json:"counted_quantity"`            // temel birimde
+	CountedUnit      string   `json:"counted_unit,omitempty"`      // sayımda girilen birim
+	UnitQuantity     *float64 `json:"unit_quantity,omitempty"`     // girilen birimdeki miktar
+	ExpectedQuantity *float64 `json:"expected_quantity,omitempty"` // kör sayımda onaya gönderilene kadar gizli
+	Variance         *float64 `json:"variance,omitempty"`          // sayılan - beklenen
+	VarianceValue    *float64 `json:"variance_value,omitempty"`
+	StockEntryID     *uint    `json:"stock_entry_id,omitempty"`
+	Note             string   `json:"note"`
+	CountedAt        string   `json:"counted_at,omitempty"`
+}
+
+// loadCountSession: Oturumu raf sırasıyla yükler, başka şubenin oturumu bulunamadı döner
+func loadCountSession(c *fiber.Ctx) (models.CountSession, error) {
+	var session models.CountSession
+	if err := tenancy.DB(c).Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order, id")
+	}).Preload("Lines.Product").First(&session, "id = ?", c.Params("id")).Error; err != nil {
+		return session, fiber.NewError(fiber.StatusNotFound, "Sayım oturumu bulunamadı")
+	}
+	return session, nil
+}
+
+// countSessionExpected: Onaylanmamış oturumda beklenen miktarlar - sayım tarihindeki bakiye
+// (onayda sayım farkı ledger.RecordCount ile bu bakiyeye göre yazılır; sonraki hareketler sayılmaz)
+func countSessionExpected(db *gorm.DB, session models.CountSession) (map[uint]float64, error) {
+	return ledger.BalancesAt(db, session.BranchID, session.Date)
+}
+
+// buildCountSessionResponse: Beklenen miktar onaylanmamış oturumlarda sayım tarihindeki defter
+// bakiyesidir, onaylanmış oturumlarda onay anındaki bakiye. Kör sayımda açıkken hiç gösterilmez.
+// withLines false ise (liste) sadece sayım ilerlemesi hesaplanır.
+func buildCountSessionResponse(db *gorm.DB, session models.CountSession, withLines bool) (CountSessionResponse, error) {
+	resp := CountSessionResponse{
+		ID:         session.ID,
+		BranchID:   session.BranchID,
+		Date:       session.Date.Format("2006-01-02"),
+		Status:     session.Status,
+		Blind:      session.Blind,
+		Note:       session.Note,
+		TotalLines: len(session.Lines),
+		OpenedBy:   session.OpenedBy,
+		ApprovedBy: session.ApprovedBy,
+		CreatedAt:  session.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if session.SubmittedAt != nil {
+		resp.SubmittedAt = session.SubmittedAt.Format("2006-01-02 15:04:05")
+	}
+	if session.ApprovedAt != nil {
+		resp.ApprovedAt = session.ApprovedAt.Format("2006-01-02 15:04:05")
+	}
+	for _, l := range session.Lines {
+		if l.CountedQuantity != nil {
+			resp.CountedLines++
+		}
+	}
+
+	// Liste görünümünde sadece sayım ilerlemesi döner
+	if !withLines {
+		return resp, nil
+	}
+	hideExpected := session.Blind && session.Status == models.CountSessionOpen
+
+	var balances, prices map[uint]float64
+	if !hideExpected {
+		var err error
+		if session.Status != models.CountSessionApproved {
+			if balances, err = countSessionExpected(db, session); err != nil {
+				return resp, err
+			}
+		}
+		if prices, err = ledger.LastUnitPrices(db, session.BranchID, session.Date.AddDate(0, 0, 1)); err != nil {
+			return resp, err
+		}
+	}
+
+	var totalValue float64
+	for _, l := range session.Lines {
+		lr := CountSessionLineResponse{
+			ProductID:       l.ProductID,
+			ProductName:     l.Product.Name,
+			StockCode:       l.Product.StockCode,
+			Unit:            l.Product.Unit,
+			SortOrder:       l.SortOrder,
+			CountedQuantity: l.CountedQuantity,
+			CountedUnit:     l.Unit,
+			UnitQuantity:    l.UnitQuantity,
+			StockEntryID:    l.StockEntryID,
+			Note:            l.Note,
+		}
+		if l.CountedAt != nil {
+			lr.CountedAt = l.CountedAt.Format("2006-01-02 15:04:05")
+		}
+
+		if !hideExpected {
+			expected := l.ExpectedQuantity
+			if session.Status != models.CountSessionApproved {
+				b := balances[l.ProductID]
+				expected = &b
+			}
+			lr.ExpectedQuantity = expected
+			if expected != nil && l.CountedQuantity != nil {
+				variance := *l.CountedQuantity - *expected
+				value := round2(variance * prices[l.ProductID])
+				lr.Variance = &variance
+				lr.VarianceValue = &value
+				if variance != 0 {
+					resp.VarianceLines++
+					totalValue += value
+				}
+			}
+		}
+
+		resp.Lines = append(resp.Lines, lr)
+	}
+	if !hideExpected {
+		totalValue = round2(totalValue)
+		resp.VarianceValue = &totalValue
+	}
+	return resp, nil
+}
+
+// POST /api/count-sessions
+// Şube için sayım oturumu açar; ürünler şubenin raf sırasına (BranchProductOrder) göre dizilir
+func CreateCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body CreateCountSessionRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
+		if err != nil {
+			return err
+		}
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		d, err := time.Parse("2006-01-02", body.Date)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+		}
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		// Şubede aynı anda tek açık oturum olabilir
+		var openCount int64
+		if err := database.DB.Model(&models.CountSession{}).
+			Where("branch_id = ? AND status IN ?", branchID,
+				[]models.CountSessionStatus{models.CountSessionOpen, models.CountSessionSubmitted}).
+			Count(&openCount).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumları okunamadı")
+		}
+		if openCount > 0 {
+			return fiber.NewError(fiber.StatusConflict, "Bu şubede tamamlanmamış bir sayım oturumu var")
+		}
+
+		productQuery := database.DB.Where("is_center_product = ? AND is_active = ?", true, true)
+		if len(body.ProductIDs) > 0 {
+			productQuery = productQuery.Where("id IN ?", body.ProductIDs)
+		}
+		var products []models.Product
+		if err := productQuery.Find(&products).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
+		}
+		if len(products) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Sayılacak ürün bulunamadı")
+		}
+
+		// Raf sırası: sıralaması olanlar önce (sıra numarasına göre), sonra ürün adına göre
+		var branchOrders []models.BranchProductOrder
+		if err := database.DB.Where("branch_id = ?", branchID).Find(&branchOrders).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Ürün sıralaması okunamadı")
+		}
+		orderMap := make(map[uint]int, len(branchOrders))
+		for _, o := range branchOrders {
+			orderMap[o.ProductID] = o.OrderIndex
+		}
+		sort.Slice(products, func(i, j int) bool {
+			iOrder, iOk := orderMap[products[i].ID]
+			jOrder, jOk := orderMap[products[j].ID]
+			if iOk && jOk {
+				return iOrder < jOrder
+			}
+			if iOk != jOk {
+				return iOk
+			}
+			return products[i].Name < products[j].Name
+		})
+
+		session := models.CountSession{
+			BranchID: branchID,
+			Date:     d,
+			Status:   models.CountSessionOpen,
+			Blind:    body.Blind,
+			Note:     body.Note,
+			OpenedBy: userID,
+			Lines:    make([]models.CountSessionLine, 0, len(products)),
+		}
+		for i, p := range products {
+			session.Lines = append(session.Lines, models.CountSessionLine{
+				ProductID: p.ID,
+				SortOrder: i,
+			})
+		}
+		if err := database.DB.Create(&session).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu oluşturulamadı")
+		}
+		for i := range session.Lines {
+			session.Lines[i].Product = products[i]
+		}
+
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    &branchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "count_session",
+			EntityID:    session.ID,
+			Action:      models.AuditActionCreate,
+			Description: fmt.Sprintf("Sayım oturumu açıldı: %s, %d ürün", session.Date.Format("2006-01-02"), len(session.Lines)),
+			Before:      nil,
+			After:       map[string]interface{}{"id": session.ID, "date": session.Date, "blind": session.Blind, "lines": len(session.Lines)},
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+		}
+
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+		return c.Status(fiber.StatusCreated).JSON(resp)
+	}
+}
+
+// GET /api/count-sessions?status=...
+func ListCountSessionsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		query := database.DB.Preload("Lines").Where("branch_id = ?", branchID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		var sessions []models.CountSession
+		if err := query.Order("date DESC, id DESC").Limit(100).Find(&sessions).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumları listelenemedi")
+		}
+
+		resp := make([]CountSessionResponse, 0, len(sessions))
+		for _, s := range sessions {
+			r, err := buildCountSessionResponse(database.DB, s, false)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumları listelenemedi")
+			}
+			resp = append(resp, r)
+		}
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/count-sessions/:id
+// Raf sırasıyla ürünler; onaya gönderilmiş oturumlarda beklenen miktar ve fark
+func GetCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// PUT /api/count-sessions/:id/lines
+// Sayılan miktarları kaydeder (sadece açık oturumda); stoka onaydan sonra yazılır
+func RecordCountLinesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountSessionOpen {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece açık sayım oturumuna miktar girilebilir")
+		}
+
+		var body RecordCountLinesRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		if len(body.Lines) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "En az bir ürün eklenmelidir")
+		}
+		userID, _, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		lineIndex := make(map[uint]int, len(session.Lines))
+		for i, l := range session.Lines {
+			lineIndex[l.ProductID] = i
+		}
+		for _, l := range body.Lines {
+			if _, ok := lineIndex[l.ProductID]; !ok {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bu sayım oturumunda yok: %d", l.ProductID))
+			}
+			if l.CountedQuantity != nil && *l.CountedQuantity < 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "counted_quantity negatif olamaz")
+			}
+		}
+
+		now := time.Now()
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			for _, l := range body.Lines {
+				line := &session.Lines[lineIndex[l.ProductID]]
+				line.Note = l.Note
+				if l.CountedQuantity != nil {
+					// Girilen birimdeki miktar temel birime çevrilerek saklanır
+					quantity, _, err := units.ToBase(tx, models.UnitKindProduct, line.ProductID, line.Product.Name, line.Product.Unit, l.Unit, *l.CountedQuantity)
+					if err != nil {
+						return err
+					}
+					line.CountedQuantity = &quantity
+					line.Unit = units.EnteredUnit(line.Product.Unit, l.Unit)
+					line.UnitQuantity = l.CountedQuantity
+					line.CountedBy = &userID
+					line.CountedAt = &now
+				} else {
+					line.CountedQuantity = nil
+					line.Unit = ""
+					line.UnitQuantity = nil
+					line.CountedBy = nil
+					line.CountedAt = nil
+				}
+				if err := tx.Model(&models.CountSessionLine{}).Where("id = ?", line.ID).Updates(map[string]interface{}{
+					"counted_quantity": line.CountedQuantity,
+					"unit":             line.Unit,
+					"unit_quantity":    line.UnitQuantity,
+					"note":             line.Note,
+					"counted_by":       line.CountedBy,
+					"counted_at":       line.CountedAt,
+				}).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Sayım kaydedilemedi")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/count-sessions/:id/submit
+// Sayımı bitirip onaya gönderir; bundan sonra beklenen miktarlar ve farklar görünür
+func SubmitCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountSessionOpen {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece açık sayım oturumu onaya gönderilebilir")
+		}
+		counted := 0
+		for _, l := range session.Lines {
+			if l.CountedQuantity != nil {
+				counted++
+			}
+		}
+		if counted == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Hiç ürün sayılmamış")
+		}
+
+		userID, _, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		session.Status = models.CountSessionSubmitted
+		session.SubmittedBy = &userID
+		session.SubmittedAt = &now
+		if err := database.DB.Model(&models.CountSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"status":       session.Status,
+			"submitted_by": userID,
+			"submitted_at": now,
+		}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu güncellenemedi")
+		}
+
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/count-sessions/:id/reopen
+// Onay bekleyen sayımı yeniden saymak için açar
+func ReopenCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountSessionSubmitted {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece onay bekleyen sayım yeniden açılabilir")
+		}
+
+		session.Status = models.CountSessionOpen
+		session.SubmittedBy = nil
+		session.SubmittedAt = nil
+		if err := database.DB.Model(&models.CountSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"status":       session.Status,
+			"submitted_by": nil,
+			"submitted_at": nil,
+		}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu güncellenemedi")
+		}
+
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/count-sessions/:id/cancel
+func CancelCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		if session.Status == models.CountSessionApproved || session.Status == models.CountSessionCancelled {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu sayım oturumu iptal edilemez")
+		}
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		if err := database.DB.Model(&models.CountSession{}).Where("id = ?", session.ID).
+			Update("status", models.CountSessionCancelled).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu güncellenemedi")
+		}
+		before := session.Status
+		session.Status = models.CountSessionCancelled
+
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    &session.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "count_session",
+			EntityID:    session.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Sayım oturumu iptal edildi: %s", session.Date.Format("2006-01-02")),
+			Before:      map[string]interface{}{"id": session.ID, "status": before},
+			After:       map[string]interface{}{"id": session.ID, "status": session.Status},
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+		}
+
+		return c.JSON(fiber.Map{"message": "Sayım oturumu iptal edildi"})
+	}
+}
+
+// POST /api/count-sessions/:id/approve
+// Onay bekleyen sayımın sayılan ürünleri için stok girişlerini tek transaction'da yazar
+// (her biri defterde sayım farkı hareketi olur) ve tek bir audit kaydı oluşturur
+func ApproveCountSessionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session, err := loadCountSession(c)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountSessionSubmitted {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece onay bekleyen sayım onaylanabilir")
+		}
+		if err := period.EnsureOpen(session.BranchID, session.Date); err != nil {
+			return err
+		}
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var entries []models.StockEntry
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			// Aynı oturumun iki kez onaylanmasını engelle
+			res := tx.Model(&models.CountSession{}).
+				Where("id = ? AND status = ?", session.ID, models.CountSessionSubmitted).
+				Updates(map[string]interface{}{
+					"status":      models.CountSessionApproved,
+					"approved_by": userID,
+					"approved_at": now,
+				})
+			if res.Error != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu güncellenemedi")
+			}
+			if res.RowsAffected == 0 {
+				return fiber.NewError(fiber.StatusConflict, "Sayım oturumu başka bir işlemle değişti")
+			}
+
+			for i := range session.Lines {
+				line := &session.Lines[i]
+				if line.CountedQuantity == nil {
+					continue
+				}
+				entry := models.StockEntry{
+					BranchID:  session.BranchID,
+					ProductID: line.ProductID,
+					Date:      session.Date,
+					Quantity:  *line.CountedQuantity,
+					Unit:      line.Unit,
+					Note:      fmt.Sprintf("Sayım oturumu #%d", session.ID),
+				}
+				if line.UnitQuantity != nil {
+					entry.UnitQuantity = *line.UnitQuantity
+				}
+				if err := tx.Create(&entry).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Stok girişi oluşturulamadı")
+				}
+				movement, err := ledger.RecordCount(tx, entry)
+				if err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+				}
+				expected := *line.CountedQuantity - movement.Quantity
+				line.ExpectedQuantity = &expected
+				line.StockEntryID = &entry.ID
+				if err := tx.Model(&models.CountSessionLine{}).Where("id = ?", line.ID).Updates(map[string]interface{}{
+					"expected_quantity": expected,
+					"stock_entry_id":    entry.ID,
+				}).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Sayım kaydı güncellenemedi")
+				}
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		session.Status = models.CountSessionApproved
+		session.ApprovedBy = &userID
+		session.ApprovedAt = &now
+
+		resp, err := buildCountSessionResponse(database.DB, session, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sayım oturumu yüklenemedi")
+		}
+
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    &session.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "count_session",
+			EntityID:    session.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Sayım oturumu onaylandı: %s, %d ürün stoka yazıldı, %d üründe fark", session.Date.Format("2006-01-02"), len(entries), resp.VarianceLines),
+			Before:      map[string]interface{}{"id": session.ID, "status": models.CountSessionSubmitted},
+			After: map[string]interface{}{
+				"id":            session.ID,
+				"status":        session.Status,
+				"stock_entries": entries,
+				"lines":         resp.Lines,
+			},
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+		}
+
+		return c.JSON(resp)
+	}
+}
@@ -0,0 +1,47 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+)
+
+// Onay bekleyen oturumda beklenen miktar sayım tarihindeki bakiyedir: sonradan gelen sevkiyat
+// sayılmaz ve onayda RecordCount'un yazdığı beklenen miktarla aynıdır
+func TestCountSessionExpectedAtSessionDate(t *testing.T) {
+	db := database.DB
+	const branchID, productID = 7, 70
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC) }
+	movement := func(id uint, d int, qty float64) models.StockMovement {
+		return models.StockMovement{BranchID: branchID, ProductID: productID, Type: models.MovementReceipt, Quantity: qty, Date: day(d),
+			SourceType: ledger.SourceShipment, SourceID: id}
+	}
+	if err := ledger.Record(db, movement(701, 1, 10), movement(702, 5, -2), movement(703, 15, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	session := models.CountSession{BranchID: branchID, Date: day(10), Status: models.CountSessionSubmitted, OpenedBy: 1}
+	expected, err := countSessionExpected(db, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected[productID] != 8 {
+		t.Errorf("beklenen miktar = %v, sayım tarihindeki bakiye 8 bekleniyordu", expected[productID])
+	}
+
+	// Onayda yazılan beklenen miktar: sayılan - sayım farkı
+	entry := models.StockEntry{BranchID: branchID, ProductID: productID, Date: session.Date, Quantity: 7}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	m, err := ledger.RecordCount(db, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved := entry.Quantity - m.Quantity; approved != expected[productID] {
+		t.Errorf("onaydaki beklenen miktar = %v, oturumda gösterilen %v", approved, expected[productID])
+	}
+}
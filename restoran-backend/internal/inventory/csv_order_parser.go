@@ -0,0 +1,152 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvOrderParser: Tedarikçi panellerinden alınan CSV sipariş dökümü.
+// Kolonlar başlık satırındaki adlara göre bulunur (Stok Kodu, Ürün, Birim Fiyat, Miktar, Birim, KDV Oranı, Toplam Tutar).
+type csvOrderParser struct{}
+
+func (csvOrderParser) Name() string { return "csv" }
+
+func (csvOrderParser) Match(doc OrderDocument) bool {
+	return doc.ContentType == ContentTypeCSV
+}
+
+// CSV başlıklarının karşılık geldiği alanlar (normalize edilmiş başlık -> alan)
+var csvOrderColumns = map[string]string{
+	"stok kodu":    "stock_code",
+	"stok kod":     "stock_code",
+	"kod":          "stock_code",
+	"urun kodu":    "stock_code",
+	"urun":         "name",
+	"urun adi":     "name",
+	"aciklama":     "name",
+	"birim fiyat":  "unit_price",
+	"fiyat":        "unit_price",
+	"miktar":       "quantity",
+	"birim":        "unit",
+	"kdv orani":    "vat_rate",
+	"kdv":          "vat_rate",
+	"toplam tutar": "total",
+	"toplam":       "total",
+	"tutar":        "total",
+}
+
+func (csvOrderParser) Parse(doc OrderDocument) (*ParsePDFResponse, error) {
+	data := bytes.TrimPrefix(doc.Data, []byte("\xef\xbb\xbf")) // Excel'in eklediği BOM
+
+	// Türkçe Excel ";" ve ondalık virgül kullanır, diğerleri "," ve ondalık nokta
+	firstLine := string(data)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	delimiter := ','
+	switch {
+	case strings.Count(firstLine, ";") > strings.Count(firstLine, ","):
+		delimiter = ';'
+	case strings.Count(firstLine, "\t") > strings.Count(firstLine, ","):
+		delimiter = '\t'
+	}
+	decimalComma := delimiter == ';'
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV okunamadı: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV dosyasında ürün satırı yok")
+	}
+
+	cols := make(map[string]int)
+	for i, h := range records[0] {
+		if field, ok := csvOrderColumns[strings.Join(strings.Fields(normalizeTurkish(h)), " ")]; ok {
+			if _, exists := cols[field]; !exists {
+				cols[field] = i
+			}
+		}
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, fmt.Errorf("CSV başlığında ürün kolonu bulunamadı")
+	}
+	if _, ok := cols["quantity"]; !ok {
+		return nil, fmt.Errorf("CSV başlığında miktar kolonu bulunamadı")
+	}
+
+	cell := func(row []string, field string) string {
+		if i, ok := cols[field]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var products []ParsedProduct
+	for _, row := range records[1:] {
+		stockCode := cell(row, "stock_code")
+		productName := cell(row, "name")
+		if stockCode == "" && productName == "" {
+			continue
+		}
+
+		quantity, quantityUnit := extractQuantityAndUnit(cell(row, "quantity"))
+		if unit := cell(row, "unit"); unit != "" {
+			quantityUnit = unit
+		}
+		if quantity <= 0 {
+			continue
+		}
+
+		unitPrice, _ := parseCSVNumber(cell(row, "unit_price"), decimalComma)
+		vatRate, hasVAT := parseCSVNumber(strings.TrimPrefix(cell(row, "vat_rate"), "%"), decimalComma)
+		totalAmount, hasTotal := parseCSVNumber(cell(row, "total"), decimalComma)
+		if !hasTotal {
+			totalAmount = unitPrice * quantity * (1 + vatRate/100)
+		}
+
+		unitPriceWithVAT := totalAmount / quantity
+		if hasVAT && unitPrice > 0 {
+			unitPriceWithVAT = unitPrice * (1 + vatRate/100)
+		}
+
+		products = append(products, ParsedProduct{
+			StockCode:        stockCode,
+			ProductName:      productName,
+			UnitPrice:        unitPrice,
+			UnitPriceWithVAT: unitPriceWithVAT,
+			Quantity:         quantity,
+			QuantityUnit:     quantityUnit,
+			TotalAmount:      totalAmount,
+			VATRate:          vatRate,
+		})
+	}
+
+	if len(products) == 0 {
+		return nil, fmt.Errorf("CSV dosyasında ürün bulunamadı")
+	}
+
+	matchParsedProducts(products)
+	return &ParsePDFResponse{Products: products}, nil
+}
+
+// parseCSVNumber: "1.234,56 TL" (ondalık virgül) veya "1234.56" (ondalık nokta) formatındaki sayıyı çevirir
+func parseCSVNumber(s string, decimalComma bool) (float64, bool) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "TL", ""))
+	if s == "" {
+		return 0, false
+	}
+	if decimalComma {
+		v, err := parseTurkishFloat(s)
+		return v, err == nil
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	return v, err == nil
+}
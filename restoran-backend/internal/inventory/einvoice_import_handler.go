@@ -0,0 +1,446 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// e-Fatura içe aktarma hedefleri
+const (
+	EInvoiceTargetShipment = "shipment" // merkez mutfak faturası -> sevkiyat
+	EInvoiceTargetProduce  = "produce"  // manav faturası -> manav alımları
+	EInvoiceTargetExpense  = "expense"  // diğer tedarikçiler -> gider
+)
+
+type EInvoiceImportResponse struct {
+	Target             string   `json:"target"`
+	InvoiceNumber      string   `json:"invoice_number"`
+	Date               string   `json:"date"`
+	SupplierName       string   `json:"supplier_name"`
+	SupplierTaxID      string   `json:"supplier_tax_id"`
+	TotalAmount        float64  `json:"total_amount"`
+	ShipmentID         *uint    `json:"shipment_id,omitempty"`
+	ExpenseID          *uint    `json:"expense_id,omitempty"`
+	ProducePurchaseIDs []uint   `json:"produce_purchase_ids,omitempty"`
+	SkippedLines       []string `json:"skipped_lines,omitempty"` // miktarı veya fiyatı olmayan satırlar
+}
+
+// pendingLog: Transaction bittikten sonra yazılacak audit kaydı
+type pendingLog struct {
+	branchID    *uint
+	entityType  string
+	entityID    uint
+	description string
+	after       any
+}
+
+// isCentralSupplier: Faturayı kesen VKN/TCKN merkez mutfağa mı ait?
+func isCentralSupplier(cfg *config.Config, taxID string) bool {
+	if taxID == "" {
+		return false
+	}
+	for _, id := range strings.Split(cfg.CentralSupplierTaxIDs, ",") {
+		if strings.TrimSpace(id) == taxID {
+			return true
+		}
+	}
+	return false
+}
+
+// POST /api/invoices/import-ubl
+// Multipart form: file (UBL-TR XML), branch_id (super_admin), target (shipment/produce/expense),
+// expense_category_id (target=expense), produce_supplier_id (target=produce, opsiyonel)
+// Merkez mutfağın faturaları sevkiyat olarak, diğer tedarikçilerinkiler manav alımı veya gider olarak kaydedilir.
+func ImportEInvoiceHandler(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "e-Fatura XML dosyası gönderilmelidir ('file' alanı)")
+		}
+		if fileHeader.Size > maxShipmentPDFSize {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya en fazla 10 MB olabilir")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya okunamadı")
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya okunamadı")
+		}
+
+		inv, err := parseUBLInvoice(data)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		parsed, err := ublInvoiceParser{}.Parse(OrderDocument{FileName: fileHeader.Filename, ContentType: ContentTypeXML, Data: data})
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		var bodyBranchID *uint
+		if v := c.FormValue("branch_id"); v != "" {
+			var bid uint
+			if _, err := fmt.Sscan(v, &bid); err != nil || bid == 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
+			}
+			bodyBranchID = &bid
+		}
+		branchID, err := tenancy.ResolveBranchID(c, bodyBranchID)
+		if err != nil {
+			return err
+		}
+
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(inv.IssueDate))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Fatura tarihi okunamadı (IssueDate)")
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		central := isCentralSupplier(cfg, parsed.SupplierTaxID)
+		target := c.FormValue("target")
+		switch target {
+		case "":
+			if !central {
+				return fiber.NewError(fiber.StatusBadRequest, "Merkez dışı tedarikçi faturası için target (produce/expense) seçilmelidir")
+			}
+			target = EInvoiceTargetShipment
+		case EInvoiceTargetShipment:
+			if !central && cfg.CentralSupplierTaxIDs != "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Sadece merkez mutfağın faturaları sevkiyat olarak aktarılabilir")
+			}
+		case EInvoiceTargetProduce, EInvoiceTargetExpense:
+		default:
+			return fiber.NewError(fiber.StatusBadRequest, "target 'shipment', 'produce' veya 'expense' olmalı")
+		}
+
+		invoiceNumber := strings.TrimSpace(inv.ID)
+		if invoiceNumber == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Fatura numarası okunamadı (ID)")
+		}
+		// Kayıtların notuna yazılan fatura işareti; aynı faturanın iki kez aktarılmasını engeller
+		marker := fmt.Sprintf("e-Fatura %s", invoiceNumber)
+		note := marker
+		if parsed.SupplierName != "" {
+			note = fmt.Sprintf("%s - %s", marker, parsed.SupplierName)
+		}
+		if r := []rune(note); len(r) > 255 {
+			note = string(r[:255])
+		}
+
+		var exists int64
+		switch target {
+		case EInvoiceTargetShipment:
+			database.DB.Model(&models.Shipment{}).Where("branch_id = ? AND note LIKE ?", branchID, marker+"%").Count(&exists)
+		case EInvoiceTargetProduce:
+			database.DB.Model(&models.ProducePurchase{}).Where("branch_id = ? AND description LIKE ?", branchID, marker+"%").Count(&exists)
+		case EInvoiceTargetExpense:
+			database.DB.Model(&models.Expense{}).Where("branch_id = ? AND description LIKE ?", branchID, marker+"%").Count(&exists)
+		}
+		if exists > 0 {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Bu fatura daha önce içe aktarılmış: %s", invoiceNumber))
+		}
+
+		resp := EInvoiceImportResponse{
+			Target:        target,
+			InvoiceNumber: invoiceNumber,
+			Date:          d.Format("2006-01-02"),
+			SupplierName:  parsed.SupplierName,
+			SupplierTaxID: parsed.SupplierTaxID,
+		}
+
+		// Miktarı veya fiyatı olmayan satırlar (promosyon, iskonto vb.) aktarılmaz
+		var lines []ParsedProduct
+		for _, p := range parsed.Products {
+			if p.Quantity <= 0 || p.UnitPrice <= 0 {
+				resp.SkippedLines = append(resp.SkippedLines, p.ProductName)
+				continue
+			}
+			lines = append(lines, p)
+		}
+		if len(lines) == 0 && target != EInvoiceTargetExpense {
+			return fiber.NewError(fiber.StatusBadRequest, "Faturada aktarılabilecek satır bulunamadı")
+		}
+
+		var logs []pendingLog
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			switch target {
+			case EInvoiceTargetShipment:
+				items := make([]ShipmentItemRequest, 0, len(lines))
+				for _, p := range lines {
+					item := ShipmentItemRequest{
+						Quantity:         p.Quantity,
+						UnitPrice:        p.UnitPrice,
+						UnitPriceWithVAT: p.UnitPriceWithVAT,
+						TotalPrice:       p.TotalAmount,
+						VATRate:          p.VATRate,
+						ProductName:      p.ProductName,
+						StockCode:        p.StockCode,
+						Unit:             p.QuantityUnit,
+					}
+					if p.MatchedProductID != nil {
+						item.ProductID = *p.MatchedProductID
+					} else if item.Unit == "" {
+						item.Unit = "Adet"
+					}
+					items = append(items, item)
+				}
+				shipment, err := createShipment(tx, branchID, d, note, items)
+				if err != nil {
+					return err
+				}
+				if _, err := matchShipmentToPurchaseOrder(tx, &shipment, nil); err != nil {
+					return err
+				}
+				resp.ShipmentID = &shipment.ID
+				resp.TotalAmount = shipment.TotalAmount
+				logs = append(logs, pendingLog{
+					branchID:    &branchID,
+					entityType:  "shipment",
+					entityID:    shipment.ID,
+					description: fmt.Sprintf("Sevkiyat e-Faturadan eklendi (%s): %d ürün, Toplam: %.2f TL", invoiceNumber, len(shipment.Items), shipment.TotalAmount),
+					after:       shipment,
+				})
+
+			case EInvoiceTargetExpense:
+				var categoryID uint
+				if _, err := fmt.Sscan(c.FormValue("expense_category_id"), &categoryID); err != nil || categoryID == 0 {
+					return fiber.NewError(fiber.StatusBadRequest, "expense_category_id zorunlu")
+				}
+				var cat models.ExpenseCategory
+				if err := tx.First(&cat, "id = ? AND branch_id = ?", categoryID, branchID).Error; err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, "Kategori bulunamadı veya bu şubeye ait değil")
+				}
+
+				amount := inv.PayableAmount
+				if amount <= 0 {
+					for _, p := range parsed.Products {
+						amount += p.TotalAmount
+					}
+				}
+				if amount <= 0 {
+					return fiber.NewError(fiber.StatusBadRequest, "Fatura tutarı okunamadı")
+				}
+
+				exp := models.Expense{
+					BranchID:    branchID,
+					CategoryID:  cat.ID,
+					Date:        d,
+					Amount:      amount,
+					Description: note,
+				}
+				if err := tx.Create(&exp).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Gider kaydedilemedi")
+				}
+				resp.ExpenseID = &exp.ID
+				resp.TotalAmount = exp.Amount
+				logs = append(logs, pendingLog{
+					branchID:    &branchID,
+					entityType:  "expense",
+					entityID:    exp.ID,
+					description: fmt.Sprintf("Gider e-Faturadan eklendi (%s): %s - %.2f TL", invoiceNumber, cat.Name, exp.Amount),
+					after: map[string]interface{}{
+						"id":          exp.ID,
+						"branch_id":   exp.BranchID,
+						"category_id": exp.CategoryID,
+						"date":        exp.Date.Format("2006-01-02"),
+						"amount":      exp.Amount,
+						"description": exp.Description,
+					},
+				})
+
+			case EInvoiceTargetProduce:
+				supplier, created, err := resolveProduceSupplier(tx, branchID, c.FormValue("produce_supplier_id"), parsed.SupplierName)
+				if err != nil {
+					return err
+				}
+				if created {
+					logs = append(logs, pendingLog{
+						branchID:    &branchID,
+						entityType:  "produce_supplier",
+						entityID:    supplier.ID,
+						description: fmt.Sprintf("Manav tedarikçi e-Faturadan eklendi: %s", supplier.Name),
+						after: map[string]interface{}{
+							"id":          supplier.ID,
+							"branch_id":   supplier.BranchID,
+							"name":        supplier.Name,
+							"description": supplier.Description,
+						},
+					})
+				}
+
+				for _, p := range lines {
+					product, created, err := resolveProduceProduct(tx, p)
+					if err != nil {
+						return err
+					}
+					if created {
+						logs = append(logs, pendingLog{
+							entityType:  "produce_product",
+							entityID:    product.ID,
+							description: fmt.Sprintf("Manav ürünü e-Faturadan eklendi: %s", product.Name),
+							after:       map[string]interface{}{"id": product.ID, "name": product.Name, "unit": product.Unit, "stock_code": product.StockCode},
+						})
+					}
+
+					// Manav hesabı ödenecek tutar üzerinden tutulduğu için KDV dahil fiyat kullanılır
+					unitPrice := p.UnitPriceWithVAT
+					if unitPrice <= 0 {
+						unitPrice = p.UnitPrice
+					}
+					// Fatura birimi ürünün temel birimine çevrilir
+					quantity, factor, err := units.ToBase(tx, models.UnitKindProduce, product.ID, product.Name, product.Unit, p.QuantityUnit, p.Quantity)
+					if err != nil {
+						return err
+					}
+					purchase := models.ProducePurchase{
+						BranchID:     branchID,
+						SupplierID:   supplier.ID,
+						ProductID:    product.ID,
+						Quantity:     quantity,
+						Unit:         units.EnteredUnit(product.Unit, p.QuantityUnit),
+						UnitQuantity: p.Quantity,
+						UnitPrice:    unitPrice / factor,
+						TotalAmount:  p.Quantity * unitPrice,
+						Date:         d,
+						Description:  note,
+					}
+					if err := tx.Create(&purchase).Error; err != nil {
+						return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Alım kaydedilemedi: %v", err))
+					}
+					resp.ProducePurchaseIDs = append(resp.ProducePurchaseIDs, purchase.ID)
+					resp.TotalAmount += purchase.TotalAmount
+					logs = append(logs, pendingLog{
+						branchID:    &branchID,
+						entityType:  "produce_purchase",
+						entityID:    purchase.ID,
+						description: fmt.Sprintf("Manav alımı e-Faturadan eklendi (%s): %s - %.2f %s - %.2f TL", invoiceNumber, product.Name, purchase.Quantity, product.Unit, purchase.TotalAmount),
+						after: map[string]interface{}{
+							"id":           purchase.ID,
+							"branch_id":    purchase.BranchID,
+							"product_id":   purchase.ProductID,
+							"quantity":     purchase.Quantity,
+							"unit_price":   purchase.UnitPrice,
+							"total_amount": purchase.TotalAmount,
+							"date":         purchase.Date.Format("2006-01-02"),
+							"description":  purchase.Description,
+						},
+					})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Audit log yaz
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			for _, l := range logs {
+				if logErr := audit.WriteLog(audit.LogOptions{
+					BranchID:    l.branchID,
+					UserID:      userID,
+					UserName:    userName,
+					EntityType:  l.entityType,
+					EntityID:    l.entityID,
+					Action:      models.AuditActionCreate,
+					Description: l.description,
+					Before:      nil,
+					After:       l.after,
+				}); logErr != nil {
+					fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+				}
+			}
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(resp)
+	}
+}
+
+// resolveProduceSupplier: Seçilen manav tedarikçisini döndürür; seçilmediyse
+// şubede faturadaki satıcı adıyla kayıtlı tedarikçiyi bulur, yoksa oluşturur
+func resolveProduceSupplier(tx *gorm.DB, branchID uint, supplierIDStr string, supplierName string) (models.ProduceSupplier, bool, error) {
+	var supplier models.ProduceSupplier
+	if supplierIDStr != "" {
+		var sid uint
+		if _, err := fmt.Sscan(supplierIDStr, &sid); err != nil || sid == 0 {
+			return supplier, false, fiber.NewError(fiber.StatusBadRequest, "produce_supplier_id geçersiz")
+		}
+		if err := tx.First(&supplier, "id = ? AND branch_id = ?", sid, branchID).Error; err != nil {
+			return supplier, false, fiber.NewError(fiber.StatusBadRequest, "Tedarikçi bulunamadı veya bu şubeye ait değil")
+		}
+		return supplier, false, nil
+	}
+
+	supplierName = strings.TrimSpace(supplierName)
+	if supplierName == "" {
+		return supplier, false, fiber.NewError(fiber.StatusBadRequest, "Faturada satıcı adı yok, produce_supplier_id seçilmelidir")
+	}
+	if err := tx.Where("branch_id = ? AND LOWER(name) = LOWER(?)", branchID, supplierName).First(&supplier).Error; err == nil {
+		return supplier, false, nil
+	}
+
+	supplier = models.ProduceSupplier{
+		BranchID:    branchID,
+		Name:        supplierName,
+		Description: "e-Faturadan otomatik oluşturuldu",
+	}
+	if err := tx.Create(&supplier).Error; err != nil {
+		return supplier, false, fiber.NewError(fiber.StatusInternalServerError, "Tedarikçi kaydedilemedi")
+	}
+	return supplier, true, nil
+}
+
+// resolveProduceProduct: Fatura satırını stok kodu veya isimle manav ürününe eşler, bulunamazsa oluşturur
+func resolveProduceProduct(tx *gorm.DB, line ParsedProduct) (models.ProduceProduct, bool, error) {
+	var product models.ProduceProduct
+	if line.StockCode != "" {
+		if err := tx.Where("stock_code = ?", line.StockCode).First(&product).Error; err == nil {
+			return product, false, nil
+		}
+	}
+
+	var products []models.ProduceProduct
+	if err := tx.Find(&products).Error; err != nil {
+		return product, false, fiber.NewError(fiber.StatusInternalServerError, "Manav ürünleri okunamadı")
+	}
+	name := strings.Join(strings.Fields(normalizeTurkish(line.ProductName)), " ")
+	for _, p := range products {
+		if strings.Join(strings.Fields(normalizeTurkish(p.Name)), " ") == name {
+			return p, false, nil
+		}
+	}
+
+	unit := line.QuantityUnit
+	if unit == "" {
+		unit = "Adet"
+	}
+	product = models.ProduceProduct{
+		Name:      strings.TrimSpace(line.ProductName),
+		Unit:      unit,
+		StockCode: line.StockCode,
+	}
+	if err := tx.Create(&product).Error; err != nil {
+		return product, false, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Manav ürünü oluşturulamadı: %v", err))
+	}
+	return product, true, nil
+}
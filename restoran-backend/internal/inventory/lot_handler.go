@@ -0,0 +1,252 @@
+package inventory
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Parti kaynakları
+const (
+	lotSourceShipment = "shipment" // sevkiyat kalemi (merkez ürünü)
+	lotSourceProduce  = "produce"  // manav alımı
+)
+
+type LotResponse struct {
+	Source       string  `json:"source"`
+	ID           uint    `json:"id"` // shipment_item_id veya produce purchase id
+	ProductID    uint    `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	Unit         string  `json:"unit"`
+	LotNumber    string  `json:"lot_number"`
+	ReceivedDate string  `json:"received_date"`
+	ExpiryDate   string  `json:"expiry_date,omitempty"`
+	DaysLeft     *int    `json:"days_left,omitempty"` // negatif: süresi geçmiş
+	Received     float64 `json:"received"`
+	Wasted       float64 `json:"wasted"`
+	Remaining    float64 `json:"remaining"`
+	Value        float64 `json:"value"` // kalan miktarın alış fiyatıyla tutarı
+}
+
+type ExpiringLotsResponse struct {
+	BranchID   uint          `json:"branch_id"`
+	Date       string        `json:"date"`
+	Days       int           `json:"days"`
+	Items      []LotResponse `json:"items"`
+	TotalValue float64       `json:"total_value"`
+}
+
+// GET /api/lots?product_id=...&all=true
+// Şubedeki partili sevkiyat kalemleri ve FIFO tüketime göre kalan miktarları
+// (all=true verilmezse sadece kalanı olanlar)
+func ListLotsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		var productID uint
+		if c.Query("product_id") != "" {
+			id, err := queryPositiveInt(c, "product_id", 0)
+			if err != nil {
+				return err
+			}
+			productID = uint(id)
+		}
+
+		lots, err := ledger.Lots(database.DB, branchID, productID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Parti bakiyeleri hesaplanamadı")
+		}
+		if c.Query("all") != "true" {
+			filtered := lots[:0]
+			for _, l := range lots {
+				if l.Remaining > 0 {
+					filtered = append(filtered, l)
+				}
+			}
+			lots = filtered
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		resp, err := shipmentLotResponses(lots, today)
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/expiring-lots?days=3
+// Son kullanma tarihi days gün içinde dolacak partiler:
+//   - Merkez ürünleri: FIFO tüketime göre stokta kalanı olan partiler (süresi geçmiş olanlar dahil)
+//   - Manav alımları: stok sayımı olmadığından tüketim bilinmez; süresi henüz geçmemiş,
+//     bağlı zayiatlar düşüldükten sonra miktarı kalan partiler
+func ExpiringLotsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+		days, err := queryPositiveInt(c, "days", 3)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		limit := today.AddDate(0, 0, days)
+
+		lots, err := ledger.Lots(database.DB, branchID, 0)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Parti bakiyeleri hesaplanamadı")
+		}
+		expiring := lots[:0]
+		for _, l := range lots {
+			if l.Remaining > 0 && l.ExpiryDate != nil && !l.ExpiryDate.After(limit) {
+				expiring = append(expiring, l)
+			}
+		}
+
+		items, err := shipmentLotResponses(expiring, today)
+		if err != nil {
+			return err
+		}
+
+		var purchases []models.ProducePurchase
+		if err := database.DB.Preload("Product").
+			Where("branch_id = ? AND expiry_date >= ? AND expiry_date <= ?", branchID, today, limit).
+			Find(&purchases).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Manav partileri okunamadı")
+		}
+		wasted, err := producePurchaseWaste(purchases)
+		if err != nil {
+			return err
+		}
+		for _, p := range purchases {
+			remaining := p.Quantity - wasted[p.ID]
+			if remaining <= 0 {
+				continue
+			}
+			items = append(items, LotResponse{
+				Source:       lotSourceProduce,
+				ID:           p.ID,
+				ProductID:    p.ProductID,
+				ProductName:  p.Product.Name,
+				Unit:         p.Product.Unit,
+				LotNumber:    p.LotNumber,
+				ReceivedDate: formatDatePtr(p.ReceivedDate),
+				ExpiryDate:   formatDatePtr(p.ExpiryDate),
+				DaysLeft:     daysUntil(today, p.ExpiryDate),
+				Received:     p.Quantity,
+				Wasted:       wasted[p.ID],
+				Remaining:    remaining,
+				Value:        round2(remaining * p.UnitPrice),
+			})
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].ExpiryDate != items[j].ExpiryDate {
+				return items[i].ExpiryDate < items[j].ExpiryDate
+			}
+			return items[i].ProductName < items[j].ProductName
+		})
+
+		resp := ExpiringLotsResponse{
+			BranchID: branchID,
+			Date:     today.Format("2006-01-02"),
+			Days:     days,
+			Items:    items,
+		}
+		for _, it := range items {
+			resp.TotalValue += it.Value
+		}
+		resp.TotalValue = round2(resp.TotalValue)
+
+		return c.JSON(resp)
+	}
+}
+
+// shipmentLotResponses: Defter partilerine ürün adı ve alış fiyatını ekler
+func shipmentLotResponses(lots []ledger.LotBalance, today time.Time) ([]LotResponse, error) {
+	itemIDs := make([]uint, 0, len(lots))
+	for _, l := range lots {
+		itemIDs = append(itemIDs, l.ShipmentItemID)
+	}
+	items := make(map[uint]models.ShipmentItem, len(itemIDs))
+	if len(itemIDs) > 0 {
+		var rows []models.ShipmentItem
+		if err := database.DB.Preload("Product").Where("id IN ?", itemIDs).Find(&rows).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Partiler okunamadı")
+		}
+		for _, r := range rows {
+			items[r.ID] = r
+		}
+	}
+
+	resp := make([]LotResponse, 0, len(lots))
+	for _, l := range lots {
+		item := items[l.ShipmentItemID]
+		resp = append(resp, LotResponse{
+			Source:       lotSourceShipment,
+			ID:           l.ShipmentItemID,
+			ProductID:    l.ProductID,
+			ProductName:  item.Product.Name,
+			Unit:         item.Product.Unit,
+			LotNumber:    l.LotNumber,
+			ReceivedDate: l.ReceivedDate.Format("2006-01-02"),
+			ExpiryDate:   formatDatePtr(l.ExpiryDate),
+			DaysLeft:     daysUntil(today, l.ExpiryDate),
+			Received:     l.Received,
+			Wasted:       l.Wasted,
+			Remaining:    l.Remaining,
+			Value:        round2(l.Remaining * item.UnitPriceWithVAT),
+		})
+	}
+	return resp, nil
+}
+
+// producePurchaseWaste: Alımlara (partilere) bağlanmış zayiat toplamları (alım ID -> miktar)
+func producePurchaseWaste(purchases []models.ProducePurchase) (map[uint]float64, error) {
+	wasted := make(map[uint]float64)
+	if len(purchases) == 0 {
+		return wasted, nil
+	}
+	ids := make([]uint, 0, len(purchases))
+	for _, p := range purchases {
+		ids = append(ids, p.ID)
+	}
+	type wasteRow struct {
+		PurchaseID uint
+		Quantity   float64
+	}
+	var rows []wasteRow
+	if err := database.DB.Model(&models.ProduceWaste{}).
+		Select("purchase_id, SUM(quantity) AS quantity").
+		Where("purchase_id IN ?", ids).
+		Group("purchase_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Parti zayiatı hesaplanamadı")
+	}
+	for _, r := range rows {
+		wasted[r.PurchaseID] = r.Quantity
+	}
+	return wasted, nil
+}
+
+func daysUntil(today time.Time, expiry *time.Time) *int {
+	if expiry == nil {
+		return nil
+	}
+	d := int(math.Round(expiry.Sub(today).Hours() / 24))
+	return &d
+}
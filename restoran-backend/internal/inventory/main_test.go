@@ -0,0 +1,58 @@
+package inventory
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMain: Paket testleri için geçici SQLite veritabanı (handler'lar database.DB kullanır).
+// Undo gibi transaction içinden database.DB'yi de okuyan (dönem kilidi) işlemler birden fazla
+// bağlantı gerektirdiği için bellek içi yerine WAL modunda dosya kullanılır.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "inventory-test")
+	if err != nil {
+		log.Fatalf("geçici klasör oluşturulamadı: %v", err)
+	}
+	dsn := filepath.Join(dir, "test.db") + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		log.Fatalf("veritabanı açılamadı: %v", err)
+	}
+
+	if err := db.AutoMigrate(
+		&models.Product{},
+		&models.AuditLog{},
+		&models.PeriodLock{},
+		&models.StockEntry{},
+		&models.StockMovement{},
+		&models.CountSession{},
+		&models.CountSessionLine{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.ProductUnit{},
+	); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+
+	code := m.Run()
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,192 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Desteklenen belge içerik tipleri
+const (
+	ContentTypeHTML = "text/html"
+	ContentTypePDF  = "application/pdf"
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXML  = "application/xml"
+)
+
+// OrderDocument: Parse edilecek tedarikçi sipariş/fatura belgesi
+type OrderDocument struct {
+	URL         string // URL'den çekildiyse
+	Host        string // URL host'u (örn: b2b.cadininevi.com.tr)
+	FileName    string // dosya olarak yüklendiyse
+	ContentType string // ContentTypeHTML, ContentTypePDF, ...
+	Data        []byte
+}
+
+// OrderParser: Tedarikçi belgesini ürün listesine çeviren parser.
+// Yeni tedarikçi formatları ayrı bir implementasyon olarak RegisterOrderParser ile eklenir.
+type OrderParser interface {
+	// Name: Parser'ın kısa adı (log ve yanıtlarda kullanılır)
+	Name() string
+	// Match: Belge bu parser tarafından okunabilir mi? (URL host'u veya içerik tipine göre)
+	Match(doc OrderDocument) bool
+	Parse(doc OrderDocument) (*ParsePDFResponse, error)
+}
+
+var orderParsers []OrderParser
+
+// RegisterOrderParser: Parser'ı kayıt defterine ekler.
+// Parser'lar kayıt sırasıyla denenir; tedarikçiye özel parser'lar genel olanlardan önce kaydedilmeli.
+func RegisterOrderParser(p OrderParser) {
+	orderParsers = append(orderParsers, p)
+}
+
+func init() {
+	RegisterOrderParser(cadininEviOrderParser{})
+	RegisterOrderParser(ublInvoiceParser{})
+	RegisterOrderParser(shipmentPDFParser{})
+	RegisterOrderParser(csvOrderParser{})
+}
+
+// FindOrderParser: Belgeye uygun ilk parser'ı bulur
+func FindOrderParser(doc OrderDocument) (OrderParser, error) {
+	for _, p := range orderParsers {
+		if p.Match(doc) {
+			return p, nil
+		}
+	}
+	source := doc.FileName
+	if source == "" {
+		source = doc.Host
+	}
+	return nil, fmt.Errorf("bu belge için parser bulunamadı (%s, %s)", source, doc.ContentType)
+}
+
+// ParseOrderDocument: Uygun parser'ı seçip belgeyi parse eder
+func ParseOrderDocument(doc OrderDocument) (*ParsePDFResponse, OrderParser, error) {
+	parser, err := FindOrderParser(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := parser.Parse(doc)
+	if err != nil {
+		return nil, parser, err
+	}
+	return result, parser, nil
+}
+
+// NewOrderDocument: Yüklenen dosyadan belge oluşturur; içerik tipi dosya adı ve içerikten belirlenir
+func NewOrderDocument(fileName string, contentType string, data []byte) OrderDocument {
+	return OrderDocument{
+		FileName:    fileName,
+		ContentType: detectOrderContentType(fileName, contentType, data),
+		Data:        data,
+	}
+}
+
+// FetchOrderDocument: URL'deki belgeyi indirir
+func FetchOrderDocument(rawURL string) (OrderDocument, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return OrderDocument{}, fmt.Errorf("geçersiz URL: %s", rawURL)
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return OrderDocument{}, fmt.Errorf("HTTP isteği oluşturulamadı: %v", err)
+	}
+
+	// User-Agent ekle (bazı siteler bot isteklerini engeller)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return OrderDocument{}, fmt.Errorf("HTTP isteği başarısız: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return OrderDocument{}, fmt.Errorf("HTTP hatası: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OrderDocument{}, fmt.Errorf("içerik okunamadı: %v", err)
+	}
+
+	return OrderDocument{
+		URL:         rawURL,
+		Host:        strings.ToLower(u.Hostname()),
+		FileName:    filepath.Base(u.Path),
+		ContentType: detectOrderContentType(filepath.Base(u.Path), resp.Header.Get("Content-Type"), data),
+		Data:        data,
+	}, nil
+}
+
+// detectOrderContentType: Content-Type başlığı, dosya uzantısı ve içerikten belge tipini belirler
+func detectOrderContentType(fileName string, header string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil {
+		switch mediaType {
+		case ContentTypeHTML, ContentTypePDF, ContentTypeCSV:
+			return mediaType
+		case ContentTypeXML, "text/xml":
+			return ContentTypeXML
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".pdf":
+		return ContentTypePDF
+	case ".xml":
+		return ContentTypeXML
+	case ".csv":
+		return ContentTypeCSV
+	case ".html", ".htm":
+		return ContentTypeHTML
+	}
+
+	detected := http.DetectContentType(data)
+	switch {
+	case strings.HasPrefix(detected, ContentTypePDF):
+		return ContentTypePDF
+	case strings.HasPrefix(detected, ContentTypeHTML):
+		return ContentTypeHTML
+	case strings.HasPrefix(detected, "text/xml"):
+		return ContentTypeXML
+	}
+	mediaType, _, _ := mime.ParseMediaType(detected)
+	return mediaType
+}
+
+// matchParsedProducts: Parse edilen ürünleri sistemdeki ürünlerle eşleştirir
+func matchParsedProducts(products []ParsedProduct) {
+	for i := range products {
+		matched, err := matchProduct(products[i].ProductName, products[i].StockCode)
+		if err == nil && matched != nil {
+			products[i].MatchedProductID = &matched.ID
+			products[i].MatchedProductName = matched.Name
+		}
+	}
+}
+
+// shipmentPDFParser: Merkez mutfağın PDF faturası
+type shipmentPDFParser struct{}
+
+func (shipmentPDFParser) Name() string { return "shipment_pdf" }
+
+func (shipmentPDFParser) Match(doc OrderDocument) bool {
+	return doc.ContentType == ContentTypePDF
+}
+
+func (shipmentPDFParser) Parse(doc OrderDocument) (*ParsePDFResponse, error) {
+	return ParseShipmentPDF(doc.Data)
+}
@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// go test ./internal/inventory -run TestOrderParsersGolden -update
+var updateGolden = flag.Bool("update", false, "golden dosyalarını yeniden yaz")
+
+func TestOrderParsersGolden(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		host        string // URL'den çekilen belgeler için
+		contentType string // sunucunun gönderdiği Content-Type
+		wantParser  string
+	}{
+		{name: "cadininevi_html", file: "orders/cadininevi_order.html", host: b2bOrderHost, contentType: "text/html; charset=utf-8", wantParser: "cadininevi_html"},
+		{name: "cadininevi_html", file: "orders/cadininevi_order.html", contentType: "text/html", wantParser: "cadininevi_html"}, // kayıtlı sayfa yüklemesi
+		{name: "ubl_tr", file: "orders/efatura.xml", wantParser: "ubl_tr"},
+		{name: "shipment_pdf", file: "shipment_single_page.pdf", wantParser: "shipment_pdf"},
+		{name: "csv_tr", file: "orders/order_tr.csv", wantParser: "csv"},
+		{name: "csv_en", file: "orders/order_en.csv", wantParser: "csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := NewOrderDocument(filepath.Base(tt.file), tt.contentType, data)
+			doc.Host = tt.host
+
+			result, parser, err := ParseOrderDocument(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parser.Name() != tt.wantParser {
+				t.Errorf("parser = %s, beklenen %s", parser.Name(), tt.wantParser)
+			}
+
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "orders", tt.name+".golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("golden dosyası okunamadı (-update ile oluşturun): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s ile sonuç farklı:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestFindOrderParserUnknownDocument(t *testing.T) {
+	// Host'u B2B olmayan (benzer adlı alan adı dahil) HTML sayfası, sipariş içeriği olmayan yüklenmiş
+	// HTML ve ürün satırı olmayan XML için parser yok
+	order, err := os.ReadFile(filepath.Join("testdata", "orders", "cadininevi_order.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []OrderDocument{
+		{Host: "example.com", ContentType: ContentTypeHTML, Data: []byte("<html></html>")},
+		{Host: "evil" + b2bOrderHost, ContentType: ContentTypeHTML, Data: order},
+		{FileName: "sayfa.html", ContentType: ContentTypeHTML, Data: []byte("<html><body>Merhaba</body></html>")},
+		{FileName: "katalog.xml", ContentType: ContentTypeXML, Data: []byte("<Catalogue/>")},
+	}
+	for _, doc := range docs {
+		if p, err := FindOrderParser(doc); err == nil {
+			t.Errorf("%s %s için parser bulunmamalıydı, bulunan: %s", doc.Host+doc.FileName, doc.ContentType, p.Name())
+		}
+	}
+}
@@ -2,31 +2,47 @@ package inventory
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// PDF faturası için üst boyut sınırı
+const maxShipmentPDFSize = 10 << 20 // 10 MB
+
 // POST /api/shipments/parse-pdf
-// PDF text'ini parse eder, ürün bilgilerini döndürür
-// Frontend'den PDF text'i JSON body'de "text" field'ı olarak gönderilir
+// Merkez mutfağın PDF faturasını parse eder, ürün bilgilerini döndürür
+// PDF dosyası multipart form'da "file" alanı olarak gönderilir
 func ParseShipmentPDFHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var body struct {
-			Text string `json:"text"`
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "PDF dosyası gönderilmelidir ('file' alanı)")
+		}
+
+		if !strings.EqualFold(filepath.Ext(fileHeader.Filename), ".pdf") {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece PDF dosyası yüklenebilir")
 		}
-		if err := c.BodyParser(&body); err != nil {
-			log.Printf("PDF parse request body parse error: %v", err)
-			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi. 'text' field'ı gönderilmelidir.")
+		if fileHeader.Size > maxShipmentPDFSize {
+			return fiber.NewError(fiber.StatusBadRequest, "PDF dosyası en fazla 10 MB olabilir")
 		}
 
-		if body.Text == "" {
-			return fiber.NewError(fiber.StatusBadRequest, "PDF text'i boş olamaz")
+		file, err := fileHeader.Open()
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "PDF dosyası okunamadı")
 		}
+		defer file.Close()
 
-		// PDF text'ini parse et
-		log.Printf("PDF text parsing başladı, text uzunluğu: %d", len(body.Text))
-		result, err := ParseShipmentPDF([]byte(body.Text))
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "PDF dosyası okunamadı")
+		}
+
+		log.Printf("PDF parsing başladı: %s (%d byte)", fileHeader.Filename, len(data))
+		result, err := ParseShipmentPDF(data)
 		if err != nil {
 			log.Printf("PDF parse error: %v", err)
 			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("PDF parse edilemedi: %v", err))
@@ -37,3 +53,38 @@ func ParseShipmentPDFHandler() fiber.Handler {
 	}
 }
 
+// POST /api/shipments/parse-order-file
+// Tedarikçi belgesini (PDF, CSV, e-Fatura XML, HTML) multipart form'daki "file" alanından alır
+// ve içerik tipine uygun parser ile ürün bilgilerini döndürür
+func ParseOrderFileHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya gönderilmelidir ('file' alanı)")
+		}
+		if fileHeader.Size > maxShipmentPDFSize {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya en fazla 10 MB olabilir")
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya okunamadı")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Dosya okunamadı")
+		}
+
+		doc := NewOrderDocument(fileHeader.Filename, fileHeader.Header.Get("Content-Type"), data)
+		result, parser, err := ParseOrderDocument(doc)
+		if err != nil {
+			log.Printf("Sipariş belgesi parse error (%s): %v", fileHeader.Filename, err)
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Belge parse edilemedi: %v", err))
+		}
+
+		log.Printf("Sipariş belgesi parse başarılı (%s), %d ürün bulundu", parser.Name(), len(result.Products))
+		return c.JSON(result)
+	}
+}
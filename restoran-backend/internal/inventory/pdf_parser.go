@@ -20,6 +20,7 @@ type ParsedProduct struct {
 	Quantity          float64 `json:"quantity"`            // Miktar (2)
 	QuantityUnit      string  `json:"quantity_unit"`       // Miktar birimi (Paket, Adet, Kilogram)
 	TotalAmount       float64 `json:"total_amount"`        // KDV'li toplam tutar (336.00)
+	VATRate           float64 `json:"vat_rate"`            // KDV oranı (%), belgede varsa
 	MatchedProductID  *uint   `json:"matched_product_id"`  // Eşleşen ürün ID (nil ise eşleşme yok)
 	MatchedProductName string `json:"matched_product_name"` // Eşleşen ürün adı
 }
@@ -29,6 +30,8 @@ type ParsePDFResponse struct {
 	Products    []ParsedProduct `json:"products"`
 	Date        string          `json:"date"`        // Sipariş tarihi (varsa)
 	OrderNumber string          `json:"order_number"` // Sipariş numarası (varsa)
+	SupplierName  string        `json:"supplier_name,omitempty"`   // Satıcı (e-Fatura)
+	SupplierTaxID string        `json:"supplier_tax_id,omitempty"` // Satıcı VKN/TCKN (e-Fatura)
 }
 
 // parseTurkishFloat: Türkçe formatındaki sayıyı float'a çevir (1.234,56 -> 1234.56)
@@ -80,6 +83,20 @@ func extractQuantityAndUnit(s string) (float64, string) {
 	return quantity, unitText
 }
 
+// isTableHeader: Satır ürün tablosunun başlığı mı? (Stok Kodu | Ürün | Birim Fiyat | ...)
+func isTableHeader(line string) bool {
+	lineLower := strings.ToLower(line)
+	// Farklı olası başlık formatlarını kontrol et
+	return (strings.Contains(lineLower, "stok") && strings.Contains(lineLower, "kod")) ||
+		(strings.Contains(lineLower, "ürün") && (strings.Contains(lineLower, "birim") || strings.Contains(lineLower, "fiyat")))
+}
+
+// isSeparatorRow: Başlığın altındaki "| --- | --- |" satırı mı?
+func isSeparatorRow(line string) bool {
+	trimmed := strings.Trim(line, "|-: ")
+	return trimmed == "" && strings.Contains(line, "---")
+}
+
 // parsePDFTable: PDF text'inden tablo verilerini çıkar
 func parsePDFTable(text string) ([]ParsedProduct, error) {
 	var products []ParsedProduct
@@ -93,10 +110,7 @@ func parsePDFTable(text string) ([]ParsedProduct, error) {
 	// Tablo başlığını bul (esnek arama)
 	tableStartIdx := -1
 	for i, line := range lines {
-		lineLower := strings.ToLower(line)
-		// Farklı olası başlık formatlarını kontrol et
-		if (strings.Contains(lineLower, "stok") && strings.Contains(lineLower, "kod")) ||
-			(strings.Contains(lineLower, "ürün") && (strings.Contains(lineLower, "birim") || strings.Contains(lineLower, "fiyat"))) {
+		if isTableHeader(line) {
 			tableStartIdx = i
 			break
 		}
@@ -119,6 +133,11 @@ func parsePDFTable(text string) ([]ParsedProduct, error) {
 		if line == "" || strings.Contains(line, "Toplam:") || strings.Contains(line, "KDV:") || strings.Contains(line, "Genel Toplam:") {
 			continue
 		}
+
+		// Çok sayfalı faturalarda her sayfada tekrarlanan tablo başlığı ve ayırıcı satırı
+		if isTableHeader(line) || isSeparatorRow(line) {
+			continue
+		}
 		
 		// Tablo formatı: | Stok Kodu | Ürün | Birim Fiyat | Miktar | Kdv Oranı | Kdv Tutarı | Toplam Tutar |
 		// Pipe karakterleriyle ayrılmış kolonlar
@@ -281,14 +300,17 @@ func extractOrderNumberFromPDF(text string) string {
 	return ""
 }
 
-// ParseShipmentPDF: PDF dosyasını parse edip ürün bilgilerini çıkar
-// NOT: Bu fonksiyon şu an için PDF'den direkt text extraction yapmıyor
-// Frontend'den PDF text'i gönderilmesi bekleniyor veya daha sonra PDF parsing kütüphanesi eklenebilir
+// ParseShipmentPDF: PDF dosyasından sayfa sayfa metni çıkarıp ürün bilgilerini parse eder
 func ParseShipmentPDF(pdfData []byte) (*ParsePDFResponse, error) {
-	// Şimdilik PDF'i string olarak kabul ediyoruz (frontend'den text gönderilecek)
-	// Gelecekte gerçek PDF parsing eklenebilir
-	fullText := string(pdfData)
-	
+	pages, err := extractPDFPages(pdfData)
+	if err != nil {
+		return nil, err
+	}
+	return ParseShipmentText(strings.Join(pages, "\n"))
+}
+
+// ParseShipmentText: PDF'ten çıkarılmış metni parse edip ürün bilgilerini çıkarır
+func ParseShipmentText(fullText string) (*ParsePDFResponse, error) {
 	// Tarih ve sipariş numarasını çıkar
 	date := extractDateFromPDF(fullText)
 	orderNumber := extractOrderNumberFromPDF(fullText)
@@ -300,13 +322,7 @@ func ParseShipmentPDF(pdfData []byte) (*ParsePDFResponse, error) {
 	}
 	
 	// Her ürün için sistemdeki ürünlerle eşleştir
-	for i := range products {
-		matched, err := matchProduct(products[i].ProductName, products[i].StockCode)
-		if err == nil && matched != nil {
-			products[i].MatchedProductID = &matched.ID
-			products[i].MatchedProductName = matched.Name
-		}
-	}
+	matchParsedProducts(products)
 	
 	return &ParsePDFResponse{
 		Products:    products,
@@ -314,4 +330,3 @@ func ParseShipmentPDF(pdfData []byte) (*ParsePDFResponse, error) {
 		OrderNumber: orderNumber,
 	}, nil
 }
-
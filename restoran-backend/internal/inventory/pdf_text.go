@@ -0,0 +1,225 @@
+package inventory
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"rsc.io/pdf"
+)
+
+// pdfPhrase: Aynı satırda birbirine yakın duran kelimelerden oluşan metin parçası
+type pdfPhrase struct {
+	X, EndX float64
+	Text    string
+}
+
+// pdfLine: Aynı Y koordinatındaki metin parçaları (soldan sağa)
+type pdfLine struct {
+	Y        float64
+	FontSize float64
+	Phrases  []pdfPhrase
+}
+
+func (l pdfLine) text() string {
+	parts := make([]string, 0, len(l.Phrases))
+	for _, p := range l.Phrases {
+		parts = append(parts, p.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// extractPDFPages: PDF dosyasındaki her sayfanın metnini satır satır çıkarır.
+// Ürün tablosu bulunan sayfalarda satırlar, parsePDFTable'ın beklediği
+// "| Stok Kodu | Ürün | ... |" formatına çevrilir.
+func extractPDFPages(data []byte) (pages []string, err error) {
+	// rsc.io/pdf bozuk dosyalarda panic atabiliyor
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("PDF okunamadı: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("PDF açılamadı: %v", err)
+	}
+
+	var table pdfTableState
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		lines := groupPDFLines(page.Content().Text)
+		pages = append(pages, table.render(lines))
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("PDF'te sayfa bulunamadı")
+	}
+	return pages, nil
+}
+
+// groupPDFLines: Karakterleri Y koordinatına göre satırlara, satır içinde X'e göre kelime ve parçalara ayırır
+func groupPDFLines(texts []pdf.Text) []pdfLine {
+	chars := make([]pdf.Text, 0, len(texts))
+	for _, t := range texts {
+		if t.S != "" {
+			chars = append(chars, t)
+		}
+	}
+	// PDF'te Y aşağıdan yukarı artar; yukarıdan aşağı oku
+	sort.SliceStable(chars, func(i, j int) bool {
+		if math.Abs(chars[i].Y-chars[j].Y) > lineTolerance(chars[i]) {
+			return chars[i].Y > chars[j].Y
+		}
+		return chars[i].X < chars[j].X
+	})
+
+	var lines []pdfLine
+	var current []pdf.Text
+	flush := func() {
+		if len(current) > 0 {
+			lines = append(lines, buildPDFLine(current))
+			current = nil
+		}
+	}
+	for _, ch := range chars {
+		if len(current) > 0 && math.Abs(current[0].Y-ch.Y) > lineTolerance(current[0]) {
+			flush()
+		}
+		current = append(current, ch)
+	}
+	flush()
+	return lines
+}
+
+func lineTolerance(t pdf.Text) float64 {
+	if t.FontSize > 0 {
+		return t.FontSize * 0.4
+	}
+	return 2
+}
+
+func buildPDFLine(chars []pdf.Text) pdfLine {
+	sort.SliceStable(chars, func(i, j int) bool { return chars[i].X < chars[j].X })
+
+	line := pdfLine{Y: chars[0].Y, FontSize: chars[0].FontSize}
+	if line.FontSize <= 0 {
+		line.FontSize = 10
+	}
+
+	var sb strings.Builder
+	var phrase pdfPhrase
+	lastEnd := math.Inf(-1)
+	for _, ch := range chars {
+		gap := ch.X - lastEnd
+		switch {
+		case sb.Len() > 0 && gap > line.FontSize*0.6:
+			// Geniş boşluk: yeni kolon/parça
+			phrase.Text = strings.Join(strings.Fields(sb.String()), " ")
+			if phrase.Text != "" {
+				line.Phrases = append(line.Phrases, phrase)
+			}
+			sb.Reset()
+		case sb.Len() > 0 && gap > line.FontSize*0.2:
+			// Kelime arası boşluk
+			sb.WriteByte(' ')
+		}
+		if sb.Len() == 0 {
+			phrase = pdfPhrase{X: ch.X}
+		}
+		sb.WriteString(ch.S)
+		phrase.EndX = ch.X + ch.W
+		lastEnd = phrase.EndX
+	}
+	phrase.Text = strings.Join(strings.Fields(sb.String()), " ")
+	if phrase.Text != "" {
+		line.Phrases = append(line.Phrases, phrase)
+	}
+	return line
+}
+
+// pdfTableState: Sayfalar boyunca tablo kolonlarının takibi
+type pdfTableState struct {
+	boundaries []float64 // kolonlar arası sınırlar (X); nil ise tablo içinde değiliz
+	lastY      float64   // son tablo satırının Y koordinatı
+	seen       bool      // tablo başlığı en az bir kez görüldü mü
+}
+
+// render: Satırları metne çevirir; tablo başlığından sonraki satırları
+// başlık kolonlarının konumuna göre pipe ile ayrılmış kolonlara yerleştirir.
+// Tablo başladıktan sonra tablo dışında kalan satırlar (sayfa altı/üstü bilgileri)
+// ürün adına eklenmesin diye atlanır.
+func (t *pdfTableState) render(lines []pdfLine) string {
+	var out []string
+	// Her sayfada tablo başlığı yeniden aranır
+	t.boundaries = nil
+
+	for _, line := range lines {
+		text := line.text()
+		if text == "" {
+			continue
+		}
+
+		if isTableHeader(text) && len(line.Phrases) >= 3 {
+			t.boundaries = make([]float64, 0, len(line.Phrases)-1)
+			cols := make([]string, 0, len(line.Phrases))
+			for i, p := range line.Phrases {
+				cols = append(cols, p.Text)
+				if i > 0 {
+					prev := line.Phrases[i-1]
+					t.boundaries = append(t.boundaries, (prev.EndX+p.X)/2)
+				}
+			}
+			out = append(out, pipeRow(cols))
+			out = append(out, pipeRow(repeatString("---", len(cols))))
+			t.lastY = line.Y
+			t.seen = true
+			continue
+		}
+
+		// Tablo bitti mi? (toplam satırı veya satırlar arasında büyük boşluk)
+		if t.boundaries != nil {
+			lower := strings.ToLower(text)
+			if strings.Contains(lower, "toplam") || t.lastY-line.Y > line.FontSize*3 {
+				t.boundaries = nil
+			}
+		}
+
+		if t.boundaries == nil {
+			if !t.seen {
+				out = append(out, text)
+			}
+			continue
+		}
+
+		cols := make([]string, len(t.boundaries)+1)
+		for _, p := range line.Phrases {
+			idx := sort.SearchFloat64s(t.boundaries, p.X)
+			if cols[idx] != "" {
+				cols[idx] += " "
+			}
+			cols[idx] += p.Text
+		}
+		out = append(out, pipeRow(cols))
+		t.lastY = line.Y
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func pipeRow(cols []string) string {
+	return "| " + strings.Join(cols, " | ") + " |"
+}
+
+func repeatString(s string, n int) []string {
+	res := make([]string, n)
+	for i := range res {
+		res[i] = s
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+)
+
+func TestParseShipmentPDF(t *testing.T) {
+	product := models.Product{Name: "Salça", Unit: "paket", StockCode: "TM0012", IsCenterProduct: true, IsActive: true}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.DB.Delete(&product) })
+
+	tests := []struct {
+		file        string
+		wantPages   int
+		wantMatched map[string]uint // stok kodu -> eşleşen ürün
+		want        []ParsedProduct
+	}{
+		{
+			file:        "shipment_single_page.pdf",
+			wantPages:   1,
+			wantMatched: map[string]uint{"TM0012": product.ID},
+			want: []ParsedProduct{
+				{StockCode: "TM0012", ProductName: "Domates Salçası 830 gr", UnitPrice: 140, Quantity: 2, QuantityUnit: "Paket", TotalAmount: 336},
+				// İkinci satıra taşan ürün adı
+				{StockCode: "TM0034", ProductName: "Kaşar Peyniri Tam Yağlı Vakumlu 2 kg", UnitPrice: 320.5, Quantity: 1, QuantityUnit: "Kilogram", TotalAmount: 352.55},
+			},
+		},
+		{
+			// İkinci sayfada tablo başlığı tekrarlanıyor, sayfa altında "Sayfa 1 / 2" yazıyor
+			file:      "shipment_multi_page.pdf",
+			wantPages: 2,
+			want: []ParsedProduct{
+				{StockCode: "TM0001", ProductName: "Ayçiçek Yağı 5 L", UnitPrice: 210, Quantity: 3, QuantityUnit: "Adet", TotalAmount: 693},
+				{StockCode: "TM0002", ProductName: "Dana Kıyma Özel Çekim Orta Yağlı", UnitPrice: 450, Quantity: 2, QuantityUnit: "Kilogram", TotalAmount: 909},
+				{StockCode: "TM0003", ProductName: "Pirinç Baldo", UnitPrice: 1250, Quantity: 1, QuantityUnit: "Çuval", TotalAmount: 1262.5},
+				{StockCode: "TM0004", ProductName: "Şeker Küp 1 kg", UnitPrice: 75.25, Quantity: 10, QuantityUnit: "Paket", TotalAmount: 760.03},
+				// Üç satıra taşan ürün adı
+				{StockCode: "TM0005", ProductName: "Tereyağı Kahvaltılık Çiğ Süt Ürünü 250 gr Paket", UnitPrice: 180, Quantity: 4, QuantityUnit: "Adet", TotalAmount: 727.2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pages, err := extractPDFPages(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != tt.wantPages {
+				t.Errorf("sayfa sayısı = %d, beklenen %d", len(pages), tt.wantPages)
+			}
+
+			res, err := ParseShipmentPDF(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Date != "12.12.2025" {
+				t.Errorf("tarih = %q, beklenen 12.12.2025", res.Date)
+			}
+			if res.OrderNumber != "A22A7ABE52039AA" {
+				t.Errorf("sipariş no = %q, beklenen A22A7ABE52039AA", res.OrderNumber)
+			}
+			if len(res.Products) != len(tt.want) {
+				t.Fatalf("ürün sayısı = %d, beklenen %d: %+v", len(res.Products), len(tt.want), res.Products)
+			}
+			for i, want := range tt.want {
+				got := res.Products[i]
+				matched := got.MatchedProductID
+				got.MatchedProductID, got.MatchedProductName = nil, ""
+				if got != want {
+					t.Errorf("ürün %d:\n got  %+v\n want %+v", i, got, want)
+				}
+				wantID, ok := tt.wantMatched[want.StockCode]
+				switch {
+				case ok && (matched == nil || *matched != wantID):
+					t.Errorf("ürün %d (%s) eşleşmesi = %v, beklenen %d", i, want.StockCode, matched, wantID)
+				case !ok && matched != nil:
+					t.Errorf("ürün %d (%s) beklenmedik şekilde eşleşti: %d", i, want.StockCode, *matched)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePDFTableSkipsRepeatedHeaders(t *testing.T) {
+	text := "| Stok Kodu | Ürün | Birim Fiyat | Miktar | Kdv Oranı | Kdv Tutarı | Toplam Tutar |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| TM0001 | Un | 10,00 | 1 Adet | %1 | 0,10 | 10,10 |\n" +
+		"|  | Tip 550 |  |  |  |  |  |\n" +
+		"| Stok Kodu | Ürün | Birim Fiyat | Miktar | Kdv Oranı | Kdv Tutarı | Toplam Tutar |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"|  | 25 kg |  |  |  |  |  |\n" +
+		"| TM0002 | Tuz | 5,00 | 2 Adet | %1 | 0,10 | 10,10 |"
+
+	products, err := parsePDFTable(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("ürün sayısı = %d, beklenen 2: %+v", len(products), products)
+	}
+	// Sayfa sonunda bölünen ürün adı başlık satırlarına rağmen birleşir
+	if products[0].ProductName != "Un Tip 550 25 kg" {
+		t.Errorf("ürün adı = %q, beklenen %q", products[0].ProductName, "Un Tip 550 25 kg")
+	}
+}
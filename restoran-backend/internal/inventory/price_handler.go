@@ -0,0 +1,363 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// PricePoint: Bir alış kaydındaki birim fiyat
+type PricePoint struct {
+	Date      string  `json:"date"`
+	Source    string  `json:"source"` // "shipment", "center_shipment", "produce_purchase"
+	SourceID  uint    `json:"source_id"`
+	Quantity  float64 `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"` // KDV'siz
+}
+
+type PriceHistoryResponse struct {
+	Kind            string       `json:"kind"` // "product" (merkez) / "produce" (manav)
+	ProductID       uint         `json:"product_id"`
+	ProductName     string       `json:"product_name"`
+	Unit            string       `json:"unit"`
+	BranchID        uint         `json:"branch_id"`
+	Points          []PricePoint `json:"points"`
+	LastPrice       float64      `json:"last_price"`
+	PreviousPrice   float64      `json:"previous_price"`
+	ChangePct       float64      `json:"change_pct"`    // son fiyatın bir önceki fiyata göre değişimi
+	AveragePrice    float64      `json:"average_price"` // miktar ağırlıklı ortalama
+	MinPrice        float64      `json:"min_price"`
+	MaxPrice        float64      `json:"max_price"`
+	PeriodChangePct float64      `json:"period_change_pct"` // son fiyatın dönemdeki ilk fiyata göre değişimi
+}
+
+// PriceChange: Sevkiyat kalemindeki fiyatın önceki alış fiyatına göre değişimi
+type PriceChange struct {
+	PreviousPrice float64 `json:"previous_price"`
+	ChangePct     float64 `json:"change_pct"`
+	Alert         bool    `json:"alert"` // eşik aşıldı, uyarı kaydı oluşturuldu
+	AlertID       uint    `json:"alert_id,omitempty"`
+}
+
+type PriceAlertResponse struct {
+	ID             uint    `json:"id"`
+	BranchID       uint    `json:"branch_id"`
+	ProductID      uint    `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	StockCode      string  `json:"stock_code"`
+	ShipmentID     uint    `json:"shipment_id"`
+	ShipmentItemID uint    `json:"shipment_item_id"`
+	Date           string  `json:"date"`
+	PreviousPrice  float64 `json:"previous_price"`
+	NewPrice       float64 `json:"new_price"`
+	ChangePct      float64 `json:"change_pct"`
+	ThresholdPct   float64 `json:"threshold_pct"`
+	Status         string  `json:"status"`
+	ReviewedByName string  `json:"reviewed_by_name,omitempty"`
+	ReviewedAt     string  `json:"reviewed_at,omitempty"`
+	ReviewNote     string  `json:"review_note,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+type ReviewPriceAlertRequest struct {
+	Note string `json:"note"`
+}
+
+func toPriceAlertResponse(a models.PriceAlert) PriceAlertResponse {
+	resp := PriceAlertResponse{
+		ID:             a.ID,
+		BranchID:       a.BranchID,
+		ProductID:      a.ProductID,
+		ProductName:    a.Product.Name,
+		StockCode:      a.Product.StockCode,
+		ShipmentID:     a.ShipmentID,
+		ShipmentItemID: a.ShipmentItemID,
+		Date:           a.Date.Format("2006-01-02"),
+		PreviousPrice:  a.PreviousPrice,
+		NewPrice:       a.NewPrice,
+		ChangePct:      a.ChangePct,
+		ThresholdPct:   a.ThresholdPct,
+		Status:         string(a.Status),
+		ReviewedByName: a.ReviewedByName,
+		ReviewNote:     a.ReviewNote,
+		CreatedAt:      a.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if a.ReviewedAt != nil {
+		resp.ReviewedAt = a.ReviewedAt.Format("2006-01-02 15:04:05")
+	}
+	return resp
+}
+
+func pctChange(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+	return math.Round((to-from)/from*10000) / 100
+}
+
+// lastProductUnitPrice: Ürünün şubedeki, verilen sevkiyattan önceki son KDV'siz alış fiyatı
+// (sevkiyat kalemleri ve merkezden gelen ürün kayıtları). Kayıt yoksa ok=false.
+func lastProductUnitPrice(db *gorm.DB, branchID, productID, excludeShipmentID uint, d time.Time) (float64, bool, error) {
+	var rows []struct {
+		UnitPrice float64
+	}
+	err := db.Raw(`
+		SELECT unit_price FROM (
+			SELECT si.unit_price, s.date, 1 AS src, si.id AS sid
+			FROM shipment_items si
+			JOIN shipments s ON s.id = si.shipment_id
+			WHERE s.branch_id = ? AND si.product_id = ? AND si.shipment_id <> ? AND s.date <= ? AND si.unit_price > 0
+			UNION ALL
+			SELECT unit_price, date, 0 AS src, id AS sid
+			FROM center_shipments
+			WHERE branch_id = ? AND product_id = ? AND date <= ? AND unit_price > 0
+		) p
+		ORDER BY date DESC, src DESC, sid DESC
+		LIMIT 1`,
+		branchID, productID, excludeShipmentID, d,
+		branchID, productID, d).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return 0, false, err
+	}
+	return rows[0].UnitPrice, true, nil
+}
+
+// checkShipmentPrices: Sevkiyat kalemlerinin fiyatını önceki alış fiyatıyla karşılaştırır;
+// değişim eşik yüzdesini aşarsa PriceAlert kaydı oluşturur. Sonuç kalem ID'sine göre döner.
+func checkShipmentPrices(db *gorm.DB, shipment models.Shipment, thresholdPct float64) (map[uint]*PriceChange, error) {
+	changes := make(map[uint]*PriceChange)
+	for _, item := range shipment.Items {
+		if item.UnitPrice <= 0 {
+			continue
+		}
+		prev, ok, err := lastProductUnitPrice(db, shipment.BranchID, item.ProductID, shipment.ID, shipment.Date)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		change := &PriceChange{PreviousPrice: prev, ChangePct: pctChange(prev, item.UnitPrice)}
+		if thresholdPct > 0 && math.Abs(change.ChangePct) > thresholdPct {
+			alert := models.PriceAlert{
+				BranchID:       shipment.BranchID,
+				ProductID:      item.ProductID,
+				ShipmentID:     shipment.ID,
+				ShipmentItemID: item.ID,
+				Date:           shipment.Date,
+				PreviousPrice:  prev,
+				NewPrice:       item.UnitPrice,
+				ChangePct:      change.ChangePct,
+				ThresholdPct:   thresholdPct,
+				Status:         models.PriceAlertPending,
+			}
+			if err := db.Create(&alert).Error; err != nil {
+				return nil, err
+			}
+			change.Alert = true
+			change.AlertID = alert.ID
+		}
+		changes[item.ID] = change
+	}
+	return changes, nil
+}
+
+// GET /api/price-history?branch_id=...&kind=product|produce&product_id=...&from=...&to=...
+// Ürünün şubedeki alış fiyatı geçmişi: merkez ürünlerinde sevkiyat kalemleri ve merkezden
+// gelen ürün kayıtları, manav ürünlerinde manav alımları kullanılır.
+func PriceHistoryHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		kind := c.Query("kind", "product")
+		if kind != "product" && kind != "produce" {
+			return fiber.NewError(fiber.StatusBadRequest, "kind 'product' veya 'produce' olmalı")
+		}
+
+		var productID uint
+		if _, err := fmt.Sscan(c.Query("product_id"), &productID); err != nil || productID == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "product_id geçersiz")
+		}
+
+		from := time.Time{}
+		to := time.Now().AddDate(100, 0, 0)
+		if fromStr := c.Query("from"); fromStr != "" {
+			if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "from geçersiz")
+			}
+		}
+		if toStr := c.Query("to"); toStr != "" {
+			if to, err = time.Parse("2006-01-02", toStr); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "to geçersiz")
+			}
+		}
+
+		resp := PriceHistoryResponse{
+			Kind:      kind,
+			ProductID: productID,
+			BranchID:  branchID,
+			Points:    []PricePoint{},
+		}
+
+		type pointRow struct {
+			Date      time.Time
+			Source    string
+			SourceID  uint
+			Quantity  float64
+			UnitPrice float64
+		}
+		var rows []pointRow
+
+		if kind == "product" {
+			var product models.Product
+			if err := database.DB.First(&product, "id = ?", productID).Error; err != nil {
+				return fiber.NewError(fiber.StatusNotFound, "Ürün bulunamadı")
+			}
+			resp.ProductName, resp.Unit = product.Name, product.Unit
+
+			if err := database.DB.Raw(`
+				SELECT s.date, 'shipment' AS source, s.id AS source_id, si.quantity, si.unit_price
+				FROM shipment_items si
+				JOIN shipments s ON s.id = si.shipment_id
+				WHERE s.branch_id = ? AND si.product_id = ? AND s.date >= ? AND s.date <= ? AND si.unit_price > 0
+				UNION ALL
+				SELECT date, 'center_shipment', id, quantity, unit_price
+				FROM center_shipments
+				WHERE branch_id = ? AND product_id = ? AND date >= ? AND date <= ? AND unit_price > 0
+				ORDER BY date ASC, source_id ASC`,
+				branchID, productID, from, to,
+				branchID, productID, from, to).Scan(&rows).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Fiyat geçmişi okunamadı")
+			}
+		} else {
+			var product models.ProduceProduct
+			if err := database.DB.First(&product, "id = ?", productID).Error; err != nil {
+				return fiber.NewError(fiber.StatusNotFound, "Ürün bulunamadı")
+			}
+			resp.ProductName, resp.Unit = product.Name, product.Unit
+
+			if err := database.DB.Model(&models.ProducePurchase{}).
+				Select("date, 'produce_purchase' AS source, id AS source_id, quantity, unit_price").
+				Where("branch_id = ? AND product_id = ? AND date >= ? AND date <= ? AND unit_price > 0", branchID, productID, from, to).
+				Order("date asc, id asc").
+				Scan(&rows).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Fiyat geçmişi okunamadı")
+			}
+		}
+
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+
+		var totalQty, totalCost float64
+		for i, r := range rows {
+			resp.Points = append(resp.Points, PricePoint{
+				Date:      r.Date.Format("2006-01-02"),
+				Source:    r.Source,
+				SourceID:  r.SourceID,
+				Quantity:  r.Quantity,
+				UnitPrice: r.UnitPrice,
+			})
+			totalQty += r.Quantity
+			totalCost += r.Quantity * r.UnitPrice
+			if i == 0 || r.UnitPrice < resp.MinPrice {
+				resp.MinPrice = r.UnitPrice
+			}
+			if r.UnitPrice > resp.MaxPrice {
+				resp.MaxPrice = r.UnitPrice
+			}
+		}
+
+		if n := len(rows); n > 0 {
+			resp.LastPrice = rows[n-1].UnitPrice
+			if n > 1 {
+				resp.PreviousPrice = rows[n-2].UnitPrice
+				resp.ChangePct = pctChange(resp.PreviousPrice, resp.LastPrice)
+			}
+			resp.PeriodChangePct = pctChange(rows[0].UnitPrice, resp.LastPrice)
+			if totalQty > 0 {
+				resp.AveragePrice = math.Round(totalCost/totalQty*100) / 100
+			}
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/price-alerts?branch_id=...&status=pending
+func ListPriceAlertsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		dbq := database.DB.Preload("Product").Where("branch_id = ?", branchID)
+		if status := c.Query("status"); status != "" {
+			dbq = dbq.Where("status = ?", status)
+		}
+
+		var alerts []models.PriceAlert
+		if err := dbq.Order("date desc, id desc").Find(&alerts).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Fiyat uyarıları listelenemedi")
+		}
+
+		resp := make([]PriceAlertResponse, 0, len(alerts))
+		for _, a := range alerts {
+			resp = append(resp, toPriceAlertResponse(a))
+		}
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/price-alerts/:id/review
+func ReviewPriceAlertHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var alert models.PriceAlert
+		if err := tenancy.DB(c).Preload("Product").First(&alert, "id = ?", c.Params("id")).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Fiyat uyarısı bulunamadı")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Fiyat uyarısı okunamadı")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		var body ReviewPriceAlertRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		now := time.Now()
+		alert.Status = models.PriceAlertReviewed
+		alert.ReviewedBy = &userID
+		alert.ReviewedByName = userName
+		alert.ReviewedAt = &now
+		alert.ReviewNote = body.Note
+		if err := database.DB.Model(&alert).Updates(map[string]interface{}{
+			"status":           alert.Status,
+			"reviewed_by":      userID,
+			"reviewed_by_name": userName,
+			"reviewed_at":      now,
+			"review_note":      body.Note,
+		}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Fiyat uyarısı güncellenemedi")
+		}
+
+		return c.JSON(toPriceAlertResponse(alert))
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -93,7 +94,7 @@ func UpdateProductCategoryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var cat models.ProductCategory
-		if err := database.DB.First(&cat, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&cat, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
 		}
 
@@ -127,6 +128,11 @@ func DeleteProductCategoryHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 
+		var cat models.ProductCategory
+		if err := tenancy.DB(c).First(&cat, "id = ?", id).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
+		}
+
 		// Kategoriye ait ürün var mı kontrol et
 		var count int64
 		database.DB.Model(&models.Product{}).Where("category_id = ?", id).Count(&count)
@@ -134,7 +140,7 @@ func DeleteProductCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Bu kategoriye ait ürünler var, önce ürünleri silin")
 		}
 
-		if err := database.DB.Delete(&models.ProductCategory{}, "id = ?", id).Error; err != nil {
+		if err := database.DB.Delete(&cat).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Kategori silinemedi")
 		}
 
@@ -10,6 +10,7 @@ import (
 	"restoran-backend/internal/config"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,6 +21,7 @@ type ProductResponse struct {
 	Unit      string `json:"unit"`
 	StockCode string `json:"stock_code"`
 	Category  string `json:"category"`
+	IsActive  bool   `json:"is_active"`
 }
 
 type CreateProductRequest struct {
@@ -34,13 +36,19 @@ type UpdateProductRequest struct {
 	Unit      *string `json:"unit"`
 	StockCode *string `json:"stock_code"` // Opsiyonel
 	Category  *string `json:"category"`   // Opsiyonel
+	IsActive  *bool   `json:"is_active"`  // Opsiyonel
 }
 
-// GET /api/products (tüm authenticated kullanıcılar görebilir)
+// GET /api/products?active=true (tüm authenticated kullanıcılar görebilir)
 func ListProductsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		dbq := database.DB
+		if c.Query("active") == "true" {
+			dbq = dbq.Where("is_active = ?", true)
+		}
+
 		var products []models.Product
-		if err := database.DB.Order("name asc").Find(&products).Error; err != nil {
+		if err := dbq.Order("name asc").Find(&products).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
 		}
 
@@ -52,6 +60,7 @@ func ListProductsHandler() fiber.Handler {
 				Unit:      p.Unit,
 				StockCode: p.StockCode,
 				Category:  p.Category,
+				IsActive:  p.IsActive,
 			})
 		}
 		return c.JSON(res)
@@ -89,6 +98,7 @@ func CreateProductHandler() fiber.Handler {
 			StockCode:       body.StockCode,
 			Category:        body.Category,
 			IsCenterProduct: true, // Normal ürün yönetimi için her zaman true
+			IsActive:        true,
 		}
 
 		if err := database.DB.Create(&p).Error; err != nil {
@@ -101,6 +111,7 @@ func CreateProductHandler() fiber.Handler {
 			Unit:      p.Unit,
 			StockCode: p.StockCode,
 			Category:  p.Category,
+			IsActive:  p.IsActive,
 		})
 	}
 }
@@ -133,6 +144,9 @@ func UpdateProductHandler() fiber.Handler {
 			if unit == "" {
 				return fiber.NewError(fiber.StatusBadRequest, "Unit boş olamaz")
 			}
+			if err := units.EnsureBaseUnitChange(database.DB, models.UnitKindProduct, p.ID, p.Unit, unit); err != nil {
+				return err
+			}
 			p.Unit = unit
 		}
 
@@ -144,6 +158,10 @@ func UpdateProductHandler() fiber.Handler {
 			p.Category = strings.TrimSpace(*body.Category)
 		}
 
+		if body.IsActive != nil {
+			p.IsActive = *body.IsActive
+		}
+
 		if err := database.DB.Save(&p).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Ürün güncellenemedi")
 		}
@@ -154,6 +172,7 @@ func UpdateProductHandler() fiber.Handler {
 			Unit:      p.Unit,
 			StockCode: p.StockCode,
 			Category:  p.Category,
+			IsActive:  p.IsActive,
 		})
 	}
 }
@@ -0,0 +1,799 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Teslimatta birim fiyat farkı bu tutarın altındaysa fark sayılmaz (kuruş yuvarlamaları)
+const purchaseOrderPriceTolerance = 0.01
+
+type PurchaseOrderRequest struct {
+	Supplier     string                     `json:"supplier"`      // boşsa "Merkez"
+	OrderDate    string                     `json:"order_date"`    // "2025-12-09"
+	ExpectedDate string                     `json:"expected_date"` // opsiyonel
+	Note         string                     `json:"note"`
+	Lines        []PurchaseOrderLineRequest `json:"lines"`
+	BranchID     *uint                      `json:"branch_id"` // super_admin için
+}
+
+type PurchaseOrderLineRequest struct {
+	ProductID         uint    `json:"product_id"`
+	Quantity          float64 `json:"quantity"`
+	ExpectedUnitPrice float64 `json:"expected_unit_price"` // KDV'siz
+}
+
+type PurchaseOrderResponse struct {
+	ID           uint                        `json:"id"`
+	BranchID     uint                        `json:"branch_id"`
+	Supplier     string                      `json:"supplier"`
+	Status       models.PurchaseOrderStatus  `json:"status"`
+	OrderDate    string                      `json:"order_date"`
+	ExpectedDate string                      `json:"expected_date,omitempty"`
+	TotalAmount  float64                     `json:"total_amount"`
+	Note         string                      `json:"note"`
+	Lines        []PurchaseOrderLineResponse `json:"lines"`
+	Unordered    []PurchaseOrderLineResponse `json:"unordered,omitempty"` // siparişte olmayıp gelen ürünler
+	ShipmentIDs  []uint                      `json:"shipment_ids"`
+	SentAt       string                      `json:"sent_at,omitempty"`
+	ReceivedAt   string                      `json:"received_at,omitempty"`
+	CreatedAt    string                      `json:"created_at"`
+}
+
+type PurchaseOrderLineResponse struct {
+	LineID            uint    `json:"line_id,omitempty"`
+	ProductID         uint    `json:"product_id"`
+	ProductName       string  `json:"product_name"`
+	Unit              string  `json:"unit"`
+	OrderedQuantity   float64 `json:"ordered_quantity"`
+	ReceivedQuantity  float64 `json:"received_quantity"`
+	QuantityDiff      float64 `json:"quantity_diff"` // gelen - sipariş (eksi: eksik teslimat)
+	ExpectedUnitPrice float64 `json:"expected_unit_price"`
+	ReceivedUnitPrice float64 `json:"received_unit_price"` // gelen kalemlerin miktar ağırlıklı ortalaması (KDV'siz)
+	PriceDiff         float64 `json:"price_diff"`          // birim fiyat farkı
+	PriceDiffAmount   float64 `json:"price_diff_amount"`   // fiyat farkı * gelen miktar
+	Discrepancy       string  `json:"discrepancy,omitempty"`
+}
+
+// Kalem uyuşmazlık tipleri (aynı kalemde birden fazlası olabilir, virgülle ayrılır)
+const (
+	discrepancyShort      = "short"       // eksik teslimat
+	discrepancyOver       = "over"        // fazla teslimat
+	discrepancyPrice      = "price"       // fiyat farkı
+	discrepancyNotOrdered = "not_ordered" // siparişte olmayan ürün
+)
+
+// receivedLine: Sipariş kalemine eşleşen sevkiyat kalemlerinin toplamı
+type receivedLine struct {
+	Quantity float64
+	Amount   float64 // KDV'siz birim fiyat * miktar
+}
+
+// purchaseOrderReceipts: Siparişe eşleşen sevkiyat kalemleri; kalem ID'sine göre toplam ve
+// siparişte olmayan ürünler (ürün ID'sine göre), ayrıca eşleşen sevkiyat ID'leri
+func purchaseOrderReceipts(db *gorm.DB, poID uint) (map[uint]receivedLine, map[uint]receivedLine, []uint, error) {
+	var items []models.ShipmentItem
+	if err := db.Joins("JOIN shipments ON shipments.id = shipment_items.shipment_id").
+		Where("shipments.purchase_order_id = ?", poID).
+		Order("shipment_items.id").
+		Find(&items).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	byLine := make(map[uint]receivedLine)
+	unordered := make(map[uint]receivedLine)
+	seen := make(map[uint]bool)
+	shipmentIDs := []uint{}
+	for _, item := range items {
+		if !seen[item.ShipmentID] {
+			seen[item.ShipmentID] = true
+			shipmentIDs = append(shipmentIDs, item.ShipmentID)
+		}
+		target := unordered
+		key := item.ProductID
+		if item.PurchaseOrderLineID != nil {
+			target = byLine
+			key = *item.PurchaseOrderLineID
+		}
+		r := target[key]
+		r.Quantity += item.Quantity
+		r.Amount += item.Quantity * item.UnitPrice
+		target[key] = r
+	}
+	return byLine, unordered, shipmentIDs, nil
+}
+
+// reconcileLine: Sipariş edilen ile gelen miktar / fiyat karşılaştırması
+func reconcileLine(resp *PurchaseOrderLineResponse, received receivedLine) {
+	resp.ReceivedQuantity = received.Quantity
+	resp.QuantityDiff = received.Quantity - resp.OrderedQuantity
+	if received.Quantity > 0 {
+		resp.ReceivedUnitPrice = round2(received.Amount / received.Quantity)
+	}
+
+	var kinds []string
+	switch {
+	case resp.OrderedQuantity == 0:
+		kinds = append(kinds, discrepancyNotOrdered)
+	case resp.QuantityDiff < 0:
+		kinds = append(kinds, discrepancyShort)
+	case resp.QuantityDiff > 0:
+		kinds = append(kinds, discrepancyOver)
+	}
+	if received.Quantity > 0 && resp.ExpectedUnitPrice > 0 {
+		resp.PriceDiff = round2(resp.ReceivedUnitPrice - resp.ExpectedUnitPrice)
+		if math.Abs(resp.PriceDiff) >= purchaseOrderPriceTolerance {
+			resp.PriceDiffAmount = round2(resp.PriceDiff * received.Quantity)
+			kinds = append(kinds, discrepancyPrice)
+		} else {
+			resp.PriceDiff = 0
+		}
+	}
+	resp.Discrepancy = strings.Join(kinds, ",")
+}
+
+func buildPurchaseOrderResponse(db *gorm.DB, po models.PurchaseOrder) (PurchaseOrderResponse, error) {
+	resp := PurchaseOrderResponse{
+		ID:          po.ID,
+		BranchID:    po.BranchID,
+		Supplier:    po.Supplier,
+		Status:      po.Status,
+		OrderDate:   po.OrderDate.Format("2006-01-02"),
+		TotalAmount: po.TotalAmount,
+		Note:        po.Note,
+		Lines:       make([]PurchaseOrderLineResponse, 0, len(po.Lines)),
+		ShipmentIDs: []uint{},
+		CreatedAt:   po.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if po.ExpectedDate != nil {
+		resp.ExpectedDate = po.ExpectedDate.Format("2006-01-02")
+	}
+	if po.SentAt != nil {
+		resp.SentAt = po.SentAt.Format("2006-01-02 15:04:05")
+	}
+	if po.ReceivedAt != nil {
+		resp.ReceivedAt = po.ReceivedAt.Format("2006-01-02 15:04:05")
+	}
+
+	byLine, unordered, shipmentIDs, err := purchaseOrderReceipts(db, po.ID)
+	if err != nil {
+		return resp, err
+	}
+	resp.ShipmentIDs = shipmentIDs
+
+	for _, line := range po.Lines {
+		lr := PurchaseOrderLineResponse{
+			LineID:            line.ID,
+			ProductID:         line.ProductID,
+			ProductName:       line.Product.Name,
+			Unit:              line.Product.Unit,
+			OrderedQuantity:   line.Quantity,
+			ExpectedUnitPrice: line.ExpectedUnitPrice,
+		}
+		// Henüz teslimat yoksa (taslak / gönderildi) uyuşmazlık hesaplanmaz
+		if len(shipmentIDs) > 0 {
+			reconcileLine(&lr, byLine[line.ID])
+		}
+		resp.Lines = append(resp.Lines, lr)
+	}
+
+	if len(unordered) > 0 {
+		productIDs := make([]uint, 0, len(unordered))
+		for id := range unordered {
+			productIDs = append(productIDs, id)
+		}
+		var products []models.Product
+		if err := db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			return resp, err
+		}
+		for _, p := range products {
+			lr := PurchaseOrderLineResponse{
+				ProductID:   p.ID,
+				ProductName: p.Name,
+				Unit:        p.Unit,
+			}
+			reconcileLine(&lr, unordered[p.ID])
+			resp.Unordered = append(resp.Unordered, lr)
+		}
+		sort.Slice(resp.Unordered, func(i, j int) bool {
+			return resp.Unordered[i].ProductName < resp.Unordered[j].ProductName
+		})
+	}
+
+	return resp, nil
+}
+
+// parsePurchaseOrderRequest: İstek doğrulaması, sipariş kalemlerini ve toplamı hazırlar
+func parsePurchaseOrderRequest(body PurchaseOrderRequest) (models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+
+	po.Supplier = strings.TrimSpace(body.Supplier)
+	if po.Supplier == "" {
+		po.Supplier = "Merkez"
+	}
+	if len(body.Lines) == 0 {
+		return po, fiber.NewError(fiber.StatusBadRequest, "En az bir ürün eklenmelidir")
+	}
+
+	d, err := time.Parse("2006-01-02", body.OrderDate)
+	if err != nil {
+		return po, fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+	}
+	po.OrderDate = d
+	if body.ExpectedDate != "" {
+		ed, err := time.Parse("2006-01-02", body.ExpectedDate)
+		if err != nil {
+			return po, fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+		}
+		if ed.Before(d) {
+			return po, fiber.NewError(fiber.StatusBadRequest, "expected_date, order_date'den önce olamaz")
+		}
+		po.ExpectedDate = &ed
+	}
+	po.Note = body.Note
+
+	seen := make(map[uint]bool, len(body.Lines))
+	for _, l := range body.Lines {
+		if l.ProductID == 0 || l.Quantity <= 0 || l.ExpectedUnitPrice < 0 {
+			return po, fiber.NewError(fiber.StatusBadRequest, "Tüm kalemler için product_id ve quantity zorunlu, fiyat negatif olamaz")
+		}
+		if seen[l.ProductID] {
+			return po, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün %d birden fazla kez eklendi", l.ProductID))
+		}
+		seen[l.ProductID] = true
+
+		var product models.Product
+		if err := database.DB.Where("id = ? AND is_center_product = ?", l.ProductID, true).First(&product).Error; err != nil {
+			return po, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı veya manav ürünü: %d", l.ProductID))
+		}
+		po.Lines = append(po.Lines, models.PurchaseOrderLine{
+			ProductID:         l.ProductID,
+			Product:           product,
+			Quantity:          l.Quantity,
+			ExpectedUnitPrice: l.ExpectedUnitPrice,
+		})
+		po.TotalAmount += l.Quantity * l.ExpectedUnitPrice
+	}
+	po.TotalAmount = round2(po.TotalAmount)
+	return po, nil
+}
+
+// loadPurchaseOrder: Siparişi kalemleriyle yükler, başka şubenin siparişi bulunamadı döner
+func loadPurchaseOrder(c *fiber.Ctx) (models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	if err := tenancy.DB(c).Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Lines.Product").First(&po, "id = ?", c.Params("id")).Error; err != nil {
+		return po, fiber.NewError(fiber.StatusNotFound, "Sipariş bulunamadı")
+	}
+	return po, nil
+}
+
+func writePurchaseOrderAudit(c *fiber.Ctx, po models.PurchaseOrder, action models.AuditAction, description string, before any) {
+	userID, userName, err := tenancy.CurrentUser(c)
+	if err != nil {
+		return
+	}
+	var after any = po
+	if action == models.AuditActionDelete {
+		after = nil
+	}
+	if logErr := audit.WriteLog(audit.LogOptions{
+		BranchID:    &po.BranchID,
+		UserID:      userID,
+		UserName:    userName,
+		EntityType:  "purchase_order",
+		EntityID:    po.ID,
+		Action:      action,
+		Description: description,
+		Before:      before,
+		After:       after,
+	}); logErr != nil {
+		fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+	}
+}
+
+// POST /api/purchase-orders
+// Taslak sipariş oluşturur (sipariş önerisindeki kalemler product_id / quantity / unit_price ile gönderilebilir)
+func CreatePurchaseOrderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body PurchaseOrderRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
+		if err != nil {
+			return err
+		}
+		userID, _, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		po, err := parsePurchaseOrderRequest(body)
+		if err != nil {
+			return err
+		}
+		po.BranchID = branchID
+		po.Status = models.PurchaseOrderDraft
+		po.CreatedBy = userID
+
+		if err := database.DB.Omit("Lines.Product").Create(&po).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş oluşturulamadı")
+		}
+
+		writePurchaseOrderAudit(c, po, models.AuditActionCreate,
+			fmt.Sprintf("Sipariş oluşturuldu: %s, %d ürün, Tutar: %.2f TL", po.Supplier, len(po.Lines), po.TotalAmount), nil)
+
+		resp, err := buildPurchaseOrderResponse(database.DB, po)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		return c.Status(fiber.StatusCreated).JSON(resp)
+	}
+}
+
+// GET /api/purchase-orders?status=...&supplier=...
+func ListPurchaseOrdersHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		query := database.DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).Preload("Lines.Product").Where("branch_id = ?", branchID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if supplier := c.Query("supplier"); supplier != "" {
+			query = query.Where("supplier = ?", supplier)
+		}
+
+		var orders []models.PurchaseOrder
+		if err := query.Order("order_date DESC, id DESC").Find(&orders).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Siparişler listelenemedi")
+		}
+
+		resp := make([]PurchaseOrderResponse, 0, len(orders))
+		for _, po := range orders {
+			r, err := buildPurchaseOrderResponse(database.DB, po)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Siparişler listelenemedi")
+			}
+			resp = append(resp, r)
+		}
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/purchase-orders/:id
+func GetPurchaseOrderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		po, err := loadPurchaseOrder(c)
+		if err != nil {
+			return err
+		}
+		resp, err := buildPurchaseOrderResponse(database.DB, po)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// PUT /api/purchase-orders/:id
+// Sadece taslak siparişler düzenlenebilir; kalemler baştan yazılır
+func UpdatePurchaseOrderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		po, err := loadPurchaseOrder(c)
+		if err != nil {
+			return err
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece taslak siparişler düzenlenebilir")
+		}
+
+		var body PurchaseOrderRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		updated, err := parsePurchaseOrderRequest(body)
+		if err != nil {
+			return err
+		}
+
+		before := po
+		po.Supplier = updated.Supplier
+		po.OrderDate = updated.OrderDate
+		po.ExpectedDate = updated.ExpectedDate
+		po.Note = updated.Note
+		po.TotalAmount = updated.TotalAmount
+		po.Lines = updated.Lines
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("purchase_order_id = ?", po.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Sipariş kalemleri silinemedi")
+			}
+			for i := range po.Lines {
+				po.Lines[i].PurchaseOrderID = po.ID
+			}
+			if err := tx.Omit("Lines.Product").Save(&po).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Sipariş güncellenemedi")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		writePurchaseOrderAudit(c, po, models.AuditActionUpdate,
+			fmt.Sprintf("Sipariş güncellendi: %s, %d ürün, Tutar: %.2f TL", po.Supplier, len(po.Lines), po.TotalAmount), before)
+
+		resp, err := buildPurchaseOrderResponse(database.DB, po)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// POST /api/purchase-orders/:id/send
+// Taslak siparişi gönderildi olarak işaretler; bundan sonra gelen sevkiyatlar bu siparişle eşleşir
+func SendPurchaseOrderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		po, err := loadPurchaseOrder(c)
+		if err != nil {
+			return err
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			return fiber.NewError(fiber.StatusBadRequest, "Sipariş zaten gönderilmiş")
+		}
+
+		before := po
+		now := time.Now()
+		po.Status = models.PurchaseOrderSent
+		po.SentAt = &now
+		if err := database.DB.Model(&po).Updates(map[string]interface{}{
+			"status":  po.Status,
+			"sent_at": now,
+		}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş güncellenemedi")
+		}
+
+		writePurchaseOrderAudit(c, po, models.AuditActionUpdate,
+			fmt.Sprintf("Sipariş gönderildi: %s", po.Supplier), before)
+
+		resp, err := buildPurchaseOrderResponse(database.DB, po)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+// DELETE /api/purchase-orders/:id
+// Sadece taslak siparişler silinebilir
+func DeletePurchaseOrderHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		po, err := loadPurchaseOrder(c)
+		if err != nil {
+			return err
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			return fiber.NewError(fiber.StatusBadRequest, "Sadece taslak siparişler silinebilir")
+		}
+
+		if err := database.DB.Delete(&po).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş silinemedi")
+		}
+
+		writePurchaseOrderAudit(c, po, models.AuditActionDelete,
+			fmt.Sprintf("Taslak sipariş silindi: %s", po.Supplier), po)
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// matchShipmentToPurchaseOrder: Sevkiyatı açık (gönderilmiş / kısmi teslim) siparişle eşleştirir.
+// poID verilmezse şubenin sevkiyat tarihinden önce verilmiş açık siparişlerinden kalan
+// kalemleriyle en çok ürünü örtüşen seçilir (eşitlikte en eski). Kalemler aynı ürünlü sipariş
+// kalemine bağlanır ve siparişin durumu güncellenir. Eşleşecek sipariş yoksa nil döner.
+// Hatalar fiber.Error olarak döner.
+func matchShipmentToPurchaseOrder(tx *gorm.DB, shipment *models.Shipment, poID *uint) (*models.PurchaseOrder, error) {
+	openStatuses := []models.PurchaseOrderStatus{models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived}
+
+	var po models.PurchaseOrder
+	if poID != nil {
+		if err := tx.Preload("Lines").First(&po, "id = ?", *poID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Sipariş bulunamadı")
+		}
+		if po.BranchID != shipment.BranchID {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Sipariş bu şubeye ait değil")
+		}
+		if po.Status != models.PurchaseOrderSent && po.Status != models.PurchaseOrderPartiallyReceived {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Sadece gönderilmiş ve teslimatı tamamlanmamış siparişlerle eşleştirilebilir")
+		}
+	} else {
+		var candidates []models.PurchaseOrder
+		if err := tx.Preload("Lines").
+			Where("branch_id = ? AND status IN ? AND order_date <= ?", shipment.BranchID, openStatuses, shipment.Date).
+			Order("order_date, id").
+			Find(&candidates).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Açık siparişler okunamadı")
+		}
+
+		shipped := make(map[uint]bool, len(shipment.Items))
+		for _, item := range shipment.Items {
+			shipped[item.ProductID] = true
+		}
+		best := -1
+		bestOverlap := 0
+		for i, cand := range candidates {
+			byLine, _, _, err := purchaseOrderReceipts(tx, cand.ID)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusInternalServerError, "Sipariş teslimatları okunamadı")
+			}
+			overlap := 0
+			for _, line := range cand.Lines {
+				if shipped[line.ProductID] && byLine[line.ID].Quantity < line.Quantity {
+					overlap++
+				}
+			}
+			if overlap > bestOverlap {
+				best, bestOverlap = i, overlap
+			}
+		}
+		if best < 0 {
+			return nil, nil
+		}
+		po = candidates[best]
+	}
+
+	lineByProduct := make(map[uint]uint, len(po.Lines))
+	for _, line := range po.Lines {
+		lineByProduct[line.ProductID] = line.ID
+	}
+	for i := range shipment.Items {
+		var lineID *uint
+		if id, ok := lineByProduct[shipment.Items[i].ProductID]; ok {
+			lineID = &id
+		}
+		shipment.Items[i].PurchaseOrderLineID = lineID
+		if err := tx.Model(&models.ShipmentItem{}).Where("id = ?", shipment.Items[i].ID).
+			Update("purchase_order_line_id", lineID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat kalemi eşleştirilemedi")
+		}
+	}
+	shipment.PurchaseOrderID = &po.ID
+	if err := tx.Model(&models.Shipment{}).Where("id = ?", shipment.ID).
+		Update("purchase_order_id", po.ID).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat eşleştirilemedi")
+	}
+
+	if err := refreshPurchaseOrderStatus(tx, &po); err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+// refreshPurchaseOrderStatus: Gelen miktarlara göre siparişin durumunu günceller
+func refreshPurchaseOrderStatus(tx *gorm.DB, po *models.PurchaseOrder) error {
+	byLine, _, shipmentIDs, err := purchaseOrderReceipts(tx, po.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Sipariş teslimatları okunamadı")
+	}
+
+	status := models.PurchaseOrderSent
+	if len(shipmentIDs) > 0 {
+		status = models.PurchaseOrderReceived
+		for _, line := range po.Lines {
+			if byLine[line.ID].Quantity < line.Quantity {
+				status = models.PurchaseOrderPartiallyReceived
+				break
+			}
+		}
+	}
+	if status == po.Status {
+		return nil
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if status == models.PurchaseOrderReceived {
+		now := time.Now()
+		po.ReceivedAt = &now
+		updates["received_at"] = now
+	} else {
+		po.ReceivedAt = nil
+		updates["received_at"] = nil
+	}
+	po.Status = status
+	if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", po.ID).Updates(updates).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Sipariş durumu güncellenemedi")
+	}
+	return nil
+}
+
+// MatchShipmentRequest: Sevkiyatı elle siparişle eşleştirme
+type MatchShipmentRequest struct {
+	PurchaseOrderID uint `json:"purchase_order_id"`
+}
+
+// POST /api/shipments/:id/match
+// Otomatik eşleşme yanlış / eksikse sevkiyatı belirtilen siparişle eşleştirir
+func MatchShipmentHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body MatchShipmentRequest
+		if err := c.BodyParser(&body); err != nil || body.PurchaseOrderID == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "purchase_order_id zorunlu")
+		}
+
+		var shipment models.Shipment
+		if err := tenancy.DB(c).Preload("Items").First(&shipment, "id = ?", c.Params("id")).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Sevkiyat bulunamadı")
+		}
+
+		before := shipment
+		var po *models.PurchaseOrder
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			previous := shipment.PurchaseOrderID
+			var err error
+			po, err = matchShipmentToPurchaseOrder(tx, &shipment, &body.PurchaseOrderID)
+			if err != nil {
+				return err
+			}
+			// Önceki siparişin durumu, bu sevkiyat çıktığı için yeniden hesaplanır
+			if previous != nil && *previous != po.ID {
+				var prev models.PurchaseOrder
+				if err := tx.Preload("Lines").First(&prev, "id = ?", *previous).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return nil
+					}
+					return fiber.NewError(fiber.StatusInternalServerError, "Önceki sipariş okunamadı")
+				}
+				return refreshPurchaseOrderStatus(tx, &prev)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &shipment.BranchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "shipment",
+				EntityID:    shipment.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Sevkiyat siparişle eşleştirildi: #%d (%s)", po.ID, po.Supplier),
+				Before:      before,
+				After:       shipment,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		if err := database.DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).Preload("Lines.Product").First(po, "id = ?", po.ID).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		resp, err := buildPurchaseOrderResponse(database.DB, *po)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Sipariş yüklenemedi")
+		}
+		return c.JSON(resp)
+	}
+}
+
+type SupplierDiscrepancies struct {
+	Supplier        string                     `json:"supplier"`
+	ShortCount      int                        `json:"short_count"`
+	OverCount       int                        `json:"over_count"`
+	PriceCount      int                        `json:"price_count"`
+	PriceDiffAmount float64                    `json:"price_diff_amount"` // toplam fiyat farkı tutarı (+: pahalı geldi)
+	Lines           []PurchaseOrderDiscrepancy `json:"lines"`
+}
+
+type PurchaseOrderDiscrepancy struct {
+	PurchaseOrderID uint                       `json:"purchase_order_id"`
+	OrderDate       string                     `json:"order_date"`
+	Status          models.PurchaseOrderStatus `json:"status"`
+	PurchaseOrderLineResponse
+}
+
+// GET /api/purchase-orders/discrepancies?supplier=...&from=...&to=...
+// Teslimatı başlamış siparişlerde eksik / fazla teslimat ve fiyat farkları, tedarikçi bazında
+func PurchaseOrderDiscrepanciesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		query := database.DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).Preload("Lines.Product").
+			Where("branch_id = ? AND status IN ?", branchID,
+				[]models.PurchaseOrderStatus{models.PurchaseOrderPartiallyReceived, models.PurchaseOrderReceived})
+		if supplier := c.Query("supplier"); supplier != "" {
+			query = query.Where("supplier = ?", supplier)
+		}
+		if from := c.Query("from"); from != "" {
+			d, err := time.Parse("2006-01-02", from)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+			query = query.Where("order_date >= ?", d)
+		}
+		if to := c.Query("to"); to != "" {
+			d, err := time.Parse("2006-01-02", to)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+			query = query.Where("order_date < ?", d.AddDate(0, 0, 1))
+		}
+
+		var orders []models.PurchaseOrder
+		if err := query.Order("supplier, order_date, id").Find(&orders).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Siparişler listelenemedi")
+		}
+
+		resp := []SupplierDiscrepancies{}
+		index := make(map[string]int)
+		for _, po := range orders {
+			r, err := buildPurchaseOrderResponse(database.DB, po)
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Uyuşmazlıklar hesaplanamadı")
+			}
+
+			for _, line := range append(r.Lines, r.Unordered...) {
+				if line.Discrepancy == "" {
+					continue
+				}
+				i, ok := index[po.Supplier]
+				if !ok {
+					i = len(resp)
+					index[po.Supplier] = i
+					resp = append(resp, SupplierDiscrepancies{Supplier: po.Supplier})
+				}
+				s := &resp[i]
+				switch {
+				case line.QuantityDiff < 0:
+					s.ShortCount++
+				case line.QuantityDiff > 0:
+					s.OverCount++
+				}
+				if line.PriceDiff != 0 {
+					s.PriceCount++
+					s.PriceDiffAmount = round2(s.PriceDiffAmount + line.PriceDiffAmount)
+				}
+				s.Lines = append(s.Lines, PurchaseOrderDiscrepancy{
+					PurchaseOrderID:           po.ID,
+					OrderDate:                 r.OrderDate,
+					Status:                    po.Status,
+					PurchaseOrderLineResponse: line,
+				})
+			}
+		}
+
+		return c.JSON(resp)
+	}
+}
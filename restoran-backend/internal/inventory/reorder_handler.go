@@ -0,0 +1,492 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SaveParLevelsRequest struct {
+	Items    []ParLevelRequest `json:"items"`
+	BranchID *uint             `json:"branch_id"` // super_admin için
+}
+
+type ParLevelRequest struct {
+	ProductID     uint    `json:"product_id"`
+	MinQuantity   float64 `json:"min_quantity"`   // bu seviyenin altına düşünce sipariş önerilir
+	ParQuantity   float64 `json:"par_quantity"`   // hedef seviye
+	OrderMultiple float64 `json:"order_multiple"` // opsiyonel sipariş katı (koli içi adet vb.)
+}
+
+type ParLevelResponse struct {
+	ID            uint    `json:"id"`
+	BranchID      uint    `json:"branch_id"`
+	ProductID     uint    `json:"product_id"`
+	ProductName   string  `json:"product_name"`
+	Unit          string  `json:"unit"`
+	MinQuantity   float64 `json:"min_quantity"`
+	ParQuantity   float64 `json:"par_quantity"`
+	OrderMultiple float64 `json:"order_multiple"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+func toParLevelResponse(p models.ParLevel) ParLevelResponse {
+	return ParLevelResponse{
+		ID:            p.ID,
+		BranchID:      p.BranchID,
+		ProductID:     p.ProductID,
+		ProductName:   p.Product.Name,
+		Unit:          p.Product.Unit,
+		MinQuantity:   p.MinQuantity,
+		ParQuantity:   p.ParQuantity,
+		OrderMultiple: p.OrderMultiple,
+		UpdatedAt:     p.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// GET /api/par-levels
+func ListParLevelsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		var levels []models.ParLevel
+		if err := database.DB.Preload("Product").
+			Where("branch_id = ?", branchID).
+			Find(&levels).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok seviyeleri listelenemedi")
+		}
+		sort.Slice(levels, func(i, j int) bool {
+			return levels[i].Product.Name < levels[j].Product.Name
+		})
+
+		resp := make([]ParLevelResponse, 0, len(levels))
+		for _, l := range levels {
+			resp = append(resp, toParLevelResponse(l))
+		}
+		return c.JSON(resp)
+	}
+}
+
+// PUT /api/par-levels
+// Ürünlerin minimum / hedef seviyelerini kaydeder (varsa günceller)
+func SaveParLevelsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body SaveParLevelsRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+		if len(body.Items) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "En az bir ürün eklenmelidir")
+		}
+
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
+		if err != nil {
+			return err
+		}
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool, len(body.Items))
+		for _, item := range body.Items {
+			if item.ProductID == 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "product_id zorunlu")
+			}
+			if seen[item.ProductID] {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün %d birden fazla kez gönderildi", item.ProductID))
+			}
+			seen[item.ProductID] = true
+			if item.MinQuantity < 0 || item.ParQuantity <= 0 || item.OrderMultiple < 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "par_quantity 0'dan büyük, min_quantity ve order_multiple negatif olmamalı")
+			}
+			if item.MinQuantity > item.ParQuantity {
+				return fiber.NewError(fiber.StatusBadRequest, "min_quantity, par_quantity'den büyük olamaz")
+			}
+		}
+
+		type change struct {
+			before *models.ParLevel
+			after  models.ParLevel
+		}
+		var changes []change
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			for _, item := range body.Items {
+				var product models.Product
+				if err := tx.First(&product, "id = ?", item.ProductID).Error; err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı: %d", item.ProductID))
+				}
+
+				var level models.ParLevel
+				var before *models.ParLevel
+				err := tx.Where("branch_id = ? AND product_id = ?", branchID, item.ProductID).First(&level).Error
+				switch {
+				case err == nil:
+					prev := level
+					before = &prev
+				case !errors.Is(err, gorm.ErrRecordNotFound):
+					return fiber.NewError(fiber.StatusInternalServerError, "Stok seviyesi okunamadı")
+				}
+
+				level.BranchID = branchID
+				level.ProductID = item.ProductID
+				level.MinQuantity = item.MinQuantity
+				level.ParQuantity = item.ParQuantity
+				level.OrderMultiple = item.OrderMultiple
+				level.UpdatedBy = userID
+				if err := tx.Save(&level).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Stok seviyeleri kaydedilemedi")
+				}
+				level.Product = product
+				changes = append(changes, change{before: before, after: level})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		resp := make([]ParLevelResponse, 0, len(changes))
+		for _, ch := range changes {
+			action := models.AuditActionCreate
+			var before any
+			if ch.before != nil {
+				action = models.AuditActionUpdate
+				before = *ch.before
+			}
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &branchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "par_level",
+				EntityID:    ch.after.ID,
+				Action:      action,
+				Description: fmt.Sprintf("Stok seviyesi: %s min %.2f / hedef %.2f %s", ch.after.Product.Name, ch.after.MinQuantity, ch.after.ParQuantity, ch.after.Product.Unit),
+				Before:      before,
+				After:       ch.after,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+			resp = append(resp, toParLevelResponse(ch.after))
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// DELETE /api/par-levels/:id
+func DeleteParLevelHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var level models.ParLevel
+		if err := tenancy.DB(c).Preload("Product").First(&level, "id = ?", c.Params("id")).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Stok seviyesi bulunamadı")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		if err := database.DB.Delete(&level).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok seviyesi silinemedi")
+		}
+
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    &level.BranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "par_level",
+			EntityID:    level.ID,
+			Action:      models.AuditActionDelete,
+			Description: fmt.Sprintf("Stok seviyesi silindi: %s", level.Product.Name),
+			Before:      level,
+			After:       nil,
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+type ReorderSuggestion struct {
+	ProductID      uint     `json:"product_id"`
+	ProductName    string   `json:"product_name"`
+	StockCode      string   `json:"stock_code"`
+	Unit           string   `json:"unit"`
+	CurrentStock   float64  `json:"current_stock"`
+	AvgDailyUsage  *float64 `json:"avg_daily_usage"` // son sayımlardan; yeterli sayım yoksa null
+	DaysOfCover    *float64 `json:"days_of_cover"`   // mevcut stok kaç gün yeter
+	MinQuantity    *float64 `json:"min_quantity"`
+	ParQuantity    *float64 `json:"par_quantity"`
+	TargetQuantity float64  `json:"target_quantity"`
+	SuggestedQty   float64  `json:"suggested_quantity"` // birime / sipariş katına yuvarlanmış
+	Reason         string   `json:"reason"`             // "below_min" veya "low_cover"
+}
+
+type ReorderSuggestionsResponse struct {
+	BranchID     uint                  `json:"branch_id"`
+	Date         string                `json:"date"`
+	LookbackDays int                   `json:"lookback_days"`
+	CoverDays    int                   `json:"cover_days"`
+	Items        []ReorderSuggestion   `json:"items"`
+	Shipment     CreateShipmentRequest `json:"shipment"` // POST /api/shipments gövdesi olarak kullanılabilir
+}
+
+const (
+	reorderReasonBelowMin = "below_min"
+	reorderReasonLowCover = "low_cover"
+)
+
+// GET /api/reorder-suggestions?lookback_days=28&cover_days=7
+// Sipariş önerisi:
+//   - Stok seviyesi tanımlı ürünler: mevcut stok min_quantity'ye düştüyse par_quantity'ye tamamlanır
+//   - Tanımsız ürünler: son sayımlardan hesaplanan günlük kullanımla stok cover_days'ten az
+//     yetiyorsa cover_days'lik kullanım kadar sipariş önerilir
+func ReorderSuggestionsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		lookbackDays, err := queryPositiveInt(c, "lookback_days", 28)
+		if err != nil {
+			return err
+		}
+		coverDays, err := queryPositiveInt(c, "cover_days", 7)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+		var products []models.Product
+		if err := database.DB.Where("is_center_product = ? AND is_active = ?", true, true).Find(&products).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
+		}
+
+		var levels []models.ParLevel
+		if err := database.DB.Where("branch_id = ?", branchID).Find(&levels).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok seviyeleri okunamadı")
+		}
+		levelMap := make(map[uint]models.ParLevel, len(levels))
+		for _, l := range levels {
+			levelMap[l.ProductID] = l
+		}
+
+		stock, err := ledger.Balances(database.DB, branchID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok hesaplanamadı")
+		}
+
+		usage, err := averageDailyUsage(database.DB, branchID, today.AddDate(0, 0, -lookbackDays))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok kullanımı hesaplanamadı")
+		}
+
+		prices, err := lastShipmentItemPrices(database.DB, branchID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
+
+		resp := ReorderSuggestionsResponse{
+			BranchID:     branchID,
+			Date:         today.Format("2006-01-02"),
+			LookbackDays: lookbackDays,
+			CoverDays:    coverDays,
+			Items:        []ReorderSuggestion{},
+			Shipment: CreateShipmentRequest{
+				Date:     today.Format("2006-01-02"),
+				Items:    []ShipmentItemRequest{},
+				Note:     "Sipariş önerisi",
+				BranchID: &branchID,
+			},
+		}
+
+		for _, p := range products {
+			current := stock[p.ID]
+			s := ReorderSuggestion{
+				ProductID:    p.ID,
+				ProductName:  p.Name,
+				StockCode:    p.StockCode,
+				Unit:         p.Unit,
+				CurrentStock: current,
+			}
+
+			avg, hasUsage := usage[p.ID]
+			if hasUsage {
+				s.AvgDailyUsage = &avg
+				if avg > 0 {
+					cover := math.Max(current, 0) / avg
+					s.DaysOfCover = &cover
+				}
+			}
+
+			var multiple float64
+			if level, ok := levelMap[p.ID]; ok {
+				s.MinQuantity = &level.MinQuantity
+				s.ParQuantity = &level.ParQuantity
+				multiple = level.OrderMultiple
+				if current > level.MinQuantity {
+					continue
+				}
+				s.TargetQuantity = level.ParQuantity
+				s.Reason = reorderReasonBelowMin
+			} else {
+				if s.DaysOfCover == nil || *s.DaysOfCover >= float64(coverDays) {
+					continue
+				}
+				s.TargetQuantity = avg * float64(coverDays)
+				s.Reason = reorderReasonLowCover
+			}
+
+			s.SuggestedQty = roundOrderQuantity(s.TargetQuantity-math.Max(current, 0), p.Unit, multiple)
+			if s.SuggestedQty <= 0 {
+				continue
+			}
+			resp.Items = append(resp.Items, s)
+
+			item := ShipmentItemRequest{
+				ProductID:   p.ID,
+				Quantity:    s.SuggestedQty,
+				ProductName: p.Name,
+				StockCode:   p.StockCode,
+				Unit:        p.Unit,
+			}
+			if price, ok := prices[p.ID]; ok {
+				item.UnitPrice = price.UnitPrice
+				item.UnitPriceWithVAT = price.UnitPriceWithVAT
+				item.VATRate = price.VATRate
+				item.TotalPrice = round2(price.UnitPriceWithVAT * s.SuggestedQty)
+			}
+			resp.Shipment.Items = append(resp.Shipment.Items, item)
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// averageDailyUsage: since tarihinden sonraki ilk ve son sayım arasındaki günlük ortalama çıkış
+// (ilk sayım + aradaki girişler - son sayım) / gün. En az iki farklı günde sayım gerekir.
+// Sayımlar ve girişler defterdeki gibi tarihe göre sıralanır; aynı gündekilerde yazılma sırası (ID)
+// esas alınır, böylece geriye tarihli kayıtlar doğru aralığa düşer.
+func averageDailyUsage(db *gorm.DB, branchID uint, since time.Time) (map[uint]float64, error) {
+	type usageScan struct {
+		ProductID  uint
+		FirstCount float64
+		FirstDate  time.Time
+		LastCount  float64
+		LastDate   time.Time
+		Incoming   float64
+	}
+
+	var scanned []usageScan
+	if err := db.Raw(`
+		WITH counts AS (
+			SELECT id, product_id, date, counted_quantity,
+				ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY date ASC, id ASC) AS rn_first,
+				ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY date DESC, id DESC) AS rn_last
+			FROM stock_movements
+			WHERE branch_id = ? AND type = ? AND date >= ?
+		)
+		SELECT f.product_id,
+			f.counted_quantity AS first_count, f.date AS first_date,
+			l.counted_quantity AS last_count, l.date AS last_date,
+			COALESCE(SUM(CASE WHEN m.type IN ? THEN m.quantity ELSE 0 END), 0) AS incoming
+		FROM counts f
+		JOIN counts l ON l.product_id = f.product_id AND l.rn_last = 1
+		LEFT JOIN stock_movements m ON m.branch_id = ? AND m.product_id = f.product_id
+			AND (m.date > f.date OR (m.date = f.date AND m.id > f.id))
+			AND (m.date < l.date OR (m.date = l.date AND m.id < l.id))
+		WHERE f.rn_first = 1 AND l.date > f.date
+		GROUP BY f.product_id, f.counted_quantity, f.date, l.counted_quantity, l.date`,
+		branchID, models.MovementCountAdjustment, since,
+		[]models.StockMovementType{models.MovementReceipt, models.MovementTransferIn},
+		branchID,
+	).Scan(&scanned).Error; err != nil {
+		return nil, err
+	}
+
+	usage := make(map[uint]float64, len(scanned))
+	for _, r := range scanned {
+		days := r.LastDate.Sub(r.FirstDate).Hours() / 24
+		consumed := r.FirstCount + r.Incoming - r.LastCount
+		if consumed < 0 {
+			consumed = 0
+		}
+		usage[r.ProductID] = consumed / days
+	}
+	return usage, nil
+}
+
+type shipmentItemPrice struct {
+	ProductID        uint
+	UnitPrice        float64
+	UnitPriceWithVAT float64
+	VATRate          float64
+}
+
+// lastShipmentItemPrices: Şubenin ürün bazında son sevkiyat kalemi fiyatları
+func lastShipmentItemPrices(db *gorm.DB, branchID uint) (map[uint]shipmentItemPrice, error) {
+	var rows []shipmentItemPrice
+	if err := db.Raw(`
+		SELECT DISTINCT ON (si.product_id) si.product_id, si.unit_price, si.unit_price_with_vat, si.vat_rate
+		FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.branch_id = ? AND si.unit_price > 0
+		ORDER BY si.product_id, s.date DESC, si.id DESC`, branchID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	prices := make(map[uint]shipmentItemPrice, len(rows))
+	for _, r := range rows {
+		prices[r.ProductID] = r
+	}
+	return prices, nil
+}
+
+// roundOrderQuantity: Sipariş miktarını yukarı yuvarlar. Sipariş katı varsa katına,
+// yoksa tartılan birimlerde (kg, lt) 0.1'e, diğer birimlerde (adet, paket, koli) tam sayıya.
+func roundOrderQuantity(qty float64, unit string, multiple float64) float64 {
+	if qty <= 0 {
+		return 0
+	}
+	// Kayan nokta hatası yüzünden bir üst kata atlamamak için küçük pay
+	const eps = 1e-9
+	if multiple > 0 {
+		return math.Ceil(qty/multiple-eps) * multiple
+	}
+	switch normalizeTurkish(unit) {
+	case "kg", "kilogram", "lt", "litre", "l":
+		return math.Ceil(qty*10-eps) / 10
+	}
+	return math.Ceil(qty - eps)
+}
+
+func queryPositiveInt(c *fiber.Ctx, key string, def int) (int, error) {
+	s := c.Query(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, key+" pozitif bir sayı olmalı")
+	}
+	return n, nil
+}
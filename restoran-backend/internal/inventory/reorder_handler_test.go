@@ -0,0 +1,40 @@
+package inventory
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+)
+
+// Geriye tarihli sayım ve giriş (sonradan yazılan, ID'si büyük) tarihine göre sıralanır:
+// ilk sayım 1. gün 30, 5. gün 10 giriş, son sayım 10. gün 20 -> 9 günde 20 çıkış
+func TestAverageDailyUsageOrdersByDate(t *testing.T) {
+	db := database.DB
+	const branchID, productID = 8, 80
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC) }
+	counted := func(q float64) *float64 { return &q }
+
+	movements := []models.StockMovement{
+		{BranchID: branchID, ProductID: productID, Type: models.MovementCountAdjustment, Quantity: 20, CountedQuantity: counted(20), Date: day(10)},
+		{BranchID: branchID, ProductID: productID, Type: models.MovementCountAdjustment, Quantity: 30, CountedQuantity: counted(30), Date: day(1)},
+		{BranchID: branchID, ProductID: productID, Type: models.MovementReceipt, Quantity: 10, Date: day(5)},
+		// Son sayımdan sonraki giriş sayılmaz
+		{BranchID: branchID, ProductID: productID, Type: models.MovementReceipt, Quantity: 50, Date: day(12)},
+	}
+	for i := range movements {
+		if err := db.Create(&movements[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := averageDailyUsage(db, branchID, day(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := usage[productID], 20.0/9; math.Abs(got-want) > 1e-9 {
+		t.Errorf("günlük kullanım = %v, beklenen %v", got, want)
+	}
+}
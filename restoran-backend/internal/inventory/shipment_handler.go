@@ -2,13 +2,20 @@ package inventory
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"restoran-backend/internal/audit"
+	"restoran-backend/internal/config"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // CreateShipmentRequest: Yeni sevkiyat oluşturma
@@ -17,6 +24,8 @@ type CreateShipmentRequest struct {
 	Items    []ShipmentItemRequest `json:"items"` // ürün listesi
 	Note     string                `json:"note"`
 	BranchID *uint                 `json:"branch_id"` // super_admin için
+	// Eşleştirilecek sipariş; verilmezse şubenin açık siparişleri arasından ürünlere göre bulunur
+	PurchaseOrderID *uint `json:"purchase_order_id,omitempty"`
 }
 
 type ShipmentItemRequest struct {
@@ -25,10 +34,16 @@ type ShipmentItemRequest struct {
 	UnitPrice        float64 `json:"unit_price"`         // KDV'siz birim fiyat
 	UnitPriceWithVAT float64 `json:"unit_price_with_vat"` // KDV'li birim fiyat
 	TotalPrice       float64 `json:"total_price"`        // KDV'li toplam tutar (UnitPriceWithVAT * Quantity)
+	VATRate          float64 `json:"vat_rate"`           // KDV oranı (%), opsiyonel
 	// Otomatik ürün oluşturma için (product_id = 0 olduğunda)
 	ProductName string `json:"product_name"` // Ürün adı
 	StockCode   string `json:"stock_code"`   // Stok kodu
-	Unit        string `json:"unit"`         // Birim (Paket, Koli, Adet, Kilogram)
+	// Birim (Paket, Koli, Adet, Kilogram). Mevcut ürünlerde miktar ve fiyat bu birimden temel birime çevrilir
+	Unit string `json:"unit"`
+	// Parti (lot) takibi - opsiyonel
+	LotNumber    string `json:"lot_number,omitempty"`
+	ReceivedDate string `json:"received_date,omitempty"` // "2025-12-09", boşsa sevkiyat tarihi
+	ExpiryDate   string `json:"expiry_date,omitempty"`   // "2025-12-20"
 }
 
 // ShipmentResponse: Sevkiyat yanıtı
@@ -41,6 +56,8 @@ type ShipmentResponse struct {
 	Note        string                 `json:"note"`
 	Items       []ShipmentItemResponse `json:"items"`
 	CreatedAt   string                 `json:"created_at"`
+	PriceAlerts int                    `json:"price_alerts,omitempty"` // eşiği aşan fiyat değişimi sayısı
+	PurchaseOrderID *uint              `json:"purchase_order_id,omitempty"` // eşleşen sipariş
 }
 
 type ShipmentItemResponse struct {
@@ -48,14 +65,24 @@ type ShipmentItemResponse struct {
 	ProductID        uint    `json:"product_id"`
 	ProductName      string  `json:"product_name"`
 	StockCode        string  `json:"stock_code"`         // Ürün stok kodu
-	Quantity         float64 `json:"quantity"`
+	Quantity         float64 `json:"quantity"`           // temel birimde
+	BaseUnit         string  `json:"base_unit"`
+	Unit             string  `json:"unit"`               // girilen birim
+	UnitQuantity     float64 `json:"unit_quantity"`      // girilen birimdeki miktar
 	UnitPrice        float64 `json:"unit_price"`         // KDV'siz birim fiyat
 	UnitPriceWithVAT float64 `json:"unit_price_with_vat"` // KDV'li birim fiyat
 	TotalPrice       float64 `json:"total_price"`        // KDV'li toplam tutar
+	VATRate          float64 `json:"vat_rate"`           // KDV oranı (%)
+	PriceChange      *PriceChange `json:"price_change,omitempty"` // önceki alış fiyatına göre değişim (sadece oluştururken)
+	LotNumber        string       `json:"lot_number,omitempty"`
+	ReceivedDate     string       `json:"received_date,omitempty"`
+	ExpiryDate       string       `json:"expiry_date,omitempty"`
 }
 
 // POST /api/shipments
-func CreateShipmentHandler() fiber.Handler {
+// Birim fiyatı önceki alış fiyatından eşik yüzdesinden fazla sapan kalemler için fiyat uyarısı oluşturulur.
+// Sevkiyat açık bir satın alma siparişiyle eşleştirilir (purchase_order_id verilmezse otomatik)
+func CreateShipmentHandler(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var body CreateShipmentRequest
 		if err := c.BodyParser(&body); err != nil {
@@ -66,7 +93,7 @@ func CreateShipmentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "En az bir ürün eklenmelidir")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -76,114 +103,33 @@ func CreateShipmentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
-		// Toplam tutarı hesapla ve ürünleri kontrol et
-		var totalAmount float64
-		var shipmentItems []models.ShipmentItem
-
-		for _, itemReq := range body.Items {
-			if itemReq.Quantity <= 0 || itemReq.UnitPrice <= 0 {
-				return fiber.NewError(fiber.StatusBadRequest, "Tüm ürünler için quantity ve unit_price zorunlu ve 0'dan büyük olmalı")
-			}
-
-			var product models.Product
-			
-			// Eğer product_id = 0 ise, ürünü otomatik oluştur
-			if itemReq.ProductID == 0 {
-				if itemReq.ProductName == "" || itemReq.Unit == "" {
-					return fiber.NewError(fiber.StatusBadRequest, "Yeni ürün için product_name ve unit zorunlu")
-				}
-				
-				// Stok kodu varsa, aynı stok kodlu ürün var mı kontrol et
-				if itemReq.StockCode != "" {
-					var existingProduct models.Product
-					if err := database.DB.Where("stock_code = ?", itemReq.StockCode).First(&existingProduct).Error; err == nil {
-						// Stok kodu ile eşleşen ürün bulundu, onu kullan
-						product = existingProduct
-					} else {
-						// Yeni ürün oluştur
-						product = models.Product{
-							Name:            itemReq.ProductName,
-							Unit:            itemReq.Unit,
-							StockCode:       itemReq.StockCode,
-							IsCenterProduct: true,
-						}
-						if err := database.DB.Create(&product).Error; err != nil {
-							return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Ürün oluşturulamadı: %v", err))
-						}
-					}
-				} else {
-					// Stok kodu yoksa, sadece isim ve birimle oluştur
-					product = models.Product{
-						Name:            itemReq.ProductName,
-						Unit:            itemReq.Unit,
-						StockCode:       "",
-						IsCenterProduct: true,
-					}
-					if err := database.DB.Create(&product).Error; err != nil {
-						return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Ürün oluşturulamadı: %v", err))
-					}
-				}
-			} else {
-				// Mevcut ürünü kullan (sadece IsCenterProduct = true olanlar)
-				if err := database.DB.Where("id = ? AND is_center_product = ?", itemReq.ProductID, true).First(&product).Error; err != nil {
-					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı veya manav ürünü: %d", itemReq.ProductID))
-				}
-			}
-
-			// Fiyat bilgilerini belirle
-			var unitPrice, unitPriceWithVAT, totalPrice float64
-			
-			if itemReq.TotalPrice > 0 && itemReq.UnitPriceWithVAT > 0 {
-				// B2B'den gelen veriler: KDV'li toplam ve birim fiyatlar kullan
-				unitPrice = itemReq.UnitPrice        // KDV'siz birim fiyat
-				unitPriceWithVAT = itemReq.UnitPriceWithVAT // KDV'li birim fiyat
-				totalPrice = itemReq.TotalPrice       // KDV'li toplam tutar
-			} else {
-				// Manuel girilen ürünler için: Sadece KDV'siz birim fiyat var, KDV'li fiyatları hesapla
-				unitPrice = itemReq.UnitPrice
-				// KDV'siz birim fiyat = KDV'li birim fiyat (varsayılan olarak KDV yok sayılıyor)
-				unitPriceWithVAT = itemReq.UnitPrice
-				totalPrice = itemReq.Quantity * unitPriceWithVAT
-			}
-			
-			totalAmount += totalPrice
-
-			// Yeni oluşturulan ürün için product.ID kullan, aksi halde itemReq.ProductID kullan
-			productID := itemReq.ProductID
-			if itemReq.ProductID == 0 {
-				productID = product.ID // Yeni oluşturulan ürünün ID'sini kullan
-			}
-
-			shipmentItems = append(shipmentItems, models.ShipmentItem{
-				ProductID:        productID,
-				Quantity:         itemReq.Quantity,
-				UnitPrice:        unitPrice,        // KDV'siz birim fiyat
-				UnitPriceWithVAT: unitPriceWithVAT, // KDV'li birim fiyat
-				TotalPrice:       totalPrice,       // KDV'li toplam tutar
-			})
-		}
-
-		// Sevkiyat oluştur
-		shipment := models.Shipment{
-			BranchID:    branchID,
-			Date:        d,
-			TotalAmount: totalAmount,
-			IsStocked:   false,
-			Note:        body.Note,
-			Items:       shipmentItems,
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
 		}
 
-		if err := database.DB.Create(&shipment).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat oluşturulamadı")
+		var shipment models.Shipment
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			shipment, err = createShipment(tx, branchID, d, body.Note, body.Items)
+			if err != nil {
+				return err
+			}
+			_, err = matchShipmentToPurchaseOrder(tx, &shipment, body.PurchaseOrderID)
+			return err
+		})
+		if err != nil {
+			return err
 		}
 
-		// Items'ları tekrar yükle (ID'ler için)
-		if err := database.DB.Preload("Product").Where("shipment_id = ?", shipment.ID).Find(&shipment.Items).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat ürünleri yüklenemedi")
+		// Fiyat değişimi kontrolü (sevkiyat kaydedildi, hata olursa sadece log'la)
+		priceChanges, err := checkShipmentPrices(database.DB, shipment, cfg.PriceAlertThresholdPct)
+		if err != nil {
+			fmt.Printf("Fiyat kontrolü yapılamadı: %v\n", err)
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &branchID, // Sevkiyatın branch_id'sini kullan
@@ -199,17 +145,30 @@ func CreateShipmentHandler() fiber.Handler {
 		}
 
 		// Response oluştur
+		priceAlerts := 0
 		itemsResp := make([]ShipmentItemResponse, 0, len(shipment.Items))
 		for _, item := range shipment.Items {
+			change := priceChanges[item.ID]
+			if change != nil && change.Alert {
+				priceAlerts++
+			}
 			itemsResp = append(itemsResp, ShipmentItemResponse{
 				ID:               item.ID,
 				ProductID:        item.ProductID,
 				ProductName:      item.Product.Name,
 				StockCode:        item.Product.StockCode,
 				Quantity:         item.Quantity,
+				BaseUnit:         item.Product.Unit,
+				Unit:             units.EnteredUnit(item.Product.Unit, item.Unit),
+				UnitQuantity:     enteredQuantity(item.UnitQuantity, item.Quantity),
 				UnitPrice:        item.UnitPrice,        // KDV'siz birim fiyat
 				UnitPriceWithVAT: item.UnitPriceWithVAT, // KDV'li birim fiyat
 				TotalPrice:       item.TotalPrice,       // KDV'li toplam tutar
+				VATRate:          item.VATRate,
+				PriceChange:      change,
+				LotNumber:        item.LotNumber,
+				ReceivedDate:     formatDatePtr(item.ReceivedDate),
+				ExpiryDate:       formatDatePtr(item.ExpiryDate),
 			})
 		}
 
@@ -222,14 +181,151 @@ func CreateShipmentHandler() fiber.Handler {
 			Note:        shipment.Note,
 			Items:       itemsResp,
 			CreatedAt:   shipment.CreatedAt.Format("2006-01-02 15:04:05"),
+			PriceAlerts: priceAlerts,
+			PurchaseOrderID: shipment.PurchaseOrderID,
+		})
+	}
+}
+
+// createShipment: Sevkiyatı ve kalemlerini oluşturur; product_id = 0 olan kalemler için ürünü otomatik oluşturur
+func createShipment(db *gorm.DB, branchID uint, d time.Time, note string, items []ShipmentItemRequest) (models.Shipment, error) {
+	// Toplam tutarı hesapla ve ürünleri kontrol et
+	var totalAmount float64
+	var shipmentItems []models.ShipmentItem
+
+	for _, itemReq := range items {
+		if itemReq.Quantity <= 0 || itemReq.UnitPrice <= 0 {
+			return models.Shipment{}, fiber.NewError(fiber.StatusBadRequest, "Tüm ürünler için quantity ve unit_price zorunlu ve 0'dan büyük olmalı")
+		}
+
+		var product models.Product
+		
+		// Eğer product_id = 0 ise, ürünü otomatik oluştur
+		if itemReq.ProductID == 0 {
+			if itemReq.ProductName == "" || itemReq.Unit == "" {
+				return models.Shipment{}, fiber.NewError(fiber.StatusBadRequest, "Yeni ürün için product_name ve unit zorunlu")
+			}
+			
+			// Stok kodu varsa, aynı stok kodlu ürün var mı kontrol et
+			if itemReq.StockCode != "" {
+				var existingProduct models.Product
+				if err := db.Where("stock_code = ?", itemReq.StockCode).First(&existingProduct).Error; err == nil {
+					// Stok kodu ile eşleşen ürün bulundu, onu kullan
+					product = existingProduct
+				} else {
+					// Yeni ürün oluştur
+					product = models.Product{
+						Name:            itemReq.ProductName,
+						Unit:            itemReq.Unit,
+						StockCode:       itemReq.StockCode,
+						IsCenterProduct: true,
+					}
+					if err := db.Create(&product).Error; err != nil {
+						return models.Shipment{}, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Ürün oluşturulamadı: %v", err))
+					}
+				}
+			} else {
+				// Stok kodu yoksa, sadece isim ve birimle oluştur
+				product = models.Product{
+					Name:            itemReq.ProductName,
+					Unit:            itemReq.Unit,
+					StockCode:       "",
+					IsCenterProduct: true,
+				}
+				if err := db.Create(&product).Error; err != nil {
+					return models.Shipment{}, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Ürün oluşturulamadı: %v", err))
+				}
+			}
+		} else {
+			// Mevcut ürünü kullan (sadece IsCenterProduct = true olanlar)
+			if err := db.Where("id = ? AND is_center_product = ?", itemReq.ProductID, true).First(&product).Error; err != nil {
+				return models.Shipment{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı veya manav ürünü: %d", itemReq.ProductID))
+			}
+		}
+
+		// Fiyat bilgilerini belirle
+		var unitPrice, unitPriceWithVAT, totalPrice float64
+		
+		if itemReq.TotalPrice > 0 && itemReq.UnitPriceWithVAT > 0 {
+			// B2B'den gelen veriler: KDV'li toplam ve birim fiyatlar kullan
+			unitPrice = itemReq.UnitPrice        // KDV'siz birim fiyat
+			unitPriceWithVAT = itemReq.UnitPriceWithVAT // KDV'li birim fiyat
+			totalPrice = itemReq.TotalPrice       // KDV'li toplam tutar
+		} else if itemReq.VATRate > 0 {
+			// KDV oranı girilmiş: KDV'li fiyatları orandan hesapla
+			unitPrice = itemReq.UnitPrice
+			unitPriceWithVAT = itemReq.UnitPrice * (1 + itemReq.VATRate/100)
+			totalPrice = itemReq.Quantity * unitPriceWithVAT
+		} else {
+			// Manuel girilen ürünler için: Sadece KDV'siz birim fiyat var, KDV'li fiyatları hesapla
+			unitPrice = itemReq.UnitPrice
+			// KDV'siz birim fiyat = KDV'li birim fiyat (varsayılan olarak KDV yok sayılıyor)
+			unitPriceWithVAT = itemReq.UnitPrice
+			totalPrice = itemReq.Quantity * unitPriceWithVAT
+		}
+		
+		totalAmount += totalPrice
+
+		// Yeni oluşturulan ürün için product.ID kullan, aksi halde itemReq.ProductID kullan
+		productID := itemReq.ProductID
+		if itemReq.ProductID == 0 {
+			productID = product.ID // Yeni oluşturulan ürünün ID'sini kullan
+		}
+
+		// Miktar ve birim fiyatlar ürünün temel birimine çevrilir (toplam tutar değişmez).
+		// Dönüşümü tanımlı olmayan birim (B2B/e-Fatura'dan gelen Paket, Koli...) eskisi gibi
+		// olduğu gibi kaydedilir, girilen birim gösterim için saklanır.
+		quantity, factor, err := units.ToBaseIfKnown(db, models.UnitKindProduct, product.ID, product.Unit, itemReq.Unit, itemReq.Quantity)
+		if err != nil {
+			return models.Shipment{}, err
+		}
+
+		lotNumber, receivedDate, expiryDate, err := parseLot(itemReq.LotNumber, itemReq.ReceivedDate, itemReq.ExpiryDate, d)
+		if err != nil {
+			return models.Shipment{}, err
+		}
+
+		shipmentItems = append(shipmentItems, models.ShipmentItem{
+			ProductID:        productID,
+			Quantity:         quantity,
+			Unit:             units.EnteredUnit(product.Unit, itemReq.Unit),
+			UnitQuantity:     itemReq.Quantity,
+			UnitPrice:        unitPrice / factor,        // KDV'siz birim fiyat
+			UnitPriceWithVAT: unitPriceWithVAT / factor, // KDV'li birim fiyat
+			TotalPrice:       totalPrice,                // KDV'li toplam tutar
+			VATRate:          itemReq.VATRate,
+			LotNumber:        lotNumber,
+			ReceivedDate:     receivedDate,
+			ExpiryDate:       expiryDate,
 		})
 	}
+
+	// Sevkiyat oluştur
+	shipment := models.Shipment{
+		BranchID:    branchID,
+		Date:        d,
+		TotalAmount: totalAmount,
+		IsStocked:   false,
+		Note:        note,
+		Items:       shipmentItems,
+	}
+
+	if err := db.Create(&shipment).Error; err != nil {
+		return shipment, fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat oluşturulamadı")
+	}
+
+	// Items'ları tekrar yükle (ID'ler için)
+	if err := db.Preload("Product").Where("shipment_id = ?", shipment.ID).Find(&shipment.Items).Error; err != nil {
+		return shipment, fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat ürünleri yüklenemedi")
+	}
+
+	return shipment, nil
 }
 
 // GET /api/shipments
 func ListShipmentsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -253,9 +349,16 @@ func ListShipmentsHandler() fiber.Handler {
 					ProductName:      item.Product.Name,
 					StockCode:        item.Product.StockCode,
 					Quantity:         item.Quantity,
+					BaseUnit:         item.Product.Unit,
+					Unit:             units.EnteredUnit(item.Product.Unit, item.Unit),
+					UnitQuantity:     enteredQuantity(item.UnitQuantity, item.Quantity),
 					UnitPrice:        item.UnitPrice,        // KDV'siz birim fiyat
 					UnitPriceWithVAT: item.UnitPriceWithVAT, // KDV'li birim fiyat
 					TotalPrice:       item.TotalPrice,       // KDV'li toplam tutar
+					VATRate:          item.VATRate,
+					LotNumber:        item.LotNumber,
+					ReceivedDate:     formatDatePtr(item.ReceivedDate),
+					ExpiryDate:       formatDatePtr(item.ExpiryDate),
 				})
 			}
 
@@ -268,6 +371,7 @@ func ListShipmentsHandler() fiber.Handler {
 				Note:        s.Note,
 				Items:       itemsResp,
 				CreatedAt:   s.CreatedAt.Format("2006-01-02 15:04:05"),
+				PurchaseOrderID: s.PurchaseOrderID,
 			})
 		}
 
@@ -276,13 +380,13 @@ func ListShipmentsHandler() fiber.Handler {
 }
 
 // POST /api/shipments/:id/stock
-// Sevkiyatı stoka kaydet
+// Sevkiyatı stoka kaydet (henüz bir siparişle eşleşmemişse açık siparişlerle eşleştirilir)
 func StockShipmentHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 
 		var shipment models.Shipment
-		if err := database.DB.Preload("Items.Product").First(&shipment, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).Preload("Items.Product").First(&shipment, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Sevkiyat bulunamadı")
 		}
 
@@ -290,28 +394,35 @@ func StockShipmentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Bu sevkiyat zaten stoka kaydedilmiş")
 		}
 
-		// Sevkiyat stoka kaydedildiğinde her item için StockEntry oluştur
-		for _, item := range shipment.Items {
-			entry := models.StockEntry{
-				BranchID:  shipment.BranchID,
-				ProductID: item.ProductID,
-				Date:      shipment.Date,
-				Quantity:  item.Quantity,
-				Note:      fmt.Sprintf("Sevkiyat #%d", shipment.ID),
-			}
-			if err := database.DB.Create(&entry).Error; err != nil {
-				return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Stok girişi oluşturulamadı: %v", err))
-			}
+		// Kapalı döneme stok girişi yazılamaz
+		if err := period.EnsureOpen(shipment.BranchID, shipment.Date); err != nil {
+			return err
 		}
 
-		// Sevkiyatı stoka kaydedildi olarak işaretle
-		shipment.IsStocked = true
-		if err := database.DB.Save(&shipment).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat güncellenemedi")
+		before := shipment
+
+		// Sevkiyat kalemlerini stok defterine giriş hareketi olarak yaz ve sevkiyatı işaretle
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := ledger.Record(tx, ledger.ShipmentMovements(shipment)...); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Stok hareketi oluşturulamadı: %v", err))
+			}
+			if shipment.PurchaseOrderID == nil {
+				if _, err := matchShipmentToPurchaseOrder(tx, &shipment, nil); err != nil {
+					return err
+				}
+			}
+			shipment.IsStocked = true
+			if err := tx.Save(&shipment).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Sevkiyat güncellenemedi")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &shipment.BranchID,
@@ -321,14 +432,61 @@ func StockShipmentHandler() fiber.Handler {
 				EntityID:    shipment.ID,
 				Action:      models.AuditActionUpdate,
 				Description: fmt.Sprintf("Sevkiyat stoka kaydedildi"),
-				Before:      nil,
+				Before:      before,
 				After:       shipment,
 			})
 		}
 
 		return c.JSON(fiber.Map{
-			"message":     "Sevkiyat başarıyla stoka kaydedildi",
-			"shipment_id": shipment.ID,
+			"message":           "Sevkiyat başarıyla stoka kaydedildi",
+			"shipment_id":       shipment.ID,
+			"purchase_order_id": shipment.PurchaseOrderID,
 		})
 	}
 }
+
+// enteredQuantity: Girilen birimdeki miktar (birim desteğinden önceki kayıtlarda temel miktar)
+func enteredQuantity(unitQuantity, quantity float64) float64 {
+	if unitQuantity == 0 {
+		return quantity
+	}
+	return unitQuantity
+}
+
+// parseLot: Sevkiyat kaleminin parti bilgileri. Parti numarası veya son kullanma tarihi yoksa
+// parti takibi yapılmaz; varsa teslim tarihi verilmediğinde sevkiyat tarihi kullanılır.
+func parseLot(lotNumber, receivedStr, expiryStr string, shipmentDate time.Time) (string, *time.Time, *time.Time, error) {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if lotNumber == "" && expiryStr == "" {
+		return "", nil, nil, nil
+	}
+
+	received := shipmentDate
+	if receivedStr != "" {
+		d, err := time.Parse("2006-01-02", receivedStr)
+		if err != nil {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, "received_date formatı 'YYYY-MM-DD' olmalı")
+		}
+		received = d
+	}
+
+	var expiry *time.Time
+	if expiryStr != "" {
+		d, err := time.Parse("2006-01-02", expiryStr)
+		if err != nil {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, "expiry_date formatı 'YYYY-MM-DD' olmalı")
+		}
+		if d.Before(received) {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Son kullanma tarihi teslim tarihinden önce olamaz (parti: %s)", lotNumber))
+		}
+		expiry = &d
+	}
+	return lotNumber, &received, expiry, nil
+}
+
+func formatDatePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,54 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+)
+
+// Mevcut üründe dönüşümü tanımlı birim temel birime çevrilir; tanımsız birim (B2B siparişindeki
+// Paket) reddedilmez, miktar olduğu gibi temel birimde sayılır
+func TestCreateShipmentUnitConversion(t *testing.T) {
+	db := database.DB
+	product := models.Product{Name: "Peçete", Unit: "adet", IsCenterProduct: true, IsActive: true}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ProductUnit{Kind: models.UnitKindProduct, ProductID: product.ID, Unit: "koli", Label: "Koli", Factor: 12}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		unit      string
+		wantQty   float64
+		wantPrice float64
+	}{
+		{"Paket", 3, 10},
+		{"Koli", 36, 10.0 / 12},
+		{"", 3, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			shipment, err := createShipment(db, 1, time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC), "", []ShipmentItemRequest{
+				{ProductID: product.ID, Quantity: 3, UnitPrice: 10, Unit: tt.unit},
+			})
+			if err != nil {
+				t.Fatalf("sevkiyat oluşturulamadı: %v", err)
+			}
+			item := shipment.Items[0]
+			if item.Quantity != tt.wantQty || item.UnitPrice != tt.wantPrice || item.UnitQuantity != 3 {
+				t.Errorf("miktar = %v, birim fiyat = %v, girilen miktar = %v; %v, %v, 3 bekleniyordu",
+					item.Quantity, item.UnitPrice, item.UnitQuantity, tt.wantQty, tt.wantPrice)
+			}
+			wantUnit := tt.unit
+			if wantUnit == "" {
+				wantUnit = product.Unit
+			}
+			if item.Unit != wantUnit {
+				t.Errorf("birim = %q, %q bekleniyordu", item.Unit, wantUnit)
+			}
+		})
+	}
+}
@@ -6,59 +6,39 @@ import (
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type CreateStockEntryRequest struct {
 	Date            string  `json:"date"` // "2025-12-09"
 	ProductID       uint    `json:"product_id"`
 	Quantity        float64 `json:"quantity"`         // Sayım miktarı (yeni stok durumu)
+	Unit            string  `json:"unit"`             // Opsiyonel, boşsa ürünün temel birimi
 	CurrentQuantity float64 `json:"current_quantity"` // Mevcut stok (frontend'den gelen)
 	BranchID        *uint   `json:"branch_id"`        // super_admin için
 }
 
 type StockEntryResponse struct {
-	ID          uint    `json:"id"`
-	BranchID    uint    `json:"branch_id"`
-	ProductID   uint    `json:"product_id"`
-	ProductName string  `json:"product_name"`
-	StockCode   string  `json:"stock_code"`
-	Date        string  `json:"date"`
-	Quantity    float64 `json:"quantity"`
-	Note        string  `json:"note"`
-	CreatedAt   string  `json:"created_at"`
-}
-
-// Yardımcı: Kullanıcı bilgilerini al
-func getUserInfoForStock(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserRoleKey)
-	_, ok := userIDVal.(models.UserRole)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	userIDVal2 := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal2.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
+	ID           uint    `json:"id"`
+	BranchID     uint    `json:"branch_id"`
+	ProductID    uint    `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	StockCode    string  `json:"stock_code"`
+	Date         string  `json:"date"`
+	Quantity     float64 `json:"quantity"` // temel birimde
+	BaseUnit     string  `json:"base_unit"`
+	Unit         string  `json:"unit"`          // girilen birim
+	UnitQuantity float64 `json:"unit_quantity"` // girilen birimdeki miktar
+	Note         string  `json:"note"`
+	CreatedAt    string  `json:"created_at"`
 }
 
 // POST /api/stock-entries
@@ -73,7 +53,7 @@ func CreateStockEntryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "product_id zorunlu, quantity negatif olamaz")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -89,87 +69,48 @@ func CreateStockEntryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Ürün kontrolü
 		var product models.Product
 		if err := database.DB.First(&product, "id = ?", body.ProductID).Error; err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Ürün bulunamadı")
 		}
 
-		// Mevcut stok bilgisini hesapla (GetCurrentStockHandler mantığı ile aynı)
-		// En son manuel stok sayımını bul
-		var lastCountEntry models.StockEntry
-		err = database.DB.
-			Where("branch_id = ? AND product_id = ?", branchID, body.ProductID).
-			Order("date DESC, created_at DESC").
-			First(&lastCountEntry).Error
-
-		baseQuantity := 0.0
-		countCreatedAt := time.Time{}
-		if err == nil {
-			baseQuantity = lastCountEntry.Quantity
-			countCreatedAt = lastCountEntry.CreatedAt
-		}
-
-		// En son sayımdan sonra stoka kaydedilmiş sevkiyatları topla
-		// Önemli: Sevkiyat tarihine değil, stoka kaydedilme zamanına (updated_at) bakıyoruz
-		var shipmentsAfterCount []models.Shipment
-		shipmentQuantity := 0.0
-		if err == nil {
-			// Sayım oluşturulma zamanından sonra stoka kaydedilmiş sevkiyatlar (updated_at > countCreatedAt)
-			err = database.DB.
-				Preload("Items", "product_id = ?", body.ProductID).
-				Where("branch_id = ? AND updated_at > ? AND is_stocked = true", branchID, countCreatedAt).
-				Find(&shipmentsAfterCount).Error
-			if err == nil {
-				for _, shipment := range shipmentsAfterCount {
-					for _, item := range shipment.Items {
-						if item.ProductID == body.ProductID {
-							shipmentQuantity += item.Quantity
-						}
-					}
-				}
-			}
-		} else {
-			// Hiç stok sayımı yoksa, tüm stoka kaydedilmiş sevkiyatları topla
-			err = database.DB.
-				Preload("Items", "product_id = ?", body.ProductID).
-				Where("branch_id = ? AND is_stocked = true", branchID).
-				Find(&shipmentsAfterCount).Error
-			if err == nil {
-				for _, shipment := range shipmentsAfterCount {
-					for _, item := range shipment.Items {
-						if item.ProductID == body.ProductID {
-							shipmentQuantity += item.Quantity
-						}
-					}
-				}
-			}
-		}
-
-		// Backend'deki mevcut stok = En son sayım + (sayımdan sonra gelen sevkiyatlar)
-		backendCurrentQuantity := baseQuantity + shipmentQuantity
-
-		// Frontend'den gelen mevcut stok ile backend'deki mevcut stok uyumlu mu kontrol et
-		// (Eğer farklıysa, başka bir kullanıcı aynı anda güncelleme yapmış olabilir)
-		if body.CurrentQuantity != backendCurrentQuantity {
-			// Uyumsuzluk var, ama yine de kaydet (kullanıcı uyarısı frontend'de yapılabilir)
-			// Şimdilik direkt kaydediyoruz
+		quantity, _, err := units.ToBase(database.DB, models.UnitKindProduct, product.ID, product.Name, product.Unit, body.Unit, body.Quantity)
+		if err != nil {
+			return err
 		}
 
-		// Stok girişi oluştur (sayım miktarı = yeni stok durumu)
+		// Stok girişi oluştur (sayım miktarı = yeni stok durumu) ve
+		// sayım farkını (sayılan - defterdeki bakiye) deftere düzeltme hareketi olarak yaz
 		entry := models.StockEntry{
-			BranchID:  branchID,
-			ProductID: body.ProductID,
-			Date:      d,
-			Quantity:  body.Quantity, // Sayım sonucu = yeni stok durumu
+			BranchID:     branchID,
+			ProductID:    body.ProductID,
+			Date:         d,
+			Quantity:     quantity, // Sayım sonucu = yeni stok durumu (temel birimde)
+			Unit:         units.EnteredUnit(product.Unit, body.Unit),
+			UnitQuantity: body.Quantity,
 		}
 
-		if err := database.DB.Create(&entry).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Stok girişi oluşturulamadı")
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&entry).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok girişi oluşturulamadı")
+			}
+			if _, err := ledger.RecordCount(tx, entry); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfoForStock(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &branchID,
@@ -185,15 +126,18 @@ func CreateStockEntryHandler() fiber.Handler {
 		}
 
 		return c.Status(fiber.StatusCreated).JSON(StockEntryResponse{
-			ID:          entry.ID,
-			BranchID:    entry.BranchID,
-			ProductID:   entry.ProductID,
-			ProductName: product.Name,
-			StockCode:   product.StockCode,
-			Date:        entry.Date.Format("2006-01-02"),
-			Quantity:    entry.Quantity,
-			Note:        entry.Note,
-			CreatedAt:   entry.CreatedAt.Format("2006-01-02 15:04:05"),
+			ID:           entry.ID,
+			BranchID:     entry.BranchID,
+			ProductID:    entry.ProductID,
+			ProductName:  product.Name,
+			StockCode:    product.StockCode,
+			Date:         entry.Date.Format("2006-01-02"),
+			Quantity:     entry.Quantity,
+			BaseUnit:     product.Unit,
+			Unit:         entry.Unit,
+			UnitQuantity: entry.UnitQuantity,
+			Note:         entry.Note,
+			CreatedAt:    entry.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 }
@@ -201,7 +145,7 @@ func CreateStockEntryHandler() fiber.Handler {
 // GET /api/stock-entries
 func ListStockEntriesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -218,15 +162,18 @@ func ListStockEntriesHandler() fiber.Handler {
 		resp := make([]StockEntryResponse, 0, len(entries))
 		for _, e := range entries {
 			resp = append(resp, StockEntryResponse{
-				ID:          e.ID,
-				BranchID:    e.BranchID,
-				ProductID:   e.ProductID,
-				ProductName: e.Product.Name,
-				StockCode:   e.Product.StockCode,
-				Date:        e.Date.Format("2006-01-02"),
-				Quantity:    e.Quantity,
-				Note:        e.Note,
-				CreatedAt:   e.CreatedAt.Format("2006-01-02 15:04:05"),
+				ID:           e.ID,
+				BranchID:     e.BranchID,
+				ProductID:    e.ProductID,
+				ProductName:  e.Product.Name,
+				StockCode:    e.Product.StockCode,
+				Date:         e.Date.Format("2006-01-02"),
+				Quantity:     e.Quantity,
+				BaseUnit:     e.Product.Unit,
+				Unit:         units.EnteredUnit(e.Product.Unit, e.Unit),
+				UnitQuantity: enteredQuantity(e.UnitQuantity, e.Quantity),
+				Note:         e.Note,
+				CreatedAt:    e.CreatedAt.Format("2006-01-02 15:04:05"),
 			})
 		}
 
@@ -236,15 +183,14 @@ func ListStockEntriesHandler() fiber.Handler {
 
 // GET /api/stock-entries/current
 // Mevcut stok durumunu getir
-// Mantık: En son manuel stok sayımı + (sayımdan sonra gelen sevkiyatlar)
+// Mantık: Stok defterindeki hareketlerin ürün bazında toplamı (tek sorgu)
 func GetCurrentStockHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
 
-		// Her ürün için mevcut stoku hesapla
 		var products []models.Product
 		if err := database.DB.Find(&products).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
@@ -267,6 +213,25 @@ func GetCurrentStockHandler() fiber.Handler {
 			}
 		}
 
+		// Ürün bazında bakiye ve son hareket tarihi
+		type balanceRow struct {
+			ProductID uint
+			Quantity  float64
+			LastDate  time.Time
+		}
+		var balances []balanceRow
+		if err := database.DB.Model(&models.StockMovement{}).
+			Select("product_id, SUM(quantity) AS quantity, MAX(date) AS last_date").
+			Where("branch_id = ?", branchID).
+			Group("product_id").
+			Scan(&balances).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok hesaplanamadı")
+		}
+		balanceMap := make(map[uint]balanceRow, len(balances))
+		for _, b := range balances {
+			balanceMap[b.ProductID] = b
+		}
+
 		type CurrentStock struct {
 			ProductID   uint    `json:"product_id"`
 			ProductName string  `json:"product_name"`
@@ -277,76 +242,12 @@ func GetCurrentStockHandler() fiber.Handler {
 			OrderIndex  *int    `json:"order_index,omitempty"` // Sıralama için (nil ise sıralama yok)
 		}
 
-		currentStocks := make([]CurrentStock, 0)
+		currentStocks := make([]CurrentStock, 0, len(products))
 		for _, product := range products {
-			// En son manuel stok sayımını bul (en son StockEntry)
-			var lastCountEntry models.StockEntry
-			err := database.DB.
-				Where("branch_id = ? AND product_id = ?", branchID, product.ID).
-				Order("date DESC, created_at DESC").
-				First(&lastCountEntry).Error
-
-			baseQuantity := 0.0
-			countCreatedAt := time.Time{}
-			if err == nil {
-				baseQuantity = lastCountEntry.Quantity
-				countCreatedAt = lastCountEntry.CreatedAt
-			}
-
-			// En son sayımdan sonra stoka kaydedilmiş sevkiyatları topla
-			// Önemli: Sevkiyat tarihine değil, stoka kaydedilme zamanına (updated_at) bakıyoruz
-			// Çünkü sevkiyat tarihi önce olabilir ama stoka kaydedilme zamanı sonra olabilir
-			var shipmentsAfterCount []models.Shipment
-			shipmentQuantity := 0.0
-			if err == nil {
-				// Sayım oluşturulma zamanından sonra stoka kaydedilmiş sevkiyatlar (updated_at > countCreatedAt)
-				// updated_at, IsStocked=true yapıldığında güncellenir
-				// countCreatedAt kullanıyoruz çünkü stok sayımı oluşturulduğu anda kesin sonuç olur
-				err = database.DB.
-					Preload("Items", "product_id = ?", product.ID).
-					Where("branch_id = ? AND updated_at > ? AND is_stocked = true", branchID, countCreatedAt).
-					Find(&shipmentsAfterCount).Error
-				if err == nil {
-					for _, shipment := range shipmentsAfterCount {
-						for _, item := range shipment.Items {
-							if item.ProductID == product.ID {
-								shipmentQuantity += item.Quantity
-							}
-						}
-					}
-				}
-			} else {
-				// Hiç stok sayımı yoksa, tüm stoka kaydedilmiş sevkiyatları topla
-				err = database.DB.
-					Preload("Items", "product_id = ?", product.ID).
-					Where("branch_id = ? AND is_stocked = true", branchID).
-					Find(&shipmentsAfterCount).Error
-				if err == nil {
-					for _, shipment := range shipmentsAfterCount {
-						for _, item := range shipment.Items {
-							if item.ProductID == product.ID {
-								shipmentQuantity += item.Quantity
-							}
-						}
-					}
-				}
-			}
-
-			// Toplam stok = En son sayım + (sayımdan sonra gelen sevkiyatlar)
-			totalQuantity := baseQuantity + shipmentQuantity
-
 			lastUpdate := ""
-			if err == nil {
-				lastUpdate = lastCountEntry.Date.Format("2006-01-02")
-			} else if len(shipmentsAfterCount) > 0 {
-				// Sadece sevkiyat varsa, en son sevkiyat tarihini göster
-				lastShipment := shipmentsAfterCount[0]
-				for _, s := range shipmentsAfterCount {
-					if s.Date.After(lastShipment.Date) {
-						lastShipment = s
-					}
-				}
-				lastUpdate = lastShipment.Date.Format("2006-01-02")
+			b, hasMovement := balanceMap[product.ID]
+			if hasMovement {
+				lastUpdate = b.LastDate.Format("2006-01-02")
 			}
 
 			orderIdx := orderMap[product.ID]
@@ -360,31 +261,26 @@ func GetCurrentStockHandler() fiber.Handler {
 				ProductName: product.Name,
 				StockCode:   product.StockCode,
 				Unit:        product.Unit,
-				Quantity:    totalQuantity,
+				Quantity:    b.Quantity,
 				LastUpdate:  lastUpdate,
 				OrderIndex:  orderIdxPtr,
 			})
 		}
 
-		// Sıralamaya göre sort et (order_index olanlar önce, sonra diğerleri)
-		// Go'da slice sort için sort.Slice kullan
+		// Sıralamaya göre sort et (order_index olanlar önce, sonra ürün adına göre)
 		sort.Slice(currentStocks, func(i, j int) bool {
 			iOrder := currentStocks[i].OrderIndex
 			jOrder := currentStocks[j].OrderIndex
 
-			// İkisi de sıralama varsa, order_index'e göre
 			if iOrder != nil && jOrder != nil {
 				return *iOrder < *jOrder
 			}
-			// Sadece i sıralama varsa, i önce gelsin
 			if iOrder != nil {
 				return true
 			}
-			// Sadece j sıralama varsa, j önce gelsin
 			if jOrder != nil {
 				return false
 			}
-			// İkisi de yoksa, ürün adına göre alfabetik
 			return currentStocks[i].ProductName < currentStocks[j].ProductName
 		})
 
@@ -392,11 +288,80 @@ func GetCurrentStockHandler() fiber.Handler {
 	}
 }
 
+// StockUsageRow: Aylık stok kullanımı (ürün bazında)
+type StockUsageRow struct {
+	ProductID   uint    `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	StockCode   string  `json:"stock_code"`
+	Unit        string  `json:"unit"`
+	StartQty    float64 `json:"start_qty"`    // ay başı stok
+	IncomingQty float64 `json:"incoming_qty"` // ay içi gelen (sevkiyat/transfer)
+	EndQty      float64 `json:"end_qty"`      // ay sonu stok
+	ConsumedQty float64 `json:"consumed_qty"` // toplam çıkış = start + incoming - end
+	UsedQty     float64 `json:"used_qty"`     // sayımla tespit edilen kullanım (satış/tüketim)
+	WastedQty   float64 `json:"wasted_qty"`   // kayıtlı zayiat
+	TransferQty float64 `json:"transfer_qty"` // başka şubelere gönderilen
+	VarianceQty float64 `json:"variance_qty"` // açıklanamayan fark = consumed - used - wasted - transfer
+	UnitPrice   float64 `json:"unit_price"`   // son sevkiyat birim fiyatı
+	WastedValue float64 `json:"wasted_value"` // wasted_qty * unit_price
+	CountLoss   float64 `json:"-" gorm:"column:count_loss"`
+}
+
+// MonthlyStockUsage: [firstDay, nextMonth) dönemindeki merkez ürünleri kullanımı.
+// Tüm değerler stok defterinden tek sorguda ürün bazında toplanır.
+func MonthlyStockUsage(branchID uint, firstDay, nextMonth time.Time) ([]StockUsageRow, error) {
+	var rows []StockUsageRow
+	if err := database.DB.Table("stock_movements AS m").
+		Select(`m.product_id, p.name AS product_name, p.stock_code, p.unit,
+			COALESCE(SUM(CASE WHEN m.date < ? THEN m.quantity ELSE 0 END), 0) AS start_qty,
+			COALESCE(SUM(CASE WHEN m.date >= ? AND m.date < ? AND m.type IN ? THEN m.quantity ELSE 0 END), 0) AS incoming_qty,
+			COALESCE(SUM(CASE WHEN m.date < ? THEN m.quantity ELSE 0 END), 0) AS end_qty,
+			COALESCE(SUM(CASE WHEN m.date >= ? AND m.date < ? AND m.type = ? THEN -m.quantity ELSE 0 END), 0) AS wasted_qty,
+			COALESCE(SUM(CASE WHEN m.date >= ? AND m.date < ? AND m.type = ? THEN -m.quantity ELSE 0 END), 0) AS transfer_qty,
+			COALESCE(SUM(CASE WHEN m.date >= ? AND m.date < ? AND m.type = ? THEN -m.quantity ELSE 0 END), 0) AS count_loss`,
+			firstDay, firstDay, nextMonth,
+			[]models.StockMovementType{models.MovementReceipt, models.MovementTransferIn},
+			nextMonth,
+			firstDay, nextMonth, models.MovementWaste,
+			firstDay, nextMonth, models.MovementTransferOut,
+			firstDay, nextMonth, models.MovementCountAdjustment).
+		Joins("JOIN products p ON p.id = m.product_id").
+		Where("m.branch_id = ? AND p.is_center_product = ?", branchID, true).
+		Group("m.product_id, p.name, p.stock_code, p.unit").
+		Order("p.name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	prices, err := ledger.LastUnitPrices(database.DB, branchID, nextMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		r := &rows[i]
+		// Toplam çıkış = Başlangıç + Gelen - Son
+		r.ConsumedQty = r.StartQty + r.IncomingQty - r.EndQty
+		// Sayımda eksik çıkan miktar kullanım sayılır; fazla çıkan miktar farka kalır
+		if r.CountLoss > 0 {
+			r.UsedQty = r.CountLoss
+		}
+		r.VarianceQty = r.ConsumedQty - r.UsedQty - r.WastedQty - r.TransferQty
+		r.UnitPrice = prices[r.ProductID]
+		r.WastedValue = r.WastedQty * r.UnitPrice
+	}
+	if rows == nil {
+		rows = make([]StockUsageRow, 0)
+	}
+	return rows, nil
+}
+
 // GET /api/stock-usage/monthly
 // Aylık harcama raporu: Başlangıç + Gelen - Son = Harcanan
+// Başlangıç/son: ay başındaki ve ay sonundaki defter bakiyesi, gelen: ay içi giriş hareketleri
 func GetMonthlyStockUsageHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -417,234 +382,142 @@ func GetMonthlyStockUsageHandler() fiber.Handler {
 
 		loc := time.Now().Location()
 		firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
-		lastDay := firstDay.AddDate(0, 1, -1)
-
-		// Ay başındaki stok (ayın ilk gününden önceki en son stok girişi)
-		// Ay içindeki sevkiyatlar (stoka kaydedilmiş olanlar + center shipments)
-		// Ay sonundaki stok (ayın son günündeki veya sonrasındaki en son stok girişi)
-
-		// Geri alınan (undo edilen) sevkiyatların ID'lerini bul
-		type UndoneLog struct {
-			EntityID uint `gorm:"column:entity_id"`
-		}
-		var undoneShipmentIDs []UndoneLog
-		var undoneCenterShipmentIDs []UndoneLog
-		
-		// Geri alınan Shipment'ları bul
-		database.DB.Model(&models.AuditLog{}).
-			Select("entity_id").
-			Where("entity_type = ? AND action = ? AND is_undone = ? AND (branch_id = ? OR branch_id IS NULL)", 
-				"shipment", models.AuditActionCreate, true, branchID).
-			Scan(&undoneShipmentIDs)
-		
-		// Geri alınan CenterShipment'ları bul
-		database.DB.Model(&models.AuditLog{}).
-			Select("entity_id").
-			Where("entity_type = ? AND action = ? AND is_undone = ? AND (branch_id = ? OR branch_id IS NULL)", 
-				"center_shipment", models.AuditActionCreate, true, branchID).
-			Scan(&undoneCenterShipmentIDs)
-		
-		undoneShipmentIDMap := make(map[uint]bool)
-		for _, log := range undoneShipmentIDs {
-			undoneShipmentIDMap[log.EntityID] = true
-		}
-		
-		undoneCenterShipmentIDMap := make(map[uint]bool)
-		for _, log := range undoneCenterShipmentIDs {
-			undoneCenterShipmentIDMap[log.EntityID] = true
-		}
-
-		// Tüm ürünleri al (sadece merkez ürünleri)
-		var products []models.Product
-		if err := database.DB.Where("is_center_product = ?", true).Find(&products).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
-		}
+		nextMonth := firstDay.AddDate(0, 1, 0)
 
-		type StockUsageRow struct {
-			ProductID    uint    `json:"product_id"`
-			ProductName  string  `json:"product_name"`
-			StockCode    string  `json:"stock_code"`
-			Unit         string  `json:"unit"`
-			StartQty     float64 `json:"start_qty"`     // ay başı stok
-			IncomingQty  float64 `json:"incoming_qty"`  // ay içi gelen (sevkiyat)
-			EndQty       float64 `json:"end_qty"`       // ay sonu stok
-			UsedQty      float64 `json:"used_qty"`      // harcanan = start + incoming - end
+		rows, err := MonthlyStockUsage(branchID, firstDay, nextMonth)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok kullanımı hesaplanamadı")
 		}
 
-		rows := make([]StockUsageRow, 0)
-		for _, product := range products {
-			// Ay başı stok (ayın ilk gününden önceki en son giriş)
-			var startEntry models.StockEntry
-			startQty := 0.0
-			err := database.DB.
-				Where("branch_id = ? AND product_id = ? AND date < ?", branchID, product.ID, firstDay).
-				Order("date DESC, created_at DESC").
-				First(&startEntry).Error
-			if err == nil {
-				startQty = startEntry.Quantity
-			}
-
-			// Ay içi gelen sevkiyatlar (stoka kaydedilmiş) - geri alınanları hariç tut
-			incomingQty := 0.0
-			
-			// 1. Shipment'lar (B2B sevkiyatlar)
-			var incomingShipments []models.Shipment
-			err = database.DB.
-				Preload("Items", "product_id = ?", product.ID).
-				Where("branch_id = ? AND date >= ? AND date <= ? AND is_stocked = true", branchID, firstDay, lastDay).
-				Find(&incomingShipments).Error
-			if err == nil {
-				for _, shipment := range incomingShipments {
-					// Geri alınmış sevkiyatları atla
-					if undoneShipmentIDMap[shipment.ID] {
-						continue
-					}
-					for _, item := range shipment.Items {
-						if item.ProductID == product.ID {
-							incomingQty += item.Quantity
-						}
-					}
-				}
-			}
-			
-			// 2. CenterShipment'lar (manuel merkez sevkiyatları) - geri alınanları hariç tut
-			var centerShipments []models.CenterShipment
-			err = database.DB.
-				Where("branch_id = ? AND product_id = ? AND date >= ? AND date <= ?", branchID, product.ID, firstDay, lastDay).
-				Find(&centerShipments).Error
-			if err == nil {
-				for _, cs := range centerShipments {
-					// Geri alınmış sevkiyatları atla
-					if undoneCenterShipmentIDMap[cs.ID] {
-						continue
-					}
-					incomingQty += cs.Quantity
-				}
-			}
-
-			// Ay sonu stok (ayın son günündeki veya sonrasındaki en son giriş)
-			var endEntry models.StockEntry
-			endQty := 0.0
-			err = database.DB.
-				Where("branch_id = ? AND product_id = ? AND date >= ?", branchID, product.ID, firstDay).
-				Order("date DESC, created_at DESC").
-				First(&endEntry).Error
-			if err == nil {
-				endQty = endEntry.Quantity
-			}
-
-			// Harcanan = Başlangıç + Gelen - Son
-			usedQty := startQty + incomingQty - endQty
-			if usedQty < 0 {
-				usedQty = 0 // Negatif olamaz (muhtemelen veri hatası)
-			}
+		totalWastedValue := 0.0
+		for _, r := range rows {
+			totalWastedValue += r.WastedValue
+		}
 
-			rows = append(rows, StockUsageRow{
-				ProductID:   product.ID,
-				ProductName: product.Name,
-				StockCode:   product.StockCode,
-				Unit:        product.Unit,
-				StartQty:    startQty,
-				IncomingQty: incomingQty,
-				EndQty:      endQty,
-				UsedQty:     usedQty,
-			})
+		// Zayiatın sorumlu kişi bazında dağılımı
+		waste, err := summarizeWaste(branchID, firstDay, nextMonth)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat özeti hesaplanamadı")
 		}
 
 		return c.JSON(fiber.Map{
-			"year": year,
-			"month": month,
-			"branch_id": branchID,
-			"rows": rows,
+			"year":               year,
+			"month":              month,
+			"branch_id":          branchID,
+			"rows":               rows,
+			"waste_by_person":    waste.People,
+			"total_wasted_value": totalWastedValue,
 		})
 	}
 }
 
 // GET /api/stock-entries/usage-between-counts
 // Son iki stok sayımı arasındaki kullanımı hesapla
-// Mantık: (Önceki sayım + Aradaki sevkiyatlar) - Son sayım = Kullanım
+// Mantık: (Önceki sayım + Aradaki girişler) - Son sayım = Kullanım
+// Defter sırası kayıt sırasıdır (id); sayımlar arası hareketler tek sorguda toplanır
 func GetStockUsageBetweenCountsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
 
-		// Tüm ürünleri al (sadece IsCenterProduct = true olanlar)
-		var products []models.Product
-		if err := database.DB.Where("is_center_product = ?", true).Find(&products).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Ürünler listelenemedi")
-		}
-
 		type UsageRow struct {
-			ProductID          uint    `json:"product_id"`
-			ProductName        string  `json:"product_name"`
-			Unit               string  `json:"unit"`
-			PreviousCount      float64 `json:"previous_count"`      // Önceki sayım miktarı
-			PreviousCountDate  string  `json:"previous_count_date"` // Önceki sayım tarihi
-			ShipmentsBetween   float64 `json:"shipments_between"`   // İki sayım arası gelen sevkiyatlar
-			CurrentCount       float64 `json:"current_count"`       // Son sayım miktarı
-			CurrentCountDate   string  `json:"current_count_date"`  // Son sayım tarihi
-			Usage              float64 `json:"usage"`               // Kullanım = (Önceki + Sevkiyat) - Son
-		}
-
-		rows := make([]UsageRow, 0)
-		for _, product := range products {
-			// En son iki stok sayımını bul
-			var stockEntries []models.StockEntry
-			err := database.DB.
-				Where("branch_id = ? AND product_id = ?", branchID, product.ID).
-				Order("date DESC, created_at DESC").
-				Limit(2).
-				Find(&stockEntries).Error
-
-			if err != nil || len(stockEntries) < 2 {
-				// En az 2 sayım yoksa, bu ürün için kullanım hesaplanamaz
-				continue
-			}
-
-			// En son sayım (index 0)
-			currentEntry := stockEntries[0]
-			// Önceki sayım (index 1)
-			previousEntry := stockEntries[1]
-
-			// İki sayım arasındaki sevkiyatları bul
-			// Önceki sayımın oluşturulma zamanından sonra, son sayımın oluşturulma zamanından önce
-			// stoka kaydedilmiş sevkiyatlar
-			var shipmentsBetween []models.Shipment
-			shipmentQuantity := 0.0
-			err = database.DB.
-				Preload("Items", "product_id = ?", product.ID).
-				Where("branch_id = ? AND updated_at > ? AND updated_at < ? AND is_stocked = true", 
-					branchID, previousEntry.CreatedAt, currentEntry.CreatedAt).
-				Find(&shipmentsBetween).Error
-			
-			if err == nil {
-				for _, shipment := range shipmentsBetween {
-					for _, item := range shipment.Items {
-						if item.ProductID == product.ID {
-							shipmentQuantity += item.Quantity
-						}
-					}
-				}
-			}
+			ProductID         uint    `json:"product_id"`
+			ProductName       string  `json:"product_name"`
+			Unit              string  `json:"unit"`
+			PreviousCount     float64 `json:"previous_count"`      // Önceki sayım miktarı
+			PreviousCountDate string  `json:"previous_count_date"` // Önceki sayım tarihi
+			ShipmentsBetween  float64 `json:"shipments_between"`   // İki sayım arası gelen sevkiyatlar
+			CurrentCount      float64 `json:"current_count"`       // Son sayım miktarı
+			CurrentCountDate  string  `json:"current_count_date"`  // Son sayım tarihi
+			Consumed          float64 `json:"consumed"`            // Toplam çıkış = (Önceki + Sevkiyat) - Son
+			Usage             float64 `json:"usage"`               // Son sayımda eksik çıkan (satış/tüketim)
+			Wasted            float64 `json:"wasted"`              // İki sayım arası kayıtlı zayiat
+			Transferred       float64 `json:"transferred"`         // İki sayım arası başka şubelere gönderilen
+			Variance          float64 `json:"variance"`            // Açıklanamayan fark = Consumed - Usage - Wasted - Transferred
+			UnitPrice         float64 `json:"unit_price"`          // Son sevkiyat birim fiyatı
+			WastedValue       float64 `json:"wasted_value"`        // Wasted * UnitPrice
+		}
+
+		type usageScan struct {
+			ProductID        uint
+			ProductName      string
+			Unit             string
+			PreviousCount    float64
+			PreviousDate     time.Time
+			ShipmentsBetween float64
+			WastedBetween    float64
+			TransferBetween  float64
+			CurrentCount     float64
+			CurrentDate      time.Time
+			CurrentAdjust    float64
+		}
+
+		var scanned []usageScan
+		if err := database.DB.Raw(`
+			WITH counts AS (
+				SELECT id, product_id, date, counted_quantity, quantity,
+					ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY id DESC) AS rn
+				FROM stock_movements
+				WHERE branch_id = ? AND type = ?
+			)
+			SELECT cur.product_id, p.name AS product_name, p.unit,
+				prev.counted_quantity AS previous_count, prev.date AS previous_date,
+				cur.counted_quantity AS current_count, cur.date AS current_date, cur.quantity AS current_adjust,
+				COALESCE(SUM(CASE WHEN m.type IN ? THEN m.quantity ELSE 0 END), 0) AS shipments_between,
+				COALESCE(SUM(CASE WHEN m.type = ? THEN -m.quantity ELSE 0 END), 0) AS wasted_between,
+				COALESCE(SUM(CASE WHEN m.type = ? THEN -m.quantity ELSE 0 END), 0) AS transfer_between
+			FROM counts cur
+			JOIN counts prev ON prev.product_id = cur.product_id AND prev.rn = 2
+			JOIN products p ON p.id = cur.product_id AND p.is_center_product = ?
+			LEFT JOIN stock_movements m ON m.branch_id = ? AND m.product_id = cur.product_id
+				AND m.id > prev.id AND m.id < cur.id
+			WHERE cur.rn = 1
+			GROUP BY cur.product_id, p.name, p.unit, prev.counted_quantity, prev.date,
+				cur.counted_quantity, cur.date, cur.quantity
+			ORDER BY p.name`,
+			branchID, models.MovementCountAdjustment,
+			[]models.StockMovementType{models.MovementReceipt, models.MovementTransferIn},
+			models.MovementWaste,
+			models.MovementTransferOut,
+			true, branchID,
+		).Scan(&scanned).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok kullanımı hesaplanamadı")
+		}
+
+		prices, err := ledger.LastUnitPrices(database.DB, branchID, time.Now().AddDate(0, 0, 1))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
 
-			// Kullanım = (Önceki sayım + Aradaki sevkiyatlar) - Son sayım
-			usage := previousEntry.Quantity + shipmentQuantity - currentEntry.Quantity
-			if usage < 0 {
-				usage = 0 // Negatif olamaz
+		rows := make([]UsageRow, 0, len(scanned))
+		for _, r := range scanned {
+			// Toplam çıkış = (Önceki sayım + Aradaki girişler) - Son sayım
+			consumed := r.PreviousCount + r.ShipmentsBetween - r.CurrentCount
+			// Son sayımın düzeltme hareketi eksiyse o miktar kullanım sayılır
+			usage := 0.0
+			if r.CurrentAdjust < 0 {
+				usage = -r.CurrentAdjust
 			}
+			unitPrice := prices[r.ProductID]
 
 			rows = append(rows, UsageRow{
-				ProductID:         product.ID,
-				ProductName:       product.Name,
-				Unit:              product.Unit,
-				PreviousCount:     previousEntry.Quantity,
-				PreviousCountDate: previousEntry.Date.Format("2006-01-02"),
-				ShipmentsBetween:  shipmentQuantity,
-				CurrentCount:      currentEntry.Quantity,
-				CurrentCountDate:  currentEntry.Date.Format("2006-01-02"),
+				ProductID:         r.ProductID,
+				ProductName:       r.ProductName,
+				Unit:              r.Unit,
+				PreviousCount:     r.PreviousCount,
+				PreviousCountDate: r.PreviousDate.Format("2006-01-02"),
+				ShipmentsBetween:  r.ShipmentsBetween,
+				CurrentCount:      r.CurrentCount,
+				CurrentCountDate:  r.CurrentDate.Format("2006-01-02"),
+				Consumed:          consumed,
 				Usage:             usage,
+				Wasted:            r.WastedBetween,
+				Transferred:       r.TransferBetween,
+				Variance:          consumed - usage - r.WastedBetween - r.TransferBetween,
+				UnitPrice:         unitPrice,
+				WastedValue:       r.WastedBetween * unitPrice,
 			})
 		}
 
@@ -654,4 +527,3 @@ func GetStockUsageBetweenCountsHandler() fiber.Handler {
 		})
 	}
 }
-
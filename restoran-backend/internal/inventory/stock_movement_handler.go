@@ -0,0 +1,183 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreateStockCorrectionRequest struct {
+	Date      string  `json:"date"`       // "2025-12-09"
+	ProductID uint    `json:"product_id"` // zorunlu
+	Quantity  float64 `json:"quantity"`   // işaretli düzeltme miktarı (+ ekler, - düşer)
+	Note      string  `json:"note"`       // zorunlu: düzeltme sebebi
+	BranchID  *uint   `json:"branch_id"`  // super_admin için
+}
+
+type StockMovementResponse struct {
+	ID              uint     `json:"id"`
+	BranchID        uint     `json:"branch_id"`
+	ProductID       uint     `json:"product_id"`
+	ProductName     string   `json:"product_name"`
+	Type            string   `json:"type"`
+	Quantity        float64  `json:"quantity"`
+	CountedQuantity *float64 `json:"counted_quantity,omitempty"`
+	Date            string   `json:"date"`
+	SourceType      string   `json:"source_type"`
+	SourceID        uint     `json:"source_id"`
+	Note            string   `json:"note"`
+	CreatedAt       string   `json:"created_at"`
+}
+
+func toStockMovementResponse(m models.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		ID:              m.ID,
+		BranchID:        m.BranchID,
+		ProductID:       m.ProductID,
+		ProductName:     m.Product.Name,
+		Type:            string(m.Type),
+		Quantity:        m.Quantity,
+		CountedQuantity: m.CountedQuantity,
+		Date:            m.Date.Format("2006-01-02"),
+		SourceType:      m.SourceType,
+		SourceID:        m.SourceID,
+		Note:            m.Note,
+		CreatedAt:       m.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// POST /api/stock-movements/corrections
+// Manuel stok düzeltmesi (sayım dışı, örn. hatalı giriş telafisi)
+func CreateStockCorrectionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body CreateStockCorrectionRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		body.Note = strings.TrimSpace(body.Note)
+		if body.ProductID == 0 || body.Quantity == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "product_id zorunlu, quantity 0 olamaz")
+		}
+		if len(body.Note) < 3 {
+			return fiber.NewError(fiber.StatusBadRequest, "note zorunludur ve en az 3 karakter olmalıdır (düzeltme sebebi)")
+		}
+
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
+		if err != nil {
+			return err
+		}
+
+		d, err := time.Parse("2006-01-02", body.Date)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		var product models.Product
+		if err := database.DB.First(&product, "id = ?", body.ProductID).Error; err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Ürün bulunamadı")
+		}
+
+		movement := models.StockMovement{
+			BranchID:  branchID,
+			ProductID: body.ProductID,
+			Type:      models.MovementCorrection,
+			Quantity:  body.Quantity,
+			Date:      d,
+			Note:      body.Note,
+		}
+		// Defter üzerinden yazılır: ürün kilitlenir, sonraki tarihli sayım farkları yeniden hesaplanır
+		movements := []models.StockMovement{movement}
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			return ledger.Record(tx, movements...)
+		})
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok düzeltmesi oluşturulamadı")
+		}
+		movement = movements[0]
+		movement.Product = product
+
+		// Audit log
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			_ = audit.WriteLog(audit.LogOptions{
+				BranchID:    &branchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "stock_movement",
+				EntityID:    movement.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Stok düzeltmesi: %s %+.2f %s (Not: %s)", product.Name, movement.Quantity, product.Unit, movement.Note),
+				Before:      nil,
+				After:       movement,
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(toStockMovementResponse(movement))
+	}
+}
+
+// GET /api/stock-movements?product_id=...&from=...&to=...
+// Stok defteri hareketleri
+func ListStockMovementsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		query := database.DB.Preload("Product").Where("branch_id = ?", branchID)
+
+		if pidStr := c.Query("product_id"); pidStr != "" {
+			var pid uint
+			if _, err := fmt.Sscan(pidStr, &pid); err != nil || pid == 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "product_id geçersiz")
+			}
+			query = query.Where("product_id = ?", pid)
+		}
+		if t := c.Query("type"); t != "" {
+			query = query.Where("type = ?", t)
+		}
+		if from := c.Query("from"); from != "" {
+			d, err := time.Parse("2006-01-02", from)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+			query = query.Where("date >= ?", d)
+		}
+		if to := c.Query("to"); to != "" {
+			d, err := time.Parse("2006-01-02", to)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+			query = query.Where("date < ?", d.AddDate(0, 0, 1))
+		}
+
+		var movements []models.StockMovement
+		if err := query.Order("date DESC, id DESC").Find(&movements).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketleri listelenemedi")
+		}
+
+		resp := make([]StockMovementResponse, 0, len(movements))
+		for _, m := range movements {
+			resp = append(resp, toStockMovementResponse(m))
+		}
+
+		return c.JSON(resp)
+	}
+}
@@ -4,12 +4,11 @@ import (
 	"fmt"
 	"log"
 	"regexp"
-	"strconv"
 	"strings"
 
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -96,7 +95,7 @@ func isNumericOrUnit(word string) bool {
 // Mevcut ürün sıralamasını getirir
 func GetProductOrderHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromContext(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -132,7 +131,7 @@ func GetProductOrderHandler() fiber.Handler {
 // Ürün sıralamasını kaydeder
 func SaveProductOrderHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromContext(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -190,7 +189,7 @@ func SaveProductOrderHandler() fiber.Handler {
 // Ürün sıralamasını temizler
 func ClearProductOrderHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromContext(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -206,38 +205,3 @@ func ClearProductOrderHandler() fiber.Handler {
 	}
 }
 
-// resolveBranchIDFromContext: Context'ten branch ID'yi al
-func resolveBranchIDFromContext(c *fiber.Ctx) (uint, error) {
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		return *bPtr, nil
-	}
-
-	// Super admin ise query'den al
-	userIDVal := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal.(uint)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	if user.Role == models.RoleSuperAdmin {
-		// Query'den branch_id al
-		branchIDStr := c.Query("branch_id")
-		if branchIDStr == "" {
-			return 0, fiber.NewError(fiber.StatusBadRequest, "Super admin için branch_id gerekli")
-		}
-		branchID, err := strconv.ParseUint(branchIDStr, 10, 32)
-		if err != nil {
-			return 0, fiber.NewError(fiber.StatusBadRequest, "Geçersiz branch_id")
-		}
-		return uint(branchID), nil
-	}
-
-	return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi alınamadı")
-}
-
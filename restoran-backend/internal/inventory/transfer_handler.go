@@ -0,0 +1,361 @@
+package inventory
+
+import (
+	"fmt"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type CreateStockTransferRequest struct {
+	ToBranchID   uint                       `json:"to_branch_id"` // hedef şube
+	Date         string                     `json:"date"`         // gönderim tarihi "2025-12-09"
+	Items        []StockTransferItemRequest `json:"items"`
+	Note         string                     `json:"note"`
+	FromBranchID *uint                      `json:"from_branch_id"` // super_admin için
+}
+
+type StockTransferItemRequest struct {
+	ProductID uint    `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+type ReceiveStockTransferRequest struct {
+	Date string `json:"date"` // teslim alma tarihi "2025-12-10"
+}
+
+type StockTransferResponse struct {
+	ID             uint                        `json:"id"`
+	FromBranchID   uint                        `json:"from_branch_id"`
+	FromBranchName string                      `json:"from_branch_name"`
+	ToBranchID     uint                        `json:"to_branch_id"`
+	ToBranchName   string                      `json:"to_branch_name"`
+	Status         models.StockTransferStatus  `json:"status"`
+	SentDate       string                      `json:"sent_date"`
+	ReceivedDate   *string                     `json:"received_date"`
+	TotalCost      float64                     `json:"total_cost"`
+	Note           string                      `json:"note"`
+	Items          []StockTransferItemResponse `json:"items"`
+	CreatedAt      string                      `json:"created_at"`
+}
+
+type StockTransferItemResponse struct {
+	ID          uint    `json:"id"`
+	ProductID   uint    `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Unit        string  `json:"unit"`
+	Quantity    float64 `json:"quantity"`
+	UnitCost    float64 `json:"unit_cost"`
+	TotalCost   float64 `json:"total_cost"`
+}
+
+func toStockTransferResponse(t models.StockTransfer) StockTransferResponse {
+	items := make([]StockTransferItemResponse, 0, len(t.Items))
+	for _, item := range t.Items {
+		items = append(items, StockTransferItemResponse{
+			ID:          item.ID,
+			ProductID:   item.ProductID,
+			ProductName: item.Product.Name,
+			Unit:        item.Product.Unit,
+			Quantity:    item.Quantity,
+			UnitCost:    item.UnitCost,
+			TotalCost:   item.TotalCost,
+		})
+	}
+
+	var receivedDate *string
+	if t.ReceivedDate != nil {
+		s := t.ReceivedDate.Format("2006-01-02")
+		receivedDate = &s
+	}
+
+	return StockTransferResponse{
+		ID:             t.ID,
+		FromBranchID:   t.FromBranchID,
+		FromBranchName: t.FromBranch.Name,
+		ToBranchID:     t.ToBranchID,
+		ToBranchName:   t.ToBranch.Name,
+		Status:         t.Status,
+		SentDate:       t.SentDate.Format("2006-01-02"),
+		ReceivedDate:   receivedDate,
+		TotalCost:      t.TotalCost,
+		Note:           t.Note,
+		Items:          items,
+		CreatedAt:      t.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// loadStockTransfer - Transferi kalemleri ve şubeleriyle getirir;
+// kaynak ya da hedef şubesinde yetkisi olmayan kullanıcı için bulunamadı döner
+func loadStockTransfer(c *fiber.Ctx) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	if err := database.DB.
+		Scopes(tenancy.ScopeColumns(c, "from_branch_id", "to_branch_id")).
+		Preload("Items.Product").
+		Preload("FromBranch").
+		Preload("ToBranch").
+		First(&t, "id = ?", c.Params("id")).Error; err != nil {
+		return t, fiber.NewError(fiber.StatusNotFound, "Transfer bulunamadı")
+	}
+	return t, nil
+}
+
+// POST /api/stock-transfers
+// Transfer oluşturulduğunda gönderilmiş sayılır, ürünler kaynak şubenin stokundan düşülür
+func CreateStockTransferHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body CreateStockTransferRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		if len(body.Items) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "En az bir ürün eklenmelidir")
+		}
+
+		fromBranchID, err := tenancy.ResolveBranchID(c, body.FromBranchID)
+		if err != nil {
+			return err
+		}
+
+		if body.ToBranchID == 0 || body.ToBranchID == fromBranchID {
+			return fiber.NewError(fiber.StatusBadRequest, "to_branch_id zorunlu ve kaynak şubeden farklı olmalı")
+		}
+		var toBranch models.Branch
+		if err := database.DB.First(&toBranch, "id = ?", body.ToBranchID).Error; err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Hedef şube bulunamadı (ID: %d)", body.ToBranchID))
+		}
+
+		d, err := time.Parse("2006-01-02", body.Date)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(fromBranchID, d); err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		// Maliyet: gönderim tarihine kadarki son sevkiyat birim fiyatı
+		prices, err := ledger.LastUnitPrices(database.DB, fromBranchID, d.AddDate(0, 0, 1))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
+
+		var items []models.StockTransferItem
+		totalCost := 0.0
+		for _, itemReq := range body.Items {
+			if itemReq.ProductID == 0 || itemReq.Quantity <= 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "Tüm ürünler için product_id ve quantity zorunlu, quantity 0'dan büyük olmalı")
+			}
+			var product models.Product
+			if err := database.DB.First(&product, "id = ?", itemReq.ProductID).Error; err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı: %d", itemReq.ProductID))
+			}
+
+			unitCost := prices[itemReq.ProductID]
+			items = append(items, models.StockTransferItem{
+				ProductID: itemReq.ProductID,
+				Quantity:  itemReq.Quantity,
+				UnitCost:  unitCost,
+				TotalCost: unitCost * itemReq.Quantity,
+			})
+			totalCost += unitCost * itemReq.Quantity
+		}
+
+		transfer := models.StockTransfer{
+			FromBranchID: fromBranchID,
+			ToBranchID:   body.ToBranchID,
+			Status:       models.TransferStatusSent,
+			SentDate:     d,
+			TotalCost:    totalCost,
+			Note:         body.Note,
+			SentBy:       userID,
+			Items:        items,
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&transfer).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Transfer oluşturulamadı")
+			}
+			if err := ledger.Record(tx, ledger.TransferMovements(transfer)...); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := database.DB.Preload("Items.Product").Preload("FromBranch").Preload("ToBranch").
+			First(&transfer, "id = ?", transfer.ID).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Transfer yüklenemedi")
+		}
+
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    &transfer.FromBranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "stock_transfer",
+			EntityID:    transfer.ID,
+			Action:      models.AuditActionCreate,
+			Description: fmt.Sprintf("Transfer gönderildi: %s -> %s, %d ürün, Maliyet: %.2f TL", transfer.FromBranch.Name, transfer.ToBranch.Name, len(transfer.Items), transfer.TotalCost),
+			Before:      nil,
+			After:       transfer,
+		})
+
+		return c.Status(fiber.StatusCreated).JSON(toStockTransferResponse(transfer))
+	}
+}
+
+// POST /api/stock-transfers/:id/receive
+// Hedef şube transferi teslim alır, ürünler hedef şubenin stokuna eklenir
+func ReceiveStockTransferHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body ReceiveStockTransferRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		transfer, err := loadStockTransfer(c)
+		if err != nil {
+			return err
+		}
+
+		// Teslim almayı sadece hedef şube (veya super_admin) yapabilir
+		if !tenancy.BranchAllowed(c, transfer.ToBranchID) {
+			return fiber.NewError(fiber.StatusForbidden, "Transferi sadece hedef şube teslim alabilir")
+		}
+
+		if transfer.Status != models.TransferStatusSent {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu transfer zaten teslim alınmış")
+		}
+
+		d := time.Now()
+		if body.Date != "" {
+			if d, err = time.Parse("2006-01-02", body.Date); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+		}
+		d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+		if d.Before(transfer.SentDate) {
+			return fiber.NewError(fiber.StatusBadRequest, "Teslim tarihi gönderim tarihinden önce olamaz")
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(transfer.ToBranchID, d); err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		before := transfer
+		transfer.Status = models.TransferStatusReceived
+		transfer.ReceivedDate = &d
+		transfer.ReceivedBy = &userID
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.StockTransfer{}).Where("id = ?", transfer.ID).Updates(map[string]interface{}{
+				"status":        transfer.Status,
+				"received_date": transfer.ReceivedDate,
+				"received_by":   transfer.ReceivedBy,
+			}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Transfer güncellenemedi")
+			}
+			// Kaynak taraftaki çıkış hareketleri zaten var; hareketler transferin son haliyle yeniden yazılır
+			if err := ledger.RemoveSource(tx, ledger.SourceStockTransfer, transfer.ID); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi güncellenemedi")
+			}
+			if err := ledger.Record(tx, ledger.TransferMovements(transfer)...); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_ = audit.WriteLog(audit.LogOptions{
+			BranchID:    &transfer.ToBranchID,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "stock_transfer",
+			EntityID:    transfer.ID,
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Transfer teslim alındı: %s -> %s, %d ürün", transfer.FromBranch.Name, transfer.ToBranch.Name, len(transfer.Items)),
+			Before:      before,
+			After:       transfer,
+		})
+
+		return c.JSON(toStockTransferResponse(transfer))
+	}
+}
+
+// GET /api/stock-transfers?direction=incoming|outgoing&status=sent|received
+func ListStockTransfersHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		dbq := database.DB.Model(&models.StockTransfer{}).
+			Preload("Items.Product").
+			Preload("FromBranch").
+			Preload("ToBranch")
+
+		switch c.Query("direction") {
+		case "incoming":
+			dbq = dbq.Where("to_branch_id = ?", branchID)
+		case "outgoing":
+			dbq = dbq.Where("from_branch_id = ?", branchID)
+		case "":
+			dbq = dbq.Where("from_branch_id = ? OR to_branch_id = ?", branchID, branchID)
+		default:
+			return fiber.NewError(fiber.StatusBadRequest, "direction 'incoming' veya 'outgoing' olmalı")
+		}
+
+		if status := c.Query("status"); status != "" {
+			dbq = dbq.Where("status = ?", status)
+		}
+
+		var transfers []models.StockTransfer
+		if err := dbq.Order("sent_date DESC, id DESC").Find(&transfers).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Transferler listelenemedi")
+		}
+
+		resp := make([]StockTransferResponse, 0, len(transfers))
+		for _, t := range transfers {
+			resp = append(resp, toStockTransferResponse(t))
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/stock-transfers/:id
+func GetStockTransferHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		transfer, err := loadStockTransfer(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(toStockTransferResponse(transfer))
+	}
+}
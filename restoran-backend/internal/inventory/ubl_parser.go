@@ -0,0 +1,169 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// UBL-TR (GİB e-Fatura / e-Arşiv) faturasının kullandığımız alanları.
+// Etiketler namespace'siz yazıldığı için cac:/cbc: önekleri fark etmez.
+type ublInvoice struct {
+	XMLName         xml.Name         `xml:"Invoice"`
+	ID              string           `xml:"ID"` // fatura no
+	UUID            string           `xml:"UUID"`
+	IssueDate       string           `xml:"IssueDate"` // "2025-12-12"
+	InvoiceTypeCode string           `xml:"InvoiceTypeCode"`
+	Currency        string           `xml:"DocumentCurrencyCode"`
+	Supplier        ublParty         `xml:"AccountingSupplierParty>Party"`
+	Lines           []ublInvoiceLine `xml:"InvoiceLine"`
+	PayableAmount   float64          `xml:"LegalMonetaryTotal>PayableAmount"`
+}
+
+type ublParty struct {
+	Name            string          `xml:"PartyName>Name"`
+	Identifications []ublPartyIdent `xml:"PartyIdentification>ID"`
+}
+
+type ublPartyIdent struct {
+	SchemeID string `xml:"schemeID,attr"` // VKN / TCKN / MERSISNO ...
+	Value    string `xml:",chardata"`
+}
+
+type ublInvoiceLine struct {
+	ID                  string         `xml:"ID"`
+	Quantity            ublQuantity    `xml:"InvoicedQuantity"`
+	LineExtensionAmount float64        `xml:"LineExtensionAmount"` // KDV hariç satır tutarı
+	TaxAmount           float64        `xml:"TaxTotal>TaxAmount"`
+	TaxSubtotals        []ublTaxDetail `xml:"TaxTotal>TaxSubtotal"`
+	ItemName            string         `xml:"Item>Name"`
+	SellersItemID       string         `xml:"Item>SellersItemIdentification>ID"`
+	BuyersItemID        string         `xml:"Item>BuyersItemIdentification>ID"`
+	PriceAmount         float64        `xml:"Price>PriceAmount"` // KDV hariç birim fiyat
+}
+
+type ublQuantity struct {
+	UnitCode string  `xml:"unitCode,attr"`
+	Value    float64 `xml:",chardata"`
+}
+
+type ublTaxDetail struct {
+	TaxAmount   float64 `xml:"TaxAmount"`
+	Percent     float64 `xml:"Percent"`
+	TaxTypeCode string  `xml:"TaxCategory>TaxScheme>TaxTypeCode"` // 0015 = KDV
+}
+
+// ublKDVTypeCode: GİB vergi kodu listesinde KDV
+const ublKDVTypeCode = "0015"
+
+// ublUnitNames: UN/ECE birim kodlarının sistemde kullandığımız karşılıkları
+var ublUnitNames = map[string]string{
+	"C62": "Adet",
+	"NIU": "Adet",
+	"KGM": "Kilogram",
+	"GRM": "Gram",
+	"LTR": "Litre",
+	"MLT": "Mililitre",
+	"PA":  "Paket",
+	"BX":  "Kutu",
+	"CS":  "Koli",
+	"PR":  "Çift",
+	"SET": "Set",
+	"MTR": "Metre",
+}
+
+// vatRate: Satırın KDV oranı (yüzde)
+func (l ublInvoiceLine) vatRate() float64 {
+	for _, t := range l.TaxSubtotals {
+		if t.TaxTypeCode == ublKDVTypeCode {
+			return t.Percent
+		}
+	}
+	if len(l.TaxSubtotals) > 0 {
+		return l.TaxSubtotals[0].Percent
+	}
+	return 0
+}
+
+// taxID: Satıcının VKN/TCKN numarası
+func (p ublParty) taxID() string {
+	for _, id := range p.Identifications {
+		if id.SchemeID == "VKN" || id.SchemeID == "TCKN" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+func parseUBLInvoice(data []byte) (*ublInvoice, error) {
+	var inv ublInvoice
+	if err := xml.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("e-Fatura XML okunamadı: %v", err)
+	}
+	if len(inv.Lines) == 0 {
+		return nil, fmt.Errorf("e-Fatura'da fatura satırı bulunamadı")
+	}
+	return &inv, nil
+}
+
+// ublInvoiceParser: GİB e-Fatura / e-Arşiv UBL-TR XML faturası
+type ublInvoiceParser struct{}
+
+func (ublInvoiceParser) Name() string { return "ubl_tr" }
+
+func (ublInvoiceParser) Match(doc OrderDocument) bool {
+	return doc.ContentType == ContentTypeXML && bytes.Contains(doc.Data, []byte("InvoiceLine"))
+}
+
+func (ublInvoiceParser) Parse(doc OrderDocument) (*ParsePDFResponse, error) {
+	inv, err := parseUBLInvoice(doc.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]ParsedProduct, 0, len(inv.Lines))
+	for _, line := range inv.Lines {
+		stockCode := strings.TrimSpace(line.SellersItemID)
+		if stockCode == "" {
+			stockCode = strings.TrimSpace(line.BuyersItemID)
+		}
+
+		quantity := line.Quantity.Value
+		unit := ublUnitNames[line.Quantity.UnitCode]
+		if unit == "" {
+			unit = line.Quantity.UnitCode
+		}
+
+		unitPrice := line.PriceAmount
+		if unitPrice == 0 && quantity != 0 {
+			unitPrice = line.LineExtensionAmount / quantity
+		}
+
+		totalAmount := line.LineExtensionAmount + line.TaxAmount
+		unitPriceWithVAT := 0.0
+		if quantity != 0 {
+			unitPriceWithVAT = totalAmount / quantity
+		}
+
+		products = append(products, ParsedProduct{
+			StockCode:        stockCode,
+			ProductName:      strings.TrimSpace(line.ItemName),
+			UnitPrice:        unitPrice,
+			UnitPriceWithVAT: unitPriceWithVAT,
+			Quantity:         quantity,
+			QuantityUnit:     unit,
+			TotalAmount:      totalAmount,
+			VATRate:          line.vatRate(),
+		})
+	}
+
+	matchParsedProducts(products)
+	return &ParsePDFResponse{
+		Products:      products,
+		Date:          inv.IssueDate,
+		OrderNumber:   inv.ID,
+		SupplierName:  strings.TrimSpace(inv.Supplier.Name),
+		SupplierTaxID: inv.Supplier.taxID(),
+	}, nil
+}
@@ -0,0 +1,313 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+
+	"gorm.io/gorm"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("shipment", audit.EntityConfig{
+		Model: &models.Shipment{},
+		Children: []audit.ChildRelation{
+			{Key: "Items", Model: &models.ShipmentItem{}, ForeignKey: "shipment_id"},
+		},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncShipmentMovements,
+	})
+	audit.RegisterEntity("center_shipment", audit.EntityConfig{
+		Model:        &models.CenterShipment{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncCenterShipmentMovement,
+	})
+	audit.RegisterEntity("stock_snapshot", audit.EntityConfig{
+		Model:        &models.StockSnapshot{},
+		BranchColumn: "branch_id",
+		DateColumn:   "snapshot_date",
+	})
+	audit.RegisterEntity("stock_entry", audit.EntityConfig{
+		Model:        &models.StockEntry{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncStockEntryMovement,
+	})
+	audit.RegisterEntity("waste_entry", audit.EntityConfig{
+		Model:        &models.WasteEntry{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncWasteMovement,
+	})
+	// Transfer iki şubeyi ilgilendirdiği için şube/dönem kontrolleri OnChange içinde yapılır
+	audit.RegisterEntity("stock_transfer", audit.EntityConfig{
+		Model: &models.StockTransfer{},
+		Children: []audit.ChildRelation{
+			{Key: "Items", Model: &models.StockTransferItem{}, ForeignKey: "transfer_id"},
+		},
+		OnChange: syncTransferMovements,
+	})
+	// Manuel stok düzeltmeleri
+	audit.RegisterEntity("stock_movement", audit.EntityConfig{
+		Model:        &models.StockMovement{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncCorrectionMovement,
+	})
+	// Şube bazlı minimum / hedef stok seviyeleri
+	audit.RegisterEntity("par_level", audit.EntityConfig{
+		Model:        &models.ParLevel{},
+		BranchColumn: "branch_id",
+	})
+	// Eşleşmiş sevkiyatı olan siparişin oluşturulması geri alınamaz
+	audit.RegisterEntity("purchase_order", audit.EntityConfig{
+		Model: &models.PurchaseOrder{},
+		Children: []audit.ChildRelation{
+			{Key: "Lines", Model: &models.PurchaseOrderLine{}, ForeignKey: "purchase_order_id"},
+			{Key: "Shipments", Model: &models.Shipment{}, ForeignKey: "purchase_order_id", Restrict: true},
+		},
+		BranchColumn: "branch_id",
+		DateColumn:   "order_date",
+		OnChange:     syncPurchaseOrderStatus,
+	})
+	// Sayım oturumu: onay geri alınınca oturumun yazdığı stok girişleri ve sayım hareketleri silinir
+	audit.RegisterEntity("count_session", audit.EntityConfig{
+		Model: &models.CountSession{},
+		Children: []audit.ChildRelation{
+			{Key: "Lines", Model: &models.CountSessionLine{}, ForeignKey: "session_id"},
+		},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+		OnChange:     syncCountSessionEntries,
+	})
+	// B2B katalog senkronizasyonundaki ürün değişiklikleri
+	audit.RegisterEntity("product", audit.EntityConfig{
+		Model: &models.Product{},
+	})
+}
+
+// Undo sonrası stok defterini kaydın son haliyle eşitle:
+// kayda ait hareketler silinir, kayıt hâlâ varsa yeniden yazılır.
+
+func syncShipmentMovements(tx *gorm.DB, before, after any) error {
+	var id uint
+	if s, ok := before.(*models.Shipment); ok {
+		id = s.ID
+	}
+	s, ok := after.(*models.Shipment)
+	if ok {
+		id = s.ID
+	}
+	if err := ledger.RemoveSource(tx, ledger.SourceShipment, id); err != nil {
+		return err
+	}
+	if ok && s.IsStocked {
+		if err := tx.Where("shipment_id = ?", s.ID).Find(&s.Items).Error; err != nil {
+			return err
+		}
+		if err := ledger.Record(tx, ledger.ShipmentMovements(*s)...); err != nil {
+			return err
+		}
+	}
+	return refreshShipmentOrders(tx, before, after)
+}
+
+// refreshShipmentOrders: Sevkiyatın önceki ve sonraki halinde eşleştiği siparişlerin
+// teslim durumu yeniden hesaplanır (taslak siparişler hariç)
+func refreshShipmentOrders(tx *gorm.DB, before, after any) error {
+	seen := map[uint]bool{}
+	for _, v := range []any{before, after} {
+		s, ok := v.(*models.Shipment)
+		if !ok || s.PurchaseOrderID == nil || seen[*s.PurchaseOrderID] {
+			continue
+		}
+		seen[*s.PurchaseOrderID] = true
+
+		var po models.PurchaseOrder
+		err := tx.Preload("Lines").First(&po, "id = ?", *s.PurchaseOrderID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if po.Status == models.PurchaseOrderDraft {
+			continue
+		}
+		if err := refreshPurchaseOrderStatus(tx, &po); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sipariş geri alındığında teslim durumu eşleşmiş sevkiyatlardan yeniden hesaplanır;
+// sevkiyat eşleşmiş sipariş taslağa döndürülemez
+func syncPurchaseOrderStatus(tx *gorm.DB, before, after any) error {
+	po, ok := after.(*models.PurchaseOrder)
+	if !ok {
+		return nil
+	}
+	if po.Status == models.PurchaseOrderDraft {
+		var count int64
+		if err := tx.Model(&models.Shipment{}).Where("purchase_order_id = ?", po.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("siparişe eşleşmiş %d sevkiyat var, taslağa döndürülemez", count)
+		}
+		return nil
+	}
+	if err := tx.Where("purchase_order_id = ?", po.ID).Order("id").Find(&po.Lines).Error; err != nil {
+		return err
+	}
+	return refreshPurchaseOrderStatus(tx, po)
+}
+
+func syncCenterShipmentMovement(tx *gorm.DB, before, after any) error {
+	if cs, ok := before.(*models.CenterShipment); ok {
+		if err := ledger.RemoveSource(tx, ledger.SourceCenterShipment, cs.ID); err != nil {
+			return err
+		}
+	}
+	if cs, ok := after.(*models.CenterShipment); ok {
+		return ledger.Record(tx, ledger.CenterShipmentMovement(*cs))
+	}
+	return nil
+}
+
+func syncStockEntryMovement(tx *gorm.DB, before, after any) error {
+	if e, ok := before.(*models.StockEntry); ok {
+		if err := ledger.RemoveSource(tx, ledger.SourceStockEntry, e.ID); err != nil {
+			return err
+		}
+	}
+	if e, ok := after.(*models.StockEntry); ok {
+		_, err := ledger.RecordCount(tx, *e)
+		return err
+	}
+	return nil
+}
+
+// Düzeltme hareketi doğrudan geri yazıldığı/silindiği için defter üzerinden yeniden yazılır
+// (kilit alınır, sonraki sayımlar yeniden hesaplanır)
+func syncCorrectionMovement(tx *gorm.DB, before, after any) error {
+	if m, ok := before.(*models.StockMovement); ok {
+		if err := ledger.RemoveMovement(tx, *m); err != nil {
+			return err
+		}
+	}
+	if m, ok := after.(*models.StockMovement); ok {
+		if err := ledger.RemoveMovement(tx, *m); err != nil {
+			return err
+		}
+		return ledger.Record(tx, *m)
+	}
+	return nil
+}
+
+// Onaylı oturumun stok girişlerinin sayım hareketleri silinir; oturum hâlâ onaylıysa
+// yeniden yazılır, değilse girişler silinip oturum onay öncesine döner
+func syncCountSessionEntries(tx *gorm.DB, before, after any) error {
+	b, ok := before.(*models.CountSession)
+	if !ok || b.Status != models.CountSessionApproved {
+		return nil
+	}
+	var lines []models.CountSessionLine
+	if err := tx.Where("session_id = ? AND stock_entry_id IS NOT NULL", b.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if err := ledger.RemoveSource(tx, ledger.SourceStockEntry, *l.StockEntryID); err != nil {
+			return err
+		}
+	}
+
+	if a, ok := after.(*models.CountSession); ok && a.Status == models.CountSessionApproved {
+		for _, l := range lines {
+			var entry models.StockEntry
+			if err := tx.First(&entry, "id = ?", *l.StockEntryID).Error; err != nil {
+				return err
+			}
+			if _, err := ledger.RecordCount(tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, l := range lines {
+		if err := tx.Delete(&models.StockEntry{}, "id = ?", *l.StockEntryID).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.CountSessionLine{}).Where("session_id = ?", b.ID).Updates(map[string]interface{}{
+		"stock_entry_id":    nil,
+		"expected_quantity": nil,
+	}).Error; err != nil {
+		return err
+	}
+	if after == nil {
+		return nil
+	}
+	return tx.Model(&models.CountSession{}).Where("id = ?", b.ID).Updates(map[string]interface{}{
+		"approved_by": nil,
+		"approved_at": nil,
+	}).Error
+}
+
+func syncWasteMovement(tx *gorm.DB, before, after any) error {
+	if w, ok := before.(*models.WasteEntry); ok {
+		if err := ledger.RemoveSource(tx, ledger.SourceWasteEntry, w.ID); err != nil {
+			return err
+		}
+	}
+	if w, ok := after.(*models.WasteEntry); ok {
+		return ledger.Record(tx, ledger.WasteMovement(*w))
+	}
+	return nil
+}
+
+func syncTransferMovements(tx *gorm.DB, before, after any) error {
+	// Etkilenen hareketlerin tarihleri her iki şubede de açık dönemde olmalı
+	for _, v := range []any{before, after} {
+		t, ok := v.(*models.StockTransfer)
+		if !ok {
+			continue
+		}
+		if err := period.EnsureOpen(t.FromBranchID, t.SentDate); err != nil {
+			return err
+		}
+		if t.ReceivedDate != nil {
+			if err := period.EnsureOpen(t.ToBranchID, *t.ReceivedDate); err != nil {
+				return err
+			}
+		}
+	}
+
+	var id uint
+	if t, ok := before.(*models.StockTransfer); ok {
+		id = t.ID
+	}
+	t, ok := after.(*models.StockTransfer)
+	if ok {
+		id = t.ID
+	}
+	if err := ledger.RemoveSource(tx, ledger.SourceStockTransfer, id); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if err := tx.Where("transfer_id = ?", t.ID).Find(&t.Items).Error; err != nil {
+		return err
+	}
+	return ledger.Record(tx, ledger.TransferMovements(*t)...)
+}
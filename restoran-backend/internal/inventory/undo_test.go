@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+)
+
+// Sayım onayı geri alınınca oturumun yazdığı stok girişleri ve sayım hareketleri silinir
+func TestUndoCountSessionApprovalRemovesEntries(t *testing.T) {
+	db := database.DB
+	date := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)
+	counted := 7.0
+
+	session := models.CountSession{BranchID: 1, Date: date, Status: models.CountSessionApproved, OpenedBy: 1, Lines: []models.CountSessionLine{
+		{ProductID: 1, SortOrder: 0, CountedQuantity: &counted},
+	}}
+	if err := db.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	entry := models.StockEntry{BranchID: 1, ProductID: 1, Date: date, Quantity: counted}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.RecordCount(db, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.CountSessionLine{}).Where("session_id = ?", session.ID).
+		Updates(map[string]interface{}{"stock_entry_id": entry.ID, "expected_quantity": 0}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.WriteLog(audit.LogOptions{
+		BranchID:   &session.BranchID,
+		EntityType: "count_session",
+		EntityID:   session.ID,
+		Action:     models.AuditActionUpdate,
+		Before:     map[string]interface{}{"id": session.ID, "status": models.CountSessionSubmitted},
+		After:      map[string]interface{}{"id": session.ID, "status": models.CountSessionApproved},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var log models.AuditLog
+	if err := db.Where("entity_type = ? AND entity_id = ?", "count_session", session.ID).Last(&log).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := audit.UndoLog(log.ID, 1, "test"); err != nil {
+		t.Fatalf("geri alınamadı: %v", err)
+	}
+
+	var got models.CountSession
+	if err := db.Preload("Lines").First(&got, session.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.CountSessionSubmitted {
+		t.Errorf("durum = %s, %s bekleniyordu", got.Status, models.CountSessionSubmitted)
+	}
+	if l := got.Lines[0]; l.StockEntryID != nil || l.ExpectedQuantity != nil {
+		t.Errorf("sayım satırı temizlenmedi: stock_entry_id=%v expected=%v", l.StockEntryID, l.ExpectedQuantity)
+	}
+	var entries, movements int64
+	db.Model(&models.StockEntry{}).Where("id = ?", entry.ID).Count(&entries)
+	db.Model(&models.StockMovement{}).Where("source_type = ? AND source_id = ?", ledger.SourceStockEntry, entry.ID).Count(&movements)
+	if entries != 0 || movements != 0 {
+		t.Errorf("stok girişi = %d, hareket = %d; ikisi de silinmeliydi", entries, movements)
+	}
+}
+
+// Sevkiyatın oluşturulması geri alınınca eşleştiği siparişin teslim durumu yeniden hesaplanır
+func TestUndoShipmentRefreshesPurchaseOrderStatus(t *testing.T) {
+	db := database.DB
+	date := time.Date(2026, time.March, 12, 0, 0, 0, 0, time.UTC)
+
+	po := models.PurchaseOrder{BranchID: 1, Supplier: "Merkez", Status: models.PurchaseOrderSent, OrderDate: date, TotalAmount: 50, CreatedBy: 1,
+		Lines: []models.PurchaseOrderLine{{ProductID: 1, Quantity: 5, ExpectedUnitPrice: 10}},
+	}
+	if err := db.Create(&po).Error; err != nil {
+		t.Fatal(err)
+	}
+	shipment := models.Shipment{BranchID: 1, Date: date, TotalAmount: 50, PurchaseOrderID: &po.ID, Items: []models.ShipmentItem{
+		{ProductID: 1, Quantity: 5, UnitPrice: 10, UnitPriceWithVAT: 10, TotalPrice: 50, PurchaseOrderLineID: &po.Lines[0].ID},
+	}}
+	if err := db.Create(&shipment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshPurchaseOrderStatus(db, &po); err != nil {
+		t.Fatal(err)
+	}
+	if po.Status != models.PurchaseOrderReceived {
+		t.Fatalf("sipariş durumu = %s, %s bekleniyordu", po.Status, models.PurchaseOrderReceived)
+	}
+
+	if err := audit.WriteLog(audit.LogOptions{
+		BranchID:   &shipment.BranchID,
+		EntityType: "shipment",
+		EntityID:   shipment.ID,
+		Action:     models.AuditActionCreate,
+		After:      shipment,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var log models.AuditLog
+	if err := db.Where("entity_type = ? AND entity_id = ?", "shipment", shipment.ID).Last(&log).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.UndoLog(log.ID, 1, "test"); err != nil {
+		t.Fatalf("geri alınamadı: %v", err)
+	}
+
+	var got models.PurchaseOrder
+	if err := db.First(&got, po.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.PurchaseOrderSent || got.ReceivedAt != nil {
+		t.Errorf("sipariş durumu = %s (received_at=%v), %s bekleniyordu", got.Status, got.ReceivedAt, models.PurchaseOrderSent)
+	}
+}
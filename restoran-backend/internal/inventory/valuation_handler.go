@@ -0,0 +1,116 @@
+package inventory
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StockValuationItem struct {
+	ProductID       uint    `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	Category        string  `json:"category"`
+	Unit            string  `json:"unit"`
+	Quantity        float64 `json:"quantity"`
+	FIFOUnitCost    float64 `json:"fifo_unit_cost"`
+	FIFOValue       float64 `json:"fifo_value"`
+	AverageUnitCost float64 `json:"average_unit_cost"`
+	AverageValue    float64 `json:"average_value"`
+	Priced          bool    `json:"priced"` // false: hiç fiyatlı giriş yok, değer 0
+}
+
+type StockValuationResponse struct {
+	BranchID          uint                 `json:"branch_id"`
+	Date              string               `json:"date"` // bu günün sonu itibarıyla
+	Items             []StockValuationItem `json:"items"`
+	TotalFIFOValue    float64              `json:"total_fifo_value"`
+	TotalAverageValue float64              `json:"total_average_value"`
+	UnpricedCount     int                  `json:"unpriced_count"`
+}
+
+// GET /api/stock-valuation?date=2025-12-31&branch_id=...
+// Eldeki stoğun FIFO ve ağırlıklı ortalama maliyetle değeri (tarih verilmezse bugün)
+func StockValuationHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if dateStr := c.Query("date"); dateStr != "" {
+			day, err = time.Parse("2006-01-02", dateStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+		}
+
+		valuations, err := ledger.ValueStock(database.DB, branchID, day.AddDate(0, 0, 1))
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok değeri hesaplanamadı")
+		}
+
+		productIDs := make([]uint, 0, len(valuations))
+		for id := range valuations {
+			productIDs = append(productIDs, id)
+		}
+		var products []models.Product
+		if len(productIDs) > 0 {
+			if err := database.DB.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Ürünler okunamadı")
+			}
+		}
+
+		resp := StockValuationResponse{
+			BranchID: branchID,
+			Date:     day.Format("2006-01-02"),
+			Items:    make([]StockValuationItem, 0, len(products)),
+		}
+		for _, p := range products {
+			v := valuations[p.ID]
+			item := StockValuationItem{
+				ProductID:       p.ID,
+				ProductName:     p.Name,
+				Category:        p.Category,
+				Unit:            p.Unit,
+				Quantity:        v.Quantity,
+				FIFOValue:       round2(v.FIFOValue),
+				AverageUnitCost: round2(v.AverageCost),
+				AverageValue:    round2(v.AverageValue),
+				Priced:          v.Priced,
+			}
+			if v.Quantity > 0 {
+				item.FIFOUnitCost = round2(v.FIFOValue / v.Quantity)
+			}
+			if !v.Priced {
+				resp.UnpricedCount++
+			}
+			resp.TotalFIFOValue += v.FIFOValue
+			resp.TotalAverageValue += v.AverageValue
+			resp.Items = append(resp.Items, item)
+		}
+		resp.TotalFIFOValue = round2(resp.TotalFIFOValue)
+		resp.TotalAverageValue = round2(resp.TotalAverageValue)
+
+		sort.Slice(resp.Items, func(i, j int) bool {
+			if resp.Items[i].Category != resp.Items[j].Category {
+				return resp.Items[i].Category < resp.Items[j].Category
+			}
+			return resp.Items[i].ProductName < resp.Items[j].ProductName
+		})
+
+		return c.JSON(resp)
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -2,61 +2,71 @@ package inventory
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
+	"restoran-backend/internal/ledger"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type CreateWasteEntryRequest struct {
-	Date     string  `json:"date"`      // "2025-12-09"
-	ProductID uint   `json:"product_id"` // zorunlu
-	Quantity float64 `json:"quantity"`   // zorunlu, zayiat miktarı
-	Note     string  `json:"note"`       // zorunlu: hangi garson/mutfakçı sebep oldu
-	BranchID *uint   `json:"branch_id"`  // super_admin için
+	Date      string  `json:"date"`       // "2025-12-09"
+	ProductID uint    `json:"product_id"` // zorunlu
+	Quantity  float64 `json:"quantity"`   // zorunlu, zayiat miktarı
+	Unit      string  `json:"unit"`       // opsiyonel, boşsa ürünün temel birimi
+	// Zayiatın ait olduğu parti - opsiyonel (shipment_item_id veya lot_number)
+	ShipmentItemID *uint  `json:"shipment_item_id"`
+	LotNumber      string `json:"lot_number"`
+	Note           string `json:"note"`      // zorunlu: hangi garson/mutfakçı sebep oldu
+	BranchID       *uint  `json:"branch_id"` // super_admin için
 }
 
 type WasteEntryResponse struct {
-	ID         uint    `json:"id"`
-	BranchID   uint    `json:"branch_id"`
-	ProductID  uint    `json:"product_id"`
-	ProductName string `json:"product_name"`
-	Date       string  `json:"date"`
-	Quantity   float64 `json:"quantity"`
-	Note       string  `json:"note"`
-	CreatedAt  string  `json:"created_at"`
+	ID             uint    `json:"id"`
+	BranchID       uint    `json:"branch_id"`
+	ProductID      uint    `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	Date           string  `json:"date"`
+	Quantity       float64 `json:"quantity"` // temel birimde
+	BaseUnit       string  `json:"base_unit"`
+	Unit           string  `json:"unit"`          // girilen birim
+	UnitQuantity   float64 `json:"unit_quantity"` // girilen birimdeki miktar
+	Note           string  `json:"note"`
+	CreatedAt      string  `json:"created_at"`
+	ShipmentItemID *uint   `json:"shipment_item_id,omitempty"` // parti
+	LotNumber      string  `json:"lot_number,omitempty"`
+	ExpiryDate     string  `json:"expiry_date,omitempty"`
 }
 
-// Yardımcı: Kullanıcı bilgilerini al
-func getUserInfoForWaste(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserRoleKey)
-	_, ok := userIDVal.(models.UserRole)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
+func toWasteEntryResponse(e models.WasteEntry, product models.Product) WasteEntryResponse {
+	resp := WasteEntryResponse{
+		ID:             e.ID,
+		BranchID:       e.BranchID,
+		ProductID:      e.ProductID,
+		ProductName:    product.Name,
+		Date:           e.Date.Format("2006-01-02"),
+		Quantity:       e.Quantity,
+		BaseUnit:       product.Unit,
+		Unit:           units.EnteredUnit(product.Unit, e.Unit),
+		UnitQuantity:   enteredQuantity(e.UnitQuantity, e.Quantity),
+		Note:           e.Note,
+		CreatedAt:      e.CreatedAt.Format("2006-01-02 15:04:05"),
+		ShipmentItemID: e.ShipmentItemID,
 	}
-
-	userIDVal2 := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal2.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
+	if e.ShipmentItem != nil {
+		resp.LotNumber = e.ShipmentItem.LotNumber
+		resp.ExpiryDate = formatDatePtr(e.ShipmentItem.ExpiryDate)
 	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
+	return resp
 }
 
 // POST /api/waste-entries
@@ -78,7 +88,7 @@ func CreateWasteEntryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "note zorunludur ve en az 3 karakter olmalıdır (hangi garson/mutfakçı sebep oldu)")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -94,27 +104,59 @@ func CreateWasteEntryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Ürün kontrolü
 		var product models.Product
 		if err := database.DB.First(&product, "id = ?", body.ProductID).Error; err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Ürün bulunamadı")
 		}
 
-		// Zayiat girişi oluştur
+		quantity, _, err := units.ToBase(database.DB, models.UnitKindProduct, product.ID, product.Name, product.Unit, body.Unit, body.Quantity)
+		if err != nil {
+			return err
+		}
+
+		// Parti verildiyse zayiat o partiden düşülür (partide yeterli miktar kalmış olmalı)
+		lot, err := resolveWasteLot(branchID, product, body.ShipmentItemID, body.LotNumber, quantity)
+		if err != nil {
+			return err
+		}
+
+		// Zayiat girişi oluştur (miktar temel birimde)
 		entry := models.WasteEntry{
-			BranchID:  branchID,
-			ProductID: body.ProductID,
-			Date:      d,
-			Quantity:  body.Quantity,
-			Note:      body.Note,
+			BranchID:     branchID,
+			ProductID:    body.ProductID,
+			Date:         d,
+			Quantity:     quantity,
+			Unit:         units.EnteredUnit(product.Unit, body.Unit),
+			UnitQuantity: body.Quantity,
+			Note:         body.Note,
+		}
+		if lot != nil {
+			entry.ShipmentItemID = &lot.ID
 		}
 
-		if err := database.DB.Create(&entry).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat girişi oluşturulamadı")
+		// Zayiat stoktan düşülür (kayıt ve defter hareketi birlikte)
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&entry).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Zayiat girişi oluşturulamadı")
+			}
+			if err := ledger.Record(tx, ledger.WasteMovement(entry)); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi oluşturulamadı")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		entry.ShipmentItem = lot
 
 		// Audit log
-		userID, userName, _, err := getUserInfoForWaste(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &branchID,
@@ -129,23 +171,14 @@ func CreateWasteEntryHandler() fiber.Handler {
 			})
 		}
 
-		return c.Status(fiber.StatusCreated).JSON(WasteEntryResponse{
-			ID:          entry.ID,
-			BranchID:    entry.BranchID,
-			ProductID:   entry.ProductID,
-			ProductName: product.Name,
-			Date:        entry.Date.Format("2006-01-02"),
-			Quantity:    entry.Quantity,
-			Note:        entry.Note,
-			CreatedAt:   entry.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		return c.Status(fiber.StatusCreated).JSON(toWasteEntryResponse(entry, product))
 	}
 }
 
 // GET /api/waste-entries
 func ListWasteEntriesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -154,7 +187,7 @@ func ListWasteEntriesHandler() fiber.Handler {
 		dateFrom := c.Query("date_from")
 		dateTo := c.Query("date_to")
 
-		query := database.DB.Preload("Product").
+		query := database.DB.Preload("Product").Preload("ShipmentItem").
 			Where("branch_id = ?", branchID)
 
 		if dateFrom != "" {
@@ -177,16 +210,7 @@ func ListWasteEntriesHandler() fiber.Handler {
 
 		resp := make([]WasteEntryResponse, 0, len(entries))
 		for _, e := range entries {
-			resp = append(resp, WasteEntryResponse{
-				ID:          e.ID,
-				BranchID:    e.BranchID,
-				ProductID:   e.ProductID,
-				ProductName: e.Product.Name,
-				Date:        e.Date.Format("2006-01-02"),
-				Quantity:    e.Quantity,
-				Note:        e.Note,
-				CreatedAt:   e.CreatedAt.Format("2006-01-02 15:04:05"),
-			})
+			resp = append(resp, toWasteEntryResponse(e, e.Product))
 		}
 
 		return c.JSON(resp)
@@ -199,20 +223,11 @@ func GetWasteEntryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var entry models.WasteEntry
-		if err := database.DB.Preload("Product").First(&entry, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).Preload("Product").Preload("ShipmentItem").First(&entry, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Zayiat girişi bulunamadı")
 		}
 
-		return c.JSON(WasteEntryResponse{
-			ID:          entry.ID,
-			BranchID:    entry.BranchID,
-			ProductID:   entry.ProductID,
-			ProductName: entry.Product.Name,
-			Date:        entry.Date.Format("2006-01-02"),
-			Quantity:    entry.Quantity,
-			Note:        entry.Note,
-			CreatedAt:   entry.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		return c.JSON(toWasteEntryResponse(entry, entry.Product))
 	}
 }
 
@@ -222,12 +237,17 @@ func DeleteWasteEntryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var entry models.WasteEntry
-		if err := database.DB.First(&entry, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&entry, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Zayiat girişi bulunamadı")
 		}
 
+		// Kapalı dönemdeki kayıt silinemez
+		if err := period.EnsureOpen(entry.BranchID, entry.Date); err != nil {
+			return err
+		}
+
 		// Audit log
-		userID, userName, _, err := getUserInfoForWaste(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &entry.BranchID,
@@ -242,8 +262,17 @@ func DeleteWasteEntryHandler() fiber.Handler {
 			})
 		}
 
-		if err := database.DB.Delete(&entry).Error; err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat girişi silinemedi")
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := ledger.RemoveSource(tx, ledger.SourceWasteEntry, entry.ID); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Stok hareketi silinemedi")
+			}
+			if err := tx.Delete(&entry).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Zayiat girişi silinemedi")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		return c.JSON(fiber.Map{
@@ -252,3 +281,211 @@ func DeleteWasteEntryHandler() fiber.Handler {
 	}
 }
 
+type WasteProductSummary struct {
+	ProductID   uint    `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Unit        string  `json:"unit"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"` // son sevkiyat birim fiyatı
+	Value       float64 `json:"value"`      // quantity * unit_price
+}
+
+type WastePersonSummary struct {
+	Person     string                `json:"person"` // zayiat notundan alınan sorumlu kişi
+	EntryCount int                   `json:"entry_count"`
+	Value      float64               `json:"value"`
+	Products   []WasteProductSummary `json:"products"`
+}
+
+type WasteSummary struct {
+	Products   []WasteProductSummary `json:"products"`
+	People     []WastePersonSummary  `json:"people"`
+	TotalValue float64               `json:"total_value"`
+}
+
+// responsiblePerson: Zayiat notundan sorumlu kişiyi çıkarır.
+// Not "Ahmet - tabak düştü", "Garson Ali: kırdı" gibi yazıldığı için ilk ayraca kadar olan kısım alınır.
+func responsiblePerson(note string) string {
+	person := note
+	for _, sep := range []string{" - ", ":", ",", "(", "/"} {
+		if i := strings.Index(person, sep); i >= 0 {
+			person = person[:i]
+		}
+	}
+	person = strings.Join(strings.Fields(person), " ")
+	if person == "" {
+		return "Belirtilmemiş"
+	}
+	return person
+}
+
+// summarizeWaste: [from, to) aralığındaki zayiatı ürün ve kişi bazında toplar.
+// Değerleme, dönem sonundan önceki son sevkiyat birim fiyatıyla yapılır.
+func summarizeWaste(branchID uint, from, to time.Time) (WasteSummary, error) {
+	summary := WasteSummary{
+		Products: make([]WasteProductSummary, 0),
+		People:   make([]WastePersonSummary, 0),
+	}
+
+	var entries []models.WasteEntry
+	if err := database.DB.Preload("Product").
+		Where("branch_id = ? AND date >= ? AND date < ?", branchID, from, to).
+		Order("date, id").
+		Find(&entries).Error; err != nil {
+		return summary, err
+	}
+	if len(entries) == 0 {
+		return summary, nil
+	}
+
+	prices, err := ledger.LastUnitPrices(database.DB, branchID, to)
+	if err != nil {
+		return summary, err
+	}
+
+	productIndex := make(map[uint]int)
+	personIndex := make(map[string]int)
+	personProductIndex := make(map[string]map[uint]int)
+	for _, e := range entries {
+		price := prices[e.ProductID]
+		value := price * e.Quantity
+		summary.TotalValue += value
+
+		i, ok := productIndex[e.ProductID]
+		if !ok {
+			i = len(summary.Products)
+			productIndex[e.ProductID] = i
+			summary.Products = append(summary.Products, WasteProductSummary{
+				ProductID:   e.ProductID,
+				ProductName: e.Product.Name,
+				Unit:        e.Product.Unit,
+				UnitPrice:   price,
+			})
+		}
+		summary.Products[i].Quantity += e.Quantity
+		summary.Products[i].Value += value
+
+		// Aynı kişi farklı büyük/küçük harfle yazılmış olabilir
+		person := responsiblePerson(e.Note)
+		key := strings.ToLower(person)
+		j, ok := personIndex[key]
+		if !ok {
+			j = len(summary.People)
+			personIndex[key] = j
+			personProductIndex[key] = make(map[uint]int)
+			summary.People = append(summary.People, WastePersonSummary{
+				Person:   person,
+				Products: make([]WasteProductSummary, 0),
+			})
+		}
+		p := &summary.People[j]
+		p.EntryCount++
+		p.Value += value
+		k, ok := personProductIndex[key][e.ProductID]
+		if !ok {
+			k = len(p.Products)
+			personProductIndex[key][e.ProductID] = k
+			p.Products = append(p.Products, WasteProductSummary{
+				ProductID:   e.ProductID,
+				ProductName: e.Product.Name,
+				Unit:        e.Product.Unit,
+				UnitPrice:   price,
+			})
+		}
+		p.Products[k].Quantity += e.Quantity
+		p.Products[k].Value += value
+	}
+
+	sort.Slice(summary.Products, func(i, j int) bool {
+		return summary.Products[i].Value > summary.Products[j].Value
+	})
+	sort.Slice(summary.People, func(i, j int) bool {
+		return summary.People[i].Value > summary.People[j].Value
+	})
+
+	return summary, nil
+}
+
+// GET /api/waste-entries/summary?from=...&to=...
+// Zayiatın ürün ve sorumlu kişi bazında miktar/değer özeti (varsayılan: bu ay)
+func WasteSummaryHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		to := from.AddDate(0, 1, 0)
+		if fromStr := c.Query("from"); fromStr != "" {
+			if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+		}
+		if toStr := c.Query("to"); toStr != "" {
+			d, err := time.Parse("2006-01-02", toStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+			}
+			to = d.AddDate(0, 0, 1)
+		}
+
+		summary, err := summarizeWaste(branchID, from, to)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat özeti hesaplanamadı")
+		}
+
+		return c.JSON(fiber.Map{
+			"branch_id":   branchID,
+			"from":        from.Format("2006-01-02"),
+			"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"products":    summary.Products,
+			"people":      summary.People,
+			"total_value": summary.TotalValue,
+		})
+	}
+}
+
+// resolveWasteLot: Zayiatın bağlanacağı partiyi bulur (id veya parti numarasıyla; aynı numaralı
+// birden fazla parti varsa kalanı olan en eskisi). Parti verilmemişse nil döner.
+func resolveWasteLot(branchID uint, product models.Product, shipmentItemID *uint, lotNumber string, quantity float64) (*models.ShipmentItem, error) {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if (shipmentItemID == nil || *shipmentItemID == 0) && lotNumber == "" {
+		return nil, nil
+	}
+
+	lots, err := ledger.Lots(database.DB, branchID, product.ID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Parti bakiyeleri hesaplanamadı")
+	}
+
+	var match *ledger.LotBalance
+	for i := range lots {
+		l := &lots[i]
+		if shipmentItemID != nil && *shipmentItemID != 0 {
+			if l.ShipmentItemID == *shipmentItemID {
+				match = l
+				break
+			}
+			continue
+		}
+		if l.LotNumber == lotNumber && l.Remaining > 0 {
+			match = l
+			break
+		}
+	}
+	if match == nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s için stokta parti bulunamadı", product.Name))
+	}
+	// Küçük kayan nokta farklarını yok say
+	if quantity > match.Remaining+1e-9 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Partide yeterli miktar yok (parti: %s, kalan: %.2f %s)", match.LotNumber, match.Remaining, product.Unit))
+	}
+
+	var item models.ShipmentItem
+	if err := database.DB.First(&item, "id = ?", match.ShipmentItemID).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Parti okunamadı")
+	}
+	return &item, nil
+}
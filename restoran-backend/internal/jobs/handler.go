@@ -0,0 +1,171 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type JobResponse struct {
+	ID              uint             `json:"id"`
+	Type            string           `json:"type"`
+	Status          models.JobStatus `json:"status"`
+	Params          json.RawMessage  `json:"params,omitempty"`
+	Cursor          string           `json:"cursor"`
+	Total           int              `json:"total"`
+	Processed       int              `json:"processed"`
+	Succeeded       int              `json:"succeeded"`
+	Skipped         int              `json:"skipped"`
+	ErrorCount      int              `json:"error_count"`
+	Errors          []string         `json:"errors"`
+	LastError       string           `json:"last_error,omitempty"`
+	Result          json.RawMessage  `json:"result,omitempty"`
+	Progress        float64          `json:"progress"` // yüzde
+	CancelRequested bool             `json:"cancel_requested"`
+	CreatedBy       uint             `json:"created_by"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	FinishedAt      *time.Time       `json:"finished_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// ToJobResponse: models.Job -> JobResponse
+func ToJobResponse(j models.Job) JobResponse {
+	resp := JobResponse{
+		ID:              j.ID,
+		Type:            j.Type,
+		Status:          j.Status,
+		Cursor:          j.Cursor,
+		Total:           j.Total,
+		Processed:       j.Processed,
+		Succeeded:       j.Succeeded,
+		Skipped:         j.Skipped,
+		ErrorCount:      j.ErrorCount,
+		Errors:          []string{},
+		LastError:       j.LastError,
+		CancelRequested: j.CancelRequested,
+		CreatedBy:       j.CreatedBy,
+		StartedAt:       j.StartedAt,
+		FinishedAt:      j.FinishedAt,
+		CreatedAt:       j.CreatedAt,
+		UpdatedAt:       j.UpdatedAt,
+	}
+	if j.Params != "" && json.Valid([]byte(j.Params)) {
+		resp.Params = json.RawMessage(j.Params)
+	}
+	if j.Result != "" && json.Valid([]byte(j.Result)) {
+		resp.Result = json.RawMessage(j.Result)
+	}
+	if j.Errors != "" {
+		_ = json.Unmarshal([]byte(j.Errors), &resp.Errors)
+	}
+	if j.Total > 0 {
+		resp.Progress = float64(j.Processed) / float64(j.Total) * 100
+	}
+	if j.Status == models.JobStatusCompleted {
+		resp.Progress = 100
+	}
+	return resp
+}
+
+// GET /api/admin/jobs?type=...&status=...
+func ListJobsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dbq := database.DB.Model(&models.Job{})
+		if t := c.Query("type"); t != "" {
+			dbq = dbq.Where("type = ?", t)
+		}
+		if s := c.Query("status"); s != "" {
+			dbq = dbq.Where("status = ?", s)
+		}
+
+		var list []models.Job
+		if err := dbq.Order("id desc").Limit(100).Find(&list).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "İşler listelenemedi")
+		}
+
+		resp := make([]JobResponse, 0, len(list))
+		for _, j := range list {
+			resp = append(resp, ToJobResponse(j))
+		}
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/admin/jobs/:id
+func GetJobHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := parseJobID(c)
+		if err != nil {
+			return err
+		}
+
+		var job models.Job
+		if err := database.DB.First(&job, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "İş bulunamadı")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "İş okunamadı")
+		}
+		return c.JSON(ToJobResponse(job))
+	}
+}
+
+// POST /api/admin/jobs/:id/cancel
+func CancelJobHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := parseJobID(c)
+		if err != nil {
+			return err
+		}
+
+		job, err := Cancel(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "İş bulunamadı")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "İş iptal edilemedi")
+		}
+		return c.JSON(ToJobResponse(job))
+	}
+}
+
+// POST /api/admin/jobs/:id/resume
+// İptal edilmiş veya başarısız işi son işlenen adımdan devam ettirir
+func ResumeJobHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := parseJobID(c)
+		if err != nil {
+			return err
+		}
+
+		job, err := Resume(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				return fiber.NewError(fiber.StatusNotFound, "İş bulunamadı")
+			case errors.Is(err, ErrNotResumable):
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			case errors.Is(err, ErrAlreadyRunning):
+				return fiber.NewError(fiber.StatusConflict, err.Error())
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "İş devam ettirilemedi")
+		}
+		return c.Status(fiber.StatusAccepted).JSON(ToJobResponse(job))
+	}
+}
+
+func parseJobID(c *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Geçersiz iş ID")
+	}
+	return uint(id), nil
+}
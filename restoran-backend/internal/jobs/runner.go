@@ -0,0 +1,349 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+)
+
+// RunFunc: İşi çalıştırır. İş, Progress.Cursor()'dan devam etmeli ve
+// her adımda Progress.Step ile ilerlemeyi kaydetmeli; ctx iptal edilince dönmeli.
+type RunFunc func(ctx context.Context, p *Progress) error
+
+// Hata listesinde saklanan en fazla mesaj sayısı
+const maxStoredErrors = 100
+
+var (
+	mu       sync.Mutex
+	handlers = make(map[string]RunFunc)
+	running  = make(map[uint]context.CancelFunc)
+)
+
+// Register: İş tipini çalıştıracak fonksiyonu kaydeder (main'de, ResumeInterrupted'dan önce)
+func Register(jobType string, fn RunFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[jobType] = fn
+}
+
+// Start: Yeni bir iş oluşturur ve arka planda başlatır.
+// Aynı tipte çalışan bir iş varsa yenisi başlatılmaz.
+func Start(jobType string, params any, userID uint) (models.Job, error) {
+	mu.Lock()
+	_, ok := handlers[jobType]
+	mu.Unlock()
+	if !ok {
+		return models.Job{}, fmt.Errorf("bilinmeyen iş tipi: %s", jobType)
+	}
+
+	var active int64
+	if err := database.DB.Model(&models.Job{}).
+		Where("type = ? AND status IN ?", jobType, []models.JobStatus{models.JobStatusQueued, models.JobStatusRunning}).
+		Count(&active).Error; err != nil {
+		return models.Job{}, err
+	}
+	if active > 0 {
+		return models.Job{}, ErrAlreadyRunning
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return models.Job{}, err
+	}
+	job := models.Job{
+		Type:      jobType,
+		Status:    models.JobStatusQueued,
+		Params:    string(raw),
+		Errors:    "[]",
+		CreatedBy: userID,
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		return models.Job{}, err
+	}
+
+	launch(job)
+	return job, nil
+}
+
+// ErrAlreadyRunning: Aynı tipte devam eden bir iş var
+var ErrAlreadyRunning = errors.New("bu tipte devam eden bir iş var")
+
+// ErrNotResumable: İş devam ettirilebilecek durumda değil
+var ErrNotResumable = errors.New("sadece iptal edilmiş veya başarısız işler devam ettirilebilir")
+
+// Cancel: İşi iptal eder; çalışıyorsa mevcut adım bitince durur
+func Cancel(id uint) (models.Job, error) {
+	var job models.Job
+	if err := database.DB.First(&job, "id = ?", id).Error; err != nil {
+		return job, err
+	}
+	if job.Status != models.JobStatusQueued && job.Status != models.JobStatusRunning {
+		return job, nil
+	}
+
+	job.CancelRequested = true
+	if err := database.DB.Model(&job).Update("cancel_requested", true).Error; err != nil {
+		return job, err
+	}
+
+	mu.Lock()
+	cancel, isRunning := running[id]
+	mu.Unlock()
+	if isRunning {
+		cancel()
+		return job, nil
+	}
+
+	// Bu süreçte çalışmıyor (örn. sunucu yeniden başlamadan önce kuyruktaydı)
+	now := time.Now()
+	job.Status = models.JobStatusCancelled
+	job.FinishedAt = &now
+	err := database.DB.Model(&job).Updates(map[string]interface{}{
+		"status":      job.Status,
+		"finished_at": now,
+	}).Error
+	return job, err
+}
+
+// Resume: İptal edilmiş veya başarısız işi kaldığı yerden (Cursor) devam ettirir
+func Resume(id uint) (models.Job, error) {
+	var job models.Job
+	if err := database.DB.First(&job, "id = ?", id).Error; err != nil {
+		return job, err
+	}
+	if job.Status != models.JobStatusCancelled && job.Status != models.JobStatusFailed {
+		return job, ErrNotResumable
+	}
+
+	var active int64
+	if err := database.DB.Model(&models.Job{}).
+		Where("type = ? AND status IN ? AND id <> ?", job.Type, []models.JobStatus{models.JobStatusQueued, models.JobStatusRunning}, job.ID).
+		Count(&active).Error; err != nil {
+		return job, err
+	}
+	if active > 0 {
+		return job, ErrAlreadyRunning
+	}
+
+	job.Status = models.JobStatusQueued
+	job.CancelRequested = false
+	job.LastError = ""
+	job.FinishedAt = nil
+	if err := database.DB.Model(&job).Updates(map[string]interface{}{
+		"status":           job.Status,
+		"cancel_requested": false,
+		"last_error":       "",
+		"finished_at":      nil,
+	}).Error; err != nil {
+		return job, err
+	}
+
+	launch(job)
+	return job, nil
+}
+
+// ResumeInterrupted: Sunucu kapanırken yarım kalan işleri yeniden başlatır (main'de bir kez çağrılır)
+func ResumeInterrupted() {
+	var jobs []models.Job
+	if err := database.DB.
+		Where("status IN ?", []models.JobStatus{models.JobStatusQueued, models.JobStatusRunning}).
+		Order("id asc").
+		Find(&jobs).Error; err != nil {
+		log.Printf("Yarım kalan işler okunamadı: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.CancelRequested {
+			now := time.Now()
+			database.DB.Model(&job).Updates(map[string]interface{}{
+				"status":      models.JobStatusCancelled,
+				"finished_at": now,
+			})
+			continue
+		}
+		log.Printf("Yarım kalan iş devam ettiriliyor: #%d %s (cursor: %s)", job.ID, job.Type, job.Cursor)
+		launch(job)
+	}
+}
+
+// launch: İşi goroutine içinde çalıştırır ve bitince durumunu kaydeder
+func launch(job models.Job) {
+	mu.Lock()
+	fn, ok := handlers[job.Type]
+	if !ok {
+		mu.Unlock()
+		finish(&job, models.JobStatusFailed, fmt.Sprintf("bilinmeyen iş tipi: %s", job.Type))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	running[job.ID] = cancel
+	mu.Unlock()
+
+	go func() {
+		defer func() {
+			mu.Lock()
+			delete(running, job.ID)
+			mu.Unlock()
+			cancel()
+		}()
+
+		p := &Progress{job: job}
+		if err := json.Unmarshal([]byte(job.Errors), &p.errors); err != nil {
+			p.errors = nil
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": models.JobStatusRunning}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		if err := database.DB.Model(&models.Job{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+			log.Printf("İş durumu güncellenemedi (#%d): %v", job.ID, err)
+		}
+
+		err := runSafely(ctx, fn, p)
+		switch {
+		case ctx.Err() != nil:
+			finish(&p.job, models.JobStatusCancelled, "")
+			log.Printf("İş iptal edildi: #%d %s", job.ID, job.Type)
+		case err != nil:
+			finish(&p.job, models.JobStatusFailed, err.Error())
+			log.Printf("İş başarısız: #%d %s: %v", job.ID, job.Type, err)
+		default:
+			finish(&p.job, models.JobStatusCompleted, "")
+			log.Printf("İş tamamlandı: #%d %s", job.ID, job.Type)
+		}
+	}()
+}
+
+func runSafely(ctx context.Context, fn RunFunc, p *Progress) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, p)
+}
+
+func finish(job *models.Job, status models.JobStatus, lastError string) {
+	if len(lastError) > 500 {
+		lastError = lastError[:500]
+	}
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.LastError = lastError
+	if err := database.DB.Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":      status,
+		"finished_at": now,
+		"last_error":  lastError,
+	}).Error; err != nil {
+		log.Printf("İş durumu kaydedilemedi (#%d): %v", job.ID, err)
+	}
+}
+
+// Progress: Çalışan işin parametrelerine erişim ve ilerleme kaydı
+type Progress struct {
+	job    models.Job
+	errors []string
+}
+
+// StepResult: Bir adımın sonucu
+type StepResult int
+
+const (
+	StepSucceeded StepResult = iota
+	StepSkipped
+	StepFailed
+)
+
+// ID: İşin ID'si
+func (p *Progress) ID() uint { return p.job.ID }
+
+// Cursor: Son kaydedilen adım ("" ise iş baştan başlıyor)
+func (p *Progress) Cursor() string { return p.job.Cursor }
+
+// CreatedBy: İşi başlatan kullanıcı
+func (p *Progress) CreatedBy() uint { return p.job.CreatedBy }
+
+// Params: İş parametrelerini v'ye çözer
+func (p *Progress) Params(v any) error {
+	return json.Unmarshal([]byte(p.job.Params), v)
+}
+
+// Result: Daha önce kaydedilmiş sonuç raporunu v'ye çözer (iş devam ettiriliyorsa)
+func (p *Progress) Result(v any) error {
+	if p.job.Result == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(p.job.Result), v)
+}
+
+// SetResult: İşin sonuç raporunu kaydeder
+func (p *Progress) SetResult(v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.job.Result = string(raw)
+	return database.DB.Model(&models.Job{}).Where("id = ?", p.job.ID).Update("result", p.job.Result).Error
+}
+
+// SetTotal: Toplam adım sayısını kaydeder
+func (p *Progress) SetTotal(total int) error {
+	p.job.Total = total
+	return database.DB.Model(&models.Job{}).Where("id = ?", p.job.ID).Update("total", total).Error
+}
+
+// AddError: Adımı bitirmeden hata mesajı ekler (örn. kritik olmayan uyarılar)
+func (p *Progress) AddError(msg string) {
+	p.job.ErrorCount++
+	p.errors = append(p.errors, msg)
+	if len(p.errors) > maxStoredErrors {
+		p.errors = p.errors[len(p.errors)-maxStoredErrors:]
+	}
+}
+
+// Step: Bir adımı tamamlar ve cursor ile birlikte sayaçları kaydeder.
+// Sunucu yeniden başlarsa iş bu cursor'dan sonra devam eder.
+func (p *Progress) Step(cursor string, result StepResult, errMsg string) error {
+	p.job.Cursor = cursor
+	p.job.Processed++
+	switch result {
+	case StepSucceeded:
+		p.job.Succeeded++
+	case StepSkipped:
+		p.job.Skipped++
+	}
+	if errMsg != "" {
+		p.AddError(errMsg)
+	}
+
+	errorsJSON, err := json.Marshal(p.errors)
+	if err != nil {
+		return err
+	}
+	p.job.Errors = string(errorsJSON)
+
+	return database.DB.Model(&models.Job{}).Where("id = ?", p.job.ID).Updates(map[string]interface{}{
+		"cursor":      p.job.Cursor,
+		"processed":   p.job.Processed,
+		"succeeded":   p.job.Succeeded,
+		"skipped":     p.job.Skipped,
+		"errors":      p.job.Errors,
+		"error_count": p.job.ErrorCount,
+	}).Error
+}
+
+// Counts: Güncel sayaçlar (işlenen, başarılı, atlanan, hata)
+func (p *Progress) Counts() (processed, succeeded, skipped, errorCount int) {
+	return p.job.Processed, p.job.Succeeded, p.job.Skipped, p.job.ErrorCount
+}
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// backfillEvent: Eski kayıtlardan üretilen hareket ve kaydın yazıldığı an
+type backfillEvent struct {
+	at       time.Time
+	movement models.StockMovement
+	count    *models.StockEntry // sayım ise fark, sayım tarihindeki bakiyeye göre hesaplanır
+}
+
+// date: Hareketin defterdeki tarihi
+func (e backfillEvent) date() time.Time {
+	if e.count != nil {
+		return e.count.Date
+	}
+	return e.movement.Date
+}
+
+// Backfill: Defter boşsa mevcut StockEntry, Shipment, CenterShipment ve WasteEntry
+// kayıtlarını hareketlere çevirir. Hareketler defterin kuralıyla aynı sırada (tarih, aynı tarihte
+// yazılma sırası) yazılır ki sayım farkları Record/RebaseCounts'un hesapladığıyla aynı olsun.
+// Defterde kayıt varsa hiçbir şey yapmaz.
+func Backfill(db *gorm.DB) (int, error) {
+	var existing int64
+	if err := db.Model(&models.StockMovement{}).Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, nil
+	}
+
+	var events []backfillEvent
+
+	// Sayımlar: eski StockShipmentHandler sevkiyat kalemlerini de "Sevkiyat #N" notuyla
+	// StockEntry olarak yazıyordu; bunlar sayım değil, sevkiyatın kendisinden aktarılıyor
+	var entries []models.StockEntry
+	if err := db.Find(&entries).Error; err != nil {
+		return 0, err
+	}
+	for i := range entries {
+		if strings.HasPrefix(entries[i].Note, "Sevkiyat #") {
+			continue
+		}
+		events = append(events, backfillEvent{at: entries[i].CreatedAt, count: &entries[i]})
+	}
+
+	// Stoka kaydedilmiş sevkiyatlar: stoka kaydedilme anı updated_at
+	var shipments []models.Shipment
+	if err := db.Preload("Items").Where("is_stocked = ?", true).Find(&shipments).Error; err != nil {
+		return 0, err
+	}
+	for _, s := range shipments {
+		for _, m := range ShipmentMovements(s) {
+			events = append(events, backfillEvent{at: s.UpdatedAt, movement: m})
+		}
+	}
+
+	var centerShipments []models.CenterShipment
+	if err := db.Find(&centerShipments).Error; err != nil {
+		return 0, err
+	}
+	for _, cs := range centerShipments {
+		events = append(events, backfillEvent{at: cs.CreatedAt, movement: CenterShipmentMovement(cs)})
+	}
+
+	var wastes []models.WasteEntry
+	if err := db.Find(&wastes).Error; err != nil {
+		return 0, err
+	}
+	for _, w := range wastes {
+		events = append(events, backfillEvent{at: w.CreatedAt, movement: WasteMovement(w)})
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	// Hareket ID'leri bu sırayla verilir; aynı tarihli hareketlerde ID sırası esas alındığı için
+	// tarih eşitse kaydın yazıldığı an belirleyicidir
+	sort.SliceStable(events, func(i, j int) bool {
+		di, dj := events[i].date(), events[j].date()
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	// Sayım farkları için şube/ürün bazında bakiye takibi
+	type key struct{ branchID, productID uint }
+	balances := make(map[key]float64)
+	movements := make([]models.StockMovement, 0, len(events))
+	for _, e := range events {
+		m := e.movement
+		if e.count != nil {
+			m = CountMovement(*e.count, balances[key{e.count.BranchID, e.count.ProductID}])
+		}
+		m.CreatedAt = e.at
+		balances[key{m.BranchID, m.ProductID}] += m.Quantity
+		movements = append(movements, m)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&movements, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(movements), nil
+}
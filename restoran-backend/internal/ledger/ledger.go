@@ -0,0 +1,337 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Hareketi oluşturan kayıt tipleri (StockMovement.SourceType)
+const (
+	SourceShipment       = "shipment"
+	SourceCenterShipment = "center_shipment"
+	SourceStockEntry     = "stock_entry"
+	SourceWasteEntry     = "waste_entry"
+	SourceStockTransfer  = "stock_transfer"
+)
+
+// Balance: Şubedeki ürünün defterdeki güncel bakiyesi
+func Balance(tx *gorm.DB, branchID, productID uint) (float64, error) {
+	var total float64
+	err := tx.Model(&models.StockMovement{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("branch_id = ? AND product_id = ?", branchID, productID).
+		Scan(&total).Error
+	return total, err
+}
+
+// BalanceAt: Şubedeki ürünün verilen tarihteki bakiyesi (o tarihe kadar yazılmış tüm hareketler)
+func BalanceAt(tx *gorm.DB, branchID, productID uint, at time.Time) (float64, error) {
+	var total float64
+	err := tx.Model(&models.StockMovement{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("branch_id = ? AND product_id = ? AND date <= ?", branchID, productID, at).
+		Scan(&total).Error
+	return total, err
+}
+
+// balanceBefore: Hareketten önceki bakiye; aynı tarihli hareketlerde yazılma sırası (ID) esas alınır
+func balanceBefore(tx *gorm.DB, m models.StockMovement) (float64, error) {
+	var total float64
+	err := tx.Model(&models.StockMovement{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("branch_id = ? AND product_id = ? AND (date < ? OR (date = ? AND id < ?))",
+			m.BranchID, m.ProductID, m.Date, m.Date, m.ID).
+		Scan(&total).Error
+	return total, err
+}
+
+// Balances: Şubedeki tüm ürünlerin defterdeki güncel bakiyeleri (ürün ID -> bakiye)
+func Balances(tx *gorm.DB, branchID uint) (map[uint]float64, error) {
+	type balanceRow struct {
+		ProductID uint
+		Quantity  float64
+	}
+	var rows []balanceRow
+	if err := tx.Model(&models.StockMovement{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Where("branch_id = ?", branchID).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	balances := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		balances[r.ProductID] = r.Quantity
+	}
+	return balances, nil
+}
+
+// BalancesAt: Şubedeki tüm ürünlerin verilen tarihteki bakiyeleri (BalanceAt gibi, o tarihe kadar
+// yazılmış tüm hareketler; sayım bu bakiyeye göre yazılır)
+func BalancesAt(tx *gorm.DB, branchID uint, at time.Time) (map[uint]float64, error) {
+	type balanceRow struct {
+		ProductID uint
+		Quantity  float64
+	}
+	var rows []balanceRow
+	if err := tx.Model(&models.StockMovement{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Where("branch_id = ? AND date <= ?", branchID, at).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	balances := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		balances[r.ProductID] = r.Quantity
+	}
+	return balances, nil
+}
+
+// Record: Hareketleri deftere yazar; sonraki tarihli sayım farkları yeniden hesaplanır
+func Record(tx *gorm.DB, movements ...models.StockMovement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+	keys := affectedProducts(movements)
+	if err := lockProducts(tx, keys); err != nil {
+		return err
+	}
+	if err := tx.Create(&movements).Error; err != nil {
+		return err
+	}
+	return rebaseProducts(tx, keys)
+}
+
+// RecordCount: Sayımı, sayılan miktar ile sayım tarihindeki bakiye arasındaki fark olarak deftere yazar.
+// Bakiye, aynı şube/ürüne eşzamanlı yazılan hareketler beklenerek kilit altında hesaplanır.
+func RecordCount(tx *gorm.DB, entry models.StockEntry) (models.StockMovement, error) {
+	keys := map[productKey]time.Time{{entry.BranchID, entry.ProductID}: entry.Date}
+	if err := lockProducts(tx, keys); err != nil {
+		return models.StockMovement{}, err
+	}
+	balance, err := BalanceAt(tx, entry.BranchID, entry.ProductID, entry.Date)
+	if err != nil {
+		return models.StockMovement{}, err
+	}
+	m := CountMovement(entry, balance)
+	if err := tx.Create(&m).Error; err != nil {
+		return models.StockMovement{}, err
+	}
+	// Geriye tarihli sayım: kendisinden sonraki sayımların farkı değişir
+	if err := rebaseProducts(tx, keys); err != nil {
+		return models.StockMovement{}, err
+	}
+	return m, nil
+}
+
+// RemoveSource: Bir kayda ait tüm hareketleri siler (kayıt silindiğinde / geri alındığında)
+func RemoveSource(tx *gorm.DB, sourceType string, sourceID uint) error {
+	var movements []models.StockMovement
+	if err := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Find(&movements).Error; err != nil {
+		return err
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+	keys := affectedProducts(movements)
+	if err := lockProducts(tx, keys); err != nil {
+		return err
+	}
+	if err := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Delete(&models.StockMovement{}).Error; err != nil {
+		return err
+	}
+	return rebaseProducts(tx, keys)
+}
+
+// RemoveMovement: Kaynağı olmayan tek bir hareketi (manuel düzeltme) siler; sonraki tarihli
+// sayım farkları yeniden hesaplanır
+func RemoveMovement(tx *gorm.DB, m models.StockMovement) error {
+	keys := affectedProducts([]models.StockMovement{m})
+	if err := lockProducts(tx, keys); err != nil {
+		return err
+	}
+	if err := tx.Delete(&models.StockMovement{}, "id = ?", m.ID).Error; err != nil {
+		return err
+	}
+	return rebaseProducts(tx, keys)
+}
+
+// productKey: Defterde bakiyesi ayrı tutulan şube/ürün çifti
+type productKey struct{ BranchID, ProductID uint }
+
+// affectedProducts: Hareketlerin dokunduğu şube/ürün çiftleri ve her birinin en erken hareket tarihi
+func affectedProducts(movements []models.StockMovement) map[productKey]time.Time {
+	keys := make(map[productKey]time.Time, len(movements))
+	for _, m := range movements {
+		k := productKey{m.BranchID, m.ProductID}
+		if from, ok := keys[k]; !ok || m.Date.Before(from) {
+			keys[k] = m.Date
+		}
+	}
+	return keys
+}
+
+// lockProducts: Şube/ürün defterlerini transaction sonuna kadar kilitler (PostgreSQL advisory lock).
+// Kilitler sabit sırayla alınır ki iki transaction birbirini beklerken kilitlenmesin.
+func lockProducts(tx *gorm.DB, keys map[productKey]time.Time) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	sorted := make([]productKey, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].BranchID != sorted[j].BranchID {
+			return sorted[i].BranchID < sorted[j].BranchID
+		}
+		return sorted[i].ProductID < sorted[j].ProductID
+	})
+	for _, k := range sorted {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(k.BranchID), int32(k.ProductID)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebaseProducts: Her şube/ürün için değişiklik tarihinden itibaren sayım farklarını yeniden hesaplar
+func rebaseProducts(tx *gorm.DB, keys map[productKey]time.Time) error {
+	for k, from := range keys {
+		if err := RebaseCounts(tx, k.BranchID, k.ProductID, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebaseCounts: from tarihinden itibaren sayım hareketlerinin farkını, sayılan miktar değişmeden
+// o anki defter bakiyesine göre yeniden yazar. Önceki bir hareket eklenip silindiğinde
+// sonraki sayımlar yine sayılan miktarı göstersin diye kronolojik sırayla işlenir.
+func RebaseCounts(tx *gorm.DB, branchID, productID uint, from time.Time) error {
+	var counts []models.StockMovement
+	if err := tx.Where("branch_id = ? AND product_id = ? AND type = ? AND date >= ? AND counted_quantity IS NOT NULL",
+		branchID, productID, models.MovementCountAdjustment, from).
+		Order("date, id").
+		Find(&counts).Error; err != nil {
+		return err
+	}
+	for _, m := range counts {
+		balance, err := balanceBefore(tx, m)
+		if err != nil {
+			return err
+		}
+		delta := *m.CountedQuantity - balance
+		if delta == m.Quantity {
+			continue
+		}
+		if err := tx.Model(&models.StockMovement{}).Where("id = ?", m.ID).
+			Update("quantity", delta).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShipmentMovements: Stoka kaydedilen sevkiyatın her kalemi için giriş hareketi
+func ShipmentMovements(s models.Shipment) []models.StockMovement {
+	movements := make([]models.StockMovement, 0, len(s.Items))
+	for _, item := range s.Items {
+		movements = append(movements, models.StockMovement{
+			BranchID:   s.BranchID,
+			ProductID:  item.ProductID,
+			Type:       models.MovementReceipt,
+			Quantity:   item.Quantity,
+			Date:       s.Date,
+			SourceType: SourceShipment,
+			SourceID:   s.ID,
+			Note:       fmt.Sprintf("Sevkiyat #%d", s.ID),
+		})
+	}
+	return movements
+}
+
+// CenterShipmentMovement: Merkezden gelen ürün kaydı için giriş hareketi
+func CenterShipmentMovement(cs models.CenterShipment) models.StockMovement {
+	return models.StockMovement{
+		BranchID:   cs.BranchID,
+		ProductID:  cs.ProductID,
+		Type:       models.MovementReceipt,
+		Quantity:   cs.Quantity,
+		Date:       cs.Date,
+		SourceType: SourceCenterShipment,
+		SourceID:   cs.ID,
+		Note:       cs.Note,
+	}
+}
+
+// WasteMovement: Zayiat kaydı için çıkış hareketi
+func WasteMovement(w models.WasteEntry) models.StockMovement {
+	return models.StockMovement{
+		BranchID:   w.BranchID,
+		ProductID:  w.ProductID,
+		Type:       models.MovementWaste,
+		Quantity:   -w.Quantity,
+		Date:       w.Date,
+		SourceType: SourceWasteEntry,
+		SourceID:   w.ID,
+		Note:       w.Note,
+	}
+}
+
+// CountMovement: Sayım kaydı için düzeltme hareketi (balance: sayım tarihindeki defter bakiyesi)
+func CountMovement(entry models.StockEntry, balance float64) models.StockMovement {
+	counted := entry.Quantity
+	return models.StockMovement{
+		BranchID:        entry.BranchID,
+		ProductID:       entry.ProductID,
+		Type:            models.MovementCountAdjustment,
+		Quantity:        counted - balance,
+		Date:            entry.Date,
+		CountedQuantity: &counted,
+		SourceType:      SourceStockEntry,
+		SourceID:        entry.ID,
+		Note:            entry.Note,
+	}
+}
+
+// TransferMovements: Transferin kaynak şubede çıkış, teslim alındıysa hedef şubede giriş hareketleri
+func TransferMovements(t models.StockTransfer) []models.StockMovement {
+	movements := make([]models.StockMovement, 0, 2*len(t.Items))
+	for _, item := range t.Items {
+		movements = append(movements, models.StockMovement{
+			BranchID:   t.FromBranchID,
+			ProductID:  item.ProductID,
+			Type:       models.MovementTransferOut,
+			Quantity:   -item.Quantity,
+			Date:       t.SentDate,
+			SourceType: SourceStockTransfer,
+			SourceID:   t.ID,
+			Note:       fmt.Sprintf("Transfer #%d (hedef şube #%d)", t.ID, t.ToBranchID),
+		})
+	}
+	if t.Status != models.TransferStatusReceived || t.ReceivedDate == nil {
+		return movements
+	}
+	for _, item := range t.Items {
+		movements = append(movements, models.StockMovement{
+			BranchID:   t.ToBranchID,
+			ProductID:  item.ProductID,
+			Type:       models.MovementTransferIn,
+			Quantity:   item.Quantity,
+			Date:       *t.ReceivedDate,
+			SourceType: SourceStockTransfer,
+			SourceID:   t.ID,
+			Note:       fmt.Sprintf("Transfer #%d (kaynak şube #%d)", t.ID, t.FromBranchID),
+		})
+	}
+	return movements
+}
@@ -0,0 +1,248 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("veritabanı açılamadı: %v", err)
+	}
+	if err := db.AutoMigrate(&models.StockMovement{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func receipt(id uint, d int, qty float64) models.StockMovement {
+	return models.StockMovement{
+		BranchID: 1, ProductID: 1, Type: models.MovementReceipt, Quantity: qty, Date: day(d),
+		SourceType: SourceShipment, SourceID: id,
+	}
+}
+
+func count(id uint, d int, qty float64) models.StockEntry {
+	return models.StockEntry{ID: id, BranchID: 1, ProductID: 1, Date: day(d), Quantity: qty}
+}
+
+// countQuantity - Sayım hareketinin güncel farkı
+func countQuantity(t *testing.T, db *gorm.DB, entryID uint) float64 {
+	t.Helper()
+	var m models.StockMovement
+	if err := db.Where("source_type = ? AND source_id = ?", SourceStockEntry, entryID).First(&m).Error; err != nil {
+		t.Fatalf("sayım hareketi bulunamadı: %v", err)
+	}
+	return m.Quantity
+}
+
+func TestRecordCountUsesBalanceAtCountDate(t *testing.T) {
+	db := openTestDB(t)
+	if err := Record(db, receipt(1, 1, 10), receipt(2, 3, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := RecordCount(db, count(1, 2, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Quantity != -2 {
+		t.Errorf("sayım farkı = %v, beklenen -2", m.Quantity)
+	}
+	if b, _ := Balance(db, 1, 1); b != 13 {
+		t.Errorf("bakiye = %v, beklenen 13", b)
+	}
+}
+
+func TestLaterCountsAreRebased(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(db *gorm.DB) error
+		// sayımlar: 2. gün 8, 4. gün 12 (arada 3. gün +5 giriş)
+		wantFirst, wantSecond float64
+		wantBalance           float64
+		firstRemoved          bool
+	}{
+		{
+			name:      "değişiklik yok",
+			change:    func(db *gorm.DB) error { return nil },
+			wantFirst: -2, wantSecond: -1, wantBalance: 12,
+		},
+		{
+			name: "ilk sayımdan önce zayiat eklendi",
+			change: func(db *gorm.DB) error {
+				return Record(db, WasteMovement(models.WasteEntry{ID: 1, BranchID: 1, ProductID: 1, Quantity: 3, Date: day(1)}))
+			},
+			wantFirst: 1, wantSecond: -1, wantBalance: 12,
+		},
+		{
+			name:      "ilk sayımdan önceki giriş silindi",
+			change:    func(db *gorm.DB) error { return RemoveSource(db, SourceShipment, 1) },
+			wantFirst: 8, wantSecond: -1, wantBalance: 12,
+		},
+		{
+			name:      "sayımlar arasındaki giriş silindi",
+			change:    func(db *gorm.DB) error { return RemoveSource(db, SourceShipment, 2) },
+			wantFirst: -2, wantSecond: 4, wantBalance: 12,
+		},
+		{
+			name:         "ilk sayım silindi",
+			change:       func(db *gorm.DB) error { return RemoveSource(db, SourceStockEntry, 1) },
+			firstRemoved: true, wantSecond: -3, wantBalance: 12,
+		},
+		{
+			name:      "geriye tarihli sayım eklendi",
+			change:    func(db *gorm.DB) error { _, err := RecordCount(db, count(3, 1, 6)); return err },
+			wantFirst: 2, wantSecond: -1, wantBalance: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if err := Record(db, receipt(1, 1, 10), receipt(2, 3, 5)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := RecordCount(db, count(1, 2, 8)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := RecordCount(db, count(2, 4, 12)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(db); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.firstRemoved {
+				if got := countQuantity(t, db, 1); got != tt.wantFirst {
+					t.Errorf("ilk sayım farkı = %v, beklenen %v", got, tt.wantFirst)
+				}
+			}
+			if got := countQuantity(t, db, 2); got != tt.wantSecond {
+				t.Errorf("ikinci sayım farkı = %v, beklenen %v", got, tt.wantSecond)
+			}
+			if b, _ := Balance(db, 1, 1); b != tt.wantBalance {
+				t.Errorf("bakiye = %v, beklenen %v", b, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestRemoveMovementRebasesLaterCounts(t *testing.T) {
+	db := openTestDB(t)
+	movements := []models.StockMovement{
+		{BranchID: 1, ProductID: 1, Type: models.MovementCorrection, Quantity: 4, Date: day(1)},
+		receipt(1, 1, 10),
+	}
+	if err := Record(db, movements...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordCount(db, count(1, 2, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if got := countQuantity(t, db, 1); got != -6 {
+		t.Fatalf("sayım farkı = %v, beklenen -6", got)
+	}
+
+	if err := RemoveMovement(db, movements[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := countQuantity(t, db, 1); got != -2 {
+		t.Errorf("düzeltme silindikten sonra sayım farkı = %v, beklenen -2", got)
+	}
+	if b, _ := Balance(db, 1, 1); b != 8 {
+		t.Errorf("bakiye = %v, beklenen 8", b)
+	}
+}
+
+func TestBackfillOrdersByMovementDate(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&models.StockEntry{}, &models.Shipment{}, &models.ShipmentItem{},
+		&models.CenterShipment{}, &models.WasteEntry{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// 1. gün 10 giriş; 4. gün sayımı 8 olarak girildi, ardından 3. güne geriye tarihli 3 zayiat yazıldı
+	rows := []any{
+		&models.CenterShipment{BranchID: 1, ProductID: 1, Date: day(1), Quantity: 10, CreatedAt: day(1)},
+		&models.StockEntry{BranchID: 1, ProductID: 1, Date: day(4), Quantity: 8, CreatedAt: day(4)},
+		&models.WasteEntry{BranchID: 1, ProductID: 1, Date: day(3), Quantity: 3, Note: "zayiat", CreatedAt: day(5)},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := Backfill(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("yazılan hareket = %d, beklenen 3", n)
+	}
+	// Sayım tarihindeki bakiye 7: fark +1 (yazılma sırasına göre hesaplansaydı -2 olurdu)
+	if got := countQuantity(t, db, 1); got != 1 {
+		t.Errorf("sayım farkı = %v, beklenen 1", got)
+	}
+
+	// Defterin kendi kuralıyla yeniden hesaplama sonucu değiştirmemeli
+	if err := RebaseCounts(db, 1, 1, day(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := countQuantity(t, db, 1); got != 1 {
+		t.Errorf("yeniden hesaplamadan sonra sayım farkı = %v, beklenen 1", got)
+	}
+}
+
+// Ay içindeki merkez sevkiyatı ve teslim alınan transfer alımlara eklenir, gönderilen transfer
+// düşülür; ay dışındaki ve başka şubenin kayıtları sayılmaz
+func TestPeriodPurchaseCosts(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&models.CenterShipment{}, &models.StockTransfer{}, &models.StockTransferItem{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	received := day(12)
+	nextMonth := time.Date(2026, time.April, 2, 0, 0, 0, 0, time.UTC)
+	rows := []any{
+		&models.CenterShipment{BranchID: 1, ProductID: 1, Date: day(5), Quantity: 10, UnitPrice: 4, TotalPrice: 40},
+		&models.CenterShipment{BranchID: 1, ProductID: 1, Date: nextMonth, Quantity: 10, UnitPrice: 4, TotalPrice: 40},
+		&models.CenterShipment{BranchID: 2, ProductID: 1, Date: day(5), Quantity: 10, UnitPrice: 4, TotalPrice: 40},
+		&models.StockTransfer{FromBranchID: 2, ToBranchID: 1, Status: models.TransferStatusReceived, SentDate: day(10), ReceivedDate: &received, SentBy: 1,
+			Items: []models.StockTransferItem{{ProductID: 1, Quantity: 2, UnitCost: 5, TotalCost: 10}}},
+		&models.StockTransfer{FromBranchID: 1, ToBranchID: 2, Status: models.TransferStatusSent, SentDate: day(20), SentBy: 1,
+			Items: []models.StockTransferItem{{ProductID: 1, Quantity: 3, UnitCost: 4, TotalCost: 12}}},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := PeriodPurchaseCosts(db, 1, day(1), time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CenterShipments != 40 || p.TransfersIn != 10 || p.TransfersOut != 12 {
+		t.Errorf("merkez = %v, gelen = %v, giden = %v; 40, 10, 12 bekleniyordu", p.CenterShipments, p.TransfersIn, p.TransfersOut)
+	}
+	if p.Net() != 38 {
+		t.Errorf("net alım = %v, beklenen 38", p.Net())
+	}
+}
@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// LotBalance: Stoka kaydedilmiş partili sevkiyat kaleminin eldeki kalan miktarı
+type LotBalance struct {
+	ShipmentItemID uint
+	ShipmentID     uint
+	ProductID      uint
+	LotNumber      string
+	ReceivedDate   time.Time
+	ExpiryDate     *time.Time
+	Received       float64 // partiyle giren miktar
+	Wasted         float64 // bu partiye bağlanmış zayiat
+	Remaining      float64 // FIFO tüketimden sonra kalan
+}
+
+// receiptLayersQuery: Şubeye giren tüm miktarlar (partili veya partisiz) - eskiden yeniye.
+// Parti bilgisi sadece sevkiyat kalemlerinde tutulur.
+const receiptLayersQuery = `
+	SELECT product_id, quantity, shipment_item_id, shipment_id, lot_number, received_date, expiry_date
+	FROM (
+		SELECT si.product_id, si.quantity, si.id AS shipment_item_id, s.id AS shipment_id, si.lot_number,
+			COALESCE(si.received_date, s.date) AS received_date, si.expiry_date, 1 AS src, si.id AS seq
+		FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.branch_id = ? AND s.is_stocked = ? AND (? = 0 OR si.product_id = ?)
+		UNION ALL
+		SELECT product_id, quantity, NULL, NULL, '', date, NULL, 0 AS src, id AS seq
+		FROM center_shipments
+		WHERE branch_id = ? AND (? = 0 OR product_id = ?)
+		UNION ALL
+		SELECT ti.product_id, ti.quantity, NULL, NULL, '', t.received_date, NULL, 2 AS src, ti.id AS seq
+		FROM stock_transfer_items ti
+		JOIN stock_transfers t ON t.id = ti.transfer_id
+		WHERE t.to_branch_id = ? AND t.status = ? AND (? = 0 OR ti.product_id = ?)
+	) layers
+	WHERE quantity > 0
+	ORDER BY product_id, received_date, src, seq`
+
+// Lots: Şubedeki partilerin kalan miktarları (productID 0 ise tüm ürünler).
+// Stok FIFO tüketilir: bir partiye bağlanmış zayiat önce o partiden düşülür, kalan defter
+// bakiyesi en yeni girişlerden geriye doğru dağıtılır; yani diğer tüm çıkışlar en eski partiden yapılmış sayılır.
+func Lots(tx *gorm.DB, branchID, productID uint) ([]LotBalance, error) {
+	type layerRow struct {
+		ProductID      uint
+		Quantity       float64
+		ShipmentItemID *uint
+		ShipmentID     *uint
+		LotNumber      string
+		ReceivedDate   time.Time
+		ExpiryDate     *time.Time
+	}
+	var layers []layerRow
+	if err := tx.Raw(receiptLayersQuery,
+		branchID, true, productID, productID,
+		branchID, productID, productID,
+		branchID, models.TransferStatusReceived, productID, productID).Scan(&layers).Error; err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[uint][]layerRow)
+	hasLot := make(map[uint]bool)
+	for _, l := range layers {
+		byProduct[l.ProductID] = append(byProduct[l.ProductID], l)
+		if l.ShipmentItemID != nil && (l.LotNumber != "" || l.ExpiryDate != nil) {
+			hasLot[l.ProductID] = true
+		}
+	}
+	if len(hasLot) == 0 {
+		return []LotBalance{}, nil
+	}
+
+	balances, err := Balances(tx, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	type wasteRow struct {
+		ShipmentItemID uint
+		Quantity       float64
+	}
+	var wastes []wasteRow
+	if err := tx.Model(&models.WasteEntry{}).
+		Select("shipment_item_id, SUM(quantity) AS quantity").
+		Where("branch_id = ? AND shipment_item_id IS NOT NULL", branchID).
+		Group("shipment_item_id").
+		Scan(&wastes).Error; err != nil {
+		return nil, err
+	}
+	wasted := make(map[uint]float64, len(wastes))
+	for _, w := range wastes {
+		wasted[w.ShipmentItemID] = w.Quantity
+	}
+
+	var lots []LotBalance
+	for pid := range hasLot {
+		productLayers := byProduct[pid]
+		remaining := balances[pid]
+		allocated := make([]float64, len(productLayers))
+		for i := len(productLayers) - 1; i >= 0 && remaining > 0; i-- {
+			available := productLayers[i].Quantity
+			if id := productLayers[i].ShipmentItemID; id != nil {
+				available -= wasted[*id]
+			}
+			if available <= 0 {
+				continue
+			}
+			if available > remaining {
+				available = remaining
+			}
+			allocated[i] = available
+			remaining -= available
+		}
+
+		for i, l := range productLayers {
+			if l.ShipmentItemID == nil || (l.LotNumber == "" && l.ExpiryDate == nil) {
+				continue
+			}
+			lot := LotBalance{
+				ShipmentItemID: *l.ShipmentItemID,
+				ProductID:      pid,
+				LotNumber:      l.LotNumber,
+				ReceivedDate:   l.ReceivedDate,
+				ExpiryDate:     l.ExpiryDate,
+				Received:       l.Quantity,
+				Wasted:         wasted[*l.ShipmentItemID],
+				Remaining:      allocated[i],
+			}
+			if l.ShipmentID != nil {
+				lot.ShipmentID = *l.ShipmentID
+			}
+			lots = append(lots, lot)
+		}
+	}
+
+	sort.Slice(lots, func(i, j int) bool {
+		if lots[i].ProductID != lots[j].ProductID {
+			return lots[i].ProductID < lots[j].ProductID
+		}
+		return lots[i].ReceivedDate.Before(lots[j].ReceivedDate)
+	})
+	return lots, nil
+}
@@ -0,0 +1,227 @@
+package ledger
+
+import (
+	"time"
+
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// lastPriceQuery: Ürün bazında en son giriş fiyatı (B2B sevkiyat kalemi KDV'li birim fiyatı
+// veya merkez sevkiyatı birim fiyatı; aynı gün ikisi de varsa sevkiyat kalemi tercih edilir)
+const lastPriceQuery = `
+	SELECT DISTINCT ON (product_id) product_id, unit_price
+	FROM (
+		SELECT si.product_id, si.unit_price_with_vat AS unit_price, s.date, 1 AS src, si.id AS seq
+		FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.date < ? AND (? = 0 OR s.branch_id = ?)
+		UNION ALL
+		SELECT product_id, unit_price, date, 0 AS src, id AS seq
+		FROM center_shipments
+		WHERE date < ? AND (? = 0 OR branch_id = ?)
+	) prices
+	ORDER BY product_id, date DESC, src DESC, seq DESC`
+
+// LastUnitPrices: before tarihinden önceki son sevkiyat birim fiyatları (ürün ID -> fiyat).
+// Şubede hiç sevkiyatı olmayan ürünler için diğer şubelerdeki son fiyat kullanılır.
+func LastUnitPrices(tx *gorm.DB, branchID uint, before time.Time) (map[uint]float64, error) {
+	type priceRow struct {
+		ProductID uint
+		UnitPrice float64
+	}
+
+	prices := make(map[uint]float64)
+	for _, scope := range []uint{branchID, 0} {
+		var rows []priceRow
+		if err := tx.Raw(lastPriceQuery, before, scope, scope, before, scope, scope).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			if _, ok := prices[r.ProductID]; !ok {
+				prices[r.ProductID] = r.UnitPrice
+			}
+		}
+		if branchID == 0 {
+			break
+		}
+	}
+	return prices, nil
+}
+
+// Stok değerleme yöntemleri
+const (
+	ValuationFIFO            = "fifo"
+	ValuationWeightedAverage = "weighted_average"
+)
+
+// ProductValuation: Ürünün belirli bir tarihteki eldeki miktarı ve maliyet değeri
+type ProductValuation struct {
+	ProductID    uint
+	Quantity     float64 // defterdeki bakiye
+	FIFOValue    float64 // eldeki miktar en son girişlerden kalmış sayılarak
+	AverageCost  float64 // tüm girişlerin miktar ağırlıklı ortalama birim maliyeti
+	AverageValue float64
+	// Fiyatlı giriş bulunamayan ürünlerde diğer şubelerdeki son fiyat kullanılır;
+	// o da yoksa değer 0'dır ve Priced false olur
+	Priced bool
+}
+
+// Value: Seçilen yönteme göre değer
+func (v ProductValuation) Value(method string) float64 {
+	if method == ValuationWeightedAverage {
+		return v.AverageValue
+	}
+	return v.FIFOValue
+}
+
+// costLayersQuery: Şubeye fiyatıyla giren miktarlar (stoka kaydedilmiş sevkiyat kalemleri KDV'li
+// birim fiyatla, merkez sevkiyatları ve teslim alınan transferler) - eskiden yeniye
+const costLayersQuery = `
+	SELECT product_id, quantity, unit_price
+	FROM (
+		SELECT si.product_id, s.date, si.quantity, si.unit_price_with_vat AS unit_price, 1 AS src, si.id AS seq
+		FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.branch_id = ? AND s.is_stocked = ? AND s.date < ?
+		UNION ALL
+		SELECT product_id, date, quantity, unit_price, 0 AS src, id AS seq
+		FROM center_shipments
+		WHERE branch_id = ? AND date < ?
+		UNION ALL
+		SELECT ti.product_id, t.received_date, ti.quantity, ti.unit_cost, 2 AS src, ti.id AS seq
+		FROM stock_transfer_items ti
+		JOIN stock_transfers t ON t.id = ti.transfer_id
+		WHERE t.to_branch_id = ? AND t.status = ? AND t.received_date < ?
+	) layers
+	WHERE quantity > 0 AND unit_price > 0
+	ORDER BY product_id, date, src, seq`
+
+// ValueStock: Şubedeki stoğun before tarihinden önceki hareketlere göre değeri (ürün ID -> değerleme).
+// Sadece bakiyesi pozitif ürünler döner.
+func ValueStock(tx *gorm.DB, branchID uint, before time.Time) (map[uint]ProductValuation, error) {
+	type qtyRow struct {
+		ProductID uint
+		Quantity  float64
+	}
+	var quantities []qtyRow
+	if err := tx.Model(&models.StockMovement{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Where("branch_id = ? AND date < ?", branchID, before).
+		Group("product_id").
+		Having("SUM(quantity) > 0").
+		Scan(&quantities).Error; err != nil {
+		return nil, err
+	}
+	if len(quantities) == 0 {
+		return map[uint]ProductValuation{}, nil
+	}
+
+	type layerRow struct {
+		ProductID uint
+		Quantity  float64
+		UnitPrice float64
+	}
+	var layers []layerRow
+	if err := tx.Raw(costLayersQuery,
+		branchID, true, before,
+		branchID, before,
+		branchID, models.TransferStatusReceived, before).Scan(&layers).Error; err != nil {
+		return nil, err
+	}
+	byProduct := make(map[uint][]layerRow)
+	for _, l := range layers {
+		byProduct[l.ProductID] = append(byProduct[l.ProductID], l)
+	}
+
+	lastPrices, err := LastUnitPrices(tx, branchID, before)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]ProductValuation, len(quantities))
+	for _, q := range quantities {
+		v := ProductValuation{ProductID: q.ProductID, Quantity: q.Quantity}
+		productLayers := byProduct[q.ProductID]
+
+		if len(productLayers) == 0 {
+			if price, ok := lastPrices[q.ProductID]; ok {
+				v.Priced = true
+				v.AverageCost = price
+				v.AverageValue = q.Quantity * price
+				v.FIFOValue = q.Quantity * price
+			}
+			result[q.ProductID] = v
+			continue
+		}
+		v.Priced = true
+
+		var totalQty, totalCost float64
+		for _, l := range productLayers {
+			totalQty += l.Quantity
+			totalCost += l.Quantity * l.UnitPrice
+		}
+		v.AverageCost = totalCost / totalQty
+		v.AverageValue = q.Quantity * v.AverageCost
+
+		// FIFO: önce giren önce çıkar, eldeki miktar en yeni girişlerden oluşur.
+		// Sayım fazlası gibi girişlerden fazla kalan miktar en eski giriş fiyatıyla değerlenir.
+		remaining := q.Quantity
+		for i := len(productLayers) - 1; i >= 0 && remaining > 0; i-- {
+			take := productLayers[i].Quantity
+			if take > remaining {
+				take = remaining
+			}
+			v.FIFOValue += take * productLayers[i].UnitPrice
+			remaining -= take
+		}
+		if remaining > 0 {
+			v.FIFOValue += remaining * productLayers[0].UnitPrice
+		}
+
+		result[q.ProductID] = v
+	}
+	return result, nil
+}
+
+// PurchaseCosts: Şubeye dönem içinde sevkiyat dışında fiyatıyla giren ve transferle çıkan stoğun
+// maliyeti. Fiyatlar costLayersQuery ile aynıdır (merkez sevkiyatı birim fiyatı, transferde
+// gönderim anındaki birim maliyet), böylece açılış / kapanış stok değeriyle aynı bazda kalır.
+type PurchaseCosts struct {
+	CenterShipments float64
+	TransfersIn     float64 // teslim alınan transferler (teslim tarihine göre)
+	TransfersOut    float64 // gönderilen transferler (gönderim tarihine göre, teslim beklenmez)
+}
+
+// Net: Merkez sevkiyatları + gelen transferler - giden transferler
+func (p PurchaseCosts) Net() float64 {
+	return p.CenterShipments + p.TransfersIn - p.TransfersOut
+}
+
+// PeriodPurchaseCosts: Şubenin [from, to) aralığındaki merkez sevkiyatı ve transfer maliyetleri
+func PeriodPurchaseCosts(tx *gorm.DB, branchID uint, from, to time.Time) (PurchaseCosts, error) {
+	var p PurchaseCosts
+	if err := tx.Model(&models.CenterShipment{}).
+		Select("COALESCE(SUM(quantity * unit_price), 0)").
+		Where("branch_id = ? AND date >= ? AND date < ?", branchID, from, to).
+		Scan(&p.CenterShipments).Error; err != nil {
+		return p, err
+	}
+	if err := tx.Table("stock_transfer_items ti").
+		Joins("JOIN stock_transfers t ON t.id = ti.transfer_id").
+		Select("COALESCE(SUM(ti.quantity * ti.unit_cost), 0)").
+		Where("t.to_branch_id = ? AND t.status = ? AND t.received_date >= ? AND t.received_date < ?",
+			branchID, models.TransferStatusReceived, from, to).
+		Scan(&p.TransfersIn).Error; err != nil {
+		return p, err
+	}
+	if err := tx.Table("stock_transfer_items ti").
+		Joins("JOIN stock_transfers t ON t.id = ti.transfer_id").
+		Select("COALESCE(SUM(ti.quantity * ti.unit_cost), 0)").
+		Where("t.from_branch_id = ? AND t.sent_date >= ? AND t.sent_date < ?", branchID, from, to).
+		Scan(&p.TransfersOut).Error; err != nil {
+		return p, err
+	}
+	return p, nil
+}
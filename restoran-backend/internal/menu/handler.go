@@ -0,0 +1,361 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// -------------------------
+// Request/Response Types
+// -------------------------
+
+type RecipeLineRequest struct {
+	ProductID        *uint   `json:"product_id"`         // merkez ürünü
+	ProduceProductID *uint   `json:"produce_product_id"` // veya manav ürünü
+	Quantity         float64 `json:"quantity"`           // porsiyon başına miktar
+}
+
+type CreateMenuItemRequest struct {
+	Name      string              `json:"name"`
+	Category  string              `json:"category"`
+	SalePrice float64             `json:"sale_price"`
+	Recipe    []RecipeLineRequest `json:"recipe"`
+}
+
+type UpdateMenuItemRequest struct {
+	Name      *string             `json:"name"`
+	Category  *string             `json:"category"`
+	SalePrice *float64            `json:"sale_price"`
+	IsActive  *bool               `json:"is_active"`
+	Recipe    []RecipeLineRequest `json:"recipe"` // gönderilirse reçete tamamen değiştirilir
+}
+
+type RecipeLineResponse struct {
+	ID               uint    `json:"id"`
+	ProductID        *uint   `json:"product_id"`
+	ProduceProductID *uint   `json:"produce_product_id"`
+	IngredientName   string  `json:"ingredient_name"`
+	Unit             string  `json:"unit"`
+	Quantity         float64 `json:"quantity"`
+}
+
+type MenuItemResponse struct {
+	ID        uint                 `json:"id"`
+	Name      string               `json:"name"`
+	Category  string               `json:"category"`
+	SalePrice float64              `json:"sale_price"`
+	IsActive  bool                 `json:"is_active"`
+	Recipe    []RecipeLineResponse `json:"recipe"`
+}
+
+// -------------------------
+// Yardımcı Fonksiyonlar
+// -------------------------
+
+// buildRecipe - Reçete satırlarını doğrular (her satırda ya merkez ya manav ürünü olmalı)
+func buildRecipe(lines []RecipeLineRequest) ([]models.RecipeLine, error) {
+	recipe := make([]models.RecipeLine, 0, len(lines))
+	for _, l := range lines {
+		if (l.ProductID == nil) == (l.ProduceProductID == nil) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Her reçete satırında product_id veya produce_product_id'den sadece biri olmalı")
+		}
+		if l.Quantity <= 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Reçete miktarı 0'dan büyük olmalı")
+		}
+		if l.ProductID != nil {
+			var p models.Product
+			if err := database.DB.First(&p, "id = ?", *l.ProductID).Error; err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ürün bulunamadı: %d", *l.ProductID))
+			}
+		} else {
+			var p models.ProduceProduct
+			if err := database.DB.First(&p, "id = ?", *l.ProduceProductID).Error; err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Manav ürünü bulunamadı: %d", *l.ProduceProductID))
+			}
+		}
+		recipe = append(recipe, models.RecipeLine{
+			ProductID:        l.ProductID,
+			ProduceProductID: l.ProduceProductID,
+			Quantity:         l.Quantity,
+		})
+	}
+	return recipe, nil
+}
+
+func loadMenuItem(id any) (models.MenuItem, error) {
+	var item models.MenuItem
+	err := database.DB.
+		Preload("Recipe.Product").
+		Preload("Recipe.ProduceProduct").
+		First(&item, "id = ?", id).Error
+	return item, err
+}
+
+func toMenuItemResponse(item models.MenuItem) MenuItemResponse {
+	recipe := make([]RecipeLineResponse, 0, len(item.Recipe))
+	for _, l := range item.Recipe {
+		line := RecipeLineResponse{
+			ID:               l.ID,
+			ProductID:        l.ProductID,
+			ProduceProductID: l.ProduceProductID,
+			Quantity:         l.Quantity,
+		}
+		if l.Product != nil {
+			line.IngredientName = l.Product.Name
+			line.Unit = l.Product.Unit
+		} else if l.ProduceProduct != nil {
+			line.IngredientName = l.ProduceProduct.Name
+			line.Unit = l.ProduceProduct.Unit
+		}
+		recipe = append(recipe, line)
+	}
+	return MenuItemResponse{
+		ID:        item.ID,
+		Name:      item.Name,
+		Category:  item.Category,
+		SalePrice: item.SalePrice,
+		IsActive:  item.IsActive,
+		Recipe:    recipe,
+	}
+}
+
+// -------------------------
+// Menü ürünleri ve reçeteler
+// -------------------------
+
+// GET /api/menu-items
+func ListMenuItemsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dbq := database.DB.
+			Preload("Recipe.Product").
+			Preload("Recipe.ProduceProduct")
+		if c.Query("active") == "true" {
+			dbq = dbq.Where("is_active = ?", true)
+		}
+
+		var items []models.MenuItem
+		if err := dbq.Order("category asc, name asc").Find(&items).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünleri listelenemedi")
+		}
+
+		res := make([]MenuItemResponse, 0, len(items))
+		for _, item := range items {
+			res = append(res, toMenuItemResponse(item))
+		}
+		return c.JSON(res)
+	}
+}
+
+// POST /api/admin/menu-items
+func CreateMenuItemHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body CreateMenuItemRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
+		}
+
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "name zorunlu")
+		}
+		if body.SalePrice < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "sale_price negatif olamaz")
+		}
+
+		var existing models.MenuItem
+		if err := database.DB.Where("name = ?", body.Name).First(&existing).Error; err == nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Bu menü ürünü zaten var: %s", body.Name))
+		}
+
+		recipe, err := buildRecipe(body.Recipe)
+		if err != nil {
+			return err
+		}
+
+		item := models.MenuItem{
+			Name:      body.Name,
+			Category:  strings.TrimSpace(body.Category),
+			SalePrice: body.SalePrice,
+			IsActive:  true,
+			Recipe:    recipe,
+		}
+		if err := database.DB.Create(&item).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünü oluşturulamadı")
+		}
+
+		item, err = loadMenuItem(item.ID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünü yüklenemedi")
+		}
+
+		// Audit log (menü şubeden bağımsız)
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "menu_item",
+				EntityID:    item.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Menü ürünü eklendi: %s (%d malzeme)", item.Name, len(item.Recipe)),
+				Before:      nil,
+				After:       item,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(toMenuItemResponse(item))
+	}
+}
+
+// PUT /api/admin/menu-items/:id
+func UpdateMenuItemHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		item, err := loadMenuItem(id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Menü ürünü bulunamadı")
+		}
+		before := item
+
+		var body UpdateMenuItemRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
+		}
+
+		if body.Name != nil {
+			name := strings.TrimSpace(*body.Name)
+			if name == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "name boş olamaz")
+			}
+			var existing models.MenuItem
+			if err := database.DB.Where("name = ? AND id != ?", name, item.ID).First(&existing).Error; err == nil {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Bu menü ürünü zaten var: %s", name))
+			}
+			item.Name = name
+		}
+		if body.Category != nil {
+			item.Category = strings.TrimSpace(*body.Category)
+		}
+		if body.SalePrice != nil {
+			if *body.SalePrice < 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "sale_price negatif olamaz")
+			}
+			item.SalePrice = *body.SalePrice
+		}
+		if body.IsActive != nil {
+			item.IsActive = *body.IsActive
+		}
+
+		var recipe []models.RecipeLine
+		if body.Recipe != nil {
+			if recipe, err = buildRecipe(body.Recipe); err != nil {
+				return err
+			}
+		}
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.MenuItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"name":       item.Name,
+				"category":   item.Category,
+				"sale_price": item.SalePrice,
+				"is_active":  item.IsActive,
+			}).Error; err != nil {
+				return err
+			}
+			if body.Recipe == nil {
+				return nil
+			}
+			if err := tx.Where("menu_item_id = ?", item.ID).Delete(&models.RecipeLine{}).Error; err != nil {
+				return err
+			}
+			for i := range recipe {
+				recipe[i].MenuItemID = item.ID
+			}
+			if len(recipe) > 0 {
+				return tx.Create(&recipe).Error
+			}
+			return nil
+		})
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünü güncellenemedi")
+		}
+
+		item, err = loadMenuItem(item.ID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünü yüklenemedi")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "menu_item",
+				EntityID:    item.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Menü ürünü güncellendi: %s", item.Name),
+				Before:      before,
+				After:       item,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.JSON(toMenuItemResponse(item))
+	}
+}
+
+// DELETE /api/admin/menu-items/:id
+func DeleteMenuItemHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+
+		item, err := loadMenuItem(id)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Menü ürünü bulunamadı")
+		}
+
+		// Satış kaydı olan ürün silinmez, pasife alınmalı
+		var count int64
+		database.DB.Model(&models.MenuSale{}).Where("menu_item_id = ?", item.ID).Count(&count)
+		if count > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Bu menü ürününe ait satış kayıtları var, silmek yerine pasife alın")
+		}
+
+		if err := database.DB.Select("Recipe").Delete(&item).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Menü ürünü silinemedi")
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "menu_item",
+				EntityID:    item.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Menü ürünü silindi: %s", item.Name),
+				Before:      item,
+				After:       nil,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,238 @@
+package menu
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/inventory"
+	"restoran-backend/internal/ledger"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FoodCostRow: Malzeme bazında teorik (reçete x satış) ve gerçekleşen kullanım
+type FoodCostRow struct {
+	Kind            string  `json:"kind"` // "product" (merkez) / "produce" (manav)
+	IngredientID    uint    `json:"ingredient_id"`
+	IngredientName  string  `json:"ingredient_name"`
+	Unit            string  `json:"unit"`
+	TheoreticalQty  float64 `json:"theoretical_qty"` // satılan porsiyon x reçete miktarı
+	ActualQty       float64 `json:"actual_qty"`      // gerçekleşen tüketim (zayiat dahil)
+	WastedQty       float64 `json:"wasted_qty"`      // kayıtlı zayiat
+	VarianceQty     float64 `json:"variance_qty"`    // actual - theoretical
+	UnitPrice       float64 `json:"unit_price"`      // son alış fiyatı
+	TheoreticalCost float64 `json:"theoretical_cost"`
+	ActualCost      float64 `json:"actual_cost"`
+	VarianceCost    float64 `json:"variance_cost"`
+}
+
+type FoodCostReportResponse struct {
+	BranchID               uint          `json:"branch_id"`
+	Year                   int           `json:"year"`
+	Month                  int           `json:"month"`
+	Rows                   []FoodCostRow `json:"rows"`
+	TheoreticalCost        float64       `json:"theoretical_cost"`
+	ActualCost             float64       `json:"actual_cost"`
+	VarianceCost           float64       `json:"variance_cost"`
+	Revenue                float64       `json:"revenue"`       // dönemdeki kasa girişleri (CashMovement)
+	SalesRevenue           float64       `json:"sales_revenue"` // menü satış kayıtlarındaki tutar
+	TheoreticalFoodCostPct float64       `json:"theoretical_food_cost_pct"`
+	ActualFoodCostPct      float64       `json:"actual_food_cost_pct"`
+}
+
+// GET /api/menu-reports/food-cost?branch_id=...&year=2025&month=12
+// Teorik ve gerçekleşen malzeme kullanımı ile kasa gelirine göre yemek maliyeti yüzdesi.
+// Not: Geçmiş satışlar reçetenin güncel haliyle hesaplanır.
+func FoodCostReportHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		var year, month int
+		if _, err := fmt.Sscan(c.Query("year"), &year); err != nil || year < 2000 {
+			return fiber.NewError(fiber.StatusBadRequest, "year geçersiz")
+		}
+		if _, err := fmt.Sscan(c.Query("month"), &month); err != nil || month < 1 || month > 12 {
+			return fiber.NewError(fiber.StatusBadRequest, "month geçersiz")
+		}
+
+		loc := time.Now().Location()
+		firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+		nextMonth := firstDay.AddDate(0, 1, 0)
+
+		// 1. Teorik kullanım: satış x reçete (malzeme bazında)
+		type theoreticalRow struct {
+			ProductID        *uint
+			ProduceProductID *uint
+			TheoreticalQty   float64
+		}
+		var theoretical []theoreticalRow
+		if err := database.DB.Raw(`
+			SELECT rl.product_id, rl.produce_product_id, SUM(ms.quantity * rl.quantity) AS theoretical_qty
+			FROM menu_sales ms
+			JOIN recipe_lines rl ON rl.menu_item_id = ms.menu_item_id
+			WHERE ms.branch_id = ? AND ms.date >= ? AND ms.date < ?
+			GROUP BY rl.product_id, rl.produce_product_id`,
+			branchID, firstDay, nextMonth).Scan(&theoretical).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Teorik kullanım hesaplanamadı")
+		}
+
+		rows := make(map[string]*FoodCostRow)
+		rowFor := func(kind string, id uint) *FoodCostRow {
+			key := fmt.Sprintf("%s-%d", kind, id)
+			r, ok := rows[key]
+			if !ok {
+				r = &FoodCostRow{Kind: kind, IngredientID: id}
+				rows[key] = r
+			}
+			return r
+		}
+		for _, t := range theoretical {
+			if t.ProductID != nil {
+				rowFor("product", *t.ProductID).TheoreticalQty += t.TheoreticalQty
+			} else if t.ProduceProductID != nil {
+				rowFor("produce", *t.ProduceProductID).TheoreticalQty += t.TheoreticalQty
+			}
+		}
+
+		// 2. Merkez ürünlerinde gerçekleşen kullanım: aylık stok kullanımı (transferler hariç)
+		usage, err := inventory.MonthlyStockUsage(branchID, firstDay, nextMonth)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Stok kullanımı hesaplanamadı")
+		}
+		for _, u := range usage {
+			r := rowFor("product", u.ProductID)
+			r.ActualQty = u.ConsumedQty - u.TransferQty
+			r.WastedQty = u.WastedQty
+		}
+
+		productPrices, err := ledger.LastUnitPrices(database.DB, branchID, nextMonth)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
+
+		// 3. Manav ürünlerinde stok tutulmadığı için alınan miktar tüketilmiş sayılır
+		type produceRow struct {
+			ProductID uint
+			Qty       float64
+		}
+		var purchased, wasted []produceRow
+		if err := database.DB.Model(&models.ProducePurchase{}).
+			Select("product_id, SUM(quantity) AS qty").
+			Where("branch_id = ? AND date >= ? AND date < ?", branchID, firstDay, nextMonth).
+			Group("product_id").
+			Scan(&purchased).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Manav alımları hesaplanamadı")
+		}
+		if err := database.DB.Model(&models.ProduceWaste{}).
+			Select("product_id, SUM(quantity) AS qty").
+			Where("branch_id = ? AND date >= ? AND date < ?", branchID, firstDay, nextMonth).
+			Group("product_id").
+			Scan(&wasted).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Manav zayiatı hesaplanamadı")
+		}
+		for _, p := range purchased {
+			rowFor("produce", p.ProductID).ActualQty = p.Qty
+		}
+		for _, w := range wasted {
+			rowFor("produce", w.ProductID).WastedQty = w.Qty
+		}
+
+		type priceRow struct {
+			ProductID uint
+			UnitPrice float64
+		}
+		var producePriceRows []priceRow
+		if err := database.DB.Raw(`
+			SELECT DISTINCT ON (product_id) product_id, unit_price
+			FROM produce_purchases
+			WHERE branch_id = ? AND date < ?
+			ORDER BY product_id, date DESC, id DESC`, branchID, nextMonth).
+			Scan(&producePriceRows).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
+		producePrices := make(map[uint]float64, len(producePriceRows))
+		for _, p := range producePriceRows {
+			producePrices[p.ProductID] = p.UnitPrice
+		}
+
+		// Malzeme adları
+		var productIDs, produceIDs []uint
+		for _, r := range rows {
+			if r.Kind == "product" {
+				productIDs = append(productIDs, r.IngredientID)
+			} else {
+				produceIDs = append(produceIDs, r.IngredientID)
+			}
+		}
+		var products []models.Product
+		if len(productIDs) > 0 {
+			database.DB.Where("id IN ?", productIDs).Find(&products)
+		}
+		var produceProducts []models.ProduceProduct
+		if len(produceIDs) > 0 {
+			database.DB.Where("id IN ?", produceIDs).Find(&produceProducts)
+		}
+		for _, p := range products {
+			r := rows[fmt.Sprintf("product-%d", p.ID)]
+			r.IngredientName, r.Unit = p.Name, p.Unit
+		}
+		for _, p := range produceProducts {
+			r := rows[fmt.Sprintf("produce-%d", p.ID)]
+			r.IngredientName, r.Unit = p.Name, p.Unit
+		}
+
+		resp := FoodCostReportResponse{
+			BranchID: branchID,
+			Year:     year,
+			Month:    month,
+			Rows:     make([]FoodCostRow, 0, len(rows)),
+		}
+		for _, r := range rows {
+			if r.Kind == "product" {
+				r.UnitPrice = productPrices[r.IngredientID]
+			} else {
+				r.UnitPrice = producePrices[r.IngredientID]
+			}
+			r.VarianceQty = r.ActualQty - r.TheoreticalQty
+			r.TheoreticalCost = r.TheoreticalQty * r.UnitPrice
+			r.ActualCost = r.ActualQty * r.UnitPrice
+			r.VarianceCost = r.ActualCost - r.TheoreticalCost
+
+			resp.TheoreticalCost += r.TheoreticalCost
+			resp.ActualCost += r.ActualCost
+			resp.Rows = append(resp.Rows, *r)
+		}
+		resp.VarianceCost = resp.ActualCost - resp.TheoreticalCost
+		sort.Slice(resp.Rows, func(i, j int) bool {
+			return resp.Rows[i].VarianceCost > resp.Rows[j].VarianceCost
+		})
+
+		// 4. Gelir: kasa girişleri ve menü satış kayıtları
+		if err := database.DB.Model(&models.CashMovement{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("branch_id = ? AND direction = ? AND date >= ? AND date < ?", branchID, "in", firstDay, nextMonth).
+			Scan(&resp.Revenue).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Gelir hesaplanamadı")
+		}
+		if err := database.DB.Model(&models.MenuSale{}).
+			Select("COALESCE(SUM(revenue), 0)").
+			Where("branch_id = ? AND date >= ? AND date < ?", branchID, firstDay, nextMonth).
+			Scan(&resp.SalesRevenue).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Satış geliri hesaplanamadı")
+		}
+
+		if resp.Revenue > 0 {
+			resp.TheoreticalFoodCostPct = resp.TheoreticalCost / resp.Revenue * 100
+			resp.ActualFoodCostPct = resp.ActualCost / resp.Revenue * 100
+		}
+
+		return c.JSON(resp)
+	}
+}
@@ -0,0 +1,218 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type SaveMenuSalesRequest struct {
+	Date     string                `json:"date"` // "2025-12-09"
+	Items    []MenuSaleItemRequest `json:"items"`
+	BranchID *uint                 `json:"branch_id"` // super_admin için
+}
+
+type MenuSaleItemRequest struct {
+	MenuItemID uint    `json:"menu_item_id"`
+	Quantity   float64 `json:"quantity"` // 0 gönderilirse o günün kaydı silinir
+	Revenue    float64 `json:"revenue"`
+}
+
+type MenuSaleResponse struct {
+	ID           uint    `json:"id"`
+	BranchID     uint    `json:"branch_id"`
+	MenuItemID   uint    `json:"menu_item_id"`
+	MenuItemName string  `json:"menu_item_name"`
+	Date         string  `json:"date"`
+	Quantity     float64 `json:"quantity"`
+	Revenue      float64 `json:"revenue"`
+}
+
+func toMenuSaleResponse(s models.MenuSale) MenuSaleResponse {
+	return MenuSaleResponse{
+		ID:           s.ID,
+		BranchID:     s.BranchID,
+		MenuItemID:   s.MenuItemID,
+		MenuItemName: s.MenuItem.Name,
+		Date:         s.Date.Format("2006-01-02"),
+		Quantity:     s.Quantity,
+		Revenue:      s.Revenue,
+	}
+}
+
+// POST /api/menu-sales
+// Günün satışlarını kaydeder; aynı gün/ürün için kayıt varsa günceller
+func SaveMenuSalesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body SaveMenuSalesRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		if len(body.Items) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "En az bir satış satırı gönderilmelidir")
+		}
+
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
+		if err != nil {
+			return err
+		}
+
+		d, err := time.Parse("2006-01-02", body.Date)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
+		}
+
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		type saleLog struct {
+			action models.AuditAction
+			before any
+			after  any
+			sale   models.MenuSale
+		}
+		var logs []saleLog
+
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			for _, line := range body.Items {
+				if line.MenuItemID == 0 || line.Quantity < 0 || line.Revenue < 0 {
+					return fiber.NewError(fiber.StatusBadRequest, "menu_item_id zorunlu, quantity ve revenue negatif olamaz")
+				}
+				var item models.MenuItem
+				if err := tx.First(&item, "id = ?", line.MenuItemID).Error; err != nil {
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Menü ürünü bulunamadı: %d", line.MenuItemID))
+				}
+
+				var sale models.MenuSale
+				err := tx.Where("branch_id = ? AND menu_item_id = ? AND date = ?", branchID, line.MenuItemID, d).First(&sale).Error
+				exists := err == nil
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return fiber.NewError(fiber.StatusInternalServerError, "Satış kaydı okunamadı")
+				}
+
+				switch {
+				case !exists && line.Quantity == 0:
+					continue
+				case exists && line.Quantity == 0:
+					if err := tx.Delete(&sale).Error; err != nil {
+						return fiber.NewError(fiber.StatusInternalServerError, "Satış kaydı silinemedi")
+					}
+					sale.MenuItem = item
+					logs = append(logs, saleLog{action: models.AuditActionDelete, before: sale, sale: sale})
+				case exists:
+					before := sale
+					sale.Quantity = line.Quantity
+					sale.Revenue = line.Revenue
+					if err := tx.Save(&sale).Error; err != nil {
+						return fiber.NewError(fiber.StatusInternalServerError, "Satış kaydı güncellenemedi")
+					}
+					sale.MenuItem = item
+					logs = append(logs, saleLog{action: models.AuditActionUpdate, before: before, after: sale, sale: sale})
+				default:
+					sale = models.MenuSale{
+						BranchID:   branchID,
+						MenuItemID: line.MenuItemID,
+						Date:       d,
+						Quantity:   line.Quantity,
+						Revenue:    line.Revenue,
+					}
+					if err := tx.Create(&sale).Error; err != nil {
+						return fiber.NewError(fiber.StatusInternalServerError, "Satış kaydı oluşturulamadı")
+					}
+					sale.MenuItem = item
+					logs = append(logs, saleLog{action: models.AuditActionCreate, after: sale, sale: sale})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		resp := make([]MenuSaleResponse, 0, len(logs))
+		for _, l := range logs {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    &branchID,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "menu_sale",
+				EntityID:    l.sale.ID,
+				Action:      l.action,
+				Description: fmt.Sprintf("Menü satışı (%s): %s - %.0f porsiyon", l.sale.Date.Format("2006-01-02"), l.sale.MenuItem.Name, l.sale.Quantity),
+				Before:      l.before,
+				After:       l.after,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+			if l.action != models.AuditActionDelete {
+				resp = append(resp, toMenuSaleResponse(l.sale))
+			}
+		}
+
+		return c.JSON(resp)
+	}
+}
+
+// GET /api/menu-sales?branch_id=...&from=...&to=...&menu_item_id=...
+func ListMenuSalesHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
+		if err != nil {
+			return err
+		}
+
+		dbq := database.DB.Model(&models.MenuSale{}).
+			Preload("MenuItem").
+			Where("branch_id = ?", branchID)
+
+		if fromStr := c.Query("from"); fromStr != "" {
+			from, err := time.Parse("2006-01-02", fromStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "from geçersiz")
+			}
+			dbq = dbq.Where("date >= ?", from)
+		}
+		if toStr := c.Query("to"); toStr != "" {
+			to, err := time.Parse("2006-01-02", toStr)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "to geçersiz")
+			}
+			dbq = dbq.Where("date <= ?", to)
+		}
+		if idStr := c.Query("menu_item_id"); idStr != "" {
+			var mid uint
+			if _, err := fmt.Sscan(idStr, &mid); err != nil || mid == 0 {
+				return fiber.NewError(fiber.StatusBadRequest, "menu_item_id geçersiz")
+			}
+			dbq = dbq.Where("menu_item_id = ?", mid)
+		}
+
+		var sales []models.MenuSale
+		if err := dbq.Order("date desc, id asc").Find(&sales).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Satışlar listelenemedi")
+		}
+
+		resp := make([]MenuSaleResponse, 0, len(sales))
+		for _, s := range sales {
+			resp = append(resp, toMenuSaleResponse(s))
+		}
+		return c.JSON(resp)
+	}
+}
@@ -0,0 +1,21 @@
+package menu
+
+import (
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("menu_item", audit.EntityConfig{
+		Model: &models.MenuItem{},
+		Children: []audit.ChildRelation{
+			{Key: "Recipe", Model: &models.RecipeLine{}, ForeignKey: "menu_item_id"},
+		},
+	})
+	audit.RegisterEntity("menu_sale", audit.EntityConfig{
+		Model:        &models.MenuSale{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+}
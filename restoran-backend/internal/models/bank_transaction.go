@@ -23,3 +23,11 @@ type BankTransaction struct {
 	UpdatedAt     time.Time
 }
 
+
+// BalanceEffect: İşlemin hesap bakiyesine etkisi (yatırma +, çekme/ödeme -)
+func (t BankTransaction) BalanceEffect() float64 {
+	if t.Type == TransactionTypeDeposit {
+		return t.Amount
+	}
+	return -t.Amount
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type CountSessionStatus string
+
+const (
+	CountSessionOpen      CountSessionStatus = "open"      // sayım devam ediyor
+	CountSessionSubmitted CountSessionStatus = "submitted" // sayım bitti, onay bekliyor
+	CountSessionApproved  CountSessionStatus = "approved"  // onaylandı, stok girişleri yazıldı
+	CountSessionCancelled CountSessionStatus = "cancelled"
+)
+
+// CountSession: Şube stok sayım oturumu (ürünler raf sırasıyla sayılır, onaylanınca stoka yazılır)
+type CountSession struct {
+	ID          uint `gorm:"primaryKey"`
+	BranchID    uint `gorm:"index;not null"`
+	Branch      Branch
+	Date        time.Time          `gorm:"index;not null"` // sayım tarihi
+	Status      CountSessionStatus `gorm:"size:20;index;not null"`
+	Blind       bool               `gorm:"not null;default:false"` // kör sayım: onaya kadar beklenen miktar gösterilmez
+	Note        string             `gorm:"size:255"`
+	OpenedBy    uint               `gorm:"not null"`
+	SubmittedBy *uint
+	SubmittedAt *time.Time
+	ApprovedBy  *uint
+	ApprovedAt  *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	Lines []CountSessionLine `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+}
+
+// CountSessionLine: Oturumda sayılacak her ürün
+type CountSessionLine struct {
+	ID               uint `gorm:"primaryKey"`
+	SessionID        uint `gorm:"index;not null"`
+	ProductID        uint `gorm:"index;not null"`
+	Product          Product
+	SortOrder        int      `gorm:"not null"` // raf sırası (BranchProductOrder'dan, oturum açılırken)
+	CountedQuantity  *float64 // nil: henüz sayılmadı (ürünün temel biriminde)
+	Unit             string   `gorm:"size:20"` // girilen birim (boşsa temel birim)
+	UnitQuantity     *float64 // girilen birimdeki miktar
+	ExpectedQuantity *float64 // onay anındaki defter bakiyesi
+	StockEntryID     *uint    // onayda oluşturulan stok girişi
+	CountedBy        *uint
+	CountedAt        *time.Time
+	Note             string `gorm:"size:255"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
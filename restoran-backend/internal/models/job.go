@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusCancelled JobStatus = "cancelled"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// Job: Arka planda çalışan uzun işler (toplu ürün içe aktarma vb.)
+// İlerleme her adımda kaydedilir; sunucu yeniden başlarsa iş Cursor'dan devam eder.
+type Job struct {
+	ID              uint      `gorm:"primaryKey"`
+	Type            string    `gorm:"size:50;index;not null"` // iş tipi (örn: b2b_bulk_import)
+	Status          JobStatus `gorm:"size:20;index;not null"`
+	Params          string    `gorm:"type:text"`     // işin parametreleri (JSON)
+	Cursor          string    `gorm:"size:100"`      // son işlenen kayıt (örn: TM0123)
+	Total           int       `gorm:"default:0"`     // toplam adım sayısı (biliniyorsa)
+	Processed       int       `gorm:"default:0"`     // işlenen adım sayısı
+	Succeeded       int       `gorm:"default:0"`     // başarılı (örn: içe aktarılan ürün)
+	Skipped         int       `gorm:"default:0"`     // atlanan
+	Errors          string    `gorm:"type:text"`     // hata mesajları (JSON dizi, son 100 hata)
+	ErrorCount      int       `gorm:"default:0"`     // toplam hata sayısı
+	LastError       string    `gorm:"size:500"`      // iş başarısız olduysa sebebi
+	Result          string    `gorm:"type:text"`     // işe özel sonuç raporu (JSON, örn: katalog değişiklikleri)
+	CancelRequested bool      `gorm:"default:false"` // iptal istendi mi?
+	CreatedBy       uint      `gorm:"index"`
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MenuItem: Menüdeki satılan ürün (tüm şubelerde ortak)
+type MenuItem struct {
+	ID        uint    `gorm:"primaryKey"`
+	Name      string  `gorm:"size:100;not null;unique"`
+	Category  string  `gorm:"size:100"`
+	SalePrice float64 `gorm:"not null;default:0"` // KDV'li satış fiyatı (bilgi amaçlı)
+	IsActive  bool    `gorm:"not null;default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Recipe []RecipeLine `gorm:"foreignKey:MenuItemID;constraint:OnDelete:CASCADE"`
+}
+
+// RecipeLine: Menü ürününün bir porsiyonunda kullanılan malzeme.
+// Malzeme ya merkez ürünü (Product) ya da manav ürünüdür (ProduceProduct), ikisinden biri dolu olmalı.
+type RecipeLine struct {
+	ID               uint  `gorm:"primaryKey"`
+	MenuItemID       uint  `gorm:"index;not null"`
+	ProductID        *uint `gorm:"index"`
+	Product          *Product
+	ProduceProductID *uint `gorm:"index"`
+	ProduceProduct   *ProduceProduct
+	Quantity         float64 `gorm:"not null"` // porsiyon başına miktar (ürünün biriminde)
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
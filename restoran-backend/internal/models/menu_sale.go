@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MenuSale: Şubenin günlük menü ürünü satışı (şube + ürün + gün başına tek kayıt)
+type MenuSale struct {
+	ID         uint `gorm:"primaryKey"`
+	BranchID   uint `gorm:"uniqueIndex:idx_menu_sale_branch_item_date;not null"`
+	Branch     Branch
+	MenuItemID uint `gorm:"uniqueIndex:idx_menu_sale_branch_item_date;index;not null"`
+	MenuItem   MenuItem
+	Date       time.Time `gorm:"uniqueIndex:idx_menu_sale_branch_item_date;index;not null"`
+	Quantity   float64   `gorm:"not null"`           // satılan porsiyon
+	Revenue    float64   `gorm:"not null;default:0"` // satış tutarı (opsiyonel)
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -16,6 +16,11 @@ type MonthlyReport struct {
 	TotalExpenses   float64 `gorm:"default:0"` // toplam giderler
 	TotalShipments  float64 `gorm:"default:0"` // toplam sevkiyat maliyeti
 	NetProfit       float64 `gorm:"default:0"` // net kar
+
+	// Stok değerleme (ay kapanışında yöntem seçildiyse): net kar = ciro - gider - (sevkiyat + açılış stoğu - kapanış stoğu)
+	InventoryMethod         string  `gorm:"size:20"`   // "fifo" veya "weighted_average", boşsa stok değeri kullanılmadı
+	OpeningInventoryValue   float64 `gorm:"default:0"` // ay başındaki stok değeri
+	ClosingInventoryValue   float64 `gorm:"default:0"` // ay sonundaki stok değeri
 	
 	// Rapor detayları (JSONB)
 	ReportData string `gorm:"type:jsonb"` // detaylı rapor verileri (JSON formatında)
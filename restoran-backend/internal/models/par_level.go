@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ParLevel: Şube bazlı ürün stok seviyeleri (sipariş önerisi için)
+type ParLevel struct {
+	ID            uint `gorm:"primaryKey"`
+	BranchID      uint `gorm:"uniqueIndex:idx_par_level_branch_product;not null"`
+	Branch        Branch
+	ProductID     uint `gorm:"uniqueIndex:idx_par_level_branch_product;not null"`
+	Product       Product
+	MinQuantity   float64 `gorm:"not null"`  // bu seviyenin altına düşünce sipariş önerilir
+	ParQuantity   float64 `gorm:"not null"`  // sipariş sonrası ulaşılması istenen seviye
+	OrderMultiple float64 `gorm:"default:0"` // sipariş katı (örn. koli içi adet), 0 ise birime göre yuvarlanır
+	UpdatedBy     uint    `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PasswordResetToken: Admin'in kullanıcı için oluşturduğu tek kullanımlık şifre sıfırlama token'ı.
+// Token düz metin olarak sadece oluşturulurken döner, veritabanında SHA-256 özeti tutulur.
+type PasswordResetToken struct {
+	ID          uint       `gorm:"primaryKey"`
+	UserID      uint       `gorm:"index;not null"`
+	User        User       `gorm:"constraint:OnDelete:CASCADE"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex;not null"`
+	CreatedByID uint       `gorm:"not null"` // token'ı oluşturan admin
+	ExpiresAt   time.Time  `gorm:"not null"`
+	UsedAt      *time.Time // kullanıldığında dolar, bir daha geçmez
+	CreatedAt   time.Time
+}
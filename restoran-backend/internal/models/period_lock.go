@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PeriodLock: Şube bazlı ay kapanışı (kapalı döneme tarihli kayıt yazılamaz)
+type PeriodLock struct {
+	ID         uint `gorm:"primaryKey"`
+	BranchID   uint `gorm:"uniqueIndex:idx_period_lock_branch_period;not null"`
+	Branch     Branch
+	Year       int       `gorm:"uniqueIndex:idx_period_lock_branch_period;not null"` // yıl
+	Month      int       `gorm:"uniqueIndex:idx_period_lock_branch_period;not null"` // ay (1-12)
+	IsClosed   bool      `gorm:"default:true"`                                       // dönem kapalı mı?
+	ClosedBy   uint      `gorm:"not null"`                                           // kapatan kullanıcı
+	ClosedAt   time.Time `gorm:"not null"`
+	ReopenedBy *uint     // yeniden açan kullanıcı (super_admin)
+	ReopenedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type PriceAlertStatus string
+
+const (
+	PriceAlertPending  PriceAlertStatus = "pending"  // incelenmeyi bekliyor
+	PriceAlertReviewed PriceAlertStatus = "reviewed" // incelendi
+)
+
+// PriceAlert: Sevkiyattaki birim fiyat önceki alış fiyatından eşik yüzdesinden fazla saptığında oluşturulur
+type PriceAlert struct {
+	ID             uint `gorm:"primaryKey"`
+	BranchID       uint `gorm:"index;not null"`
+	Branch         Branch
+	ProductID      uint `gorm:"index;not null"`
+	Product        Product
+	ShipmentID     uint             `gorm:"index;not null"`
+	Shipment       *Shipment        `gorm:"constraint:OnDelete:CASCADE"` // sevkiyat silinince uyarı da silinir
+	ShipmentItemID uint             `gorm:"index"`
+	Date           time.Time        `gorm:"index;not null"` // sevkiyat tarihi
+	PreviousPrice  float64          `gorm:"not null"`       // önceki KDV'siz birim fiyat
+	NewPrice       float64          `gorm:"not null"`       // sevkiyattaki KDV'siz birim fiyat
+	ChangePct      float64          `gorm:"not null"`       // yüzde değişim (+ artış, - düşüş)
+	ThresholdPct   float64          `gorm:"not null"`       // uyarının oluştuğu andaki eşik
+	Status         PriceAlertStatus `gorm:"size:20;index;not null;default:'pending'"`
+	ReviewedBy     *uint
+	ReviewedByName string `gorm:"size:100"`
+	ReviewedAt     *time.Time
+	ReviewNote     string `gorm:"size:255"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
@@ -11,11 +11,17 @@ type ProducePurchase struct {
 	Supplier    ProduceSupplier `gorm:"foreignKey:SupplierID"`
 	ProductID   uint    `gorm:"index;not null"` // ProduceProduct ID
 	Product     ProduceProduct `gorm:"foreignKey:ProductID"`
-	Quantity    float64 `gorm:"not null"` // miktar (kg, adet vs.)
-	UnitPrice   float64 `gorm:"not null"` // birim fiyat
+	Quantity    float64 `gorm:"not null"` // miktar (ürünün temel biriminde)
+	Unit        string  `gorm:"size:20"`  // girilen birim (boşsa temel birim)
+	UnitQuantity float64                  // girilen birimdeki miktar
+	UnitPrice   float64 `gorm:"not null"` // birim fiyat (temel birim başına)
 	TotalAmount float64 `gorm:"not null"` // toplam tutar (quantity * unit_price)
 	Date        time.Time `gorm:"index;not null"`
 	Description string    `gorm:"size:255"`
+	// Parti (lot) takibi - opsiyonel
+	LotNumber    string     `gorm:"size:50;index"` // parti / lot numarası
+	ReceivedDate *time.Time // teslim alma tarihi (boşsa alım tarihi)
+	ExpiryDate   *time.Time `gorm:"index"` // son kullanma tarihi
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -11,8 +11,10 @@ type ProduceWaste struct {
 	Supplier    ProduceSupplier `gorm:"foreignKey:SupplierID"`
 	ProductID   uint    `gorm:"index;not null"` // ProduceProduct ID
 	Product     ProduceProduct `gorm:"foreignKey:ProductID"`
-	PurchaseID  *uint     `gorm:"index"` // Hangi alım kaydından zayiat (opsiyonel)
-	Quantity    float64   `gorm:"not null"` // zayiat miktarı
+	PurchaseID  *uint     `gorm:"index"` // Hangi alım kaydından (partiden) zayiat (opsiyonel)
+	Quantity    float64   `gorm:"not null"` // zayiat miktarı (ürünün temel biriminde)
+	Unit        string    `gorm:"size:20"`  // girilen birim (boşsa temel birim)
+	UnitQuantity float64                    // girilen birimdeki miktar
 	Date        time.Time `gorm:"index;not null"`
 	Description string    `gorm:"size:255"` // Açıklama (örn: "çürük çıktı", "bozuldu")
 	CreatedAt   time.Time
@@ -9,6 +9,8 @@ type Product struct {
 	StockCode       string `gorm:"size:50;index"`    // Stok kodu (PDF'deki stok kodları için)
 	Category        string `gorm:"size:100"`         // Ürün kategorisi (B2B'den gelen)
 	IsCenterProduct bool   `gorm:"not null;default:true"`
+	IsActive        bool   `gorm:"not null;default:true"` // B2B kataloğundan kalkan ürünler pasife alınır
+	ImageURL        string `gorm:"size:500"`              // B2B'deki fotoğraf adresi (değişince yeniden indirilir)
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Birim tanımının ait olduğu ürün tablosu
+const (
+	UnitKindProduct = "product" // Product (merkez ürünleri)
+	UnitKindProduce = "produce" // ProduceProduct (manav ürünleri)
+)
+
+// ProductUnit: Ürünün temel birimi (Product.Unit / ProduceProduct.Unit) dışındaki birimleri.
+// Örn. temel birim kg iken Koli = 12, Paket = 1 (1 Koli = 12 Paket = 12 kg)
+type ProductUnit struct {
+	ID        uint    `gorm:"primaryKey"`
+	Kind      string  `gorm:"size:10;not null;uniqueIndex:idx_product_unit"`
+	ProductID uint    `gorm:"not null;uniqueIndex:idx_product_unit"`
+	Unit      string  `gorm:"size:20;not null;uniqueIndex:idx_product_unit"` // normalleştirilmiş ad ("koli")
+	Label     string  `gorm:"size:20;not null"`                              // görünen ad ("Koli")
+	Factor    float64 `gorm:"not null"`                                      // 1 birim = Factor temel birim
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"              // hazırlanıyor, düzenlenebilir
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"               // tedarikçiye gönderildi, teslimat bekleniyor
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received" // bazı kalemler eksik geldi
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"           // tüm kalemler teslim alındı
+)
+
+// PurchaseOrder: Tedarikçiye verilen sipariş (gelen sevkiyatlar bu siparişle eşleştirilir)
+type PurchaseOrder struct {
+	ID           uint `gorm:"primaryKey"`
+	BranchID     uint `gorm:"index;not null"`
+	Branch       Branch
+	Supplier     string              `gorm:"size:100;index;not null"` // tedarikçi adı (örn. "Merkez")
+	Status       PurchaseOrderStatus `gorm:"size:20;index;not null"`
+	OrderDate    time.Time           `gorm:"index;not null"`
+	ExpectedDate *time.Time          // beklenen teslim tarihi
+	TotalAmount  float64             `gorm:"not null"` // beklenen tutar (KDV'siz)
+	Note         string              `gorm:"size:255"`
+	CreatedBy    uint                `gorm:"not null"`
+	SentAt       *time.Time
+	ReceivedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Lines []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID;constraint:OnDelete:CASCADE"`
+}
+
+// PurchaseOrderLine: Siparişteki her ürün
+type PurchaseOrderLine struct {
+	ID                uint `gorm:"primaryKey"`
+	PurchaseOrderID   uint `gorm:"index;not null"`
+	ProductID         uint `gorm:"index;not null"`
+	Product           Product
+	Quantity          float64 `gorm:"not null"` // sipariş edilen miktar
+	ExpectedUnitPrice float64 `gorm:"not null"` // beklenen KDV'siz birim fiyat
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
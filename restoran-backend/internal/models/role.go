@@ -0,0 +1,232 @@
+package models
+
+import "time"
+
+// Role: Kullanıcı rolü tanımı. Sistem rolleri (RoleSuperAdmin, RoleBranchAdmin, ...) silinemez,
+// super admin dışındaki tüm rollerin izinleri RolePermission tablosundan okunur.
+type Role struct {
+	ID          uint     `gorm:"primaryKey"`
+	Name        UserRole `gorm:"size:20;uniqueIndex;not null"` // User.Role ile eşleşir
+	Label       string   `gorm:"size:100;not null"`            // görünen ad ("Muhasebe")
+	Description string   `gorm:"size:255"`
+	IsSystem    bool     `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// RolePermission: Rolün sahip olduğu tek bir izin (kaynak x işlem)
+type RolePermission struct {
+	ID        uint     `gorm:"primaryKey"`
+	Role      UserRole `gorm:"size:20;not null;uniqueIndex:idx_role_permission"`
+	Resource  string   `gorm:"size:30;not null;uniqueIndex:idx_role_permission"`
+	Action    string   `gorm:"size:20;not null;uniqueIndex:idx_role_permission"`
+	CreatedAt time.Time
+}
+
+// Permission: Kaynak üzerinde bir işlem yetkisi ("stock:write")
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+func (p Permission) String() string {
+	return p.Resource + ":" + p.Action
+}
+
+// İzin işlemleri
+const (
+	PermActionRead    = "read"    // listeleme / görüntüleme
+	PermActionWrite   = "write"   // ekleme / güncelleme / silme
+	PermActionApprove = "approve" // onaylama / yeniden açma
+)
+
+// İzinler (route bazında main.go'da RequirePermission ile uygulanır)
+var (
+	PermBranchRead           = Permission{"branch", PermActionRead}
+	PermBranchWrite          = Permission{"branch", PermActionWrite}
+	PermUserRead             = Permission{"user", PermActionRead}
+	PermUserWrite            = Permission{"user", PermActionWrite}
+	PermRoleRead             = Permission{"role", PermActionRead}
+	PermRoleWrite            = Permission{"role", PermActionWrite}
+	PermJobRead              = Permission{"job", PermActionRead}
+	PermJobWrite             = Permission{"job", PermActionWrite}
+	PermProductRead          = Permission{"product", PermActionRead}
+	PermProductWrite         = Permission{"product", PermActionWrite}
+	PermCashRead             = Permission{"cash", PermActionRead}
+	PermCashWrite            = Permission{"cash", PermActionWrite}
+	PermBankAccountRead      = Permission{"bank_account", PermActionRead}
+	PermBankAccountWrite     = Permission{"bank_account", PermActionWrite}
+	PermBankRead             = Permission{"bank", PermActionRead}
+	PermBankWrite            = Permission{"bank", PermActionWrite}
+	PermExpenseRead          = Permission{"expense", PermActionRead}
+	PermExpenseWrite         = Permission{"expense", PermActionWrite}
+	PermExpenseCategoryWrite = Permission{"expense_category", PermActionWrite}
+	PermReportRead           = Permission{"report", PermActionRead}
+	PermMonthlyReportRead    = Permission{"monthly_report", PermActionRead}
+	PermMonthlyReportWrite   = Permission{"monthly_report", PermActionWrite}
+	PermMenuRead             = Permission{"menu", PermActionRead}
+	PermMenuWrite            = Permission{"menu", PermActionWrite}
+	PermSalesRead            = Permission{"sales", PermActionRead}
+	PermSalesWrite           = Permission{"sales", PermActionWrite}
+	PermPurchaseRead         = Permission{"purchase", PermActionRead}
+	PermPurchaseWrite        = Permission{"purchase", PermActionWrite}
+	PermStockRead            = Permission{"stock", PermActionRead}
+	PermStockWrite           = Permission{"stock", PermActionWrite}
+	PermStockCountRead       = Permission{"stock_count", PermActionRead}
+	PermStockCountWrite      = Permission{"stock_count", PermActionWrite}
+	PermStockCountApprove    = Permission{"stock_count", PermActionApprove}
+	PermWasteRead            = Permission{"waste", PermActionRead}
+	PermWasteWrite           = Permission{"waste", PermActionWrite}
+	PermProduceRead          = Permission{"produce", PermActionRead}
+	PermProduceWrite         = Permission{"produce", PermActionWrite}
+	PermTradeRead            = Permission{"trade", PermActionRead}
+	PermTradeWrite           = Permission{"trade", PermActionWrite}
+	PermAuditRead            = Permission{"audit", PermActionRead}
+	PermAuditWrite           = Permission{"audit", PermActionWrite}
+)
+
+// PermissionInfo: Rol yönetimi ekranı için izin açıklaması
+type PermissionInfo struct {
+	Permission  Permission
+	Description string
+}
+
+// PermissionCatalog: Rollere atanabilecek tüm izinler
+var PermissionCatalog = []PermissionInfo{
+	{PermBranchRead, "Şubeleri ve şube yöneticilerini görüntüleme"},
+	{PermBranchWrite, "Şube ve şube yöneticisi ekleme / düzenleme / silme"},
+	{PermUserRead, "Kullanıcıları görüntüleme"},
+	{PermUserWrite, "Kullanıcı yönetimi ve oturum kapatma"},
+	{PermRoleRead, "Rolleri ve izinleri görüntüleme"},
+	{PermRoleWrite, "Rol ekleme / düzenleme / silme"},
+	{PermJobRead, "Arka plan işlerini görüntüleme"},
+	{PermJobWrite, "Arka plan işlerini iptal etme / devam ettirme"},
+	{PermProductRead, "Ürün kataloğunu ve birimlerini görüntüleme"},
+	{PermProductWrite, "Ürün kataloğunu ve birimlerini düzenleme"},
+	{PermCashRead, "Para giriş/çıkışlarını görüntüleme"},
+	{PermCashWrite, "Para giriş/çıkışı ekleme"},
+	{PermBankAccountRead, "Banka/kart hesap tanımlarını görüntüleme"},
+	{PermBankAccountWrite, "Banka/kart hesabı ekleme / düzenleme / silme"},
+	{PermBankRead, "Banka/kart hareketlerini ve ekstreyi görüntüleme"},
+	{PermBankWrite, "Banka/kart hareketi ekleme / düzenleme / silme"},
+	{PermExpenseRead, "Giderleri ve ödemeleri görüntüleme"},
+	{PermExpenseWrite, "Gider ve gider ödemesi ekleme"},
+	{PermExpenseCategoryWrite, "Gider kategorisi ekleme / düzenleme / silme"},
+	{PermReportRead, "Dashboard ve finansal özetleri görüntüleme"},
+	{PermMonthlyReportRead, "Aylık raporları görüntüleme"},
+	{PermMonthlyReportWrite, "Aylık rapor oluşturma ve dönemi yeniden açma"},
+	{PermMenuRead, "Menü ve reçeteleri görüntüleme"},
+	{PermMenuWrite, "Menü ve reçeteleri düzenleme"},
+	{PermSalesRead, "Menü satışlarını ve yemek maliyeti raporunu görüntüleme"},
+	{PermSalesWrite, "Menü satışı girme"},
+	{PermPurchaseRead, "Sevkiyat, sipariş ve alış fiyatlarını görüntüleme"},
+	{PermPurchaseWrite, "Sevkiyat, fatura ve satın alma siparişi işlemleri"},
+	{PermStockRead, "Stok defteri, değerleme, parti ve transferleri görüntüleme"},
+	{PermStockWrite, "Stok düzeltmesi, stok seviyesi ve transfer işlemleri"},
+	{PermStockCountRead, "Stok sayımlarını görüntüleme"},
+	{PermStockCountWrite, "Stok sayımı girme"},
+	{PermStockCountApprove, "Sayım oturumunu onaylama / yeniden açma / iptal etme"},
+	{PermWasteRead, "Zayiatları görüntüleme"},
+	{PermWasteWrite, "Zayiat girme / düzenleme / silme"},
+	{PermProduceRead, "Manav alımları, ödemeleri ve tedarikçilerini görüntüleme"},
+	{PermProduceWrite, "Manav alımı, ödemesi, tedarikçisi ve ürünü işlemleri"},
+	{PermTradeRead, "Alacak/verecek ve mal mülk kayıtlarını görüntüleme"},
+	{PermTradeWrite, "Alacak/verecek ve mal mülk kaydı işlemleri"},
+	{PermAuditRead, "İşlem geçmişini görüntüleme"},
+	{PermAuditWrite, "İşlemleri geri alma"},
+}
+
+// SystemRole: Kurulumda oluşturulan rol ve varsayılan izinleri
+type SystemRole struct {
+	Name        UserRole
+	Label       string
+	Description string
+	Permissions []Permission // super admin için boş: tüm izinlere sahiptir
+}
+
+// SystemRoles: Varsayılan roller. İzinler rol ilk oluşturulduğunda yazılır,
+// sonrasında rol yönetiminden yapılan değişiklikler korunur.
+var SystemRoles = []SystemRole{
+	{
+		Name:        RoleSuperAdmin,
+		Label:       "Süper Admin",
+		Description: "Tüm şubeler ve tüm işlemler",
+	},
+	{
+		Name:        RoleBranchAdmin,
+		Label:       "Şube Yöneticisi",
+		Description: "Kendi şubesinin tüm günlük işlemleri",
+		Permissions: []Permission{
+			PermProductRead,
+			PermCashRead, PermCashWrite,
+			PermBankRead, PermBankWrite,
+			PermExpenseRead, PermExpenseWrite,
+			PermReportRead,
+			PermMenuRead, PermSalesRead, PermSalesWrite,
+			PermPurchaseRead, PermPurchaseWrite,
+			PermStockRead, PermStockWrite,
+			PermStockCountRead, PermStockCountWrite, PermStockCountApprove,
+			PermWasteRead, PermWasteWrite,
+			PermProduceRead, PermProduceWrite,
+			PermTradeRead, PermTradeWrite,
+			PermAuditRead, PermAuditWrite,
+		},
+	},
+	{
+		Name:        RoleAccountant,
+		Label:       "Muhasebe",
+		Description: "Kasa, banka, gider, manav ödemeleri ve alacak/verecek; stok ve alımları görüntüleme",
+		Permissions: []Permission{
+			PermProductRead,
+			PermCashRead, PermCashWrite,
+			PermBankRead, PermBankWrite,
+			PermExpenseRead, PermExpenseWrite,
+			PermReportRead,
+			PermSalesRead,
+			PermPurchaseRead,
+			PermStockRead,
+			PermWasteRead,
+			PermProduceRead, PermProduceWrite,
+			PermTradeRead, PermTradeWrite,
+			PermAuditRead,
+		},
+	},
+	{
+		Name:        RoleKitchenStaff,
+		Label:       "Mutfak Personeli",
+		Description: "Sadece stok sayımı ve zayiat girişi",
+		Permissions: []Permission{
+			PermProductRead,
+			PermStockCountRead, PermStockCountWrite,
+			PermWasteRead, PermWasteWrite,
+		},
+	},
+	{
+		Name:        RoleCashier,
+		Label:       "Kasiyer",
+		Description: "Sadece para giriş/çıkışları",
+		Permissions: []Permission{
+			PermCashRead, PermCashWrite,
+		},
+	},
+	{
+		Name:        RoleAuditor,
+		Label:       "Denetçi",
+		Description: "Şube kayıtlarını salt okunur görüntüleme",
+		Permissions: []Permission{
+			PermProductRead,
+			PermCashRead,
+			PermBankRead,
+			PermExpenseRead,
+			PermReportRead,
+			PermMenuRead, PermSalesRead,
+			PermPurchaseRead,
+			PermStockRead,
+			PermStockCountRead,
+			PermWasteRead,
+			PermProduceRead,
+			PermTradeRead,
+			PermAuditRead,
+		},
+	},
+}
@@ -11,6 +11,7 @@ type Shipment struct {
 	TotalAmount float64   `gorm:"not null"`      // toplam maliyet
 	IsStocked   bool      `gorm:"default:false"` // stoka kaydedildi mi?
 	Note        string    `gorm:"size:255"`       // genel not
+	PurchaseOrderID *uint `gorm:"index"`        // eşleştirilen satın alma siparişi
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 
@@ -24,10 +25,18 @@ type ShipmentItem struct {
 	Shipment         Shipment
 	ProductID        uint    `gorm:"index;not null"`
 	Product          Product
-	Quantity         float64 `gorm:"not null"` // miktar
-	UnitPrice        float64 `gorm:"not null"` // KDV'siz birim fiyat
-	UnitPriceWithVAT float64 `gorm:"not null"` // KDV'li birim fiyat
+	Quantity         float64 `gorm:"not null"` // miktar (ürünün temel biriminde)
+	Unit             string  `gorm:"size:20"`  // girilen birim (boşsa temel birim)
+	UnitQuantity     float64                   // girilen birimdeki miktar
+	UnitPrice        float64 `gorm:"not null"` // KDV'siz birim fiyat (temel birim başına)
+	UnitPriceWithVAT float64 `gorm:"not null"` // KDV'li birim fiyat (temel birim başına)
 	TotalPrice       float64 `gorm:"not null"` // KDV'li toplam maliyet (Quantity * UnitPriceWithVAT)
+	VATRate          float64 `gorm:"default:0"` // KDV oranı (%), faturadan geliyorsa
+	PurchaseOrderLineID *uint `gorm:"index"`    // eşleştirilen sipariş kalemi (siparişte olmayan ürünlerde nil)
+	// Parti (lot) takibi - opsiyonel
+	LotNumber        string     `gorm:"size:50;index"` // parti / lot numarası
+	ReceivedDate     *time.Time // teslim alma tarihi (boşsa sevkiyat tarihi)
+	ExpiryDate       *time.Time `gorm:"index"` // son kullanma tarihi
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 }
@@ -10,7 +10,9 @@ type StockEntry struct {
 	ProductID uint    `gorm:"index;not null"`
 	Product   Product
 	Date      time.Time `gorm:"index;not null"` // sayım tarihi
-	Quantity  float64   `gorm:"not null"`      // o anki stok miktarı
+	Quantity  float64   `gorm:"not null"`      // o anki stok miktarı (ürünün temel biriminde)
+	Unit      string    `gorm:"size:20"`       // girilen birim (boşsa temel birim)
+	UnitQuantity float64                       // girilen birimdeki miktar
 	Note      string    `gorm:"size:255"`      // Opsiyonel not (ör: "Sevkiyat #123")
 	CreatedAt time.Time
 	UpdatedAt time.Time
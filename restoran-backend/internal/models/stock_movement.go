@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type StockMovementType string
+
+const (
+	MovementReceipt         StockMovementType = "receipt"          // sevkiyattan stoka giriş
+	MovementCountAdjustment StockMovementType = "count_adjustment" // fiziksel sayım farkı
+	MovementWaste           StockMovementType = "waste"            // zayiat
+	MovementTransferIn      StockMovementType = "transfer_in"      // başka şubeden gelen
+	MovementTransferOut     StockMovementType = "transfer_out"     // başka şubeye giden
+	MovementCorrection      StockMovementType = "correction"       // manuel düzeltme
+)
+
+// StockMovement: Stok defteri hareketi. Mevcut stok = hareketlerin toplamı.
+type StockMovement struct {
+	ID        uint `gorm:"primaryKey"`
+	BranchID  uint `gorm:"index:idx_stock_movement_branch_product;not null"`
+	Branch    Branch
+	ProductID uint `gorm:"index:idx_stock_movement_branch_product;not null"`
+	Product   Product
+	Type      StockMovementType `gorm:"size:20;index;not null"`
+	Quantity  float64           `gorm:"not null"`       // işaretli miktar (giriş +, çıkış -)
+	Date      time.Time         `gorm:"index;not null"` // hareket tarihi
+	// Sadece sayım hareketlerinde: sayılan miktar (Quantity = sayılan - önceki bakiye)
+	CountedQuantity *float64
+	// Hareketi oluşturan kayıt (örn. "shipment" #12, "waste_entry" #5)
+	SourceType string `gorm:"size:30;index:idx_stock_movement_source"`
+	SourceID   uint   `gorm:"index:idx_stock_movement_source"`
+	Note       string `gorm:"size:255"`
+	CreatedAt  time.Time
+}
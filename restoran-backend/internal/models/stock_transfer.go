@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type StockTransferStatus string
+
+const (
+	TransferStatusSent     StockTransferStatus = "sent"     // kaynak şubeden gönderildi (stoktan düşüldü)
+	TransferStatusReceived StockTransferStatus = "received" // hedef şubede teslim alındı (stoka eklendi)
+)
+
+// StockTransfer: Şubeler arası ürün transferi (gönderim -> teslim alma)
+type StockTransfer struct {
+	ID           uint                `gorm:"primaryKey"`
+	FromBranchID uint                `gorm:"index;not null"`
+	FromBranch   Branch              `gorm:"foreignKey:FromBranchID"`
+	ToBranchID   uint                `gorm:"index;not null"`
+	ToBranch     Branch              `gorm:"foreignKey:ToBranchID"`
+	Status       StockTransferStatus `gorm:"size:20;index;not null"`
+	SentDate     time.Time           `gorm:"index;not null"` // gönderim tarihi
+	ReceivedDate *time.Time          // teslim alma tarihi
+	TotalCost    float64             `gorm:"not null"` // kalemlerin toplam maliyeti
+	Note         string              `gorm:"size:255"`
+	SentBy       uint                `gorm:"not null"`
+	ReceivedBy   *uint
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	Items []StockTransferItem `gorm:"foreignKey:TransferID;constraint:OnDelete:CASCADE"`
+}
+
+// StockTransferItem: Transfer edilen her ürün
+type StockTransferItem struct {
+	ID         uint `gorm:"primaryKey"`
+	TransferID uint `gorm:"index;not null"`
+	ProductID  uint `gorm:"index;not null"`
+	Product    Product
+	Quantity   float64 `gorm:"not null"`
+	UnitCost   float64 `gorm:"not null"` // gönderim anındaki son sevkiyat birim fiyatı
+	TotalCost  float64 `gorm:"not null"` // Quantity * UnitCost
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -5,10 +5,19 @@ import "time"
 type UserRole string
 
 const (
-	RoleSuperAdmin  UserRole = "super_admin"
-	RoleBranchAdmin UserRole = "branch_admin"
+	RoleSuperAdmin   UserRole = "super_admin"
+	RoleBranchAdmin  UserRole = "branch_admin"
+	RoleAccountant   UserRole = "accountant"    // muhasebe
+	RoleKitchenStaff UserRole = "kitchen_staff" // mutfak: sayım ve zayiat
+	RoleCashier      UserRole = "cashier"       // kasa: para giriş/çıkışları
+	RoleAuditor      UserRole = "auditor"       // salt okunur denetçi
 )
 
+// IsBranchScoped: Super admin dışındaki tüm roller sadece kendi şubesinde işlem yapar
+func (r UserRole) IsBranchScoped() bool {
+	return r != RoleSuperAdmin
+}
+
 type User struct {
 	ID           uint `gorm:"primaryKey"`
 	BranchID     *uint
@@ -17,6 +26,8 @@ type User struct {
 	Email        string   `gorm:"size:100;uniqueIndex;not null"`
 	PasswordHash string   `gorm:"size:255;not null"`
 	Role         UserRole `gorm:"size:20;not null"`
+	TokenVersion int      `gorm:"not null;default:0"`    // artırılınca tüm oturumlar geçersiz olur (şifre değişimi)
+	IsActive     bool     `gorm:"not null;default:true"` // pasif kullanıcı giriş yapamaz, mevcut tokenları reddedilir
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
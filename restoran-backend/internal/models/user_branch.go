@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserBranch: Kullanıcının yetkili olduğu şube ve o şubedeki rolü (bölge müdürü birden fazla şubeye bağlı olabilir).
+// Role boşsa kullanıcının genel rolü (User.Role) geçerlidir. User.BranchID varsayılan şubedir.
+type UserBranch struct {
+	ID        uint     `gorm:"primaryKey"`
+	UserID    uint     `gorm:"not null;uniqueIndex:idx_user_branch"`
+	User      User     `gorm:"constraint:OnDelete:CASCADE"`
+	BranchID  uint     `gorm:"not null;uniqueIndex:idx_user_branch;index"`
+	Branch    Branch   `gorm:"constraint:OnDelete:CASCADE"`
+	Role      UserRole `gorm:"size:20"` // boş: User.Role
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserSession: Giriş oturumu. Access token oturum ID'sini (jti) taşır; JWTMiddleware her istekte
+// oturumun iptal edilmediğini ve kullanıcının token sürümünün değişmediğini kontrol eder.
+type UserSession struct {
+	ID               uint       `gorm:"primaryKey"`
+	SessionID        string     `gorm:"size:64;uniqueIndex;not null"` // access token jti
+	UserID           uint       `gorm:"index;not null"`
+	User             User       `gorm:"constraint:OnDelete:CASCADE"`
+	RefreshTokenHash string     `gorm:"size:64;uniqueIndex;not null"` // refresh token'ın SHA-256 özeti
+	TokenVersion     int        `gorm:"not null"`                     // oturum açıldığındaki User.TokenVersion
+	UserAgent        string     `gorm:"size:255"`
+	IP               string     `gorm:"size:64"`
+	ExpiresAt        time.Time  `gorm:"index;not null"` // refresh token geçerlilik sonu
+	LastUsedAt       time.Time  // son yenileme
+	RevokedAt        *time.Time `gorm:"index"`
+	RevokeReason     string     `gorm:"size:50"` // logout, admin, password_change...
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
@@ -10,8 +10,12 @@ type WasteEntry struct {
 	ProductID uint    `gorm:"index;not null"`
 	Product   Product
 	Date      time.Time `gorm:"index;not null"` // zayiat tarihi
-	Quantity  float64   `gorm:"not null"`      // zayiat miktarı
+	Quantity  float64   `gorm:"not null"`      // zayiat miktarı (ürünün temel biriminde)
+	Unit      string    `gorm:"size:20"`       // girilen birim (boşsa temel birim)
+	UnitQuantity float64                       // girilen birimdeki miktar
 	Note      string    `gorm:"size:500;not null"` // zorunlu: hangi garson/mutfakçı sebep oldu
+	ShipmentItemID *uint         `gorm:"index"` // zayiatın ait olduğu parti (sevkiyat kalemi), opsiyonel
+	ShipmentItem   *ShipmentItem `gorm:"foreignKey:ShipmentItemID;constraint:OnDelete:SET NULL"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
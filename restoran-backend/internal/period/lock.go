@@ -0,0 +1,38 @@
+package period
+
+import (
+	"fmt"
+	"time"
+
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IsClosed: Şubenin ilgili tarihi içeren dönemi kapatılmış mı?
+func IsClosed(branchID uint, date time.Time) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&models.PeriodLock{}).
+		Where("branch_id = ? AND year = ? AND month = ? AND is_closed = ?", branchID, date.Year(), int(date.Month()), true).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// EnsureOpen: Tarih kapalı bir döneme düşüyorsa yazma işlemini reddeder.
+// Create/update/delete handler'ları kayıt tarihini (update'te eski ve yeni tarihi) buradan geçirmeli.
+func EnsureOpen(branchID uint, dates ...time.Time) error {
+	for _, d := range dates {
+		closed, err := IsClosed(branchID, d)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Dönem durumu kontrol edilemedi")
+		}
+		if closed {
+			return fiber.NewError(fiber.StatusBadRequest,
+				fmt.Sprintf("%02d/%d dönemi kapatılmış, bu tarihe kayıt eklenemez veya değiştirilemez", int(d.Month()), d.Year()))
+		}
+	}
+	return nil
+}
@@ -3,9 +3,9 @@ package produce
 import (
 	"strings"
 
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -29,7 +29,7 @@ type UpdateProduceCategoryRequest struct {
 // GET /api/produce-categories?branch_id=...
 func ListProduceCategoriesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -65,7 +65,7 @@ func CreateProduceCategoryHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Kategori adı zorunlu")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -101,21 +101,10 @@ func UpdateProduceCategoryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var cat models.ProductCategory
-		if err := database.DB.First(&cat, "id = ? AND is_center_product = ?", id, false).Error; err != nil {
+		if err := tenancy.DB(c).First(&cat, "id = ? AND is_center_product = ?", id, false).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
 		}
 
-		// Şube kontrolü - branch_admin sadece kendi şubesine erişebilir
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != cat.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu kategoriye erişim yetkiniz yok")
-			}
-		}
-
 		var body UpdateProduceCategoryRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
@@ -153,21 +142,10 @@ func DeleteProduceCategoryHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var cat models.ProductCategory
-		if err := database.DB.First(&cat, "id = ? AND is_center_product = ?", id, false).Error; err != nil {
+		if err := tenancy.DB(c).First(&cat, "id = ? AND is_center_product = ?", id, false).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Kategori bulunamadı")
 		}
 
-		// Şube kontrolü - branch_admin sadece kendi şubesine erişebilir
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != cat.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu kategoriye erişim yetkiniz yok")
-			}
-		}
-
 		// Kategoriye ait ürün var mı kontrol et (sadece manav ürünleri)
 		var count int64
 		database.DB.Model(&models.Product{}).Where("category_id = ? AND is_center_product = ?", id, false).Count(&count)
@@ -2,12 +2,15 @@ package produce
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,10 +23,15 @@ type CreateProducePurchaseRequest struct {
 	SupplierID  uint    `json:"supplier_id"` // ProduceSupplier ID
 	ProductID   uint    `json:"product_id"`
 	Quantity    float64 `json:"quantity"`
-	UnitPrice   float64 `json:"unit_price"`
-	Date        string  `json:"date"` // "2025-12-09"
+	Unit        string  `json:"unit"`       // opsiyonel, boşsa ürünün temel birimi
+	UnitPrice   float64 `json:"unit_price"` // girilen birim başına
+	Date        string  `json:"date"`       // "2025-12-09"
 	Description string  `json:"description"`
 	BranchID    *uint   `json:"branch_id"` // super_admin için opsiyonel
+	// Parti (lot) takibi - opsiyonel
+	LotNumber    string `json:"lot_number"`
+	ReceivedDate string `json:"received_date"` // boşsa alım tarihi
+	ExpiryDate   string `json:"expiry_date"`
 }
 
 type ProducePurchaseResponse struct {
@@ -34,11 +42,16 @@ type ProducePurchaseResponse struct {
 	ProductID    uint    `json:"product_id"`
 	ProductName  string  `json:"product_name"`
 	ProductUnit  string  `json:"product_unit"`
-	Quantity     float64 `json:"quantity"`
-	UnitPrice    float64 `json:"unit_price"`
+	Quantity     float64 `json:"quantity"`      // temel birimde
+	UnitPrice    float64 `json:"unit_price"`    // temel birim başına
+	Unit         string  `json:"unit"`          // girilen birim
+	UnitQuantity float64 `json:"unit_quantity"` // girilen birimdeki miktar
 	TotalAmount  float64 `json:"total_amount"`
 	Date         string  `json:"date"`
 	Description  string  `json:"description"`
+	LotNumber    string  `json:"lot_number,omitempty"`
+	ReceivedDate string  `json:"received_date,omitempty"`
+	ExpiryDate   string  `json:"expiry_date,omitempty"`
 }
 
 type CreateProducePaymentRequest struct {
@@ -72,92 +85,25 @@ type MonthlyProduceUsageItem struct {
 	TotalQty    float64 `json:"total_qty"`
 	ProductUnit string  `json:"product_unit"`
 	TotalAmount float64 `json:"total_amount"`
+	UsedQty     float64 `json:"used_qty"`     // alınan - zayiat
+	WastedQty   float64 `json:"wasted_qty"`   // ay içi zayiat
+	UnitPrice   float64 `json:"unit_price"`   // son alım birim fiyatı
+	WastedValue float64 `json:"wasted_value"` // wasted_qty * unit_price
 }
 
 type MonthlyProduceUsageResponse struct {
-	BranchID   uint                      `json:"branch_id"`
-	Year       int                       `json:"year"`
-	Month      int                       `json:"month"`
-	Items      []MonthlyProduceUsageItem `json:"items"`
-	GrandTotal float64                   `json:"grand_total"`
+	BranchID         uint                      `json:"branch_id"`
+	Year             int                       `json:"year"`
+	Month            int                       `json:"month"`
+	Items            []MonthlyProduceUsageItem `json:"items"`
+	GrandTotal       float64                   `json:"grand_total"`
+	TotalWastedValue float64                   `json:"total_wasted_value"`
 }
 
 // -------------------------
 // Yardımcı Fonksiyonlar
 // -------------------------
 
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
-func resolveBranchIDFromBodyOrRole(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 // -------------------------
 // Produce Purchase Handlers
 // -------------------------
@@ -174,7 +120,7 @@ func CreateProducePurchaseHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "supplier_id, product_id, quantity ve unit_price zorunlu ve > 0 olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -184,6 +130,11 @@ func CreateProducePurchaseHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Tedarikçi var mı ve bu şubeye ait mi?
 		var supplier models.ProduceSupplier
 		if err := database.DB.First(&supplier, "id = ? AND branch_id = ?", body.SupplierID, branchID).Error; err != nil {
@@ -198,15 +149,31 @@ func CreateProducePurchaseHandler() fiber.Handler {
 
 		totalAmount := body.Quantity * body.UnitPrice
 
+		// Miktar ve birim fiyat ürünün temel birimine çevrilir (toplam değişmez)
+		quantity, factor, err := units.ToBase(database.DB, models.UnitKindProduce, product.ID, product.Name, product.Unit, body.Unit, body.Quantity)
+		if err != nil {
+			return err
+		}
+
+		lotNumber, receivedDate, expiryDate, err := parseLot(body.LotNumber, body.ReceivedDate, body.ExpiryDate, d)
+		if err != nil {
+			return err
+		}
+
 		purchase := models.ProducePurchase{
-			BranchID:    branchID,
-			SupplierID:  body.SupplierID,
-			ProductID:   body.ProductID,
-			Quantity:    body.Quantity,
-			UnitPrice:   body.UnitPrice,
-			TotalAmount: totalAmount,
-			Date:        d,
-			Description: body.Description,
+			BranchID:     branchID,
+			SupplierID:   body.SupplierID,
+			ProductID:    body.ProductID,
+			Quantity:     quantity,
+			Unit:         units.EnteredUnit(product.Unit, body.Unit),
+			UnitQuantity: body.Quantity,
+			UnitPrice:    body.UnitPrice / factor,
+			TotalAmount:  totalAmount,
+			Date:         d,
+			Description:  body.Description,
+			LotNumber:    lotNumber,
+			ReceivedDate: receivedDate,
+			ExpiryDate:   expiryDate,
 		}
 
 		if err := database.DB.Create(&purchase).Error; err != nil {
@@ -214,17 +181,19 @@ func CreateProducePurchaseHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
-				"id":           purchase.ID,
-				"branch_id":    purchase.BranchID,
-				"product_id":   purchase.ProductID,
-				"quantity":     purchase.Quantity,
-				"unit_price":   purchase.UnitPrice,
-				"total_amount": purchase.TotalAmount,
-				"date":         purchase.Date.Format("2006-01-02"),
-				"description":  purchase.Description,
+				"id":            purchase.ID,
+				"branch_id":     purchase.BranchID,
+				"product_id":    purchase.ProductID,
+				"quantity":      purchase.Quantity,
+				"unit":          purchase.Unit,
+				"unit_quantity": purchase.UnitQuantity,
+				"unit_price":    purchase.UnitPrice,
+				"total_amount":  purchase.TotalAmount,
+				"date":          purchase.Date.Format("2006-01-02"),
+				"description":   purchase.Description,
 			}
 			branchIDForLog := &purchase.BranchID
 			if logErr := audit.WriteLog(audit.LogOptions{
@@ -252,9 +221,14 @@ func CreateProducePurchaseHandler() fiber.Handler {
 			ProductUnit:  product.Unit,
 			Quantity:     purchase.Quantity,
 			UnitPrice:    purchase.UnitPrice,
+			Unit:         purchase.Unit,
+			UnitQuantity: purchase.UnitQuantity,
 			TotalAmount:  purchase.TotalAmount,
 			Date:         purchase.Date.Format("2006-01-02"),
 			Description:  purchase.Description,
+			LotNumber:    purchase.LotNumber,
+			ReceivedDate: formatDatePtr(purchase.ReceivedDate),
+			ExpiryDate:   formatDatePtr(purchase.ExpiryDate),
 		})
 	}
 }
@@ -262,7 +236,7 @@ func CreateProducePurchaseHandler() fiber.Handler {
 // GET /api/produce-purchases?branch_id=...&from=...&to=...&product_id=...
 func ListProducePurchasesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -325,11 +299,16 @@ func ListProducePurchasesHandler() fiber.Handler {
 				ProductID:    r.ProductID,
 				ProductName:  r.Product.Name,
 				ProductUnit:  r.Product.Unit,
-				Quantity:    r.Quantity,
-				UnitPrice:   r.UnitPrice,
-				TotalAmount: r.TotalAmount,
-				Date:        r.Date.Format("2006-01-02"),
-				Description: r.Description,
+				Quantity:     r.Quantity,
+				UnitPrice:    r.UnitPrice,
+				Unit:         units.EnteredUnit(r.Product.Unit, r.Unit),
+				UnitQuantity: enteredQuantity(r.UnitQuantity, r.Quantity),
+				TotalAmount:  r.TotalAmount,
+				Date:         r.Date.Format("2006-01-02"),
+				Description:  r.Description,
+				LotNumber:    r.LotNumber,
+				ReceivedDate: formatDatePtr(r.ReceivedDate),
+				ExpiryDate:   formatDatePtr(r.ExpiryDate),
 			})
 		}
 
@@ -340,7 +319,7 @@ func ListProducePurchasesHandler() fiber.Handler {
 // GET /api/produce-purchases/balance?branch_id=...
 func GetProduceBalanceHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -385,7 +364,7 @@ func GetProduceBalanceHandler() fiber.Handler {
 // GET /api/produce-purchases/monthly-usage?branch_id=...&year=2025&month=12
 func GetMonthlyProduceUsageHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -424,11 +403,62 @@ func GetMonthlyProduceUsageHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Aylık kullanım hesaplanamadı")
 		}
 
-		// Ürün bilgilerini çek
-		productIDs := make([]uint, 0, len(rows))
+		// Ay içi zayiat (ürün bazında)
+		type wasteRow struct {
+			ProductID uint    `gorm:"column:product_id"`
+			WastedQty float64 `gorm:"column:wasted_qty"`
+		}
+		var wasteRows []wasteRow
+		if err := database.DB.
+			Model(&models.ProduceWaste{}).
+			Select("product_id, SUM(quantity) as wasted_qty").
+			Where("branch_id = ? AND date >= ? AND date <= ?", branchID, firstDay, lastDay).
+			Group("product_id").
+			Scan(&wasteRows).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Aylık zayiat hesaplanamadı")
+		}
+		wasteMap := make(map[uint]float64, len(wasteRows))
+		for _, w := range wasteRows {
+			wasteMap[w.ProductID] = w.WastedQty
+		}
+
+		// Zayiat, ay sonuna kadarki son alım fiyatıyla değerlenir
+		type priceRow struct {
+			ProductID uint    `gorm:"column:product_id"`
+			UnitPrice float64 `gorm:"column:unit_price"`
+		}
+		var priceRows []priceRow
+		if err := database.DB.Raw(`
+			SELECT DISTINCT ON (product_id) product_id, unit_price
+			FROM produce_purchases
+			WHERE branch_id = ? AND date <= ?
+			ORDER BY product_id, date DESC, id DESC`, branchID, lastDay).
+			Scan(&priceRows).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birim fiyatlar hesaplanamadı")
+		}
+		priceMap := make(map[uint]float64, len(priceRows))
+		for _, p := range priceRows {
+			priceMap[p.ProductID] = p.UnitPrice
+		}
+
+		// Ürün bilgilerini çek (alımı olmayan ama zayiatı olan ürünler dahil)
+		productIDs := make([]uint, 0, len(rows)+len(wasteRows))
 		for _, r := range rows {
 			productIDs = append(productIDs, r.ProductID)
 		}
+		for _, w := range wasteRows {
+			found := false
+			for _, r := range rows {
+				if r.ProductID == w.ProductID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				rows = append(rows, row{ProductID: w.ProductID})
+				productIDs = append(productIDs, w.ProductID)
+			}
+		}
 
 		var products []models.ProduceProduct
 		if len(productIDs) > 0 {
@@ -456,15 +486,22 @@ func GetMonthlyProduceUsageHandler() fiber.Handler {
 				continue // Ürün bulunamadı, atla
 			}
 
+			wastedQty := wasteMap[r.ProductID]
+			unitPrice := priceMap[r.ProductID]
 			item := MonthlyProduceUsageItem{
 				ProductID:   r.ProductID,
 				ProductName: product.Name,
 				ProductUnit: product.Unit,
 				TotalQty:    r.TotalQty,
 				TotalAmount: r.TotalAmount,
+				UsedQty:     r.TotalQty - wastedQty,
+				WastedQty:   wastedQty,
+				UnitPrice:   unitPrice,
+				WastedValue: wastedQty * unitPrice,
 			}
 			resp.Items = append(resp.Items, item)
 			resp.GrandTotal += r.TotalAmount
+			resp.TotalWastedValue += item.WastedValue
 		}
 
 		return c.JSON(resp)
@@ -487,7 +524,7 @@ func CreateProducePaymentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "supplier_id ve amount zorunlu ve > 0 olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -497,6 +534,11 @@ func CreateProducePaymentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Tedarikçi var mı ve bu şubeye ait mi?
 		var supplier models.ProduceSupplier
 		if err := database.DB.First(&supplier, "id = ? AND branch_id = ?", body.SupplierID, branchID).Error; err != nil {
@@ -516,7 +558,7 @@ func CreateProducePaymentHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          payment.ID,
@@ -556,7 +598,7 @@ func CreateProducePaymentHandler() fiber.Handler {
 // GET /api/produce-payments?branch_id=...&from=...&to=...
 func ListProducePaymentsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -613,3 +655,49 @@ func ListProducePaymentsHandler() fiber.Handler {
 	}
 }
 
+
+// enteredQuantity: Girilen birimdeki miktar (birim desteğinden önceki kayıtlarda temel miktar)
+func enteredQuantity(unitQuantity, quantity float64) float64 {
+	if unitQuantity == 0 {
+		return quantity
+	}
+	return unitQuantity
+}
+
+// parseLot: Alımın parti bilgileri. Parti numarası veya son kullanma tarihi yoksa
+// parti takibi yapılmaz; varsa teslim tarihi verilmediğinde alım tarihi kullanılır.
+func parseLot(lotNumber, receivedStr, expiryStr string, purchaseDate time.Time) (string, *time.Time, *time.Time, error) {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if lotNumber == "" && expiryStr == "" {
+		return "", nil, nil, nil
+	}
+
+	received := purchaseDate
+	if receivedStr != "" {
+		d, err := time.Parse("2006-01-02", receivedStr)
+		if err != nil {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, "received_date formatı 'YYYY-MM-DD' olmalı")
+		}
+		received = d
+	}
+
+	var expiry *time.Time
+	if expiryStr != "" {
+		d, err := time.Parse("2006-01-02", expiryStr)
+		if err != nil {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, "expiry_date formatı 'YYYY-MM-DD' olmalı")
+		}
+		if d.Before(received) {
+			return "", nil, nil, fiber.NewError(fiber.StatusBadRequest, "Son kullanma tarihi teslim tarihinden önce olamaz")
+		}
+		expiry = &d
+	}
+	return lotNumber, &received, expiry, nil
+}
+
+func formatDatePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"strings"
 
+	"restoran-backend/internal/audit"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -90,6 +93,24 @@ func CreateProduceProductHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Ürün oluşturulamadı: %v", err))
 		}
 
+		// Audit log (manav ürünleri şubeden bağımsız)
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "produce_product",
+				EntityID:    p.ID,
+				Action:      models.AuditActionCreate,
+				Description: fmt.Sprintf("Manav ürünü eklendi: %s", p.Name),
+				Before:      nil,
+				After:       map[string]interface{}{"id": p.ID, "name": p.Name, "unit": p.Unit, "stock_code": p.StockCode},
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.Status(fiber.StatusCreated).JSON(ProduceProductResponse{
 			ID:        p.ID,
 			Name:      p.Name,
@@ -114,6 +135,8 @@ func UpdateProduceProductHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
 		}
 
+		beforeData := map[string]interface{}{"id": p.ID, "name": p.Name, "unit": p.Unit, "stock_code": p.StockCode}
+
 		if body.Name != nil {
 			name := strings.TrimSpace(*body.Name)
 			if name == "" {
@@ -131,6 +154,9 @@ func UpdateProduceProductHandler() fiber.Handler {
 			if unit == "" {
 				return fiber.NewError(fiber.StatusBadRequest, "Unit boş olamaz")
 			}
+			if err := units.EnsureBaseUnitChange(database.DB, models.UnitKindProduce, p.ID, p.Unit, unit); err != nil {
+				return err
+			}
 			p.Unit = unit
 		}
 		if body.StockCode != nil {
@@ -149,6 +175,24 @@ func UpdateProduceProductHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Manav ürünü güncellenemedi: %v", err))
 		}
 
+		// Audit log (manav ürünleri şubeden bağımsız)
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "produce_product",
+				EntityID:    p.ID,
+				Action:      models.AuditActionUpdate,
+				Description: fmt.Sprintf("Manav ürünü güncellendi: %s", p.Name),
+				Before:      beforeData,
+				After:       map[string]interface{}{"id": p.ID, "name": p.Name, "unit": p.Unit, "stock_code": p.StockCode},
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.JSON(ProduceProductResponse{
 			ID:        p.ID,
 			Name:      p.Name,
@@ -185,6 +229,24 @@ func DeleteProduceProductHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusInternalServerError, "Manav ürünü silinemedi")
 		}
 
+		// Audit log (manav ürünleri şubeden bağımsız)
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err == nil {
+			if logErr := audit.WriteLog(audit.LogOptions{
+				BranchID:    nil,
+				UserID:      userID,
+				UserName:    userName,
+				EntityType:  "produce_product",
+				EntityID:    p.ID,
+				Action:      models.AuditActionDelete,
+				Description: fmt.Sprintf("Manav ürünü silindi: %s", p.Name),
+				Before:      map[string]interface{}{"id": p.ID, "name": p.Name, "unit": p.Unit, "stock_code": p.StockCode},
+				After:       nil,
+			}); logErr != nil {
+				fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+			}
+		}
+
 		return c.SendStatus(fiber.StatusNoContent)
 	}
 }
@@ -5,9 +5,9 @@ import (
 	"strings"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -53,7 +53,7 @@ func CreateProduceSupplierHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "isim boş olamaz")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -69,7 +69,7 @@ func CreateProduceSupplierHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          supplier.ID,
@@ -107,7 +107,7 @@ func CreateProduceSupplierHandler() fiber.Handler {
 // GET /api/produce-suppliers?branch_id=...
 func ListProduceSuppliersHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -140,21 +140,10 @@ func UpdateProduceSupplierHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var supplier models.ProduceSupplier
-		if err := database.DB.First(&supplier, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&supplier, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Tedarikçi bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != supplier.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu tedarikçiye erişim yetkiniz yok")
-			}
-		}
-
 		var body UpdateProduceSupplierRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
@@ -198,7 +187,7 @@ func UpdateProduceSupplierHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          supplier.ID,
@@ -237,21 +226,10 @@ func DeleteProduceSupplierHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var supplier models.ProduceSupplier
-		if err := database.DB.First(&supplier, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&supplier, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Tedarikçi bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != supplier.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu tedarikçiye erişim yetkiniz yok")
-			}
-		}
-
 		// İlişkili kayıtları da sil (tüm kayıtlarıyla birlikte)
 		// Önce ilişkili kayıtları say (audit log için)
 		var purchaseCount int64
@@ -261,13 +239,25 @@ func DeleteProduceSupplierHandler() fiber.Handler {
 		var wasteCount int64
 		database.DB.Model(&models.ProduceWaste{}).Where("supplier_id = ?", supplier.ID).Count(&wasteCount)
 
+		// Silinecek kayıtlar log'a yazılır (undo ile birlikte geri gelsin)
+		var purchases []models.ProducePurchase
+		database.DB.Where("supplier_id = ?", supplier.ID).Find(&purchases)
+		var payments []models.ProducePayment
+		database.DB.Where("supplier_id = ?", supplier.ID).Find(&payments)
+		var wastes []models.ProduceWaste
+		database.DB.Where("supplier_id = ?", supplier.ID).Find(&wastes)
+
 		beforeData := map[string]interface{}{
 			"id":             supplier.ID,
+			"branch_id":      supplier.BranchID,
 			"name":           supplier.Name,
 			"description":    supplier.Description,
 			"purchase_count": purchaseCount,
 			"payment_count":  paymentCount,
 			"waste_count":    wasteCount,
+			"purchases":      purchases,
+			"payments":       payments,
+			"wastes":         wastes,
 		}
 
 		// Transaction başlat - tüm silme işlemlerini atomik yap
@@ -310,7 +300,7 @@ func DeleteProduceSupplierHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			totalDeleted := purchaseCount + paymentCount + wasteCount
 			desc := fmt.Sprintf("Manav tedarikçi silindi: %s", supplier.Name)
@@ -0,0 +1,37 @@
+package produce
+
+import (
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("produce_product", audit.EntityConfig{
+		Model: &models.ProduceProduct{}, // şubeden bağımsız
+	})
+	audit.RegisterEntity("produce_supplier", audit.EntityConfig{
+		Model: &models.ProduceSupplier{},
+		Children: []audit.ChildRelation{
+			{Key: "purchases", Model: &models.ProducePurchase{}, ForeignKey: "supplier_id", Restrict: true},
+			{Key: "payments", Model: &models.ProducePayment{}, ForeignKey: "supplier_id", Restrict: true},
+			{Key: "wastes", Model: &models.ProduceWaste{}, ForeignKey: "supplier_id", Restrict: true},
+		},
+		BranchColumn: "branch_id",
+	})
+	audit.RegisterEntity("produce_purchase", audit.EntityConfig{
+		Model:        &models.ProducePurchase{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+	audit.RegisterEntity("produce_payment", audit.EntityConfig{
+		Model:        &models.ProducePayment{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+	audit.RegisterEntity("produce_waste", audit.EntityConfig{
+		Model:        &models.ProduceWaste{},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+}
@@ -2,32 +2,40 @@ package produce
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"restoran-backend/internal/audit"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
+	"restoran-backend/internal/units"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type ProduceWasteResponse struct {
-	ID          uint    `json:"id"`
-	BranchID    uint    `json:"branch_id"`
-	ProductID   uint    `json:"product_id"`
-	ProductName string  `json:"product_name"`
-	PurchaseID  *uint   `json:"purchase_id"`
-	Quantity    float64 `json:"quantity"`
-	Date        string  `json:"date"`
-	Description string  `json:"description"`
-	CreatedAt   string  `json:"created_at"`
+	ID           uint    `json:"id"`
+	BranchID     uint    `json:"branch_id"`
+	ProductID    uint    `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	PurchaseID   *uint   `json:"purchase_id"`
+	Quantity     float64 `json:"quantity"`      // temel birimde
+	Unit         string  `json:"unit"`          // girilen birim
+	UnitQuantity float64 `json:"unit_quantity"` // girilen birimdeki miktar
+	Date         string  `json:"date"`
+	Description  string  `json:"description"`
+	CreatedAt    string  `json:"created_at"`
 }
 
 type CreateProduceWasteRequest struct {
 	SupplierID  uint    `json:"supplier_id"` // ProduceSupplier ID
 	ProductID   uint    `json:"product_id"`
-	PurchaseID  *uint   `json:"purchase_id"` // Opsiyonel
+	PurchaseID  *uint   `json:"purchase_id"` // Opsiyonel, zayiatın ait olduğu alım (parti)
+	LotNumber   string  `json:"lot_number"`  // Opsiyonel, purchase_id yerine parti numarası
 	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit"` // Opsiyonel, boşsa ürünün temel birimi
 	Date        string  `json:"date"` // "2025-12-09"
 	Description string  `json:"description"`
 	BranchID    *uint   `json:"branch_id"` // super_admin için opsiyonel
@@ -37,13 +45,11 @@ type UpdateProduceWasteRequest struct {
 	ProductID   *uint    `json:"product_id"`
 	PurchaseID  *uint    `json:"purchase_id"`
 	Quantity    *float64 `json:"quantity"`
+	Unit        *string  `json:"unit"` // quantity ile birlikte, boşsa temel birim
 	Date        *string  `json:"date"`
 	Description *string  `json:"description"`
 }
 
-// getUserInfo fonksiyonu handler.go'da tanımlı, burada kullanıyoruz
-// resolveBranchIDFromBodyOrRole ve resolveBranchIDFromQueryOrRole fonksiyonları handler.go'da tanımlı
-
 // POST /api/produce-waste
 func CreateProduceWasteHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -56,7 +62,7 @@ func CreateProduceWasteHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "supplier_id, product_id ve quantity zorunlu ve quantity 0'dan büyük olmalı")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -66,6 +72,11 @@ func CreateProduceWasteHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		// Tedarikçi var mı ve bu şubeye ait mi?
 		var supplier models.ProduceSupplier
 		if err := database.DB.First(&supplier, "id = ? AND branch_id = ?", body.SupplierID, branchID).Error; err != nil {
@@ -78,25 +89,58 @@ func CreateProduceWasteHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Manav ürünü bulunamadı")
 		}
 
-		// PurchaseID varsa kontrol et (supplier_id uyumlu olmalı)
+		// Parti numarası verildiyse şubenin o tedarikçiden aldığı aynı numaralı en son partisi
+		if (body.PurchaseID == nil || *body.PurchaseID == 0) && strings.TrimSpace(body.LotNumber) != "" {
+			var lot models.ProducePurchase
+			if err := database.DB.Where("branch_id = ? AND supplier_id = ? AND product_id = ? AND lot_number = ?",
+				branchID, body.SupplierID, body.ProductID, strings.TrimSpace(body.LotNumber)).
+				Order("date DESC, id DESC").First(&lot).Error; err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Parti bulunamadı: "+body.LotNumber)
+			}
+			body.PurchaseID = &lot.ID
+		}
+
+		quantity, _, err := units.ToBase(database.DB, models.UnitKindProduce, product.ID, product.Name, product.Unit, body.Unit, body.Quantity)
+		if err != nil {
+			return err
+		}
+
+		// PurchaseID varsa kontrol et (supplier_id uyumlu olmalı, partide yeterli miktar kalmalı)
 		if body.PurchaseID != nil && *body.PurchaseID > 0 {
 			var purchase models.ProducePurchase
-			if err := database.DB.First(&purchase, "id = ?", *body.PurchaseID).Error; err != nil {
+			if err := database.DB.First(&purchase, "id = ? AND branch_id = ?", *body.PurchaseID, branchID).Error; err != nil {
 				return fiber.NewError(fiber.StatusBadRequest, "Alım kaydı bulunamadı")
 			}
 			if purchase.SupplierID != body.SupplierID {
 				return fiber.NewError(fiber.StatusBadRequest, "Alım kaydı seçilen tedarikçiye ait değil")
 			}
+			if purchase.LotNumber != "" || purchase.ExpiryDate != nil {
+				if purchase.ProductID != body.ProductID {
+					return fiber.NewError(fiber.StatusBadRequest, "Parti seçilen ürüne ait değil")
+				}
+				var wasted float64
+				if err := database.DB.Model(&models.ProduceWaste{}).
+					Select("COALESCE(SUM(quantity), 0)").
+					Where("purchase_id = ?", purchase.ID).
+					Scan(&wasted).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Parti zayiatı hesaplanamadı")
+				}
+				if quantity > purchase.Quantity-wasted+1e-9 {
+					return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Partide yeterli miktar yok (kalan: %.2f %s)", purchase.Quantity-wasted, product.Unit))
+				}
+			}
 		}
 
 		waste := models.ProduceWaste{
-			BranchID:    branchID,
-			SupplierID:  body.SupplierID,
-			ProductID:   body.ProductID,
-			PurchaseID:  body.PurchaseID, // zaten *uint
-			Quantity:    body.Quantity,
-			Date:        d,
-			Description: body.Description,
+			BranchID:     branchID,
+			SupplierID:   body.SupplierID,
+			ProductID:    body.ProductID,
+			PurchaseID:   body.PurchaseID, // zaten *uint
+			Quantity:     quantity,
+			Unit:         units.EnteredUnit(product.Unit, body.Unit),
+			UnitQuantity: body.Quantity,
+			Date:         d,
+			Description:  body.Description,
 		}
 
 		if err := database.DB.Create(&waste).Error; err != nil {
@@ -104,7 +148,7 @@ func CreateProduceWasteHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			_ = audit.WriteLog(audit.LogOptions{
 				BranchID:    &branchID,
@@ -120,15 +164,17 @@ func CreateProduceWasteHandler() fiber.Handler {
 		}
 
 		return c.Status(fiber.StatusCreated).JSON(ProduceWasteResponse{
-			ID:          waste.ID,
-			BranchID:    waste.BranchID,
-			ProductID:   waste.ProductID,
-			ProductName: product.Name,
-			PurchaseID:  waste.PurchaseID,
-			Quantity:    waste.Quantity,
-			Date:        waste.Date.Format("2006-01-02"),
-			Description: waste.Description,
-			CreatedAt:   waste.CreatedAt.Format("2006-01-02 15:04:05"),
+			ID:           waste.ID,
+			BranchID:     waste.BranchID,
+			ProductID:    waste.ProductID,
+			ProductName:  product.Name,
+			PurchaseID:   waste.PurchaseID,
+			Quantity:     waste.Quantity,
+			Unit:         waste.Unit,
+			UnitQuantity: waste.UnitQuantity,
+			Date:         waste.Date.Format("2006-01-02"),
+			Description:  waste.Description,
+			CreatedAt:    waste.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 }
@@ -136,7 +182,7 @@ func CreateProduceWasteHandler() fiber.Handler {
 // GET /api/produce-waste
 func ListProduceWasteHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -163,15 +209,17 @@ func ListProduceWasteHandler() fiber.Handler {
 		res := make([]ProduceWasteResponse, 0, len(wastes))
 		for _, w := range wastes {
 			res = append(res, ProduceWasteResponse{
-				ID:          w.ID,
-				BranchID:    w.BranchID,
-				ProductID:   w.ProductID,
-				ProductName: w.Product.Name,
-				PurchaseID:  w.PurchaseID,
-				Quantity:    w.Quantity,
-				Date:        w.Date.Format("2006-01-02"),
-				Description: w.Description,
-				CreatedAt:   w.CreatedAt.Format("2006-01-02 15:04:05"),
+				ID:           w.ID,
+				BranchID:     w.BranchID,
+				ProductID:    w.ProductID,
+				ProductName:  w.Product.Name,
+				PurchaseID:   w.PurchaseID,
+				Quantity:     w.Quantity,
+				Unit:         units.EnteredUnit(w.Product.Unit, w.Unit),
+				UnitQuantity: enteredQuantity(w.UnitQuantity, w.Quantity),
+				Date:         w.Date.Format("2006-01-02"),
+				Description:  w.Description,
+				CreatedAt:    w.CreatedAt.Format("2006-01-02 15:04:05"),
 			})
 		}
 
@@ -185,9 +233,10 @@ func UpdateProduceWasteHandler() fiber.Handler {
 		id := c.Params("id")
 
 		var waste models.ProduceWaste
-		if err := database.DB.First(&waste, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&waste, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Zayiat kaydı bulunamadı")
 		}
+		oldDate := waste.Date
 
 		var body UpdateProduceWasteRequest
 		if err := c.BodyParser(&body); err != nil {
@@ -205,7 +254,7 @@ func UpdateProduceWasteHandler() fiber.Handler {
 		if body.PurchaseID != nil {
 			if *body.PurchaseID > 0 {
 				var purchase models.ProducePurchase
-				if err := database.DB.First(&purchase, "id = ?", *body.PurchaseID).Error; err != nil {
+				if err := database.DB.First(&purchase, "id = ? AND branch_id = ?", *body.PurchaseID, waste.BranchID).Error; err != nil {
 					return fiber.NewError(fiber.StatusBadRequest, "Alım kaydı bulunamadı")
 				}
 				waste.PurchaseID = body.PurchaseID
@@ -218,7 +267,21 @@ func UpdateProduceWasteHandler() fiber.Handler {
 			if *body.Quantity <= 0 {
 				return fiber.NewError(fiber.StatusBadRequest, "Quantity 0'dan büyük olmalı")
 			}
-			waste.Quantity = *body.Quantity
+			var product models.ProduceProduct
+			if err := database.DB.First(&product, "id = ?", waste.ProductID).Error; err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Manav ürünü bulunamadı")
+			}
+			unit := ""
+			if body.Unit != nil {
+				unit = *body.Unit
+			}
+			quantity, _, err := units.ToBase(database.DB, models.UnitKindProduce, product.ID, product.Name, product.Unit, unit, *body.Quantity)
+			if err != nil {
+				return err
+			}
+			waste.Quantity = quantity
+			waste.Unit = units.EnteredUnit(product.Unit, unit)
+			waste.UnitQuantity = *body.Quantity
 		}
 
 		if body.Date != nil {
@@ -233,6 +296,11 @@ func UpdateProduceWasteHandler() fiber.Handler {
 			waste.Description = *body.Description
 		}
 
+		// Kapalı döneme ait kayıt değiştirilemez (eski ve yeni tarih)
+		if err := period.EnsureOpen(waste.BranchID, oldDate, waste.Date); err != nil {
+			return err
+		}
+
 		if err := database.DB.Save(&waste).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat kaydı güncellenemedi")
 		}
@@ -242,15 +310,17 @@ func UpdateProduceWasteHandler() fiber.Handler {
 		database.DB.First(&product, "id = ?", waste.ProductID)
 
 		return c.JSON(ProduceWasteResponse{
-			ID:          waste.ID,
-			BranchID:    waste.BranchID,
-			ProductID:   waste.ProductID,
-			ProductName: product.Name,
-			PurchaseID:  waste.PurchaseID,
-			Quantity:    waste.Quantity,
-			Date:        waste.Date.Format("2006-01-02"),
-			Description: waste.Description,
-			CreatedAt:   waste.CreatedAt.Format("2006-01-02 15:04:05"),
+			ID:           waste.ID,
+			BranchID:     waste.BranchID,
+			ProductID:    waste.ProductID,
+			ProductName:  product.Name,
+			PurchaseID:   waste.PurchaseID,
+			Quantity:     waste.Quantity,
+			Unit:         units.EnteredUnit(product.Unit, waste.Unit),
+			UnitQuantity: enteredQuantity(waste.UnitQuantity, waste.Quantity),
+			Date:         waste.Date.Format("2006-01-02"),
+			Description:  waste.Description,
+			CreatedAt:    waste.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 }
@@ -260,11 +330,20 @@ func DeleteProduceWasteHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 
-		if err := database.DB.Delete(&models.ProduceWaste{}, "id = ?", id).Error; err != nil {
+		var waste models.ProduceWaste
+		if err := tenancy.DB(c).First(&waste, "id = ?", id).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Zayiat kaydı bulunamadı")
+		}
+
+		// Kapalı dönemdeki kayıt silinemez
+		if err := period.EnsureOpen(waste.BranchID, waste.Date); err != nil {
+			return err
+		}
+
+		if err := database.DB.Delete(&waste).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Zayiat kaydı silinemedi")
 		}
 
 		return c.SendStatus(fiber.StatusNoContent)
 	}
 }
-
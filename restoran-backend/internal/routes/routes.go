@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"restoran-backend/internal/admin"
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/cashflow"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/dashboard"
+	"restoran-backend/internal/expense"
+	"restoran-backend/internal/financial"
+	"restoran-backend/internal/inventory"
+	"restoran-backend/internal/jobs"
+	"restoran-backend/internal/menu"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/produce"
+	"restoran-backend/internal/trade"
+	"restoran-backend/internal/units"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Register: API route'larını izinleriyle birlikte uygulamaya ekler.
+// Sunucu ve şube kapsamı testleri aynı route tablosunu kullanır.
+func Register(app *fiber.App, cfg *config.Config) {
+	api := app.Group("/api")
+
+	// Public auth
+	api.Post("/auth/register-super-admin", auth.RegisterSuperAdminHandler(cfg))
+	api.Post("/auth/login", auth.LoginHandler(cfg))
+	api.Post("/auth/refresh", auth.RefreshHandler(cfg))
+	api.Post("/auth/reset-password", admin.ResetPasswordHandler()) // admin'in verdiği tek kullanımlık token ile
+
+	// Protected
+	protected := api.Group("")
+	protected.Use(auth.JWTMiddleware(cfg))
+
+	// Oturum sahibinin kendi işlemleri (izin gerektirmez)
+	protected.Get("/auth/me", auth.MeHandler())
+	protected.Post("/auth/logout", auth.LogoutHandler())
+	protected.Post("/auth/change-password", admin.ChangePasswordHandler(cfg))
+
+	// Yönetim route'ları - her route kendi iznini ister (varsayılan rollerde sadece super admin)
+	adminRoutes := protected.Group("/admin")
+
+	// Şube yönetimi
+	adminRoutes.Post("/branches", auth.RequirePermission(models.PermBranchWrite), admin.CreateBranchHandler())
+	adminRoutes.Get("/branches", auth.RequirePermission(models.PermBranchRead), admin.ListBranchesHandler())
+	adminRoutes.Get("/branches/:id", auth.RequirePermission(models.PermBranchRead), admin.GetBranchHandler())
+	adminRoutes.Put("/branches/:id", auth.RequirePermission(models.PermBranchWrite), admin.UpdateBranchHandler())
+	adminRoutes.Delete("/branches/:id", auth.RequirePermission(models.PermBranchWrite), admin.DeleteBranchHandler())
+	adminRoutes.Post("/branches/:id/admin", auth.RequirePermission(models.PermBranchWrite), admin.CreateBranchAdminHandler())
+	adminRoutes.Get("/branches/:id/admins", auth.RequirePermission(models.PermBranchRead), admin.ListBranchAdminsHandler())
+
+	// Kullanıcı yönetimi, oturumlar ve şube yetkileri
+	adminRoutes.Get("/users", auth.RequirePermission(models.PermUserRead), admin.ListUsersHandler())
+	adminRoutes.Get("/users/:id", auth.RequirePermission(models.PermUserRead), admin.GetUserHandler())
+	adminRoutes.Put("/users/:id", auth.RequirePermission(models.PermUserWrite), admin.UpdateUserHandler())
+	adminRoutes.Delete("/users/:id", auth.RequirePermission(models.PermUserWrite), admin.DeleteUserHandler())
+	adminRoutes.Post("/users/:id/deactivate", auth.RequirePermission(models.PermUserWrite), admin.DeactivateUserHandler())
+	adminRoutes.Post("/users/:id/activate", auth.RequirePermission(models.PermUserWrite), admin.ActivateUserHandler())
+	adminRoutes.Post("/users/:id/reset-password", auth.RequirePermission(models.PermUserWrite), admin.CreatePasswordResetHandler(cfg))
+	adminRoutes.Post("/users/:id/revoke-sessions", auth.RequirePermission(models.PermUserWrite), admin.RevokeUserSessionsHandler())
+	adminRoutes.Get("/users/:id/branches", auth.RequirePermission(models.PermUserRead), admin.ListUserBranchesHandler())
+	adminRoutes.Put("/users/:id/branches", auth.RequirePermission(models.PermUserWrite), admin.SaveUserBranchesHandler()) // çok şubeli kullanıcı (şube bazında rol)
+
+	// Roller ve izinler
+	adminRoutes.Get("/permissions", auth.RequirePermission(models.PermRoleRead), admin.ListPermissionsHandler())
+	adminRoutes.Get("/roles", auth.RequirePermission(models.PermRoleRead), admin.ListRolesHandler())
+	adminRoutes.Post("/roles", auth.RequirePermission(models.PermRoleWrite), admin.CreateRoleHandler())
+	adminRoutes.Put("/roles/:name", auth.RequirePermission(models.PermRoleWrite), admin.UpdateRoleHandler())
+	adminRoutes.Delete("/roles/:name", auth.RequirePermission(models.PermRoleWrite), admin.DeleteRoleHandler())
+
+	// Ürün yönetimi
+	// ÖNEMLİ: Parametresiz route'lar parametreli route'lardan ÖNCE tanımlanmalı
+	adminRoutes.Post("/products", auth.RequirePermission(models.PermProductWrite), inventory.CreateProductHandler())
+	adminRoutes.Delete("/products", auth.RequirePermission(models.PermProductWrite), inventory.DeleteAllProductsHandler(cfg)) // Parametresiz route önce
+	adminRoutes.Post("/products/bulk-import-b2b", auth.RequirePermission(models.PermProductWrite), inventory.BulkImportB2BProductsHandler(cfg))
+	adminRoutes.Put("/products/:id", auth.RequirePermission(models.PermProductWrite), inventory.UpdateProductHandler())
+	adminRoutes.Put("/products/:id/units", auth.RequirePermission(models.PermProductWrite), units.SaveUnitsHandler(models.UnitKindProduct))
+	adminRoutes.Delete("/products/:id", auth.RequirePermission(models.PermProductWrite), inventory.DeleteProductHandler()) // Parametreli route sonra
+
+	// Arka plan işleri
+	adminRoutes.Get("/jobs", auth.RequirePermission(models.PermJobRead), jobs.ListJobsHandler())
+	adminRoutes.Get("/jobs/:id", auth.RequirePermission(models.PermJobRead), jobs.GetJobHandler())
+	adminRoutes.Post("/jobs/:id/cancel", auth.RequirePermission(models.PermJobWrite), jobs.CancelJobHandler())
+	adminRoutes.Post("/jobs/:id/resume", auth.RequirePermission(models.PermJobWrite), jobs.ResumeJobHandler())
+
+	// Gider kategorileri
+	adminRoutes.Post("/expense-categories", auth.RequirePermission(models.PermExpenseCategoryWrite), expense.CreateExpenseCategoryHandler())
+	adminRoutes.Put("/expense-categories/:id", auth.RequirePermission(models.PermExpenseCategoryWrite), expense.UpdateExpenseCategoryHandler())
+	adminRoutes.Delete("/expense-categories/:id", auth.RequirePermission(models.PermExpenseCategoryWrite), expense.DeleteExpenseCategoryHandler())
+
+	// Banka/Kart yönetimi
+	adminRoutes.Post("/bank-accounts", auth.RequirePermission(models.PermBankAccountWrite), admin.CreateBankAccountHandler())
+	adminRoutes.Get("/bank-accounts", auth.RequirePermission(models.PermBankAccountRead), admin.ListBankAccountsHandler())
+	adminRoutes.Put("/bank-accounts/:id", auth.RequirePermission(models.PermBankAccountWrite), admin.UpdateBankAccountHandler())
+	adminRoutes.Delete("/bank-accounts/:id", auth.RequirePermission(models.PermBankAccountWrite), admin.DeleteBankAccountHandler())
+
+	// Aylık raporlama
+	adminRoutes.Post("/monthly-reports", auth.RequirePermission(models.PermMonthlyReportWrite), admin.CreateMonthlyReportHandler())
+	adminRoutes.Get("/monthly-reports", auth.RequirePermission(models.PermMonthlyReportRead), admin.ListMonthlyReportsHandler())
+	adminRoutes.Get("/monthly-reports/:id", auth.RequirePermission(models.PermMonthlyReportRead), admin.GetMonthlyReportHandler())
+	adminRoutes.Post("/monthly-reports/:id/reopen", auth.RequirePermission(models.PermMonthlyReportWrite), admin.ReopenMonthlyReportHandler()) // Dönemi yeniden aç
+
+	// Menü ve reçeteler
+	adminRoutes.Post("/menu-items", auth.RequirePermission(models.PermMenuWrite), menu.CreateMenuItemHandler())
+	adminRoutes.Put("/menu-items/:id", auth.RequirePermission(models.PermMenuWrite), menu.UpdateMenuItemHandler())
+	adminRoutes.Delete("/menu-items/:id", auth.RequirePermission(models.PermMenuWrite), menu.DeleteMenuItemHandler())
+
+	// Ortak (auth gerektiren) route’lar
+
+	// Ürün listesi
+	protected.Get("/products", auth.RequirePermission(models.PermProductRead), inventory.ListProductsHandler())
+	protected.Get("/products/:id/units", auth.RequirePermission(models.PermProductRead), units.ListUnitsHandler(models.UnitKindProduct))
+
+	// Para giriş/çıkış
+	protected.Post("/cash-movements", auth.RequirePermission(models.PermCashWrite), cashflow.CreateCashMovementHandler())
+	protected.Get("/cash-movements", auth.RequirePermission(models.PermCashRead), cashflow.ListCashMovementsHandler())
+	protected.Get("/cash-movements/summary/monthly", auth.RequirePermission(models.PermCashRead), cashflow.MonthlySummaryHandler())
+
+	// Banka/Kart işlemleri
+	protected.Get("/bank-accounts/:id/transactions", auth.RequirePermission(models.PermBankRead), admin.ListBankTransactionsHandler())
+	protected.Post("/bank-accounts/:id/transactions", auth.RequirePermission(models.PermBankWrite), admin.CreateBankTransactionHandler())
+	protected.Put("/bank-accounts/:id/transactions/:txId", auth.RequirePermission(models.PermBankWrite), admin.UpdateBankTransactionHandler())
+	protected.Delete("/bank-accounts/:id/transactions/:txId", auth.RequirePermission(models.PermBankWrite), admin.DeleteBankTransactionHandler())
+	protected.Get("/bank-accounts/:id/statement", auth.RequirePermission(models.PermBankRead), admin.BankAccountStatementHandler())
+
+	// Dashboard
+	protected.Get("/dashboard/cash-chart", auth.RequirePermission(models.PermReportRead), dashboard.CashChartHandler())
+
+	// Merkez sevkiyatları & stok (eski - geriye dönük uyumluluk için)
+	protected.Post("/center-shipments", auth.RequirePermission(models.PermPurchaseWrite), inventory.CreateCenterShipmentHandler())
+	protected.Get("/center-shipments", auth.RequirePermission(models.PermPurchaseRead), inventory.ListCenterShipmentsHandler())
+	protected.Post("/stock-snapshots", auth.RequirePermission(models.PermStockCountWrite), inventory.CreateStockSnapshotHandler())
+	protected.Get("/stock-snapshots", auth.RequirePermission(models.PermStockCountRead), inventory.ListStockSnapshotsHandler())
+	protected.Get("/stock-report/monthly", auth.RequirePermission(models.PermStockRead), inventory.MonthlyStockReportHandler())
+
+	// Yeni sevkiyat sistemi
+	protected.Post("/shipments", auth.RequirePermission(models.PermPurchaseWrite), inventory.CreateShipmentHandler(cfg))
+	protected.Get("/shipments", auth.RequirePermission(models.PermPurchaseRead), inventory.ListShipmentsHandler())
+	protected.Post("/shipments/:id/stock", auth.RequirePermission(models.PermPurchaseWrite), inventory.StockShipmentHandler())
+	protected.Post("/shipments/:id/match", auth.RequirePermission(models.PermPurchaseWrite), inventory.MatchShipmentHandler())             // siparişle elle eşleştirme
+	protected.Post("/shipments/parse-order-url", auth.RequirePermission(models.PermPurchaseWrite), inventory.ParseB2BOrderURLHandler(cfg)) // B2B URL parsing endpoint
+	protected.Post("/shipments/parse-pdf", auth.RequirePermission(models.PermPurchaseWrite), inventory.ParseShipmentPDFHandler())          // PDF fatura yükleme
+	protected.Post("/shipments/parse-order-file", auth.RequirePermission(models.PermPurchaseWrite), inventory.ParseOrderFileHandler())     // PDF / CSV / e-Fatura XML yükleme
+	protected.Post("/invoices/import-ubl", auth.RequirePermission(models.PermPurchaseWrite), inventory.ImportEInvoiceHandler(cfg))         // e-Fatura (UBL-TR) içe aktarma
+
+	// Alış fiyatı geçmişi ve fiyat değişim uyarıları
+	protected.Get("/price-history", auth.RequirePermission(models.PermPurchaseRead), inventory.PriceHistoryHandler())
+	protected.Get("/price-alerts", auth.RequirePermission(models.PermPurchaseRead), inventory.ListPriceAlertsHandler())
+	protected.Post("/price-alerts/:id/review", auth.RequirePermission(models.PermPurchaseWrite), inventory.ReviewPriceAlertHandler())
+
+	// Yeni stok sistemi
+	protected.Post("/stock-entries", auth.RequirePermission(models.PermStockCountWrite), inventory.CreateStockEntryHandler())
+	protected.Get("/stock-entries", auth.RequirePermission(models.PermStockCountRead), inventory.ListStockEntriesHandler())
+	protected.Get("/stock-entries/current", auth.RequirePermission(models.PermStockCountRead), inventory.GetCurrentStockHandler())
+	// Ürün sıralama yönetimi (manuel)
+	protected.Get("/stock-entries/order", auth.RequirePermission(models.PermStockCountRead), inventory.GetProductOrderHandler())
+	protected.Post("/stock-entries/order", auth.RequirePermission(models.PermStockCountWrite), inventory.SaveProductOrderHandler())
+	protected.Delete("/stock-entries/order", auth.RequirePermission(models.PermStockCountWrite), inventory.ClearProductOrderHandler())
+	protected.Get("/stock-entries/usage-between-counts", auth.RequirePermission(models.PermStockRead), inventory.GetStockUsageBetweenCountsHandler())
+	protected.Get("/stock-usage/monthly", auth.RequirePermission(models.PermStockRead), inventory.GetMonthlyStockUsageHandler())
+
+	// Stok defteri
+	protected.Get("/stock-movements", auth.RequirePermission(models.PermStockRead), inventory.ListStockMovementsHandler())
+	protected.Post("/stock-movements/corrections", auth.RequirePermission(models.PermStockWrite), inventory.CreateStockCorrectionHandler())
+	protected.Get("/stock-valuation", auth.RequirePermission(models.PermStockRead), inventory.StockValuationHandler()) // FIFO / ağırlıklı ortalama stok değeri
+
+	// Sayım oturumları (açık -> onaya gönderildi -> onaylandı; stoka onayda yazılır)
+	protected.Post("/count-sessions", auth.RequirePermission(models.PermStockCountWrite), inventory.CreateCountSessionHandler())
+	protected.Get("/count-sessions", auth.RequirePermission(models.PermStockCountRead), inventory.ListCountSessionsHandler())
+	protected.Get("/count-sessions/:id", auth.RequirePermission(models.PermStockCountRead), inventory.GetCountSessionHandler())
+	protected.Put("/count-sessions/:id/lines", auth.RequirePermission(models.PermStockCountWrite), inventory.RecordCountLinesHandler())
+	protected.Post("/count-sessions/:id/submit", auth.RequirePermission(models.PermStockCountWrite), inventory.SubmitCountSessionHandler())
+	protected.Post("/count-sessions/:id/reopen", auth.RequirePermission(models.PermStockCountApprove), inventory.ReopenCountSessionHandler())
+	protected.Post("/count-sessions/:id/approve", auth.RequirePermission(models.PermStockCountApprove), inventory.ApproveCountSessionHandler())
+	protected.Post("/count-sessions/:id/cancel", auth.RequirePermission(models.PermStockCountApprove), inventory.CancelCountSessionHandler())
+
+	// Parti (lot) takibi - stok FIFO tüketilir
+	protected.Get("/lots", auth.RequirePermission(models.PermStockRead), inventory.ListLotsHandler())
+	protected.Get("/expiring-lots", auth.RequirePermission(models.PermStockRead), inventory.ExpiringLotsHandler()) // son kullanma tarihi yaklaşan / geçmiş partiler
+
+	// Stok seviyeleri ve sipariş önerisi
+	protected.Get("/par-levels", auth.RequirePermission(models.PermStockRead), inventory.ListParLevelsHandler())
+	protected.Put("/par-levels", auth.RequirePermission(models.PermStockWrite), inventory.SaveParLevelsHandler())
+	protected.Delete("/par-levels/:id", auth.RequirePermission(models.PermStockWrite), inventory.DeleteParLevelHandler())
+	protected.Get("/reorder-suggestions", auth.RequirePermission(models.PermPurchaseRead), inventory.ReorderSuggestionsHandler()) // shipment alanı POST /api/shipments gövdesi
+
+	// Satın alma siparişleri (taslak -> gönderildi -> kısmi teslim -> teslim alındı)
+	protected.Post("/purchase-orders", auth.RequirePermission(models.PermPurchaseWrite), inventory.CreatePurchaseOrderHandler())
+	protected.Get("/purchase-orders", auth.RequirePermission(models.PermPurchaseRead), inventory.ListPurchaseOrdersHandler())
+	protected.Get("/purchase-orders/discrepancies", auth.RequirePermission(models.PermPurchaseRead), inventory.PurchaseOrderDiscrepanciesHandler()) // tedarikçi bazında teslimat farkları
+	protected.Get("/purchase-orders/:id", auth.RequirePermission(models.PermPurchaseRead), inventory.GetPurchaseOrderHandler())
+	protected.Put("/purchase-orders/:id", auth.RequirePermission(models.PermPurchaseWrite), inventory.UpdatePurchaseOrderHandler())
+	protected.Delete("/purchase-orders/:id", auth.RequirePermission(models.PermPurchaseWrite), inventory.DeletePurchaseOrderHandler())
+	protected.Post("/purchase-orders/:id/send", auth.RequirePermission(models.PermPurchaseWrite), inventory.SendPurchaseOrderHandler())
+
+	// Şubeler arası transfer
+	protected.Post("/stock-transfers", auth.RequirePermission(models.PermStockWrite), inventory.CreateStockTransferHandler())
+	protected.Get("/stock-transfers", auth.RequirePermission(models.PermStockRead), inventory.ListStockTransfersHandler())
+	protected.Get("/stock-transfers/:id", auth.RequirePermission(models.PermStockRead), inventory.GetStockTransferHandler())
+	protected.Post("/stock-transfers/:id/receive", auth.RequirePermission(models.PermStockWrite), inventory.ReceiveStockTransferHandler())
+
+	// Zayiat girişleri
+	protected.Post("/waste-entries", auth.RequirePermission(models.PermWasteWrite), inventory.CreateWasteEntryHandler())
+	protected.Get("/waste-entries", auth.RequirePermission(models.PermWasteRead), inventory.ListWasteEntriesHandler())
+	protected.Get("/waste-entries/summary", auth.RequirePermission(models.PermWasteRead), inventory.WasteSummaryHandler()) // :id'den önce
+	protected.Get("/waste-entries/:id", auth.RequirePermission(models.PermWasteRead), inventory.GetWasteEntryHandler())
+	protected.Delete("/waste-entries/:id", auth.RequirePermission(models.PermWasteWrite), inventory.DeleteWasteEntryHandler())
+
+	// Menü satışları ve yemek maliyeti
+	protected.Get("/menu-items", auth.RequirePermission(models.PermMenuRead), menu.ListMenuItemsHandler())
+	protected.Post("/menu-sales", auth.RequirePermission(models.PermSalesWrite), menu.SaveMenuSalesHandler())
+	protected.Get("/menu-sales", auth.RequirePermission(models.PermSalesRead), menu.ListMenuSalesHandler())
+	protected.Get("/menu-reports/food-cost", auth.RequirePermission(models.PermSalesRead), menu.FoodCostReportHandler())
+
+	// Giderler
+	protected.Get("/expense-categories", auth.RequirePermission(models.PermExpenseRead), expense.ListExpenseCategoriesHandler())
+	protected.Post("/expenses", auth.RequirePermission(models.PermExpenseWrite), expense.CreateExpenseHandler())
+	protected.Get("/expenses", auth.RequirePermission(models.PermExpenseRead), expense.ListExpensesHandler())
+	protected.Get("/expenses/summary/monthly", auth.RequirePermission(models.PermExpenseRead), expense.MonthlyExpenseSummaryHandler())
+	protected.Post("/expense-payments", auth.RequirePermission(models.PermExpenseWrite), expense.CreateExpensePaymentHandler())
+	protected.Get("/expense-payments", auth.RequirePermission(models.PermExpenseRead), expense.ListExpensePaymentsHandler())
+	protected.Get("/expense-payments/balance-by-category", auth.RequirePermission(models.PermExpenseRead), expense.GetCategoryExpenseBalanceHandler())
+
+	// Manav tedarikçi yönetimi
+	protected.Post("/produce-suppliers", auth.RequirePermission(models.PermProduceWrite), produce.CreateProduceSupplierHandler())
+	protected.Get("/produce-suppliers", auth.RequirePermission(models.PermProduceRead), produce.ListProduceSuppliersHandler())
+	protected.Put("/produce-suppliers/:id", auth.RequirePermission(models.PermProduceWrite), produce.UpdateProduceSupplierHandler())
+	protected.Delete("/produce-suppliers/:id", auth.RequirePermission(models.PermProduceWrite), produce.DeleteProduceSupplierHandler())
+
+	// Manav yönetimi
+	protected.Post("/produce-purchases", auth.RequirePermission(models.PermProduceWrite), produce.CreateProducePurchaseHandler())
+	protected.Get("/produce-purchases", auth.RequirePermission(models.PermProduceRead), produce.ListProducePurchasesHandler())
+	protected.Get("/produce-purchases/balance", auth.RequirePermission(models.PermProduceRead), produce.GetProduceBalanceHandler())
+	protected.Get("/produce-purchases/monthly-usage", auth.RequirePermission(models.PermProduceRead), produce.GetMonthlyProduceUsageHandler())
+	protected.Post("/produce-payments", auth.RequirePermission(models.PermProduceWrite), produce.CreateProducePaymentHandler())
+	protected.Get("/produce-payments", auth.RequirePermission(models.PermProduceRead), produce.ListProducePaymentsHandler())
+
+	// Manav ürün yönetimi
+	protected.Get("/produce-products", auth.RequirePermission(models.PermProductRead), produce.ListProduceProductsHandler())
+	protected.Post("/produce-products", auth.RequirePermission(models.PermProduceWrite), produce.CreateProduceProductHandler())
+	protected.Put("/produce-products/:id", auth.RequirePermission(models.PermProduceWrite), produce.UpdateProduceProductHandler())
+	protected.Get("/produce-products/:id/units", auth.RequirePermission(models.PermProductRead), units.ListUnitsHandler(models.UnitKindProduce))
+	protected.Put("/produce-products/:id/units", auth.RequirePermission(models.PermProduceWrite), units.SaveUnitsHandler(models.UnitKindProduce))
+	protected.Delete("/produce-products/:id", auth.RequirePermission(models.PermProduceWrite), produce.DeleteProduceProductHandler())
+
+	// Manav zayiat yönetimi
+	protected.Post("/produce-waste", auth.RequirePermission(models.PermWasteWrite), produce.CreateProduceWasteHandler())
+	protected.Get("/produce-waste", auth.RequirePermission(models.PermWasteRead), produce.ListProduceWasteHandler())
+	protected.Put("/produce-waste/:id", auth.RequirePermission(models.PermWasteWrite), produce.UpdateProduceWasteHandler())
+	protected.Delete("/produce-waste/:id", auth.RequirePermission(models.PermWasteWrite), produce.DeleteProduceWasteHandler())
+
+	// Ticaret işlemleri (Alacak/Verecek)
+	protected.Post("/trades", auth.RequirePermission(models.PermTradeWrite), trade.CreateTradeTransactionHandler())
+	protected.Get("/trades", auth.RequirePermission(models.PermTradeRead), trade.ListTradeTransactionsHandler())
+	protected.Put("/trades/:id", auth.RequirePermission(models.PermTradeWrite), trade.UpdateTradeTransactionHandler())
+	protected.Delete("/trades/:id", auth.RequirePermission(models.PermTradeWrite), trade.DeleteTradeTransactionHandler())
+	protected.Post("/trades/:id/payments", auth.RequirePermission(models.PermTradeWrite), trade.CreateTradePaymentHandler())
+	protected.Get("/trades/:id/payments", auth.RequirePermission(models.PermTradeRead), trade.ListTradePaymentsHandler())
+	protected.Delete("/trades/:id/payments/:payment_id", auth.RequirePermission(models.PermTradeWrite), trade.DeleteTradePaymentHandler())
+
+	// Mal Mülk
+	protected.Post("/properties", auth.RequirePermission(models.PermTradeWrite), trade.CreatePropertyHandler())
+	protected.Get("/properties", auth.RequirePermission(models.PermTradeRead), trade.ListPropertiesHandler())
+	protected.Put("/properties/:id", auth.RequirePermission(models.PermTradeWrite), trade.UpdatePropertyHandler())
+	protected.Delete("/properties/:id", auth.RequirePermission(models.PermTradeWrite), trade.DeletePropertyHandler())
+
+	// Genel finansal özet (eski)
+	protected.Get("/financial-summary/monthly", auth.RequirePermission(models.PermReportRead), financial.MonthlyFinancialSummaryHandler())
+
+	// Yeni finansal özet (günlük, haftalık, aylık)
+	protected.Get("/financial-summary/daily", auth.RequirePermission(models.PermReportRead), cashflow.GetDailyFinancialSummaryHandler())
+	protected.Get("/financial-summary/weekly", auth.RequirePermission(models.PermReportRead), cashflow.GetWeeklyFinancialSummaryHandler())
+	protected.Get("/financial-summary/monthly-new", auth.RequirePermission(models.PermReportRead), cashflow.GetMonthlyFinancialSummaryHandler())
+
+	// Audit logs
+	protected.Get("/audit-logs", auth.RequirePermission(models.PermAuditRead), audit.ListAuditLogsHandler())
+	protected.Post("/audit-logs/:id/undo", auth.RequirePermission(models.PermAuditWrite), audit.UndoAuditLogHandler())
+}
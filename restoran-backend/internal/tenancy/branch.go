@@ -0,0 +1,132 @@
+// Package tenancy: İsteğin şube kapsamı. Kullanıcının işlem yapabileceği şubeler JWT'deki şube
+// yetkilerinden ve RequirePermission'ın daralttığı şubelerden çözülür; handler'lar şube kontrolünü
+// kendileri yazmaz, buradaki çözümleyicileri ve şube kapsamlı sorguları (DB, Scope) kullanır.
+package tenancy
+
+import (
+	"fmt"
+	"sort"
+
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CtxBranchKey: ResolveBranchID ile çözülen etkin şube (uint)
+const CtxBranchKey = "effective_branch_id"
+
+// allowedBranches: İstekte işlem yapılabilecek şubeler (nil: tüm şubeler - super admin).
+// RequirePermission çalıştıysa sadece route iznini veren roldeki şubeler döner.
+func allowedBranches(c *fiber.Ctx) map[uint]bool {
+	if role, _ := c.Locals(auth.CtxUserRoleKey).(models.UserRole); !role.IsBranchScoped() {
+		return nil
+	}
+	if permitted, ok := c.Locals(auth.CtxPermittedBranchesKey).(map[uint]bool); ok {
+		return permitted
+	}
+
+	allowed := make(map[uint]bool)
+	if branches, ok := c.Locals(auth.CtxBranchesKey).(map[uint]models.UserRole); ok {
+		for id := range branches {
+			allowed[id] = true
+		}
+	}
+	if len(allowed) == 0 {
+		if bPtr, ok := c.Locals(auth.CtxBranchIDKey).(*uint); ok && bPtr != nil {
+			allowed[*bPtr] = true
+		}
+	}
+	return allowed
+}
+
+// BranchAllowed: Kullanıcı bu şubenin kayıtlarında işlem yapabilir mi?
+func BranchAllowed(c *fiber.Ctx, branchID uint) bool {
+	allowed := allowedBranches(c)
+	return allowed == nil || allowed[branchID]
+}
+
+// AllowedBranchIDs: İstekte işlem yapılabilecek şubeler, sıralı (super admin için nil: tüm şubeler)
+func AllowedBranchIDs(c *fiber.Ctx) []uint {
+	allowed := allowedBranches(c)
+	if allowed == nil {
+		return nil
+	}
+	ids := make([]uint, 0, len(allowed))
+	for id := range allowed {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// ResolveBranchID: İstekte işlem yapılacak şube; sonuç CtxBranchKey'e yazılır (bkz. BranchID).
+//   - super admin: requested zorunlu
+//   - diğer roller: requested verilmişse yetkili şubelerden biri olmalı; verilmemişse
+//     varsayılan şube, o da yetkili değilse tek yetkili şube kullanılır
+func ResolveBranchID(c *fiber.Ctx, requested *uint) (uint, error) {
+	branchID, err := resolve(c, requested)
+	if err != nil {
+		return 0, err
+	}
+	c.Locals(CtxBranchKey, branchID)
+	return branchID, nil
+}
+
+func resolve(c *fiber.Ctx, requested *uint) (uint, error) {
+	allowed := allowedBranches(c)
+	if allowed == nil {
+		if requested == nil {
+			return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
+		}
+		return *requested, nil
+	}
+
+	if requested != nil {
+		if !allowed[*requested] {
+			return 0, fiber.NewError(fiber.StatusForbidden, "Bu şubede işlem yetkiniz yok")
+		}
+		return *requested, nil
+	}
+
+	if bPtr, ok := c.Locals(auth.CtxBranchIDKey).(*uint); ok && bPtr != nil && allowed[*bPtr] {
+		return *bPtr, nil
+	}
+	if len(allowed) == 1 {
+		for id := range allowed {
+			return id, nil
+		}
+	}
+	if len(allowed) == 0 {
+		return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
+	}
+	return 0, fiber.NewError(fiber.StatusBadRequest, "Birden fazla şubede yetkiniz var, branch_id zorunlu")
+}
+
+// ResolveBranchIDFromQuery: ResolveBranchID, şube ?branch_id= parametresinden okunur
+func ResolveBranchIDFromQuery(c *fiber.Ctx) (uint, error) {
+	requested, err := QueryBranchID(c)
+	if err != nil {
+		return 0, err
+	}
+	return ResolveBranchID(c, requested)
+}
+
+// BranchID: İstekte daha önce çözülmüş etkin şube (çözülmemişse false)
+func BranchID(c *fiber.Ctx) (uint, bool) {
+	branchID, ok := c.Locals(CtxBranchKey).(uint)
+	return branchID, ok
+}
+
+// QueryBranchID: ?branch_id= parametresi (verilmemişse nil)
+func QueryBranchID(c *fiber.Ctx) (*uint, error) {
+	bidStr := c.Query("branch_id")
+	if bidStr == "" {
+		return nil, nil
+	}
+	var bid uint
+	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
+	}
+	return &bid, nil
+}
@@ -0,0 +1,42 @@
+package tenancy
+
+import (
+	"restoran-backend/internal/database"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Scope: Sorguyu kullanıcının yetkili olduğu şubelerin kayıtlarıyla sınırlar (super admin için koşulsuz).
+// Kayıt id ile yüklenirken kullanılır; başka şubenin kaydı gorm.ErrRecordNotFound döner.
+func Scope(c *fiber.Ctx) func(*gorm.DB) *gorm.DB {
+	return ScopeColumns(c, "branch_id")
+}
+
+// ScopeColumns: Scope, kayıt verilen kolonlardan herhangi biri yetkili şubedeyse görünür
+// (ör. transferde from_branch_id / to_branch_id)
+func ScopeColumns(c *fiber.Ctx, columns ...string) func(*gorm.DB) *gorm.DB {
+	ids := AllowedBranchIDs(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if ids == nil {
+			return db
+		}
+		if len(ids) == 0 {
+			return db.Where("1 = 0")
+		}
+		cond := db.Session(&gorm.Session{NewDB: true})
+		for i, col := range columns {
+			if i == 0 {
+				cond = cond.Where(col+" IN ?", ids)
+			} else {
+				cond = cond.Or(col+" IN ?", ids)
+			}
+		}
+		return db.Where(cond)
+	}
+}
+
+// DB: Şube kapsamlı sorgu başlangıcı (database.DB.Scopes(Scope(c)))
+func DB(c *fiber.Ctx) *gorm.DB {
+	return database.DB.Scopes(Scope(c))
+}
@@ -0,0 +1,458 @@
+package tenancy_test
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/config"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/routes"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testJWTSecret = "tenancy-test-secret"
+
+// TestMain: Handler'lar database.DB kullanır; testler bellek içi SQLite ile çalışır
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		log.Fatalf("veritabanı açılamadı: %v", err)
+	}
+	// Bellek içi veritabanı bağlantıya özeldir; tüm sorgular aynı bağlantıyı kullanmalı
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("veritabanı açılamadı: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	err = db.AutoMigrate(
+		&models.Branch{},
+		&models.User{},
+		&models.UserSession{},
+		&models.UserBranch{},
+		&models.Role{},
+		&models.RolePermission{},
+		&models.Product{},
+		&models.AuditLog{},
+		&models.BankAccount{},
+		&models.BankTransaction{},
+		&models.MonthlyReport{},
+		&models.PeriodLock{},
+		&models.ExpenseCategory{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.PriceAlert{},
+		&models.StockMovement{},
+		&models.StockEntry{},
+		&models.CountSession{},
+		&models.CountSessionLine{},
+		&models.ParLevel{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.StockTransfer{},
+		&models.StockTransferItem{},
+		&models.WasteEntry{},
+		&models.ProduceProduct{},
+		&models.ProduceSupplier{},
+		&models.ProduceWaste{},
+		&models.TradeTransaction{},
+		&models.TradePayment{},
+		&models.Property{},
+	)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+
+	os.Exit(m.Run())
+}
+
+// crossBranchFixture: 1 numaralı şubeye yetkili kullanıcı ve 2 numaralı şubenin kayıtları
+type crossBranchFixture struct {
+	token string
+
+	ownWaste models.WasteEntry // kullanıcının kendi şubesindeki kayıt (kontrol)
+
+	bankAccount  models.BankAccount
+	bankTx       models.BankTransaction
+	report       models.MonthlyReport
+	category     models.ExpenseCategory
+	shipment     models.Shipment
+	priceAlert   models.PriceAlert
+	countSession models.CountSession
+	parLevel     models.ParLevel
+	order        models.PurchaseOrder
+	transfer     models.StockTransfer
+	waste        models.WasteEntry
+	supplier     models.ProduceSupplier
+	produceWaste models.ProduceWaste
+	tradeTx      models.TradeTransaction
+	tradePayment models.TradePayment
+	property     models.Property
+	otherUser    models.User
+	auditLog     models.AuditLog
+}
+
+func mustCreate(t *testing.T, value any) {
+	t.Helper()
+	if err := database.DB.Create(value).Error; err != nil {
+		t.Fatalf("%T oluşturulamadı: %v", value, err)
+	}
+}
+
+func seedCrossBranch(t *testing.T) crossBranchFixture {
+	t.Helper()
+	now := time.Now()
+
+	own := models.Branch{Name: "Kadıköy"}
+	other := models.Branch{Name: "Beşiktaş"}
+	third := models.Branch{Name: "Şişli"}
+	mustCreate(t, &own)
+	mustCreate(t, &other)
+	mustCreate(t, &third)
+
+	// Tüm izinlere sahip, sadece kendi şubesine bağlı şube rolü
+	role := models.Role{Name: "bolge_muduru", Label: "Bölge Müdürü"}
+	mustCreate(t, &role)
+	perms := make([]models.RolePermission, 0, len(models.PermissionCatalog))
+	for _, p := range models.PermissionCatalog {
+		perms = append(perms, models.RolePermission{Role: role.Name, Resource: p.Permission.Resource, Action: p.Permission.Action})
+	}
+	mustCreate(t, &perms)
+	auth.InvalidatePermissions()
+
+	user := models.User{BranchID: &own.ID, Name: "Ayşe", Email: "ayse@example.com", PasswordHash: "x", Role: role.Name, IsActive: true}
+	mustCreate(t, &user)
+	mustCreate(t, &models.UserBranch{UserID: user.ID, BranchID: own.ID})
+	session := models.UserSession{SessionID: "cross-branch", UserID: user.ID, RefreshTokenHash: "cross-branch", ExpiresAt: now.Add(time.Hour)}
+	mustCreate(t, &session)
+
+	branches, err := auth.UserBranches(&user)
+	if err != nil {
+		t.Fatalf("şube yetkileri okunamadı: %v", err)
+	}
+	token, err := auth.GenerateToken(testJWTSecret, &user, branches, session.SessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("token üretilemedi: %v", err)
+	}
+
+	product := models.Product{Name: "Domates", Unit: "kg"}
+	mustCreate(t, &product)
+	produceProduct := models.ProduceProduct{Name: "Maydanoz", Unit: "demet"}
+	mustCreate(t, &produceProduct)
+
+	f := crossBranchFixture{token: token}
+
+	f.bankAccount = models.BankAccount{BranchID: other.ID, Type: models.AccountTypeBank, Name: "Ziraat", Balance: 100, IsActive: true}
+	mustCreate(t, &f.bankAccount)
+	f.bankTx = models.BankTransaction{BankAccountID: f.bankAccount.ID, Type: models.TransactionTypeDeposit, Amount: 100, Date: now}
+	mustCreate(t, &f.bankTx)
+	f.report = models.MonthlyReport{BranchID: other.ID, Year: now.Year(), Month: int(now.Month()), ReportDate: now, ReportData: "{}"}
+	mustCreate(t, &f.report)
+	f.category = models.ExpenseCategory{BranchID: other.ID, Name: "Kira"}
+	mustCreate(t, &f.category)
+	f.shipment = models.Shipment{BranchID: other.ID, Date: now, TotalAmount: 50, Items: []models.ShipmentItem{
+		{ProductID: product.ID, Quantity: 5, UnitPrice: 10, UnitPriceWithVAT: 10, TotalPrice: 50},
+	}}
+	mustCreate(t, &f.shipment)
+	f.priceAlert = models.PriceAlert{BranchID: other.ID, ProductID: product.ID, ShipmentID: f.shipment.ID, Date: now, PreviousPrice: 8, NewPrice: 10, ChangePct: 25, ThresholdPct: 10, Status: models.PriceAlertPending}
+	mustCreate(t, &f.priceAlert)
+	f.countSession = models.CountSession{BranchID: other.ID, Date: now, Status: models.CountSessionSubmitted, OpenedBy: user.ID, Lines: []models.CountSessionLine{
+		{ProductID: product.ID, SortOrder: 1},
+	}}
+	mustCreate(t, &f.countSession)
+	f.parLevel = models.ParLevel{BranchID: other.ID, ProductID: product.ID, MinQuantity: 2, ParQuantity: 10, UpdatedBy: user.ID}
+	mustCreate(t, &f.parLevel)
+	f.order = models.PurchaseOrder{BranchID: other.ID, Supplier: "Merkez", Status: models.PurchaseOrderDraft, OrderDate: now, TotalAmount: 50, CreatedBy: user.ID, Lines: []models.PurchaseOrderLine{
+		{ProductID: product.ID, Quantity: 5, ExpectedUnitPrice: 10},
+	}}
+	mustCreate(t, &f.order)
+	f.transfer = models.StockTransfer{FromBranchID: other.ID, ToBranchID: third.ID, Status: models.TransferStatusSent, SentDate: now, TotalCost: 10, SentBy: user.ID, Items: []models.StockTransferItem{
+		{ProductID: product.ID, Quantity: 1, UnitCost: 10, TotalCost: 10},
+	}}
+	mustCreate(t, &f.transfer)
+	f.ownWaste = models.WasteEntry{BranchID: own.ID, ProductID: product.ID, Date: now, Quantity: 1, Note: "döküldü"}
+	mustCreate(t, &f.ownWaste)
+	f.waste = models.WasteEntry{BranchID: other.ID, ProductID: product.ID, Date: now, Quantity: 1, Note: "düştü"}
+	mustCreate(t, &f.waste)
+	f.supplier = models.ProduceSupplier{BranchID: other.ID, Name: "Hal"}
+	mustCreate(t, &f.supplier)
+	f.produceWaste = models.ProduceWaste{BranchID: other.ID, SupplierID: f.supplier.ID, ProductID: produceProduct.ID, Quantity: 1, Date: now}
+	mustCreate(t, &f.produceWaste)
+	f.tradeTx = models.TradeTransaction{BranchID: other.ID, Type: models.TradeTypeReceivable, Amount: 1000, Date: now}
+	mustCreate(t, &f.tradeTx)
+	f.tradePayment = models.TradePayment{BranchID: other.ID, TradeTransactionID: f.tradeTx.ID, Amount: 100, PaymentDate: now}
+	mustCreate(t, &f.tradePayment)
+	f.property = models.Property{BranchID: other.ID, Name: "Dükkan", Value: 1000}
+	mustCreate(t, &f.property)
+	f.otherUser = models.User{BranchID: &other.ID, Name: "Mehmet", Email: "mehmet@example.com", PasswordHash: "x", Role: models.RoleBranchAdmin, IsActive: true}
+	mustCreate(t, &f.otherUser)
+	mustCreate(t, &models.UserBranch{UserID: f.otherUser.ID, BranchID: other.ID})
+	f.auditLog = models.AuditLog{BranchID: &other.ID, UserID: f.otherUser.ID, EntityType: "property", EntityID: f.property.ID, Action: models.AuditActionDelete, BeforeData: "{}", AfterData: "{}"}
+	mustCreate(t, &f.auditLog)
+
+	return f
+}
+
+// newScopedApp: Sunucunun route tablosu (main.go ile aynı routes.Register; aynı route'lar ve izinler)
+func newScopedApp() *fiber.App {
+	cfg := &config.Config{JWTSecret: testJWTSecret, PasswordResetTTL: time.Hour}
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if e, ok := err.(*fiber.Error); ok {
+				return c.Status(e.Code).JSON(fiber.Map{"error": e.Message})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		},
+	})
+	routes.Register(app, cfg)
+	return app
+}
+
+// Şubeye bağlı olmayan kayıtların id'li route'ları (şube, rol, iş, ürün ve menü tanımları)
+var unscopedRoutePrefixes = []string{
+	"/api/admin/branches/",
+	"/api/admin/roles/",
+	"/api/admin/jobs/",
+	"/api/admin/products/",
+	"/api/products/",
+	"/api/produce-products/",
+	"/api/admin/menu-items/",
+}
+
+// matchRoute: Route kalıbı ("/api/trades/:id") istek yoluyla eşleşiyor mu?
+func matchRoute(pattern, path string) bool {
+	ps, xs := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(ps) != len(xs) {
+		return false
+	}
+	for i := range ps {
+		if !strings.HasPrefix(ps[i], ":") && ps[i] != xs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot: Kaydın veritabanındaki tüm kolonları (nil: kayıt yok)
+func snapshot(t *testing.T, table string, id uint) map[string]any {
+	t.Helper()
+	var rows []map[string]any
+	if err := database.DB.Table(table).Where("id = ?", id).Find(&rows).Error; err != nil {
+		t.Fatalf("%s okunamadı: %v", table, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+// Başka şubenin kaydı id ile okunamaz, güncellenemez, silinemez: 403 veya 404 döner ve kayıt değişmez
+func TestCrossBranchAccessByID(t *testing.T) {
+	f := seedCrossBranch(t)
+	app := newScopedApp()
+
+	id := func(v uint) string { return strconv.FormatUint(uint64(v), 10) }
+	body := `{"name":"x","label":"x","description":"x","note":"x","amount":1,"value":1,"quantity":1,` +
+		`"type":"receivable","date":"2024-01-01","payment_date":"2024-01-01","supplier_id":` + id(f.supplier.ID) + `,` +
+		`"role":"branch_admin","is_active":false,"branch_ids":[` + id(*f.otherUser.BranchID) + `],` +
+		`"lines":[],"items":[],"purchase_order_id":` + id(f.order.ID) + `}`
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/admin/users/" + id(f.otherUser.ID)},
+		{"PUT", "/api/admin/users/" + id(f.otherUser.ID)},
+		{"DELETE", "/api/admin/users/" + id(f.otherUser.ID)},
+		{"POST", "/api/admin/users/" + id(f.otherUser.ID) + "/deactivate"},
+		{"POST", "/api/admin/users/" + id(f.otherUser.ID) + "/activate"},
+		{"POST", "/api/admin/users/" + id(f.otherUser.ID) + "/reset-password"},
+		{"POST", "/api/admin/users/" + id(f.otherUser.ID) + "/revoke-sessions"},
+		{"GET", "/api/admin/users/" + id(f.otherUser.ID) + "/branches"},
+		{"PUT", "/api/admin/users/" + id(f.otherUser.ID) + "/branches"},
+
+		{"PUT", "/api/admin/expense-categories/" + id(f.category.ID)},
+		{"DELETE", "/api/admin/expense-categories/" + id(f.category.ID)},
+
+		{"PUT", "/api/admin/bank-accounts/" + id(f.bankAccount.ID)},
+		{"DELETE", "/api/admin/bank-accounts/" + id(f.bankAccount.ID)},
+		{"GET", "/api/bank-accounts/" + id(f.bankAccount.ID) + "/transactions"},
+		{"POST", "/api/bank-accounts/" + id(f.bankAccount.ID) + "/transactions"},
+		{"PUT", "/api/bank-accounts/" + id(f.bankAccount.ID) + "/transactions/" + id(f.bankTx.ID)},
+		{"DELETE", "/api/bank-accounts/" + id(f.bankAccount.ID) + "/transactions/" + id(f.bankTx.ID)},
+		{"GET", "/api/bank-accounts/" + id(f.bankAccount.ID) + "/statement"},
+
+		{"GET", "/api/admin/monthly-reports/" + id(f.report.ID)},
+		{"POST", "/api/admin/monthly-reports/" + id(f.report.ID) + "/reopen"},
+
+		{"POST", "/api/shipments/" + id(f.shipment.ID) + "/stock"},
+		{"POST", "/api/shipments/" + id(f.shipment.ID) + "/match"},
+		{"POST", "/api/price-alerts/" + id(f.priceAlert.ID) + "/review"},
+
+		{"GET", "/api/count-sessions/" + id(f.countSession.ID)},
+		{"PUT", "/api/count-sessions/" + id(f.countSession.ID) + "/lines"},
+		{"POST", "/api/count-sessions/" + id(f.countSession.ID) + "/submit"},
+		{"POST", "/api/count-sessions/" + id(f.countSession.ID) + "/reopen"},
+		{"POST", "/api/count-sessions/" + id(f.countSession.ID) + "/approve"},
+		{"POST", "/api/count-sessions/" + id(f.countSession.ID) + "/cancel"},
+
+		{"DELETE", "/api/par-levels/" + id(f.parLevel.ID)},
+
+		{"GET", "/api/purchase-orders/" + id(f.order.ID)},
+		{"PUT", "/api/purchase-orders/" + id(f.order.ID)},
+		{"DELETE", "/api/purchase-orders/" + id(f.order.ID)},
+		{"POST", "/api/purchase-orders/" + id(f.order.ID) + "/send"},
+
+		{"GET", "/api/stock-transfers/" + id(f.transfer.ID)},
+		{"POST", "/api/stock-transfers/" + id(f.transfer.ID) + "/receive"},
+
+		{"GET", "/api/waste-entries/" + id(f.waste.ID)},
+		{"DELETE", "/api/waste-entries/" + id(f.waste.ID)},
+
+		{"PUT", "/api/produce-suppliers/" + id(f.supplier.ID)},
+		{"DELETE", "/api/produce-suppliers/" + id(f.supplier.ID)},
+		{"PUT", "/api/produce-waste/" + id(f.produceWaste.ID)},
+		{"DELETE", "/api/produce-waste/" + id(f.produceWaste.ID)},
+
+		{"PUT", "/api/trades/" + id(f.tradeTx.ID)},
+		{"DELETE", "/api/trades/" + id(f.tradeTx.ID)},
+		{"POST", "/api/trades/" + id(f.tradeTx.ID) + "/payments"},
+		{"GET", "/api/trades/" + id(f.tradeTx.ID) + "/payments"},
+		{"DELETE", "/api/trades/" + id(f.tradeTx.ID) + "/payments/" + id(f.tradePayment.ID)},
+		{"PUT", "/api/properties/" + id(f.property.ID)},
+		{"DELETE", "/api/properties/" + id(f.property.ID)},
+
+		{"POST", "/api/audit-logs/" + id(f.auditLog.ID) + "/undo"},
+	}
+
+	// Her istek route tablosunda tanımlı olmalı (router'ın 404'ü kapsam kontrolü sanılmasın) ve
+	// tablodaki şubeye bağlı her id'li route burada denenmeli
+	var scopedRoutes []fiber.Route
+	for _, r := range app.GetRoutes(true) {
+		if r.Method == fiber.MethodHead || !strings.Contains(r.Path, "/:") {
+			continue
+		}
+		unscoped := false
+		for _, prefix := range unscopedRoutePrefixes {
+			unscoped = unscoped || strings.HasPrefix(r.Path, prefix)
+		}
+		if !unscoped {
+			scopedRoutes = append(scopedRoutes, r)
+		}
+	}
+	for _, tt := range tests {
+		found := false
+		for _, r := range scopedRoutes {
+			found = found || (r.Method == tt.method && matchRoute(r.Path, tt.path))
+		}
+		if !found {
+			t.Errorf("%s %s route tablosunda yok", tt.method, tt.path)
+		}
+	}
+	for _, r := range scopedRoutes {
+		covered := false
+		for _, tt := range tests {
+			covered = covered || (r.Method == tt.method && matchRoute(r.Path, tt.path))
+		}
+		if !covered {
+			t.Errorf("%s %s şube kapsamı testinde denenmiyor", r.Method, r.Path)
+		}
+	}
+
+	records := []struct {
+		table string
+		id    uint
+	}{
+		{"users", f.otherUser.ID},
+		{"expense_categories", f.category.ID},
+		{"bank_accounts", f.bankAccount.ID},
+		{"bank_transactions", f.bankTx.ID},
+		{"monthly_reports", f.report.ID},
+		{"shipments", f.shipment.ID},
+		{"price_alerts", f.priceAlert.ID},
+		{"count_sessions", f.countSession.ID},
+		{"par_levels", f.parLevel.ID},
+		{"purchase_orders", f.order.ID},
+		{"stock_transfers", f.transfer.ID},
+		{"waste_entries", f.waste.ID},
+		{"produce_suppliers", f.supplier.ID},
+		{"produce_wastes", f.produceWaste.ID},
+		{"trade_transactions", f.tradeTx.ID},
+		{"trade_payments", f.tradePayment.ID},
+		{"properties", f.property.ID},
+		{"audit_logs", f.auditLog.ID},
+	}
+	before := make([]map[string]any, len(records))
+	for i, r := range records {
+		if before[i] = snapshot(t, r.table, r.id); before[i] == nil {
+			t.Fatalf("%s #%d oluşturulmamış", r.table, r.id)
+		}
+	}
+	var bankTxCount, tradePaymentCount int64
+	database.DB.Model(&models.BankTransaction{}).Count(&bankTxCount)
+	database.DB.Model(&models.TradePayment{}).Count(&tradePaymentCount)
+
+	// Kontrol: aynı kullanıcı kendi şubesinin kaydını okuyabilir (404'ler şube kapsamından gelir)
+	req := httptest.NewRequest("GET", "/api/waste-entries/"+id(f.ownWaste.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("istek başarısız: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("kendi şubesinin kaydı: durum = %d, 200 bekleniyordu", resp.StatusCode)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var reqBody io.Reader
+			if tt.method != "GET" && tt.method != "DELETE" {
+				reqBody = strings.NewReader(body)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, reqBody)
+			req.Header.Set("Authorization", "Bearer "+f.token)
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("istek başarısız: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != fiber.StatusForbidden && resp.StatusCode != fiber.StatusNotFound {
+				msg, _ := io.ReadAll(resp.Body)
+				t.Errorf("durum = %d, 403 veya 404 bekleniyordu (%s)", resp.StatusCode, msg)
+			}
+		})
+	}
+
+	for i, r := range records {
+		if after := snapshot(t, r.table, r.id); !reflect.DeepEqual(before[i], after) {
+			t.Errorf("%s #%d değişti:\nönce:  %v\nsonra: %v", r.table, r.id, before[i], after)
+		}
+	}
+	var count int64
+	if database.DB.Model(&models.BankTransaction{}).Count(&count); count != bankTxCount {
+		t.Errorf("banka hareketi sayısı = %d, %d bekleniyordu", count, bankTxCount)
+	}
+	if database.DB.Model(&models.TradePayment{}).Count(&count); count != tradePaymentCount {
+		t.Errorf("ödeme sayısı = %d, %d bekleniyordu", count, tradePaymentCount)
+	}
+}
@@ -0,0 +1,29 @@
+package tenancy
+
+import (
+	"restoran-backend/internal/auth"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const ctxUserNameKey = "user_name"
+
+// CurrentUser: İsteği yapan kullanıcının id'si ve adı (audit kayıtları için; ad istek boyunca bir kez okunur)
+func CurrentUser(c *fiber.Ctx) (uint, string, error) {
+	userID, ok := c.Locals(auth.CtxUserIDKey).(uint)
+	if !ok {
+		return 0, "", fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
+	}
+	if name, ok := c.Locals(ctxUserNameKey).(string); ok {
+		return userID, name, nil
+	}
+
+	var user models.User
+	if err := database.DB.Select("id", "name").First(&user, "id = ?", userID).Error; err != nil {
+		return 0, "", fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
+	}
+	c.Locals(ctxUserNameKey, user.Name)
+	return userID, user.Name, nil
+}
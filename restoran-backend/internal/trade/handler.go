@@ -6,9 +6,10 @@ import (
 	"time"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/period"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -63,87 +64,10 @@ type TradePaymentResponse struct {
 	CreatedAt          string  `json:"created_at"`
 }
 
-// -------------------------
-// Yardımcı: Kullanıcı bilgilerini al
-// -------------------------
-func getUserInfo(c *fiber.Ctx) (uint, string, *uint, error) {
-	userIDVal := c.Locals(auth.CtxUserIDKey)
-	userID, ok := userIDVal.(uint)
-	if !ok {
-		return 0, "", nil, fiber.NewError(fiber.StatusForbidden, "Kullanıcı bilgisi alınamadı")
-	}
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
-		return 0, "", nil, fiber.NewError(fiber.StatusInternalServerError, "Kullanıcı bulunamadı")
-	}
-
-	var branchID *uint
-	bVal := c.Locals(auth.CtxBranchIDKey)
-	if bPtr, ok := bVal.(*uint); ok && bPtr != nil {
-		branchID = bPtr
-	}
-
-	return userID, user.Name, branchID, nil
-}
-
 // -------------------------
 // Yardımcı: branch ID çöz
 // -------------------------
 
-// body'den gelen branch_id + role
-func resolveBranchIDFromBodyOrRole(c *fiber.Ctx, bodyBranchID *uint) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	if bodyBranchID == nil {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	return *bodyBranchID, nil
-}
-
-// query'den gelen branch_id + role
-func resolveBranchIDFromQueryOrRole(c *fiber.Ctx) (uint, error) {
-	roleVal := c.Locals(auth.CtxUserRoleKey)
-	role, ok := roleVal.(models.UserRole)
-	if !ok {
-		return 0, fiber.NewError(fiber.StatusForbidden, "Rol bilgisi alınamadı")
-	}
-
-	if role == models.RoleBranchAdmin {
-		bVal := c.Locals(auth.CtxBranchIDKey)
-		bPtr, ok := bVal.(*uint)
-		if !ok || bPtr == nil {
-			return 0, fiber.NewError(fiber.StatusForbidden, "Şube bilgisi bulunamadı")
-		}
-		return *bPtr, nil
-	}
-
-	// super_admin
-	bidStr := c.Query("branch_id")
-	if bidStr == "" {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id zorunlu")
-	}
-	var bid uint
-	if _, err := fmt.Sscan(bidStr, &bid); err != nil || bid == 0 {
-		return 0, fiber.NewError(fiber.StatusBadRequest, "branch_id geçersiz")
-	}
-	return bid, nil
-}
-
 // -------------------------
 // Trade Transaction CRUD
 // -------------------------
@@ -167,7 +91,7 @@ func CreateTradeTransactionHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "description boş olamaz")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -177,6 +101,11 @@ func CreateTradeTransactionHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(branchID, d); err != nil {
+			return err
+		}
+
 		tx := models.TradeTransaction{
 			BranchID:    branchID,
 			Type:        models.TradeTransactionType(body.Type),
@@ -190,7 +119,7 @@ func CreateTradeTransactionHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			typeLabel := "Alacak"
 			if tx.Type == models.TradeTypePayable {
@@ -239,7 +168,7 @@ func CreateTradeTransactionHandler() fiber.Handler {
 // GET /api/trades?branch_id=...&type=...
 func ListTradeTransactionsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -293,21 +222,10 @@ func UpdateTradeTransactionHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var tx models.TradeTransaction
-		if err := database.DB.First(&tx, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&tx, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != tx.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu işleme erişim yetkiniz yok")
-			}
-		}
-
 		var body UpdateTradeTransactionRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
@@ -320,6 +238,7 @@ func UpdateTradeTransactionHandler() fiber.Handler {
 			"description": tx.Description,
 			"date":        tx.Date.Format("2006-01-02"),
 		}
+		oldDate := tx.Date
 
 		updated := false
 
@@ -373,12 +292,17 @@ func UpdateTradeTransactionHandler() fiber.Handler {
 			})
 		}
 
+		// Kapalı döneme ait kayıt değiştirilemez (eski ve yeni tarih)
+		if err := period.EnsureOpen(tx.BranchID, oldDate, tx.Date); err != nil {
+			return err
+		}
+
 		if err := database.DB.Save(&tx).Error; err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "İşlem güncellenemedi")
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          tx.ID,
@@ -436,19 +360,19 @@ func DeleteTradeTransactionHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var tx models.TradeTransaction
-		if err := database.DB.First(&tx, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&tx, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != tx.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu işleme erişim yetkiniz yok")
-			}
+		// Kapalı dönemdeki işlem (ve ödemeleri) silinemez
+		closeCheckDates := []time.Time{tx.Date}
+		var txPayments []models.TradePayment
+		database.DB.Where("trade_transaction_id = ?", tx.ID).Find(&txPayments)
+		for _, p := range txPayments {
+			closeCheckDates = append(closeCheckDates, p.PaymentDate)
+		}
+		if err := period.EnsureOpen(tx.BranchID, closeCheckDates...); err != nil {
+			return err
 		}
 
 		// Ödemeler varsa silme (CASCADE constraint ile otomatik)
@@ -460,12 +384,25 @@ func DeleteTradeTransactionHandler() fiber.Handler {
 			database.DB.Where("trade_transaction_id = ?", tx.ID).Delete(&models.TradePayment{})
 		}
 
+		// Silinen ödemeler de log'a yazılır (undo ile birlikte geri gelsin)
+		paymentsData := make([]map[string]interface{}, 0, len(txPayments))
+		for _, p := range txPayments {
+			paymentsData = append(paymentsData, map[string]interface{}{
+				"id":           p.ID,
+				"branch_id":    p.BranchID,
+				"amount":       p.Amount,
+				"payment_date": p.PaymentDate.Format("2006-01-02"),
+				"description":  p.Description,
+			})
+		}
+
 		beforeData := map[string]interface{}{
 			"id":          tx.ID,
 			"type":        string(tx.Type),
 			"amount":      tx.Amount,
 			"description": tx.Description,
 			"date":        tx.Date.Format("2006-01-02"),
+			"payments":    paymentsData,
 		}
 
 		if err := database.DB.Delete(&tx).Error; err != nil {
@@ -473,7 +410,7 @@ func DeleteTradeTransactionHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			typeLabel := "Alacak"
 			if tx.Type == models.TradeTypePayable {
@@ -508,21 +445,10 @@ func CreateTradePaymentHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		txID := c.Params("id")
 		var tx models.TradeTransaction
-		if err := database.DB.First(&tx, "id = ?", txID).Error; err != nil {
+		if err := tenancy.DB(c).First(&tx, "id = ?", txID).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != tx.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu işleme erişim yetkiniz yok")
-			}
-		}
-
 		var body CreateTradePaymentRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
@@ -549,6 +475,11 @@ func CreateTradePaymentHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "Tarih formatı 'YYYY-MM-DD' olmalı")
 		}
 
+		// Kapalı döneme kayıt yazılamaz
+		if err := period.EnsureOpen(tx.BranchID, paymentDate); err != nil {
+			return err
+		}
+
 		payment := models.TradePayment{
 			BranchID:           tx.BranchID,
 			TradeTransactionID: tx.ID,
@@ -562,7 +493,7 @@ func CreateTradePaymentHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			typeLabel := "Alacak"
 			if tx.Type == models.TradeTypePayable {
@@ -608,21 +539,10 @@ func ListTradePaymentsHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		txID := c.Params("id")
 		var tx models.TradeTransaction
-		if err := database.DB.First(&tx, "id = ?", txID).Error; err != nil {
+		if err := tenancy.DB(c).First(&tx, "id = ?", txID).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "İşlem bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != tx.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu işleme erişim yetkiniz yok")
-			}
-		}
-
 		var payments []models.TradePayment
 		if err := database.DB.Where("trade_transaction_id = ?", tx.ID).
 			Order("payment_date desc, id desc").
@@ -652,23 +572,18 @@ func DeleteTradePaymentHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		paymentID := c.Params("payment_id")
 		var payment models.TradePayment
-		if err := database.DB.First(&payment, "id = ?", paymentID).Error; err != nil {
+		if err := tenancy.DB(c).First(&payment, "id = ?", paymentID).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Ödeme bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != payment.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu ödemeye erişim yetkiniz yok")
-			}
+		// Kapalı dönemdeki ödeme silinemez
+		if err := period.EnsureOpen(payment.BranchID, payment.PaymentDate); err != nil {
+			return err
 		}
 
 		beforeData := map[string]interface{}{
-			"id":          payment.ID,
+			"id":                   payment.ID,
+			"trade_transaction_id": payment.TradeTransactionID,
 			"amount":      payment.Amount,
 			"payment_date": payment.PaymentDate.Format("2006-01-02"),
 			"description": payment.Description,
@@ -681,7 +596,7 @@ func DeleteTradePaymentHandler() fiber.Handler {
 		// Audit log
 		var tx models.TradeTransaction
 		database.DB.First(&tx, payment.TradeTransactionID)
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			typeLabel := "Alacak"
 			if tx.Type == models.TradeTypePayable {
@@ -5,9 +5,9 @@ import (
 	"strings"
 
 	"restoran-backend/internal/audit"
-	"restoran-backend/internal/auth"
 	"restoran-backend/internal/database"
 	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -59,7 +59,7 @@ func CreatePropertyHandler() fiber.Handler {
 			return fiber.NewError(fiber.StatusBadRequest, "değer 0'dan küçük olamaz")
 		}
 
-		branchID, err := resolveBranchIDFromBodyOrRole(c, body.BranchID)
+		branchID, err := tenancy.ResolveBranchID(c, body.BranchID)
 		if err != nil {
 			return err
 		}
@@ -76,7 +76,7 @@ func CreatePropertyHandler() fiber.Handler {
 		}
 
 		// Audit log yaz
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          property.ID,
@@ -116,7 +116,7 @@ func CreatePropertyHandler() fiber.Handler {
 // GET /api/properties?branch_id=...
 func ListPropertiesHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		branchID, err := resolveBranchIDFromQueryOrRole(c)
+		branchID, err := tenancy.ResolveBranchIDFromQuery(c)
 		if err != nil {
 			return err
 		}
@@ -150,21 +150,10 @@ func UpdatePropertyHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var property models.Property
-		if err := database.DB.First(&property, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&property, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Mal mülk bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != property.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu mal mülke erişim yetkiniz yok")
-			}
-		}
-
 		var body UpdatePropertyRequest
 		if err := c.BodyParser(&body); err != nil {
 			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz veri")
@@ -218,7 +207,7 @@ func UpdatePropertyHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			afterData := map[string]interface{}{
 				"id":          property.ID,
@@ -259,21 +248,10 @@ func DeletePropertyHandler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		var property models.Property
-		if err := database.DB.First(&property, "id = ?", id).Error; err != nil {
+		if err := tenancy.DB(c).First(&property, "id = ?", id).Error; err != nil {
 			return fiber.NewError(fiber.StatusNotFound, "Mal mülk bulunamadı")
 		}
 
-		// Şube kontrolü
-		roleVal := c.Locals(auth.CtxUserRoleKey)
-		role, ok := roleVal.(models.UserRole)
-		if ok && role == models.RoleBranchAdmin {
-			bVal := c.Locals(auth.CtxBranchIDKey)
-			bPtr, ok := bVal.(*uint)
-			if !ok || bPtr == nil || *bPtr != property.BranchID {
-				return fiber.NewError(fiber.StatusForbidden, "Bu mal mülke erişim yetkiniz yok")
-			}
-		}
-
 		beforeData := map[string]interface{}{
 			"id":          property.ID,
 			"name":        property.Name,
@@ -286,7 +264,7 @@ func DeletePropertyHandler() fiber.Handler {
 		}
 
 		// Audit log
-		userID, userName, _, err := getUserInfo(c)
+		userID, userName, err := tenancy.CurrentUser(c)
 		if err == nil {
 			branchIDForLog := &property.BranchID
 			if logErr := audit.WriteLog(audit.LogOptions{
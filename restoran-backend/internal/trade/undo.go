@@ -0,0 +1,27 @@
+package trade
+
+import (
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	audit.RegisterEntity("trade_transaction", audit.EntityConfig{
+		Model: &models.TradeTransaction{},
+		Children: []audit.ChildRelation{
+			{Key: "payments", Model: &models.TradePayment{}, ForeignKey: "trade_transaction_id", Restrict: true},
+		},
+		BranchColumn: "branch_id",
+		DateColumn:   "date",
+	})
+	audit.RegisterEntity("trade_payment", audit.EntityConfig{
+		Model:        &models.TradePayment{},
+		BranchColumn: "branch_id",
+		DateColumn:   "payment_date",
+	})
+	audit.RegisterEntity("property", audit.EntityConfig{
+		Model:        &models.Property{},
+		BranchColumn: "branch_id",
+	})
+}
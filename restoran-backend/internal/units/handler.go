@@ -0,0 +1,186 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+	"restoran-backend/internal/tenancy"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type UnitRequest struct {
+	Label  string  `json:"label"`  // "Koli"
+	Factor float64 `json:"factor"` // 1 Koli = factor temel birim
+}
+
+type SaveUnitsRequest struct {
+	Units []UnitRequest `json:"units"`
+}
+
+type UnitResponse struct {
+	Unit   string  `json:"unit"`
+	Label  string  `json:"label"`
+	Factor float64 `json:"factor"`
+}
+
+type ProductUnitsResponse struct {
+	ProductID   uint           `json:"product_id"`
+	ProductName string         `json:"product_name"`
+	BaseUnit    string         `json:"base_unit"`
+	Units       []UnitResponse `json:"units"`
+}
+
+// GET /api/products/:id/units, GET /api/produce-products/:id/units
+func ListUnitsHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz ürün ID")
+		}
+
+		name, baseUnit, err := productBaseUnit(database.DB, kind, uint(id))
+		if err != nil {
+			return err
+		}
+
+		var rows []models.ProductUnit
+		if err := database.DB.Where("kind = ? AND product_id = ?", kind, id).
+			Order("factor asc").Find(&rows).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Birimler listelenemedi")
+		}
+
+		return c.JSON(toProductUnitsResponse(uint(id), name, baseUnit, rows))
+	}
+}
+
+// PUT /api/admin/products/:id/units, PUT /api/produce-products/:id/units
+// Ürünün ek birimlerini verilen liste ile değiştirir (boş liste tümünü siler)
+func SaveUnitsHandler(kind string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz ürün ID")
+		}
+
+		var body SaveUnitsRequest
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Geçersiz istek gövdesi")
+		}
+
+		name, baseUnit, err := productBaseUnit(database.DB, kind, uint(id))
+		if err != nil {
+			return err
+		}
+		base := Normalize(baseUnit)
+
+		rows := make([]models.ProductUnit, 0, len(body.Units))
+		seen := make(map[string]bool, len(body.Units))
+		for _, u := range body.Units {
+			label := strings.TrimSpace(u.Label)
+			unit := Normalize(label)
+			if unit == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "Birim adı zorunlu")
+			}
+			if u.Factor <= 0 {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("'%s' için katsayı 0'dan büyük olmalı", label))
+			}
+			if unit == base {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("'%s' ürünün temel birimi, ayrıca tanımlanamaz", label))
+			}
+			if _, ok := standard[[2]string{unit, base}]; ok {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("'%s' -> '%s' dönüşümü sabit, ayrıca tanımlanamaz", label, baseUnit))
+			}
+			if _, ok := standard[[2]string{base, unit}]; ok {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("'%s' -> '%s' dönüşümü sabit, ayrıca tanımlanamaz", label, baseUnit))
+			}
+			if seen[unit] {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("'%s' birden fazla kez gönderildi", label))
+			}
+			seen[unit] = true
+			rows = append(rows, models.ProductUnit{
+				Kind:      kind,
+				ProductID: uint(id),
+				Unit:      unit,
+				Label:     label,
+				Factor:    u.Factor,
+			})
+		}
+
+		userID, userName, err := tenancy.CurrentUser(c)
+		if err != nil {
+			return err
+		}
+
+		var before []models.ProductUnit
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("kind = ? AND product_id = ?", kind, id).Find(&before).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Birimler okunamadı")
+			}
+			if err := tx.Where("kind = ? AND product_id = ?", kind, id).Delete(&models.ProductUnit{}).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Birimler silinemedi")
+			}
+			if len(rows) > 0 {
+				if err := tx.Create(&rows).Error; err != nil {
+					return fiber.NewError(fiber.StatusInternalServerError, "Birimler kaydedilemedi")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if logErr := audit.WriteLog(audit.LogOptions{
+			BranchID:    nil,
+			UserID:      userID,
+			UserName:    userName,
+			EntityType:  "product_unit",
+			EntityID:    uint(id),
+			Action:      models.AuditActionUpdate,
+			Description: fmt.Sprintf("Birimler güncellendi: %s (%d birim)", name, len(rows)),
+			Before:      before,
+			After:       rows,
+		}); logErr != nil {
+			fmt.Printf("Audit log yazılamadı: %v\n", logErr)
+		}
+
+		return c.JSON(toProductUnitsResponse(uint(id), name, baseUnit, rows))
+	}
+}
+
+func toProductUnitsResponse(productID uint, name, baseUnit string, rows []models.ProductUnit) ProductUnitsResponse {
+	resp := ProductUnitsResponse{
+		ProductID:   productID,
+		ProductName: name,
+		BaseUnit:    baseUnit,
+		Units:       make([]UnitResponse, 0, len(rows)),
+	}
+	for _, r := range rows {
+		resp.Units = append(resp.Units, UnitResponse{Unit: r.Unit, Label: r.Label, Factor: r.Factor})
+	}
+	return resp
+}
+
+// productBaseUnit: Ürün adı ve temel birimi
+func productBaseUnit(tx *gorm.DB, kind string, id uint) (string, string, error) {
+	switch kind {
+	case models.UnitKindProduct:
+		var p models.Product
+		if err := tx.First(&p, "id = ?", id).Error; err != nil {
+			return "", "", fiber.NewError(fiber.StatusNotFound, "Ürün bulunamadı")
+		}
+		return p.Name, p.Unit, nil
+	case models.UnitKindProduce:
+		var p models.ProduceProduct
+		if err := tx.First(&p, "id = ?", id).Error; err != nil {
+			return "", "", fiber.NewError(fiber.StatusNotFound, "Manav ürünü bulunamadı")
+		}
+		return p.Name, p.Unit, nil
+	}
+	return "", "", fiber.NewError(fiber.StatusBadRequest, "Geçersiz ürün türü")
+}
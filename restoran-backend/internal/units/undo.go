@@ -0,0 +1,60 @@
+package units
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Audit log'a yazılan entity'lerin undo kayıtları
+func init() {
+	// Log ürünün birim listesini tutar (EntityID ürün ID'si); liste önceki haliyle değiştirilir
+	audit.RegisterEntity("product_unit", audit.EntityConfig{
+		Undo: restoreUnits,
+	})
+}
+
+func restoreUnits(tx *gorm.DB, log *models.AuditLog) error {
+	if log.Action != models.AuditActionUpdate {
+		return fmt.Errorf("bu işlem türü geri alınamaz")
+	}
+	var before, after []models.ProductUnit
+	if err := json.Unmarshal([]byte(log.BeforeData), &before); err != nil {
+		return fmt.Errorf("log verisi okunamadı: %w", err)
+	}
+	if err := json.Unmarshal([]byte(log.AfterData), &after); err != nil {
+		return fmt.Errorf("log verisi okunamadı: %w", err)
+	}
+
+	// Birim türü (merkez / manav ürünü) log'daki satırlardan okunur; iki liste de boşsa değişiklik yoktur
+	var kind string
+	for _, rows := range [][]models.ProductUnit{before, after} {
+		if len(rows) > 0 {
+			kind = rows[0].Kind
+			break
+		}
+	}
+	if kind == "" {
+		return nil
+	}
+	if _, _, err := productBaseUnit(tx, kind, log.EntityID); err != nil {
+		return fmt.Errorf("ürün bulunamadı, birimler geri yüklenemez")
+	}
+
+	if err := tx.Where("kind = ? AND product_id = ?", kind, log.EntityID).Delete(&models.ProductUnit{}).Error; err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		return nil
+	}
+	for i := range before {
+		before[i].ID = 0
+		before[i].Kind = kind
+		before[i].ProductID = log.EntityID
+	}
+	return tx.Create(&before).Error
+}
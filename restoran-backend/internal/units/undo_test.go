@@ -0,0 +1,72 @@
+package units
+
+import (
+	"testing"
+
+	"restoran-backend/internal/audit"
+	"restoran-backend/internal/database"
+	"restoran-backend/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Birim listesinin değiştirilmesi geri alınınca önceki birimler geri gelir, diğer türün birimleri korunur
+func TestUndoRestoresPreviousUnits(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("veritabanı açılamadı: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&models.Product{}, &models.ProductUnit{}, &models.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+
+	product := models.Product{Name: "Domates", Unit: "kg"}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+	before := []models.ProductUnit{{Kind: models.UnitKindProduct, ProductID: product.ID, Unit: "koli", Label: "Koli", Factor: 12}}
+	after := []models.ProductUnit{{Kind: models.UnitKindProduct, ProductID: product.ID, Unit: "kasa", Label: "Kasa", Factor: 20}}
+	produce := models.ProductUnit{Kind: models.UnitKindProduce, ProductID: product.ID, Unit: "demet", Label: "Demet", Factor: 0.25}
+	if err := db.Create(&after).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&produce).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.WriteLog(audit.LogOptions{
+		EntityType: "product_unit",
+		EntityID:   product.ID,
+		Action:     models.AuditActionUpdate,
+		Before:     before,
+		After:      after,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var log models.AuditLog
+	if err := db.Last(&log).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := audit.UndoLog(log.ID, 1, "test"); err != nil {
+		t.Fatalf("geri alınamadı: %v", err)
+	}
+
+	var got []models.ProductUnit
+	if err := db.Where("product_id = ?", product.ID).Order("kind, unit").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Unit != "demet" || got[1].Unit != "koli" || got[1].Factor != 12 {
+		t.Errorf("birimler = %+v, manav 'demet' ve merkez 'koli' (12) bekleniyordu", got)
+	}
+}
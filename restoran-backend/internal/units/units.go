@@ -0,0 +1,146 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"restoran-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// aliases: Faturalarda / kullanıcı girişinde görülen birim yazımlarının standart karşılıkları
+var aliases = map[string]string{
+	"kg":        "kg",
+	"kilo":      "kg",
+	"kilogram":  "kg",
+	"kgm":       "kg",
+	"gr":        "gr",
+	"g":         "gr",
+	"gram":      "gr",
+	"grm":       "gr",
+	"lt":        "lt",
+	"l":         "lt",
+	"litre":     "lt",
+	"ltr":       "lt",
+	"ml":        "ml",
+	"mililitre": "ml",
+	"mlt":       "ml",
+	"adet":      "adet",
+	"ad":        "adet",
+	"c62":       "adet",
+	"niu":       "adet",
+	"paket":     "paket",
+	"pk":        "paket",
+	"pkt":       "paket",
+	"koli":      "koli",
+	"kl":        "koli",
+	"kutu":      "kutu",
+}
+
+// standard: Ürüne bağlı olmayan sabit dönüşümler (1 birim = kaç hedef birim)
+var standard = map[[2]string]float64{
+	{"kg", "gr"}: 1000,
+	{"lt", "ml"}: 1000,
+}
+
+// UnknownUnitError: Ürün için tanımlı olmayan birim
+type UnknownUnitError struct {
+	Unit     string
+	BaseUnit string
+}
+
+func (e *UnknownUnitError) Error() string {
+	return fmt.Sprintf("'%s' birimi tanımlı değil (temel birim: %s), ürün birimlerine dönüşüm ekleyin", e.Unit, e.BaseUnit)
+}
+
+// Normalize: Birim adını karşılaştırma için standart hale getirir ("Kilogram", "KG" -> "kg", "KOLİ" -> "koli")
+func Normalize(unit string) string {
+	u := strings.NewReplacer("İ", "i", "I", "i").Replace(strings.TrimSpace(unit))
+	u = strings.ReplaceAll(strings.ToLower(u), "ı", "i")
+	u = strings.TrimSuffix(u, ".")
+	if a, ok := aliases[u]; ok {
+		return a
+	}
+	return u
+}
+
+// Factor: Girilen birimin kaç temel birim ettiği. Boş birim temel birim sayılır.
+// Önce sabit dönüşümlere (kg/gr, lt/ml), sonra ürüne tanımlı birimlere bakılır.
+func Factor(tx *gorm.DB, kind string, productID uint, baseUnit, unit string) (float64, error) {
+	u := Normalize(unit)
+	base := Normalize(baseUnit)
+	if u == "" || u == base {
+		return 1, nil
+	}
+	if f, ok := standard[[2]string{u, base}]; ok {
+		return f, nil
+	}
+	if f, ok := standard[[2]string{base, u}]; ok {
+		return 1 / f, nil
+	}
+
+	var pu models.ProductUnit
+	err := tx.Where("kind = ? AND product_id = ? AND unit = ?", kind, productID, u).First(&pu).Error
+	if err == nil {
+		return pu.Factor, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	return 0, &UnknownUnitError{Unit: unit, BaseUnit: baseUnit}
+}
+
+// ToBase: Girilen miktarı ürünün temel birimine çevirir (temel miktar, katsayı).
+// Tanımsız birim 400, diğer hatalar 500 olarak döner.
+func ToBase(tx *gorm.DB, kind string, productID uint, productName, baseUnit, unit string, quantity float64) (float64, float64, error) {
+	factor, err := Factor(tx, kind, productID, baseUnit, unit)
+	if err != nil {
+		var unknown *UnknownUnitError
+		if errors.As(err, &unknown) {
+			return 0, 0, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s: %v", productName, err))
+		}
+		return 0, 0, fiber.NewError(fiber.StatusInternalServerError, "Birim dönüşümü okunamadı")
+	}
+	return quantity * factor, factor, nil
+}
+
+// ToBaseIfKnown: ToBase gibi, fakat birim tanımsızsa miktar olduğu gibi temel birimde sayılır
+// (faturadaki birimi ürünün birimlerinden farklı gelen sevkiyatlar: B2B'de Paket/Koli, üründe adet)
+func ToBaseIfKnown(tx *gorm.DB, kind string, productID uint, baseUnit, unit string, quantity float64) (float64, float64, error) {
+	factor, err := Factor(tx, kind, productID, baseUnit, unit)
+	if err != nil {
+		var unknown *UnknownUnitError
+		if errors.As(err, &unknown) {
+			return quantity, 1, nil
+		}
+		return 0, 0, fiber.NewError(fiber.StatusInternalServerError, "Birim dönüşümü okunamadı")
+	}
+	return quantity * factor, factor, nil
+}
+
+// EnteredUnit: Kayıtta saklanacak birim adı (boşsa temel birim)
+func EnteredUnit(baseUnit, unit string) string {
+	if u := strings.TrimSpace(unit); u != "" {
+		return u
+	}
+	return baseUnit
+}
+
+// EnsureBaseUnitChange: Ek birimleri tanımlı ürünün temel birimi değiştirilemez
+// (katsayılar eski temel birime göre girildiği için anlamsız kalır)
+func EnsureBaseUnitChange(tx *gorm.DB, kind string, productID uint, oldUnit, newUnit string) error {
+	if Normalize(oldUnit) == Normalize(newUnit) {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.ProductUnit{}).Where("kind = ? AND product_id = ?", kind, productID).Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Ürün birimleri okunamadı")
+	}
+	if count > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Ek birimleri tanımlı ürünün temel birimi değiştirilemez, önce birimleri kaldırın")
+	}
+	return nil
+}